@@ -47,6 +47,7 @@ import (
 type captureOpts struct {
 	flushCheckpointInterval time.Duration
 	captureSessionTTL       int
+	labels                  map[string]string
 }
 
 // Capture represents a Capture server, it monitors the changefeed information in etcd and schedules Task on it.
@@ -124,6 +125,7 @@ func NewCapture(
 		ID:            id,
 		AdvertiseAddr: advertiseAddr,
 		Version:       version.ReleaseVersion,
+		Labels:        opts.labels,
 	}
 	processorManager := processor.NewManager(pdCli, credential, info)
 	log.Info("creating capture", zap.String("capture-id", id), util.ZapFieldCapture(ctx))
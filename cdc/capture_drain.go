@@ -0,0 +1,179 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"sort"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/scheduler"
+	"go.uber.org/zap"
+)
+
+// schedulableCaptures returns the captures which can be dispatched tables to,
+// the draining captures are excluded unless all the captures are draining.
+func schedulableCaptures(
+	captures map[model.CaptureID]*model.CaptureInfo, drainingCaptures map[model.CaptureID]struct{},
+) map[model.CaptureID]*model.CaptureInfo {
+	if len(drainingCaptures) == 0 {
+		return captures
+	}
+	schedulable := make(map[model.CaptureID]*model.CaptureInfo, len(captures))
+	for captureID, info := range captures {
+		if _, exist := drainingCaptures[captureID]; !exist {
+			schedulable[captureID] = info
+		}
+	}
+	if len(schedulable) == 0 {
+		return captures
+	}
+	return schedulable
+}
+
+// drainCaptures creates the move table jobs which move all the tables off the
+// draining captures, the tables are moved to the schedulable captures with the
+// fewest tables allowed by the placement rules.
+func (c *changeFeed) drainCaptures(
+	schedulable map[model.CaptureID]*model.CaptureInfo, drainingCaptures map[model.CaptureID]struct{},
+) {
+	c.cleanDrainedTables()
+	if len(drainingCaptures) == 0 || len(c.moveTableJobs) != 0 {
+		return
+	}
+	for _, status := range c.taskStatus {
+		if status.SomeOperationsUnapplied() {
+			return
+		}
+	}
+	tableNums := make(map[model.CaptureID]int, len(schedulable))
+	for captureID := range schedulable {
+		if _, exist := drainingCaptures[captureID]; exist {
+			continue
+		}
+		tableNums[captureID] = 0
+		if status, exist := c.taskStatus[captureID]; exist {
+			tableNums[captureID] = len(status.Tables)
+		}
+	}
+	if len(tableNums) == 0 {
+		log.Warn("no capture can take over the tables of the draining captures",
+			zap.String("changefeed", c.id), zap.Reflect("drainingCaptures", drainingCaptures))
+		return
+	}
+	targets := make([]model.CaptureID, 0, len(tableNums))
+	for captureID := range tableNums {
+		targets = append(targets, captureID)
+	}
+	sort.Strings(targets)
+
+	for captureID := range drainingCaptures {
+		status, exist := c.taskStatus[captureID]
+		if !exist || len(status.Tables) == 0 {
+			continue
+		}
+		tableIDs := make([]model.TableID, 0, len(status.Tables))
+		for tableID := range status.Tables {
+			tableIDs = append(tableIDs, tableID)
+		}
+		sort.Slice(tableIDs, func(i, j int) bool { return tableIDs[i] < tableIDs[j] })
+		for _, tableID := range tableIDs {
+			target := selectDrainTarget(targets, tableNums, tableID, c.placement)
+			tableNums[target]++
+			if c.moveTableJobs == nil {
+				c.moveTableJobs = make(map[model.TableID]*model.MoveTableJob)
+			}
+			c.moveTableJobs[tableID] = &model.MoveTableJob{
+				From:    captureID,
+				To:      target,
+				TableID: tableID,
+			}
+			if c.drainedTables == nil {
+				c.drainedTables = make(map[model.TableID]model.CaptureID)
+			}
+			c.drainedTables[tableID] = captureID
+		}
+		log.Info("move the tables off the draining capture", zap.String("changefeed", c.id),
+			zap.String("capture-id", captureID), zap.Int("tableNum", len(tableIDs)))
+	}
+}
+
+// selectDrainTarget selects the capture with the fewest tables in the targets
+// which is allowed to replicate the table, all the targets are considered if
+// no capture is allowed.
+func selectDrainTarget(
+	targets []model.CaptureID, tableNums map[model.CaptureID]int, tableID model.TableID, placement scheduler.Placement,
+) model.CaptureID {
+	target := model.CaptureID("")
+	for _, id := range targets {
+		if allowedBy(placement, tableID, id) && (target == "" || tableNums[id] < tableNums[target]) {
+			target = id
+		}
+	}
+	if target != "" {
+		return target
+	}
+	for _, id := range targets {
+		if target == "" || tableNums[id] < tableNums[target] {
+			target = id
+		}
+	}
+	return target
+}
+
+// cleanDrainedTables forgets the tables moved off the draining captures which
+// have been replicated by other captures.
+func (c *changeFeed) cleanDrainedTables() {
+	for tableID, from := range c.drainedTables {
+		if _, exist := c.moveTableJobs[tableID]; exist {
+			continue
+		}
+		if _, exist := c.orphanTables[tableID]; exist {
+			continue
+		}
+		captureID, status, exist := findTaskStatusWithTable(c.taskStatus, tableID)
+		if exist && captureID == from {
+			continue
+		}
+		if exist {
+			if op, ok := status.Operation[tableID]; ok && !op.TableApplied() {
+				continue
+			}
+		}
+		// the table is replicated by another capture, or it has been dropped
+		delete(c.drainedTables, tableID)
+	}
+}
+
+// drainingTableNum returns the number of the tables which are not replicated
+// by other captures yet in the tables of the draining capture.
+func (c *changeFeed) drainingTableNum(captureID model.CaptureID) int {
+	num := 0
+	if status, exist := c.taskStatus[captureID]; exist {
+		num = len(status.Tables)
+	}
+	for tableID, from := range c.drainedTables {
+		if from != captureID {
+			continue
+		}
+		if status, exist := c.taskStatus[captureID]; exist {
+			if _, exist := status.Tables[tableID]; exist {
+				// the table has been counted
+				continue
+			}
+		}
+		num++
+	}
+	return num
+}
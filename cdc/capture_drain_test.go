@@ -0,0 +1,121 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type captureDrainSuite struct{}
+
+var _ = check.Suite(&captureDrainSuite{})
+
+func newTaskStatusWithTables(tableIDs ...model.TableID) *model.TaskStatus {
+	status := &model.TaskStatus{Tables: make(map[model.TableID]*model.TableReplicaInfo)}
+	for _, tableID := range tableIDs {
+		status.Tables[tableID] = &model.TableReplicaInfo{}
+	}
+	return status
+}
+
+func (s *captureDrainSuite) TestSchedulableCaptures(c *check.C) {
+	defer testleak.AfterTest(c)()
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1"},
+		"capture-2": {ID: "capture-2"},
+	}
+	c.Assert(schedulableCaptures(captures, nil), check.DeepEquals, captures)
+	c.Assert(schedulableCaptures(captures, map[model.CaptureID]struct{}{"capture-1": {}}), check.DeepEquals,
+		map[model.CaptureID]*model.CaptureInfo{"capture-2": {ID: "capture-2"}})
+	// all the captures are draining
+	c.Assert(schedulableCaptures(captures, map[model.CaptureID]struct{}{"capture-1": {}, "capture-2": {}}),
+		check.DeepEquals, captures)
+}
+
+func (s *captureDrainSuite) TestDrainCaptures(c *check.C) {
+	defer testleak.AfterTest(c)()
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1"},
+		"capture-2": {ID: "capture-2"},
+		"capture-3": {ID: "capture-3"},
+	}
+	draining := map[model.CaptureID]struct{}{"capture-1": {}}
+	cf := &changeFeed{
+		id:           "test",
+		orphanTables: make(map[model.TableID]model.Ts),
+		taskStatus: model.ProcessorsInfos{
+			"capture-1": newTaskStatusWithTables(1, 2, 3),
+			"capture-2": newTaskStatusWithTables(10),
+		},
+	}
+	schedulable := schedulableCaptures(captures, draining)
+
+	// no table is moved if some operations are not applied
+	cf.taskStatus["capture-2"].Operation = map[model.TableID]*model.TableOperation{10: {}}
+	cf.drainCaptures(schedulable, draining)
+	c.Assert(cf.moveTableJobs, check.HasLen, 0)
+	c.Assert(cf.drainingTableNum("capture-1"), check.Equals, 3)
+
+	cf.taskStatus["capture-2"].Operation[10].Status = model.OperFinished
+	cf.drainCaptures(schedulable, draining)
+	c.Assert(cf.moveTableJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture-1", To: "capture-3", TableID: 1},
+		2: {From: "capture-1", To: "capture-2", TableID: 2},
+		3: {From: "capture-1", To: "capture-3", TableID: 3},
+	})
+	c.Assert(cf.drainingTableNum("capture-1"), check.Equals, 3)
+	c.Assert(cf.drainingTableNum("capture-2"), check.Equals, 1)
+
+	// no more jobs are created before the move jobs are finished
+	cf.drainCaptures(schedulable, draining)
+	c.Assert(cf.moveTableJobs, check.HasLen, 3)
+
+	// the tables are added to the target captures, but not applied yet
+	cf.moveTableJobs = nil
+	cf.taskStatus["capture-1"] = newTaskStatusWithTables()
+	cf.taskStatus["capture-2"] = newTaskStatusWithTables(2, 10)
+	cf.taskStatus["capture-3"] = newTaskStatusWithTables(1, 3)
+	cf.taskStatus["capture-3"].Operation = map[model.TableID]*model.TableOperation{
+		1: {Status: model.OperFinished},
+		3: {Status: model.OperProcessed},
+	}
+	cf.drainCaptures(schedulable, draining)
+	c.Assert(cf.moveTableJobs, check.HasLen, 0)
+	c.Assert(cf.drainingTableNum("capture-1"), check.Equals, 1)
+
+	cf.taskStatus["capture-3"].Operation[3].Status = model.OperFinished
+	cf.drainCaptures(schedulable, draining)
+	c.Assert(cf.drainingTableNum("capture-1"), check.Equals, 0)
+	c.Assert(cf.drainedTables, check.HasLen, 0)
+}
+
+func (s *captureDrainSuite) TestDrainCapturesWithoutTarget(c *check.C) {
+	defer testleak.AfterTest(c)()
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1"},
+	}
+	draining := map[model.CaptureID]struct{}{"capture-1": {}}
+	cf := &changeFeed{
+		id:           "test",
+		orphanTables: make(map[model.TableID]model.Ts),
+		taskStatus: model.ProcessorsInfos{
+			"capture-1": newTaskStatusWithTables(1),
+		},
+	}
+	cf.drainCaptures(schedulableCaptures(captures, draining), draining)
+	c.Assert(cf.moveTableJobs, check.HasLen, 0)
+	c.Assert(cf.drainingTableNum("capture-1"), check.Equals, 1)
+}
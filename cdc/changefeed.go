@@ -27,12 +27,16 @@ import (
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/scheduler"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	pd "github.com/tikv/pd/client"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/mvcc/mvccpb"
 	"go.uber.org/zap"
@@ -109,6 +113,11 @@ type changeFeed struct {
 	filter           *filter.Filter
 	sink             sink.Sink
 	scheduler        scheduler.Scheduler
+	// syncpointBarrierTs is the next syncpoint if the sync-point-barrier is
+	// on, the global resolved ts never exceeds it until it's recorded.
+	syncpointBarrierTs uint64
+	// redoWriter is nil if the redo log is disabled
+	redoWriter *redo.OwnerWriter
 
 	cyclicEnabled bool
 
@@ -126,11 +135,26 @@ type changeFeed struct {
 	moveTableJobs      map[model.TableID]*model.MoveTableJob
 	manualMoveCommands []*model.MoveTableJob
 	rebalanceNextTick  bool
+	// drainedTables records the tables moved off the draining captures which
+	// are not replicated by other captures yet, the values are the draining
+	// captures
+	drainedTables map[model.TableID]model.CaptureID
+
+	placementRules []*placementRule
+	// placement is nil if there is no placement rule
+	placement scheduler.Placement
+	// captureLabelsUnmatched is true if no capture has the capture labels
+	captureLabelsUnmatched bool
+
+	// subTables records the spans of the sub tables of the split tables, it's
+	// keyed by the physical table IDs
+	subTables map[model.TableID]map[model.TableID]regionspan.Span
 
 	lastRebalanceTime time.Time
 
-	etcdCli kv.CDCEtcdClient
-	leaseID clientv3.LeaseID
+	etcdCli  kv.CDCEtcdClient
+	leaseID  clientv3.LeaseID
+	pdClient pd.Client
 
 	// context cancel function for all internal goroutines
 	cancel context.CancelFunc
@@ -213,21 +237,13 @@ func (c *changeFeed) removeTable(sid model.SchemaID, tid model.TableID, targetTs
 	}
 	delete(c.tables, tid)
 
-	removeFunc := func(id int64) {
-		if _, ok := c.orphanTables[id]; ok {
-			delete(c.orphanTables, id)
-		} else {
-			c.toCleanTables[id] = targetTs
-		}
-	}
-
 	if pids, ok := c.partitions[tid]; ok {
 		for _, id := range pids {
-			removeFunc(id)
+			c.removeTableOrSubTables(id, targetTs)
 		}
 		delete(c.partitions, tid)
 	} else {
-		removeFunc(tid)
+		c.removeTableOrSubTables(tid, targetTs)
 	}
 }
 
@@ -250,7 +266,7 @@ func (c *changeFeed) updatePartition(tblInfo *timodel.TableInfo, startTs uint64)
 	for _, partition := range pi.Definitions {
 		pid := partition.ID
 		_, ok := c.orphanTables[pid]
-		if !ok {
+		if _, split := c.subTables[pid]; !ok && !split {
 			// new partition.
 			c.orphanTables[pid] = startTs
 		}
@@ -262,17 +278,39 @@ func (c *changeFeed) updatePartition(tblInfo *timodel.TableInfo, startTs uint64)
 
 	// drop partition.
 	for pid := range oldIDs {
-		if _, ok := c.orphanTables[pid]; ok {
-			delete(c.orphanTables, pid)
+		c.removeTableOrSubTables(pid, startTs)
+	}
+}
+
+// removeTableOrSubTables removes a physical table, or all the sub tables of it
+// if it's split.
+func (c *changeFeed) removeTableOrSubTables(tableID model.TableID, targetTs model.Ts) {
+	removeFunc := func(id model.TableID) {
+		if _, ok := c.orphanTables[id]; ok {
+			delete(c.orphanTables, id)
 		} else {
-			c.toCleanTables[pid] = startTs
+			c.toCleanTables[id] = targetTs
 		}
 	}
+	subTables, ok := c.subTables[tableID]
+	if !ok {
+		removeFunc(tableID)
+		return
+	}
+	for subTableID := range subTables {
+		removeFunc(subTableID)
+	}
+	delete(c.subTables, tableID)
 }
 
-func (c *changeFeed) tryBalance(ctx context.Context, captures map[string]*model.CaptureInfo, rebalanceNow bool,
-	manualMoveCommands []*model.MoveTableJob) error {
-	err := c.balanceOrphanTables(ctx, captures)
+func (c *changeFeed) tryBalance(ctx context.Context, captures map[string]*model.CaptureInfo,
+	drainingCaptures map[model.CaptureID]struct{}, rebalanceNow bool, manualMoveCommands []*model.MoveTableJob) error {
+	// no table is dispatched to the draining captures and the captures not
+	// having the capture labels
+	excluded := c.excludedCaptures(captures, drainingCaptures)
+	schedulable := schedulableCaptures(captures, excluded)
+	c.updatePlacement(schedulable)
+	err := c.balanceOrphanTables(ctx, schedulable)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -280,11 +318,12 @@ func (c *changeFeed) tryBalance(ctx context.Context, captures map[string]*model.
 	if rebalanceNow {
 		c.rebalanceNextTick = true
 	}
-	err = c.handleManualMoveTableJobs(ctx, captures)
+	err = c.handleManualMoveTableJobs(ctx, schedulable)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	err = c.rebalanceTables(ctx, captures)
+	c.drainCaptures(schedulable, excluded)
+	err = c.rebalanceTables(ctx, schedulable)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -339,12 +378,13 @@ func (c *changeFeed) balanceOrphanTables(ctx context.Context, captures map[model
 		cleanedTables[id] = struct{}{}
 	}
 
+	c.splitOrphanTables(ctx, len(captures))
 	operations := c.scheduler.DistributeTables(c.orphanTables)
 	for captureID, operation := range operations {
 		schemaSnapshot := c.schema
 		for tableID, op := range operation {
 			var orphanMarkTableID model.TableID
-			tableName, found := schemaSnapshot.GetTableNameByID(tableID)
+			tableName, found := schemaSnapshot.GetTableNameByID(model.PhysicalTableID(tableID))
 			if !found {
 				log.Warn("balance orphan tables delay, table not found",
 					zap.String("changefeed", c.id),
@@ -367,6 +407,9 @@ func (c *changeFeed) balanceOrphanTables(ctx context.Context, captures map[model
 				StartTs:     op.BoundaryTs,
 				MarkTableID: orphanMarkTableID,
 			}
+			if span, ok := c.subTableSpan(tableID); ok {
+				info.Span = &span
+			}
 			tableID := tableID
 			op := op
 			updateFuncs[captureID] = append(updateFuncs[captureID], func(_ int64, status *model.TaskStatus) (bool, error) {
@@ -455,6 +498,10 @@ func (c *changeFeed) handleManualMoveTableJobs(ctx context.Context, captures map
 			log.Warn("invalid manual move job, the target capture is not found", zap.Reflect("job", moveJob))
 			continue
 		}
+		if !allowedBy(c.placement, moveJob.TableID, moveJob.To) {
+			log.Warn("invalid manual move job, the target capture is not allowed by the placement rules", zap.Reflect("job", moveJob))
+			continue
+		}
 		if c.moveTableJobs == nil {
 			c.moveTableJobs = make(map[model.TableID]*model.MoveTableJob)
 		}
@@ -641,6 +688,20 @@ func (c *changeFeed) applyJob(ctx context.Context, job *timodel.Job) (skip bool,
 	return false, err
 }
 
+// verifyTableColumns checks the column rules against the table changed by the
+// DDL job, a DDL adding a table or changing the handle key of a table may make
+// a column rule drop a handle key column.
+func (c *changeFeed) verifyTableColumns(job *timodel.Job) error {
+	if job.BinlogInfo == nil || job.BinlogInfo.TableInfo == nil {
+		return nil
+	}
+	tableInfo, exist := c.schema.TableByID(job.BinlogInfo.TableInfo.ID)
+	if !exist || c.filter.ShouldIgnoreTable(tableInfo.TableName.Schema, tableInfo.TableName.Table) {
+		return nil
+	}
+	return c.filter.VerifyTableColumns(tableInfo)
+}
+
 // handleDDL check if we can change the status to be `ChangeFeedExecDDL` and execute the DDL asynchronously
 // if the status is in ChangeFeedWaitToExecDDL.
 // After executing the DDL successfully, the status will be changed to be ChangeFeedSyncDML.
@@ -688,6 +749,10 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	if err != nil {
 		return errors.Trace(err)
 	}
+	err = c.verifyTableColumns(todoDDLJob)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	err = c.schema.FillSchemaName(todoDDLJob)
 	if err != nil {
 		return errors.Trace(err)
@@ -719,6 +784,11 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 
 		ddlEvent.Query = binloginfo.AddSpecialComment(ddlEvent.Query)
 		log.Debug("DDL processed to make special features mysql-compatible", zap.String("query", ddlEvent.Query))
+		if c.redoWriter != nil && !c.filter.ShouldIgnoreDDLEvent(ddlEvent.StartTs, ddlEvent.Type, ddlEvent.TableInfo.Schema, ddlEvent.TableInfo.Table) {
+			if err := c.redoWriter.EmitDDLEvent(ctx, ddlEvent); err != nil {
+				return errors.Trace(err)
+			}
+		}
 		err = c.sink.EmitDDLEvent(ctx, ddlEvent)
 		// If DDL executing failed, pause the changefeed and print log, rather
 		// than return an error and break the running of this owner.
@@ -749,6 +819,9 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 
 // handleSyncPoint record every syncpoint to downstream if the syncpoint feature is enable
 func (c *changeFeed) handleSyncPoint(ctx context.Context) error {
+	if c.info.SyncPointEnabled && c.info.SyncPointBarrier {
+		return c.handleSyncPointBarrier(ctx)
+	}
 	// sync-point on
 	if c.info.SyncPointEnabled {
 		c.syncpointMutex.Lock()
@@ -791,6 +864,45 @@ func (c *changeFeed) handleSyncPoint(ctx context.Context) error {
 	return nil
 }
 
+// handleSyncPointBarrier records the syncpoint once all the processors flush to
+// the syncpoint barrier, and then moves the barrier to the next syncpoint.
+func (c *changeFeed) handleSyncPointBarrier(ctx context.Context) error {
+	if c.syncpointBarrierTs == 0 || c.status.CheckpointTs < c.syncpointBarrierTs {
+		return nil
+	}
+	if c.status.CheckpointTs > c.syncpointBarrierTs {
+		// the global resolved ts has exceeded the barrier before the barrier
+		// is set, e.g. the owner is restarted, so the syncpoint is skipped
+		log.Warn("syncpoint is skipped because the checkpoint ts has exceeded it",
+			zap.String("changefeed", c.id),
+			zap.Uint64("syncpointTs", c.syncpointBarrierTs),
+			zap.Uint64("checkpointTs", c.status.CheckpointTs))
+	} else {
+		log.Info("sync point reached by barrier",
+			zap.String("changefeed", c.id),
+			zap.Uint64("syncpointTs", c.syncpointBarrierTs))
+		err := c.syncpointStore.SinkSyncpoint(ctx, c.id, c.syncpointBarrierTs)
+		if err != nil {
+			log.Error("syncpoint sink fail", zap.Uint64("syncpointTs", c.syncpointBarrierTs), zap.Error(err))
+			return err
+		}
+	}
+	c.syncpointBarrierTs = nextSyncpointTs(c.status.CheckpointTs, c.info.SyncPointInterval)
+	return nil
+}
+
+// nextSyncpointTs returns the first syncpoint after ts, the syncpoints are the
+// ts whose physical time is a multiple of the interval, so the same syncpoints
+// are chosen after the owner is restarted.
+func nextSyncpointTs(ts uint64, interval time.Duration) uint64 {
+	intervalMs := interval.Milliseconds()
+	if intervalMs <= 0 {
+		intervalMs = 1
+	}
+	physical := oracle.ExtractPhysical(ts)
+	return oracle.ComposeTS((physical/intervalMs+1)*intervalMs, 0)
+}
+
 // calcResolvedTs update every changefeed's resolve ts and checkpoint ts.
 func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 	if c.ddlState != model.ChangeFeedSyncDML && c.ddlState != model.ChangeFeedWaitToExecDDL {
@@ -894,6 +1006,19 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 	}
 	checkUpdateTs()
 
+	// the global resolved ts stops at the syncpoint barrier until the
+	// syncpoint is recorded
+	if c.info.SyncPointEnabled && c.info.SyncPointBarrier {
+		if c.syncpointBarrierTs == 0 {
+			// the checkpoint ts may be a syncpoint which is not recorded yet
+			c.syncpointBarrierTs = nextSyncpointTs(c.status.CheckpointTs-1, c.info.SyncPointInterval)
+		}
+		if minResolvedTs > c.syncpointBarrierTs {
+			minResolvedTs = c.syncpointBarrierTs
+		}
+	}
+	checkUpdateTs()
+
 	// if minResolvedTs is greater than the finishedTS of ddl job which is not executed,
 	// we need to execute this ddl job
 	for len(c.ddlJobHistory) > 0 && c.ddlJobHistory[0].BinlogInfo.FinishedTS <= c.ddlExecutedTs {
@@ -909,6 +1034,22 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 		minCheckpointTs = c.ddlJobHistory[0].BinlogInfo.FinishedTS - 1
 	}
 
+	// the global resolved ts never exceeds the resolved ts in the meta of the
+	// redo log, so all the events applied to the downstream can be recovered
+	if c.redoWriter != nil {
+		metaResolvedTs := minResolvedTs
+		// the DDL waiting to be executed is not written to the redo log yet
+		if c.ddlState == model.ChangeFeedWaitToExecDDL && metaResolvedTs >= c.ddlTs {
+			metaResolvedTs = c.ddlTs - 1
+		}
+		if err := c.redoWriter.FlushMeta(ctx, c.status.CheckpointTs, metaResolvedTs); err != nil {
+			return errors.Trace(err)
+		}
+		if minResolvedTs > c.redoWriter.ResolvedTs() {
+			minResolvedTs = c.redoWriter.ResolvedTs()
+		}
+	}
+
 	// if downstream sink is the MQ sink, the MQ sink do not promise that checkpoint is less than globalResolvedTs
 	if minCheckpointTs > minResolvedTs {
 		minCheckpointTs = minResolvedTs
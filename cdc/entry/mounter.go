@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/table"
@@ -136,10 +137,12 @@ type mounterImpl struct {
 	tz               *time.Location
 	workerNum        int
 	enableOldValue   bool
+	filter           *filter.Filter
 }
 
-// NewMounter creates a mounter
-func NewMounter(schemaStorage SchemaStorage, workerNum int, enableOldValue bool) Mounter {
+// NewMounter creates a mounter, the DML events dropped by the event filters of
+// the filter are discarded in the mounter. The filter can be nil.
+func NewMounter(schemaStorage SchemaStorage, workerNum int, enableOldValue bool, filter *filter.Filter) Mounter {
 	if workerNum <= 0 {
 		workerNum = defaultMounterWorkerNum
 	}
@@ -152,6 +155,7 @@ func NewMounter(schemaStorage SchemaStorage, workerNum int, enableOldValue bool)
 		rawRowChangedChs: chs,
 		workerNum:        workerNum,
 		enableOldValue:   enableOldValue,
+		filter:           filter,
 	}
 }
 
@@ -280,6 +284,11 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 		}
 		return nil, nil
 	}()
+	if err == nil && row != nil && m.filter != nil {
+		// the columns excluded by the column rules are dropped here, before
+		// the rows reach the redo log and the sinks
+		err = m.filter.FilterColumns(row)
+	}
 	if err != nil {
 		log.Error("failed to mount and unmarshals entry, start to print debug info", zap.Error(err))
 		snap.PrintStatus(log.Error)
@@ -451,6 +460,23 @@ func (m *mounterImpl) mountRowKVEntry(tableInfo *model.TableInfo, row *rowKVEntr
 		return nil, nil
 	}
 
+	if m.filter != nil {
+		var preDatums, datums map[int64]types.Datum
+		if row.PreRowExist {
+			preDatums = row.PreRow
+		}
+		if row.RowExist {
+			datums = row.Row
+		}
+		skip, err := m.filter.ShouldSkipDMLEvent(tableInfo, preDatums, datums)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if skip {
+			return nil, nil
+		}
+	}
+
 	var err error
 	// Decode previous columns.
 	var preCols []*model.Column
@@ -258,7 +258,7 @@ func testMounterDisableOldValue(c *check.C, tc struct {
 	ver, err := store.CurrentVersion(oracle.GlobalTxnScope)
 	c.Assert(err, check.IsNil)
 	scheamStorage.AdvanceResolvedTs(ver.Ver)
-	mounter := NewMounter(scheamStorage, 1, false).(*mounterImpl)
+	mounter := NewMounter(scheamStorage, 1, false, nil).(*mounterImpl)
 	mounter.tz = time.Local
 	ctx := context.Background()
 
@@ -41,6 +41,8 @@ const (
 	APIOpVarTableID = "table-id"
 	// APIOpForceRemoveChangefeed is used when remove a changefeed
 	APIOpForceRemoveChangefeed = "force-remove"
+	// APIOpVarCaptureID is the key of capture ID in HTTP API
+	APIOpVarCaptureID = "capture-id"
 )
 
 type commonResp struct {
@@ -56,6 +58,15 @@ type ChangefeedResp struct {
 	RunningError *model.RunningError `json:"error"`
 }
 
+// DrainCaptureResp holds the progress of draining a capture
+type DrainCaptureResp struct {
+	CaptureID string `json:"capture_id"`
+	// RemainingTables is the number of the tables which are not replicated by
+	// other captures yet, -1 means the tables are not counted yet
+	RemainingTables int  `json:"remaining_tables"`
+	Done            bool `json:"done"`
+}
+
 func handleOwnerResp(w http.ResponseWriter, err error) {
 	if err != nil {
 		if errors.Cause(err) == concurrency.ErrElectionNotLeader {
@@ -210,6 +221,58 @@ func (s *Server) handleMoveTable(w http.ResponseWriter, req *http.Request) {
 	handleOwnerResp(w, nil)
 }
 
+func (s *Server) handleDrainCapture(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
+		return
+	}
+
+	s.ownerLock.RLock()
+	defer s.ownerLock.RUnlock()
+	if s.owner == nil {
+		handleOwnerResp(w, concurrency.ErrElectionNotLeader)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, cerror.WrapError(cerror.ErrInternalServerError, err))
+		return
+	}
+	captureID := req.Form.Get(APIOpVarCaptureID)
+	_, captures, err := s.owner.etcdClient.GetCaptures(req.Context())
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	alive := false
+	for _, info := range captures {
+		if info.ID == captureID {
+			alive = true
+			break
+		}
+	}
+	if !alive {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("capture %s doesn't exist", captureID))
+		return
+	}
+	remaining, err := s.owner.DrainCapture(req.Context(), captureID)
+	if err != nil {
+		if cerror.ErrCaptureNotExist.Equal(err) || cerror.ErrDrainCaptureFailed.Equal(err) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeInternalServerError(w, err)
+		return
+	}
+	writeData(w, &DrainCaptureResp{
+		CaptureID:       captureID,
+		RemainingTables: remaining,
+		Done:            remaining == 0,
+	})
+}
+
 func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
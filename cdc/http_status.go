@@ -53,6 +53,7 @@ func (s *Server) startStatusHTTP() error {
 	serverMux.HandleFunc("/capture/owner/admin", s.handleChangefeedAdmin)
 	serverMux.HandleFunc("/capture/owner/rebalance_trigger", s.handleRebalanceTrigger)
 	serverMux.HandleFunc("/capture/owner/move_table", s.handleMoveTable)
+	serverMux.HandleFunc("/capture/owner/drain_capture", s.handleDrainCapture)
 	serverMux.HandleFunc("/capture/owner/changefeed/query", s.handleChangefeedQuery)
 
 	serverMux.HandleFunc("/admin/log", handleAdminLogLevel)
@@ -73,6 +73,7 @@ func (s *httpStatusSuite) TestHTTPStatus(c *check.C) {
 	testHandleChangefeedAdmin(c)
 	testHandleRebalance(c)
 	testHandleMoveTable(c)
+	testHandleDrainCapture(c)
 	testHandleChangefeedQuery(c)
 	testHandleFailpoint(c)
 }
@@ -110,6 +111,12 @@ func testHandleMoveTable(c *check.C) {
 	testRequestNonOwnerFailed(c, uri)
 }
 
+func testHandleDrainCapture(c *check.C) {
+	uri := fmt.Sprintf("http://%s/capture/owner/drain_capture", advertiseAddr4Test)
+	testHTTPPostOnly(c, uri)
+	testRequestNonOwnerFailed(c, uri)
+}
+
 func testHandleChangefeedQuery(c *check.C) {
 	uri := fmt.Sprintf("http://%s/capture/owner/changefeed/query", advertiseAddr4Test)
 	testHTTPPostOnly(c, uri)
@@ -487,6 +487,7 @@ func (c *CDCClient) EventFeed(
 	s := newEventFeedSession(c, c.regionCache, c.kvStorage, span,
 		lockResolver, isPullerInit,
 		enableOldValue, ts, eventCh)
+	s.memoryTracker = eventMemoryTrackerFromCtx(ctx)
 	return s.eventFeed(ctx, ts)
 }
 
@@ -514,6 +515,8 @@ type eventFeedSession struct {
 
 	// The channel to send the processed events.
 	eventCh chan<- *model.RegionFeedEvent
+	// memoryTracker is nil if the memory of the events is not tracked
+	memoryTracker EventMemoryTracker
 	// The channel to put the region that will be sent requests.
 	regionCh chan singleRegionInfo
 	// The channel to notify that an error is happening, so that the error will be handled and the affected region
@@ -726,6 +729,9 @@ MainLoop:
 		case sri = <-s.regionCh:
 			s.regionChSizeGauge.Dec()
 		}
+		if err := s.waitMemoryQuota(ctx); err != nil {
+			return errors.Trace(err)
+		}
 
 		log.Debug("dispatching region", zap.Uint64("regionID", sri.verID.GetID()))
 
@@ -1497,6 +1503,7 @@ func (s *eventFeedSession) singleEventFeed(
 							if err != nil {
 								return lastResolvedTs, errors.Trace(err)
 							}
+							s.consumeEventMemory(revent)
 							select {
 							case s.eventCh <- revent:
 								metricSendEventCommitCounter.Inc()
@@ -1519,6 +1526,7 @@ func (s *eventFeedSession) singleEventFeed(
 								zap.Uint64("regionID", regionID))
 							return lastResolvedTs, errUnreachable
 						}
+						s.consumeEventMemory(revent)
 						select {
 						case s.eventCh <- revent:
 							metricSendEventCommittedCounter.Inc()
@@ -1552,6 +1560,7 @@ func (s *eventFeedSession) singleEventFeed(
 							return lastResolvedTs, errors.Trace(err)
 						}
 
+						s.consumeEventMemory(revent)
 						select {
 						case s.eventCh <- revent:
 							metricSendEventCommitCounter.Inc()
@@ -828,6 +828,45 @@ func (c CDCEtcdClient) PutCaptureInfo(ctx context.Context, info *model.CaptureIn
 	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
 }
 
+// SetCaptureDraining marks the capture as draining in etcd, the capture info
+// is kept with the lease of the capture, so the flag is removed with the
+// capture when it exits.
+func (c CDCEtcdClient) SetCaptureDraining(ctx context.Context, id string) error {
+	key := GetEtcdKeyCaptureInfo(id)
+	resp, err := c.Client.Get(ctx, key)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return cerror.ErrCaptureNotExist.GenWithStackByArgs(key)
+	}
+	kv := resp.Kvs[0]
+	info := new(model.CaptureInfo)
+	if err := info.Unmarshal(kv.Value); err != nil {
+		return errors.Trace(err)
+	}
+	if info.Draining {
+		return nil
+	}
+	info.Draining = true
+	data, err := info.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	txnResp, err := c.Client.Txn(ctx).If(
+		clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision),
+	).Then(
+		clientv3.OpPut(key, string(data), clientv3.WithLease(clientv3.LeaseID(kv.Lease))),
+	).Commit()
+	if err != nil {
+		return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	if !txnResp.Succeeded {
+		return cerror.ErrEtcdTryAgain.GenWithStackByArgs()
+	}
+	return nil
+}
+
 // DeleteCaptureInfo delete capture info from etcd.
 func (c CDCEtcdClient) DeleteCaptureInfo(ctx context.Context, id string) error {
 	key := GetEtcdKeyCaptureInfo(id)
@@ -534,6 +534,39 @@ func (s *etcdSuite) TestGetAllCaptureLeases(c *check.C) {
 	c.Check(queryLeases, check.DeepEquals, map[string]int64{})
 }
 
+func (s *etcdSuite) TestSetCaptureDraining(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defer s.TearDownTest(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := s.client.SetCaptureDraining(ctx, "not-exist")
+	c.Assert(cerror.ErrCaptureNotExist.Equal(err), check.IsTrue)
+
+	sess, err := concurrency.NewSession(s.client.Client.Unwrap(),
+		concurrency.WithTTL(10), concurrency.WithContext(ctx))
+	c.Assert(err, check.IsNil)
+	info := &model.CaptureInfo{ID: "capture-1", AdvertiseAddr: "127.0.0.1:8301"}
+	err = s.client.PutCaptureInfo(ctx, info, sess.Lease())
+	c.Assert(err, check.IsNil)
+	err = s.client.SetCaptureDraining(ctx, info.ID)
+	c.Assert(err, check.IsNil)
+	// draining a capture twice is fine
+	err = s.client.SetCaptureDraining(ctx, info.ID)
+	c.Assert(err, check.IsNil)
+	queryInfo, err := s.client.GetCaptureInfo(ctx, info.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(queryInfo, check.DeepEquals, &model.CaptureInfo{ID: "capture-1", AdvertiseAddr: "127.0.0.1:8301", Draining: true})
+
+	// the capture info is still bound to the lease of the capture
+	leases, err := s.client.GetCaptureLeases(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(leases, check.DeepEquals, map[string]int64{info.ID: int64(sess.Lease())})
+	err = s.client.RevokeAllLeases(ctx, leases)
+	c.Assert(err, check.IsNil)
+	_, err = s.client.GetCaptureInfo(ctx, info.ID)
+	c.Assert(cerror.ErrCaptureNotExist.Equal(err), check.IsTrue)
+}
+
 func (s *etcdSuite) TestGetAllCDCInfo(c *check.C) {
 	defer testleak.AfterTest(c)()
 	defer s.TearDownTest(c)
@@ -0,0 +1,72 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// memoryQuotaCheckInterval is the interval of checking whether the region
+// feeds are still paused
+const memoryQuotaCheckInterval = 50 * time.Millisecond
+
+// EventMemoryTracker tracks the memory used by the kv events sent by the kv
+// client, and decides whether the region feeds should be paused.
+type EventMemoryTracker interface {
+	// Consume records the memory used by a kv event sent by the kv client
+	Consume(raw *model.RawKVEntry)
+	// ShouldPause returns whether the kv client should stop requesting the
+	// changes of more regions
+	ShouldPause() bool
+}
+
+type memoryTrackerCtxKey struct{}
+
+// PutEventMemoryTrackerInCtx returns a new child context with the specified
+// EventMemoryTracker, which is used by the event feeds started with the context
+func PutEventMemoryTrackerInCtx(ctx context.Context, tracker EventMemoryTracker) context.Context {
+	return context.WithValue(ctx, memoryTrackerCtxKey{}, tracker)
+}
+
+func eventMemoryTrackerFromCtx(ctx context.Context) EventMemoryTracker {
+	tracker, _ := ctx.Value(memoryTrackerCtxKey{}).(EventMemoryTracker)
+	return tracker
+}
+
+// consumeEventMemory records the memory used by a kv event sent to the puller
+func (s *eventFeedSession) consumeEventMemory(e *model.RegionFeedEvent) {
+	if s.memoryTracker != nil && e.Val != nil {
+		s.memoryTracker.Consume(e.Val)
+	}
+}
+
+// waitMemoryQuota blocks until the region feeds are not paused. Requesting a
+// region starts an incremental scan of the region, which is the largest source
+// of the events, so the region feeds are paused by holding the requests.
+func (s *eventFeedSession) waitMemoryQuota(ctx context.Context) error {
+	if s.memoryTracker == nil {
+		return nil
+	}
+	for s.memoryTracker.ShouldPause() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(memoryQuotaCheckInterval):
+		}
+	}
+	return nil
+}
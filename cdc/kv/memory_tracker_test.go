@@ -0,0 +1,79 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type memoryTrackerSuite struct{}
+
+var _ = check.Suite(&memoryTrackerSuite{})
+
+type mockEventMemoryTracker struct {
+	consumed int64
+	paused   int32
+}
+
+func (t *mockEventMemoryTracker) Consume(raw *model.RawKVEntry) {
+	atomic.AddInt64(&t.consumed, raw.ApproximateSize())
+}
+
+func (t *mockEventMemoryTracker) ShouldPause() bool {
+	return atomic.LoadInt32(&t.paused) != 0
+}
+
+func (s *memoryTrackerSuite) TestEventFeedSessionMemoryTracker(c *check.C) {
+	defer testleak.AfterTest(c)()
+	tracker := &mockEventMemoryTracker{paused: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &eventFeedSession{memoryTracker: eventMemoryTrackerFromCtx(PutEventMemoryTrackerInCtx(ctx, tracker))}
+	c.Assert(session.memoryTracker, check.Equals, tracker)
+
+	// only the kv events are consumed
+	raw := &model.RawKVEntry{OpType: model.OpTypePut, Key: []byte("k"), Value: []byte("v")}
+	session.consumeEventMemory(&model.RegionFeedEvent{Val: raw})
+	session.consumeEventMemory(&model.RegionFeedEvent{Resolved: &model.ResolvedSpan{ResolvedTs: 1}})
+	c.Assert(atomic.LoadInt64(&tracker.consumed), check.Equals, raw.ApproximateSize())
+
+	// the region requests wait until the region feeds are resumed
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- session.waitMemoryQuota(ctx)
+	}()
+	select {
+	case <-errCh:
+		c.Fatal("the region feeds are not paused")
+	case <-time.After(3 * memoryQuotaCheckInterval):
+	}
+	atomic.StoreInt32(&tracker.paused, 0)
+	c.Assert(<-errCh, check.IsNil)
+
+	atomic.StoreInt32(&tracker.paused, 1)
+	cancel()
+	c.Assert(errors.Cause(session.waitMemoryQuota(ctx)), check.Equals, context.Canceled)
+
+	// the memory is not tracked without a tracker in the context
+	session = &eventFeedSession{memoryTracker: eventMemoryTrackerFromCtx(context.Background())}
+	session.consumeEventMemory(&model.RegionFeedEvent{Val: raw})
+	c.Assert(session.waitMemoryQuota(ctx), check.IsNil)
+}
@@ -475,6 +475,7 @@ func (w *regionWorker) handleEventEntry(
 				if err != nil {
 					return errors.Trace(err)
 				}
+				w.session.consumeEventMemory(revent)
 				select {
 				case w.outputCh <- revent:
 					metricSendEventCommitCounter.Inc()
@@ -497,6 +498,7 @@ func (w *regionWorker) handleEventEntry(
 					zap.Uint64("regionID", regionID))
 				return errUnreachable
 			}
+			w.session.consumeEventMemory(revent)
 			select {
 			case w.outputCh <- revent:
 				metricSendEventCommittedCounter.Inc()
@@ -530,6 +532,7 @@ func (w *regionWorker) handleEventEntry(
 				return errors.Trace(err)
 			}
 
+			w.session.consumeEventMemory(revent)
 			select {
 			case w.outputCh <- revent:
 				metricSendEventCommitCounter.Inc()
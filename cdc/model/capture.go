@@ -25,6 +25,12 @@ type CaptureInfo struct {
 	ID            CaptureID `json:"id"`
 	AdvertiseAddr string    `json:"address"`
 	Version       string    `json:"version"`
+	// Labels are the labels of the capture, such as the zone and the machine
+	// type, which are used by the placement rules of the changefeeds
+	Labels map[string]string `json:"labels,omitempty"`
+	// Draining is set by the owner when the capture is being drained, the
+	// tables are moved off the capture and no table is dispatched to it
+	Draining bool `json:"draining,omitempty"`
 }
 
 // Marshal using json.Marshal.
@@ -85,7 +85,11 @@ type ChangeFeedInfo struct {
 
 	SyncPointEnabled  bool          `json:"sync-point-enabled"`
 	SyncPointInterval time.Duration `json:"sync-point-interval"`
-	CreatorVersion    string        `json:"creator-version"`
+	// SyncPointBarrier makes the owner pause the global resolved ts at every
+	// syncpoint until all the processors flush to it, so the syncpoints
+	// recorded in the downstream are consistent snapshots.
+	SyncPointBarrier bool   `json:"sync-point-barrier"`
+	CreatorVersion   string `json:"creator-version"`
 }
 
 var changeFeedIDRe *regexp.Regexp = regexp.MustCompile(`^[a-zA-Z0-9]+(\-[a-zA-Z0-9]+)*$`)
@@ -205,6 +209,9 @@ func (info *ChangeFeedInfo) VerifyAndFix() error {
 	if info.Config.Scheduler == nil {
 		info.Config.Scheduler = defaultConfig.Scheduler
 	}
+	if info.Config.Consistent == nil {
+		info.Config.Consistent = defaultConfig.Consistent
+	}
 	return nil
 }
 
@@ -20,6 +20,7 @@ import (
 
 	"github.com/pingcap/errors"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/regionspan"
 )
 
 // AdminJobType represents for admin job type, both used in owner and processor
@@ -81,6 +82,12 @@ type TaskPosition struct {
 	ResolvedTs uint64 `json:"resolved-ts"`
 	// The count of events were synchronized. This is updated by corresponding processor.
 	Count uint64 `json:"count"`
+	// The count of rows written to the dead letter instead of the downstream.
+	// This is updated by corresponding processor.
+	DeadLetterCount uint64 `json:"dead-letter-count"`
+	// The estimated memory used by the table pipelines of the changefeed in
+	// bytes. This is updated by corresponding processor.
+	MemoryUsage uint64 `json:"memory-usage"`
 	// Error code when error happens
 	Error *RunningError `json:"error"`
 }
@@ -178,6 +185,12 @@ type TaskWorkload map[TableID]WorkloadInfo
 // WorkloadInfo records the workload info of a table
 type WorkloadInfo struct {
 	Workload uint64 `json:"workload"`
+	// EventRate is the number of the row changed events per second of the
+	// table, it's measured by the table pipeline
+	EventRate uint64 `json:"event-rate,omitempty"`
+	// RegionCount is the number of the regions of the table, it's zero if the
+	// table pipeline hasn't measured it yet
+	RegionCount uint64 `json:"region-count,omitempty"`
 }
 
 // Unmarshal unmarshals into *TaskWorkload from json marshal byte slice
@@ -200,6 +213,8 @@ func (w *TaskWorkload) Marshal() (string, error) {
 type TableReplicaInfo struct {
 	StartTs     Ts      `json:"start-ts"`
 	MarkTableID TableID `json:"mark-table-id"`
+	// Span is the key range of a sub table, it's nil for a whole table
+	Span *regionspan.Span `json:"span,omitempty"`
 }
 
 // Clone clones a TableReplicaInfo
@@ -310,6 +325,7 @@ func (ts *TaskStatus) Snapshot(cfID ChangeFeedID, captureID CaptureID, checkpoin
 		snap.Tables[tableID] = &TableReplicaInfo{
 			StartTs:     ts,
 			MarkTableID: table.MarkTableID,
+			Span:        table.Span,
 		}
 	}
 	return snap
@@ -353,6 +369,32 @@ type ChangeFeedID = string
 // TableID is the ID of the table
 type TableID = int64
 
+// subTableIndexBits is the number of the bits of the index in a sub table ID
+const subTableIndexBits = 12
+
+// MaxSubTableNum is the max number of the sub tables a table is split into
+const MaxSubTableNum = 1<<subTableIndexBits - 1
+
+// SubTableID returns the ID of the index-th sub table of a physical table. A
+// sub table replicates a key range of the physical table, the sub table IDs
+// are negative so that they never conflict with the physical table IDs.
+func SubTableID(tableID TableID, index int) TableID {
+	return -(tableID<<subTableIndexBits | TableID(index+1))
+}
+
+// IsSubTableID returns whether the ID is a sub table ID
+func IsSubTableID(tableID TableID) bool {
+	return tableID < 0
+}
+
+// PhysicalTableID returns the physical table ID of a table or a sub table
+func PhysicalTableID(tableID TableID) TableID {
+	if !IsSubTableID(tableID) {
+		return tableID
+	}
+	return -tableID >> subTableIndexBits
+}
+
 // SchemaID is the ID of the schema
 type SchemaID = int64
 
@@ -53,6 +53,22 @@ func (s *ownerCommonSuite) TestAdminJobType(c *check.C) {
 	}
 }
 
+func (s *ownerCommonSuite) TestSubTableID(c *check.C) {
+	defer testleak.AfterTest(c)()
+	for _, tableID := range []TableID{1, 47, 1 << 40} {
+		ids := make(map[TableID]struct{})
+		for _, index := range []int{0, 1, MaxSubTableNum - 1} {
+			id := SubTableID(tableID, index)
+			c.Assert(IsSubTableID(id), check.IsTrue)
+			c.Assert(PhysicalTableID(id), check.Equals, tableID)
+			ids[id] = struct{}{}
+		}
+		c.Assert(ids, check.HasLen, 3)
+		c.Assert(IsSubTableID(tableID), check.IsFalse)
+		c.Assert(PhysicalTableID(tableID), check.Equals, tableID)
+	}
+}
+
 func (s *ownerCommonSuite) TestDDLStateString(c *check.C) {
 	defer testleak.AfterTest(c)()
 	names := map[ChangeFeedDDLState]string{
@@ -73,7 +89,7 @@ func (s *ownerCommonSuite) TestTaskPositionMarshal(c *check.C) {
 		ResolvedTs:   420875942036766723,
 		CheckPointTs: 420875940070686721,
 	}
-	expected := `{"checkpoint-ts":420875940070686721,"resolved-ts":420875942036766723,"count":0,"error":null}`
+	expected := `{"checkpoint-ts":420875940070686721,"resolved-ts":420875942036766723,"count":0,"dead-letter-count":0,"memory-usage":0,"error":null}`
 
 	data, err := pos.Marshal()
 	c.Assert(err, check.IsNil)
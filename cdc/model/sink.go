@@ -37,6 +37,8 @@ const (
 	MqMessageTypeDDL
 	// MqMessageTypeResolved is resolved type of message key
 	MqMessageTypeResolved
+	// MqMessageTypeSyncpoint is syncpoint type of message key
+	MqMessageTypeSyncpoint
 )
 
 // ColumnFlagType is for encapsulating the flag operations for different flags.
@@ -29,12 +29,14 @@ import (
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/notify"
+	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/scheduler"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
@@ -84,7 +86,11 @@ type Owner struct {
 	rebalanceTigger           map[model.ChangeFeedID]bool
 	rebalanceForAllChangefeed bool
 	manualScheduleCommand     map[model.ChangeFeedID][]*model.MoveTableJob
-	rebalanceMu               sync.Mutex
+	// drainingCaptures records the number of the tables left on the draining
+	// captures, which are marked in the capture infos in etcd, -1 means the
+	// tables are not counted yet
+	drainingCaptures map[model.CaptureID]int
+	rebalanceMu      sync.Mutex
 
 	cfRWriter ChangeFeedRWriter
 
@@ -147,6 +153,7 @@ func NewOwner(
 		captures:                make(map[model.CaptureID]*model.CaptureInfo),
 		rebalanceTigger:         make(map[model.ChangeFeedID]bool),
 		manualScheduleCommand:   make(map[model.ChangeFeedID][]*model.MoveTableJob),
+		drainingCaptures:        make(map[model.CaptureID]int),
 		pdEndpoints:             endpoints,
 		cfRWriter:               cli,
 		etcdClient:              cli,
@@ -167,6 +174,16 @@ func (o *Owner) addCapture(_ context.Context, info *model.CaptureInfo) {
 	o.rebalanceMu.Unlock()
 }
 
+// updateCapture updates the info of an existing capture, such as the draining
+// flag set by the owner
+func (o *Owner) updateCapture(info *model.CaptureInfo) {
+	o.l.Lock()
+	defer o.l.Unlock()
+	if _, exist := o.captures[info.ID]; exist {
+		o.captures[info.ID] = info
+	}
+}
+
 // When a table is moved from one capture to another, the workflow is as follows
 // 1. Owner deletes the table from the original capture (we call it capture-1),
 //    and adds an table operation record in the task status
@@ -298,6 +315,10 @@ func (o *Owner) newChangeFeed(
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	placementRules, err := newPlacementRules(info.Config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	// TODO delete
 	if info.Engine == model.SortInFile {
@@ -342,6 +363,17 @@ func (o *Owner) newChangeFeed(
 	tables := make(map[model.TableID]model.TableName)
 	partitions := make(map[model.TableID][]int64)
 	orphanTables := make(map[model.TableID]model.Ts)
+	subTables := collectSubTables(processorsInfos)
+	// addOrphanTable adds a table as an orphan table, only the key ranges not
+	// replicated by the sub tables are added if the table is split
+	addOrphanTable := func(tableID model.TableID) {
+		if tableSubTables, ok := subTables[tableID]; ok {
+			tableSpan := regionspan.GetTableSpan(tableID, info.Config.EnableOldValue)
+			addUncoveredSubTables(tableID, tableSpan, tableSubTables, orphanTables, checkpointTs)
+			return
+		}
+		orphanTables[tableID] = checkpointTs
+	}
 	sinkTableInfo := make([]*model.SimpleTableInfo, len(schemaSnap.CloneTables()))
 	j := 0
 	for tid, table := range schemaSnap.CloneTables() {
@@ -374,6 +406,9 @@ func (o *Owner) newChangeFeed(
 			log.Warn("skip ineligible table", zap.Int64("tid", tid), zap.Stringer("table", table))
 			continue
 		}
+		if err := filter.VerifyTableColumns(tblInfo); err != nil {
+			return nil, errors.Trace(err)
+		}
 		// `existingTables` are tables dispatched to a processor, however the
 		// capture that this processor belongs to could have crashed or exited.
 		// So we check this before task dispatching, but after the update of
@@ -391,10 +426,10 @@ func (o *Owner) newChangeFeed(
 					log.Info("ignore known table partition", zap.Int64("tid", tid), zap.Int64("partitionID", id), zap.Stringer("table", table), zap.Uint64("ts", ts))
 					continue
 				}
-				orphanTables[id] = checkpointTs
+				addOrphanTable(id)
 			}
 		} else {
-			orphanTables[tid] = checkpointTs
+			addOrphanTable(tid)
 		}
 
 		sinkTableInfo[j-1] = new(model.SimpleTableInfo)
@@ -411,7 +446,10 @@ func (o *Owner) newChangeFeed(
 	}
 	errCh := make(chan error, 1)
 
-	primarySink, err := sink.NewSink(ctx, id, info.SinkURI, filter, info.Config, info.Opts, errCh)
+	// the sink of the owner is identified by the changefeed, such as the
+	// transactional ID of the kafka producer
+	sinkCtx := util.SetOwnerInCtx(util.PutChangefeedIDInCtx(ctx, id))
+	primarySink, err := sink.NewSink(sinkCtx, id, info.SinkURI, filter, info.Config, info.Opts, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -441,7 +479,15 @@ func (o *Owner) newChangeFeed(
 
 	var syncpointStore sink.SyncpointStore
 	if info.SyncPointEnabled {
-		syncpointStore, err = sink.NewSyncpointStore(ctx, id, info.SinkURI)
+		syncpointStore, err = sink.NewSyncpointStore(ctx, id, info.SinkURI, primarySink)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	var redoWriter *redo.OwnerWriter
+	if redo.IsConsistentEnabled(info.Config.Consistent) {
+		redoWriter, err = redo.NewOwnerWriter(ctx, id, info.Config.Consistent)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -457,12 +503,14 @@ func (o *Owner) newChangeFeed(
 		partitions:    partitions,
 		orphanTables:  orphanTables,
 		toCleanTables: make(map[model.TableID]model.Ts),
+		subTables:     subTables,
 		status: &model.ChangeFeedStatus{
 			ResolvedTs:   0,
 			CheckpointTs: checkpointTs,
 		},
 		appliedCheckpointTs: checkpointTs,
 		scheduler:           scheduler.NewScheduler(info.Config.Scheduler.Tp),
+		placementRules:      placementRules,
 		ddlState:            model.ChangeFeedSyncDML,
 		ddlExecutedTs:       checkpointTs,
 		targetTs:            info.GetTargetTs(),
@@ -470,11 +518,13 @@ func (o *Owner) newChangeFeed(
 		updateResolvedTs:    true,
 		startTimer:          make(chan bool),
 		syncpointStore:      syncpointStore,
+		redoWriter:          redoWriter,
 		syncCancel:          nil,
 		taskStatus:          processorsInfos,
 		taskPositions:       taskPositions,
 		etcdCli:             o.etcdClient,
 		leaseID:             o.session.Lease(),
+		pdClient:            o.pdClient,
 		filter:              filter,
 		sink:                primarySink,
 		cyclicEnabled:       info.Config.Cyclic.IsEnabled(),
@@ -650,7 +700,10 @@ func (o *Owner) loadChangeFeeds(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
-			newCf.startSyncPointTicker(ctx, newCf.info.SyncPointInterval)
+			// the syncpoints are set by the barrier instead of the ticker
+			if !newCf.info.SyncPointBarrier {
+				newCf.startSyncPointTicker(ctx, newCf.info.SyncPointInterval)
+			}
 		} else {
 			log.Info("syncpoint is off")
 		}
@@ -679,6 +732,7 @@ func (o *Owner) balanceTables(ctx context.Context) error {
 		o.rebalanceForAllChangefeed = false
 	}
 	o.rebalanceMu.Unlock()
+	drainingCaptures := o.getDrainingCaptures()
 	for id, changefeed := range o.changeFeeds {
 		rebalanceNow := false
 		var scheduleCommands []*model.MoveTableJob
@@ -695,14 +749,66 @@ func (o *Owner) balanceTables(ctx context.Context) error {
 			delete(o.manualScheduleCommand, id)
 		}
 		o.rebalanceMu.Unlock()
-		err := changefeed.tryBalance(ctx, o.captures, rebalanceNow, scheduleCommands)
+		err := changefeed.tryBalance(ctx, o.captures, drainingCaptures, rebalanceNow, scheduleCommands)
 		if err != nil {
 			return errors.Trace(err)
 		}
 	}
+	o.updateDrainProgress(drainingCaptures)
 	return nil
 }
 
+// getDrainingCaptures returns the alive draining captures, the captures which
+// have exited are forgotten.
+func (o *Owner) getDrainingCaptures() map[model.CaptureID]struct{} {
+	o.l.RLock()
+	defer o.l.RUnlock()
+	o.rebalanceMu.Lock()
+	defer o.rebalanceMu.Unlock()
+	drainingCaptures := make(map[model.CaptureID]struct{}, len(o.drainingCaptures))
+	for captureID, info := range o.captures {
+		if !info.Draining {
+			continue
+		}
+		drainingCaptures[captureID] = struct{}{}
+		if _, exist := o.drainingCaptures[captureID]; !exist {
+			// the capture is drained by the previous owner
+			log.Info("continue to drain the capture", zap.String("capture-id", captureID))
+			o.drainingCaptures[captureID] = -1
+		}
+	}
+	for captureID := range o.drainingCaptures {
+		if _, exist := drainingCaptures[captureID]; !exist {
+			log.Info("the draining capture has exited", zap.String("capture-id", captureID))
+			delete(o.drainingCaptures, captureID)
+		}
+	}
+	return drainingCaptures
+}
+
+// updateDrainProgress counts the tables left on the draining captures
+func (o *Owner) updateDrainProgress(drainingCaptures map[model.CaptureID]struct{}) {
+	if len(drainingCaptures) == 0 {
+		return
+	}
+	tableNums := make(map[model.CaptureID]int, len(drainingCaptures))
+	for captureID := range drainingCaptures {
+		for _, changefeed := range o.changeFeeds {
+			tableNums[captureID] += changefeed.drainingTableNum(captureID)
+		}
+	}
+	o.rebalanceMu.Lock()
+	defer o.rebalanceMu.Unlock()
+	for captureID, num := range tableNums {
+		if prev, exist := o.drainingCaptures[captureID]; exist {
+			if prev != num {
+				log.Info("drain capture progress", zap.String("capture-id", captureID), zap.Int("remainingTables", num))
+			}
+			o.drainingCaptures[captureID] = num
+		}
+	}
+}
+
 func (o *Owner) flushChangeFeedInfos(ctx context.Context) error {
 	// no running or stopped changefeed, clear gc safepoint.
 	if len(o.changeFeeds) == 0 && len(o.stoppedFeeds) == 0 {
@@ -1382,6 +1488,56 @@ func (o *Owner) ManualSchedule(changefeedID model.ChangeFeedID, to model.Capture
 	})
 }
 
+// DrainCapture moves all the tables off the capture and stops dispatching
+// tables to it, so that the capture can be restarted without replication lag.
+// The capture is marked as draining in etcd, so the draining continues after
+// the owner changes. It returns the number of the tables which are not
+// replicated by other captures yet, -1 means the tables are not counted yet.
+func (o *Owner) DrainCapture(ctx context.Context, captureID model.CaptureID) (int, error) {
+	o.l.RLock()
+	info, exist := o.captures[captureID]
+	hasTarget := false
+	for id, c := range o.captures {
+		if id != captureID && !c.Draining {
+			hasTarget = true
+			break
+		}
+	}
+	o.l.RUnlock()
+	if !exist {
+		return 0, cerror.ErrCaptureNotExist.GenWithStackByArgs(captureID)
+	}
+	if info.Draining {
+		o.rebalanceMu.Lock()
+		defer o.rebalanceMu.Unlock()
+		if num, exist := o.drainingCaptures[captureID]; exist {
+			return num, nil
+		}
+		return -1, nil
+	}
+	if !hasTarget {
+		return 0, cerror.ErrDrainCaptureFailed.GenWithStackByArgs(captureID, "no other capture can take over the tables")
+	}
+
+	if err := o.etcdClient.SetCaptureDraining(ctx, captureID); err != nil {
+		return 0, errors.Trace(err)
+	}
+	// the capture info is updated by watching the captures as well, update it
+	// here so that the draining starts at once
+	o.l.Lock()
+	if info, exist := o.captures[captureID]; exist {
+		drainingInfo := *info
+		drainingInfo.Draining = true
+		o.captures[captureID] = &drainingInfo
+	}
+	o.l.Unlock()
+	o.rebalanceMu.Lock()
+	o.drainingCaptures[captureID] = -1
+	o.rebalanceMu.Unlock()
+	log.Info("start to drain the capture", zap.String("capture-id", captureID))
+	return -1, nil
+}
+
 func (o *Owner) writeDebugInfo(w io.Writer) {
 	fmt.Fprintf(w, "** active changefeeds **:\n")
 	for _, info := range o.changeFeeds {
@@ -1543,12 +1699,13 @@ func (o *Owner) watchCapture(ctx context.Context) error {
 					zap.String("capture", c.AdvertiseAddr))
 				o.removeCapture(ctx, c)
 			case clientv3.EventTypePut:
-				if !ev.IsCreate() {
-					continue
-				}
 				if err := c.Unmarshal(ev.Kv.Value); err != nil {
 					return errors.Trace(err)
 				}
+				if !ev.IsCreate() {
+					o.updateCapture(c)
+					continue
+				}
 				log.Info("add capture",
 					zap.String("capture-id", c.ID),
 					zap.String("capture", c.AdvertiseAddr))
@@ -90,6 +90,12 @@ func (s *ownerReactorState) Update(key util.EtcdKey, value []byte, _ bool) error
 				zap.String("captureID", captureID),
 				zap.Reflect("old-capture", oldCaptureInfo),
 				zap.Reflect("new-capture", newCaptureInfo))
+
+			if !oldCaptureInfo.Draining && newCaptureInfo.Draining && s.newCaptureHandler != nil {
+				// The tables on the draining capture need to be rebalanced
+				// just like a capture is added
+				s.newCaptureHandler(captureID)
+			}
 		} else {
 			log.Info("Capture added",
 				zap.String("captureID", captureID),
@@ -743,7 +749,8 @@ func (s *ownerReactorState) CaptureExists(captureID model.CaptureID) bool {
 	return ok
 }
 
-// SetNewCaptureHandler is used to register a handler for capture-added events.
+// SetNewCaptureHandler is used to register a handler for capture-added events,
+// it's called when a capture starts draining as well.
 // This is normally used to trigger a table rebalance.
 func (s *ownerReactorState) SetNewCaptureHandler(handler func(id model.CaptureID)) {
 	s.newCaptureHandler = handler
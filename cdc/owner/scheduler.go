@@ -228,7 +228,8 @@ func (s *schedulerImpl) cleanUpAffinities() {
 func (s *schedulerImpl) triggerRebalance() {
 	tableToCaptureMap := s.ownerState.GetTableToCaptureMap(s.cfID)
 	totalTableNum := len(tableToCaptureMap)
-	captureNum := len(s.ownerState.Captures)
+	captures := s.schedulableCaptures()
+	captureNum := len(captures)
 
 	upperLimitPerCapture := int(math.Ceil(float64(totalTableNum) / float64(captureNum)))
 
@@ -240,11 +241,16 @@ func (s *schedulerImpl) triggerRebalance() {
 
 	for captureID := range s.ownerState.Captures {
 		captureTables := s.ownerState.GetCaptureTables(s.cfID, captureID)
+		limit := upperLimitPerCapture
+		if _, ok := captures[captureID]; !ok {
+			// all the tables are moved off the draining capture
+			limit = 0
+		}
 
 		// Use rand.Perm as a randomization source for choosing victims uniformly.
 		randPerm := rand.Perm(len(captureTables))
 
-		for i := 0; i < len(captureTables)-upperLimitPerCapture; i++ {
+		for i := 0; i < len(captureTables)-limit; i++ {
 			victimIdx := randPerm[i]
 			victimTableID := captureTables[victimIdx]
 
@@ -258,10 +264,25 @@ func (s *schedulerImpl) triggerRebalance() {
 	}
 }
 
+// schedulableCaptures returns the captures which tables can be dispatched to,
+// the draining captures are excluded unless all the captures are draining.
+func (s *schedulerImpl) schedulableCaptures() map[model.CaptureID]*model.CaptureInfo {
+	captures := make(map[model.CaptureID]*model.CaptureInfo, len(s.ownerState.Captures))
+	for captureID, info := range s.ownerState.Captures {
+		if !info.Draining {
+			captures[captureID] = info
+		}
+	}
+	if len(captures) == 0 {
+		return s.ownerState.Captures
+	}
+	return captures
+}
+
 func (s *schedulerImpl) getMinWorkloadCapture() model.CaptureID {
 	workloads := make(map[model.CaptureID]int)
 
-	for captureID := range s.ownerState.Captures {
+	for captureID := range s.schedulableCaptures() {
 		workloads[captureID] = s.captureWorkloadDeltas[captureID]
 	}
 
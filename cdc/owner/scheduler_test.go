@@ -265,6 +265,54 @@ func (s *schedulerTestSuite) TestPutTaskRebalance(c *check.C) {
 	c.Assert(taskStatus2.Operation[victimID].BoundaryTs, check.Equals, uint64(1501))
 }
 
+func (s *schedulerTestSuite) TestPutTaskDrainCapture(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, tester := setUp(c)
+	addCapture(c, tester, "capture-1")
+	addCapture(c, tester, "capture-2")
+
+	tasks := map[model.TableID]*tableTask{
+		1: {TableID: 1, CheckpointTs: 1000, ResolvedTs: 1200},
+		2: {TableID: 2, CheckpointTs: 1100, ResolvedTs: 1210},
+	}
+	scheduler.PutTasks(tasks)
+	err := tester.ApplyPatches()
+	c.Assert(err, check.IsNil)
+	mockProcessorTick(c, tester, "capture-1")
+	mockProcessorTick(c, tester, "capture-2")
+	taskStatus1 := readTaskStatus(c, tester, "capture-1")
+	c.Assert(taskStatus1.Tables, check.HasLen, 1)
+	var drainedID model.TableID
+	for tableID := range taskStatus1.Tables {
+		drainedID = tableID
+	}
+
+	// the owner marks capture-1 as draining
+	captureInfoJSON, err := json.Marshal(&model.CaptureInfo{ID: "capture-1", Draining: true})
+	c.Assert(err, check.IsNil)
+	err = tester.UpdateKeys(map[string][]byte{
+		kv.GetEtcdKeyCaptureInfo("capture-1"): captureInfoJSON,
+	})
+	c.Assert(err, check.IsNil)
+	scheduler.PutTasks(tasks)
+	err = tester.ApplyPatches()
+	c.Assert(err, check.IsNil)
+	taskStatus1 = readTaskStatus(c, tester, "capture-1")
+	c.Assert(taskStatus1.Operation, check.HasLen, 1)
+	c.Assert(taskStatus1.Operation[drainedID].Delete, check.IsTrue)
+
+	mockProcessorTick(c, tester, "capture-1")
+	// the drained table and the new table are dispatched to capture-2 only
+	tasks[3] = &tableTask{TableID: 3, CheckpointTs: 1300, ResolvedTs: 1300}
+	scheduler.PutTasks(tasks)
+	err = tester.ApplyPatches()
+	c.Assert(err, check.IsNil)
+	taskStatus1 = readTaskStatus(c, tester, "capture-1")
+	c.Assert(taskStatus1.Tables, check.HasLen, 0)
+	taskStatus2 := readTaskStatus(c, tester, "capture-2")
+	c.Assert(taskStatus2.Tables, check.HasLen, 3)
+}
+
 func (s *schedulerTestSuite) TestPutTaskAddAfterDelete(c *check.C) {
 	defer testleak.AfterTest(c)()
 	scheduler, tester := setUp(c)
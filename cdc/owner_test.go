@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math"
 	"net/url"
 	"sync"
 	"sync/atomic"
@@ -41,6 +42,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/util/testleak"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 	pd "github.com/tikv/pd/client"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
@@ -1206,3 +1208,94 @@ func (s *ownerSuite) TestWriteDebugInfo(c *check.C) {
 	owner.writeDebugInfo(&buf)
 	c.Assert(buf.String(), check.Matches, `[\s\S]*active changefeeds[\s\S]*stopped changefeeds[\s\S]*captures[\s\S]*`)
 }
+
+type mockSyncpointStore struct {
+	sink.SyncpointStore
+	syncpoints []uint64
+}
+
+func (m *mockSyncpointStore) SinkSyncpoint(ctx context.Context, id string, checkpointTs uint64) error {
+	m.syncpoints = append(m.syncpoints, checkpointTs)
+	return nil
+}
+
+type mockCheckpointSink struct {
+	sink.Sink
+}
+
+func (m *mockCheckpointSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+	return nil
+}
+
+type handlerForSyncPointTest struct {
+	resolvedTs uint64
+}
+
+func (h *handlerForSyncPointTest) PullDDL() (resolvedTs uint64, jobs []*timodel.Job, err error) {
+	return h.resolvedTs, nil, nil
+}
+
+func (h *handlerForSyncPointTest) Close() error {
+	return nil
+}
+
+func (s *ownerSuite) TestNextSyncpointTs(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defer s.TearDownTest(c)
+	c.Assert(nextSyncpointTs(oracle.ComposeTS(10500, 3), time.Second), check.Equals, oracle.ComposeTS(11000, 0))
+	c.Assert(nextSyncpointTs(oracle.ComposeTS(11000, 0), time.Second), check.Equals, oracle.ComposeTS(12000, 0))
+	c.Assert(nextSyncpointTs(oracle.ComposeTS(11000, 0)-1, time.Second), check.Equals, oracle.ComposeTS(11000, 0))
+	c.Assert(nextSyncpointTs(oracle.ComposeTS(59999, 0), time.Minute), check.Equals, oracle.ComposeTS(60000, 0))
+}
+
+func (s *ownerSuite) TestSyncPointBarrier(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defer s.TearDownTest(c)
+	ctx := context.Background()
+
+	startTs := oracle.ComposeTS(10500, 0)
+	store := &mockSyncpointStore{}
+	positions := map[model.CaptureID]*model.TaskPosition{
+		"capture-1": {ResolvedTs: oracle.ComposeTS(12500, 0), CheckPointTs: oracle.ComposeTS(10800, 0)},
+		"capture-2": {ResolvedTs: oracle.ComposeTS(13500, 0), CheckPointTs: oracle.ComposeTS(10900, 0)},
+	}
+	cf := &changeFeed{
+		id: "test-cf",
+		info: &model.ChangeFeedInfo{
+			SyncPointEnabled:  true,
+			SyncPointInterval: time.Second,
+			SyncPointBarrier:  true,
+		},
+		status:           &model.ChangeFeedStatus{ResolvedTs: startTs, CheckpointTs: startTs},
+		targetTs:         math.MaxUint64,
+		ddlState:         model.ChangeFeedSyncDML,
+		updateResolvedTs: true,
+		syncpointStore:   store,
+		taskStatus:       model.ProcessorsInfos{"capture-1": {}, "capture-2": {}},
+		taskPositions:    positions,
+		ddlHandler:       &handlerForSyncPointTest{resolvedTs: oracle.ComposeTS(20000, 0)},
+		sink:             &mockCheckpointSink{},
+	}
+
+	// the global resolved ts stops at the syncpoint
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.ResolvedTs, check.Equals, oracle.ComposeTS(11000, 0))
+	c.Assert(cf.status.CheckpointTs, check.Equals, oracle.ComposeTS(10800, 0))
+	c.Assert(cf.handleSyncPoint(ctx), check.IsNil)
+	c.Assert(store.syncpoints, check.HasLen, 0)
+
+	// the syncpoint is recorded after all the processors flush to it
+	positions["capture-1"].CheckPointTs = oracle.ComposeTS(11000, 0)
+	positions["capture-2"].CheckPointTs = oracle.ComposeTS(11000, 0)
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.ResolvedTs, check.Equals, oracle.ComposeTS(11000, 0))
+	c.Assert(cf.status.CheckpointTs, check.Equals, oracle.ComposeTS(11000, 0))
+	c.Assert(cf.handleSyncPoint(ctx), check.IsNil)
+	c.Assert(store.syncpoints, check.DeepEquals, []uint64{oracle.ComposeTS(11000, 0)})
+
+	// the global resolved ts moves to the next syncpoint
+	c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+	c.Assert(cf.status.ResolvedTs, check.Equals, oracle.ComposeTS(12000, 0))
+	c.Assert(cf.handleSyncPoint(ctx), check.IsNil)
+	c.Assert(store.syncpoints, check.HasLen, 1)
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/scheduler"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"go.uber.org/zap"
+)
+
+// placementRule constrains the captures the matched tables are replicated by
+type placementRule struct {
+	filter.Filter
+	labels        map[string]string
+	excludeLabels map[string]string
+}
+
+// allows returns whether the capture can replicate the matched tables
+func (r *placementRule) allows(capture *model.CaptureInfo) bool {
+	if !matchLabels(capture.Labels, r.labels) {
+		return false
+	}
+	for key, value := range r.excludeLabels {
+		if capture.Labels[key] == value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLabels returns whether the capture labels contain all the labels
+func matchLabels(captureLabels, labels map[string]string) bool {
+	for key, value := range labels {
+		if captureLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func newPlacementRules(cfg *config.ReplicaConfig) ([]*placementRule, error) {
+	rules := make([]*placementRule, 0, len(cfg.Scheduler.PlacementRules))
+	for _, ruleConfig := range cfg.Scheduler.PlacementRules {
+		if len(ruleConfig.Labels) == 0 && len(ruleConfig.ExcludeLabels) == 0 {
+			return nil, cerror.ErrInvalidPlacementRule.GenWithStackByArgs(
+				ruleConfig.Matcher, "either labels or exclude-labels is required")
+		}
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		rules = append(rules, &placementRule{
+			Filter:        f,
+			labels:        ruleConfig.Labels,
+			excludeLabels: ruleConfig.ExcludeLabels,
+		})
+	}
+	return rules, nil
+}
+
+// excludedCaptures returns the captures which the tables of the changefeed
+// should be moved off, which are the draining captures and the captures not
+// having the capture labels of the changefeed. The capture labels are ignored
+// if no capture has them, so that the changefeed keeps replicating.
+func (c *changeFeed) excludedCaptures(
+	captures map[model.CaptureID]*model.CaptureInfo, drainingCaptures map[model.CaptureID]struct{},
+) map[model.CaptureID]struct{} {
+	labels := c.info.Config.Scheduler.CaptureLabels
+	if len(labels) == 0 {
+		return drainingCaptures
+	}
+	excluded := make(map[model.CaptureID]struct{}, len(captures))
+	matched := false
+	for captureID, info := range captures {
+		_, draining := drainingCaptures[captureID]
+		if draining || !matchLabels(info.Labels, labels) {
+			excluded[captureID] = struct{}{}
+			continue
+		}
+		matched = true
+	}
+	if !matched {
+		if !c.captureLabelsUnmatched {
+			log.Warn("no capture has the capture labels of the changefeed, the labels are ignored",
+				zap.String("changefeed", c.id), zap.Reflect("labels", labels))
+		}
+		c.captureLabelsUnmatched = true
+		return drainingCaptures
+	}
+	c.captureLabelsUnmatched = false
+	return excluded
+}
+
+// updatePlacement updates the placement of the tables by the placement rules
+// and the labels of the captures.
+func (c *changeFeed) updatePlacement(captures map[model.CaptureID]*model.CaptureInfo) {
+	if len(c.placementRules) == 0 {
+		return
+	}
+	// the rules matching the tables, nil means no rule matches the table
+	tableRules := make(map[model.TableID]*placementRule)
+	c.placement = func(tableID model.TableID, captureID model.CaptureID) bool {
+		rule, exist := tableRules[tableID]
+		if !exist {
+			if tableName, ok := c.schema.GetTableNameByID(model.PhysicalTableID(tableID)); ok {
+				for _, r := range c.placementRules {
+					if r.MatchTable(tableName.Schema, tableName.Table) {
+						rule = r
+						break
+					}
+				}
+			}
+			tableRules[tableID] = rule
+		}
+		if rule == nil {
+			return true
+		}
+		capture, exist := captures[captureID]
+		return exist && rule.allows(capture)
+	}
+	c.scheduler.SetPlacement(c.placement)
+}
+
+// allowedBy returns whether the capture is allowed to replicate the table by
+// the placement
+func allowedBy(placement scheduler.Placement, tableID model.TableID, captureID model.CaptureID) bool {
+	return placement == nil || placement(tableID, captureID)
+}
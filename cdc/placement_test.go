@@ -0,0 +1,158 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/scheduler"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+)
+
+type placementSuite struct{}
+
+var _ = check.Suite(&placementSuite{})
+
+func (s *placementSuite) TestNewPlacementRules(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	rules, err := newPlacementRules(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 0)
+
+	cfg.Scheduler.PlacementRules = []*config.PlacementRule{{Matcher: []string{"test.*"}}}
+	_, err = newPlacementRules(cfg)
+	c.Assert(err, check.ErrorMatches, ".*either labels or exclude-labels is required.*")
+	cfg.Scheduler.PlacementRules = []*config.PlacementRule{
+		{Matcher: []string{"[test.*"}, Labels: map[string]string{"zone": "a"}},
+	}
+	_, err = newPlacementRules(cfg)
+	c.Assert(err, check.ErrorMatches, ".*ErrFilterRuleInvalid.*")
+}
+
+func (s *placementSuite) TestPlacementRuleAllows(c *check.C) {
+	defer testleak.AfterTest(c)()
+	rule := &placementRule{
+		labels:        map[string]string{"tier": "large"},
+		excludeLabels: map[string]string{"zone": "b"},
+	}
+	c.Assert(rule.allows(&model.CaptureInfo{Labels: map[string]string{"tier": "large", "zone": "a"}}), check.IsTrue)
+	c.Assert(rule.allows(&model.CaptureInfo{Labels: map[string]string{"tier": "large", "zone": "b"}}), check.IsFalse)
+	c.Assert(rule.allows(&model.CaptureInfo{Labels: map[string]string{"zone": "a"}}), check.IsFalse)
+	c.Assert(rule.allows(&model.CaptureInfo{}), check.IsFalse)
+}
+
+func (s *placementSuite) TestExcludedCaptures(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cf := &changeFeed{info: &model.ChangeFeedInfo{Config: cfg}}
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1", Labels: map[string]string{"zone": "a"}},
+		"capture-2": {ID: "capture-2", Labels: map[string]string{"zone": "a"}},
+		"capture-3": {ID: "capture-3", Labels: map[string]string{"zone": "b"}},
+	}
+	draining := map[model.CaptureID]struct{}{"capture-1": {}}
+	c.Assert(cf.excludedCaptures(captures, draining), check.DeepEquals, draining)
+
+	cfg.Scheduler.CaptureLabels = map[string]string{"zone": "a"}
+	c.Assert(cf.excludedCaptures(captures, draining), check.DeepEquals,
+		map[model.CaptureID]struct{}{"capture-1": {}, "capture-3": {}})
+	c.Assert(cf.excludedCaptures(captures, nil), check.DeepEquals,
+		map[model.CaptureID]struct{}{"capture-3": {}})
+
+	// the labels are ignored if no capture has them
+	cfg.Scheduler.CaptureLabels = map[string]string{"zone": "c"}
+	c.Assert(cf.excludedCaptures(captures, draining), check.DeepEquals, draining)
+	c.Assert(cf.captureLabelsUnmatched, check.IsTrue)
+}
+
+func (s *placementSuite) TestUpdatePlacement(c *check.C) {
+	defer testleak.AfterTest(c)()
+	store, err := mockstore.NewMockStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+	txn, err := store.Begin()
+	c.Assert(err, check.IsNil)
+	defer txn.Rollback() //nolint:errcheck
+	schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta.NewMeta(txn), 0, false)
+	c.Assert(err, check.IsNil)
+	for i, name := range []string{"orders", "test"} {
+		dbInfo := &timodel.DBInfo{ID: int64(i + 1), Name: timodel.NewCIStr(name)}
+		tableID := model.TableID(47 + 2*i)
+		jobs := []*timodel.Job{{
+			ID: int64(2*i + 1), SchemaID: dbInfo.ID, Type: timodel.ActionCreateSchema, State: timodel.JobStateSynced,
+			BinlogInfo: &timodel.HistoryInfo{SchemaVersion: int64(2*i + 1), DBInfo: dbInfo},
+		}, {
+			ID: int64(2*i + 2), SchemaID: dbInfo.ID, TableID: tableID, Type: timodel.ActionCreateTable, State: timodel.JobStateSynced,
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: int64(2*i + 2),
+				DBInfo:        dbInfo,
+				TableInfo:     &timodel.TableInfo{ID: tableID, Name: timodel.NewCIStr("t")},
+			},
+		}}
+		for _, job := range jobs {
+			c.Assert(schemaSnap.HandleDDL(job), check.IsNil)
+		}
+	}
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Scheduler.PlacementRules = []*config.PlacementRule{
+		{Matcher: []string{"orders.*"}, Labels: map[string]string{"tier": "large"}},
+		{Matcher: []string{"*.*"}, ExcludeLabels: map[string]string{"zone": "b"}},
+	}
+	rules, err := newPlacementRules(cfg)
+	c.Assert(err, check.IsNil)
+	cf := &changeFeed{
+		info:           &model.ChangeFeedInfo{Config: cfg},
+		schema:         schemaSnap,
+		scheduler:      scheduler.NewScheduler("table-number"),
+		placementRules: rules,
+	}
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1", Labels: map[string]string{"zone": "a", "tier": "large"}},
+		"capture-2": {ID: "capture-2", Labels: map[string]string{"zone": "a"}},
+		"capture-3": {ID: "capture-3", Labels: map[string]string{"zone": "b", "tier": "large"}},
+	}
+	cf.updatePlacement(captures)
+	testCases := []struct {
+		tableID   model.TableID
+		captureID model.CaptureID
+		allowed   bool
+	}{
+		{47, "capture-1", true},
+		{47, "capture-2", false},
+		// only the first matched rule takes effect
+		{47, "capture-3", true},
+		{model.SubTableID(47, 1), "capture-2", false},
+		{49, "capture-2", true},
+		{49, "capture-3", false},
+		// the tables not found are not constrained
+		{51, "capture-3", true},
+		{49, "capture-4", false},
+	}
+	for _, tc := range testCases {
+		c.Assert(cf.placement(tc.tableID, tc.captureID), check.Equals, tc.allowed, check.Commentf("%v", tc))
+	}
+
+	targets := []model.CaptureID{"capture-1", "capture-2", "capture-3"}
+	tableNums := map[model.CaptureID]int{"capture-1": 3, "capture-2": 1, "capture-3": 2}
+	c.Assert(selectDrainTarget(targets, tableNums, 47, cf.placement), check.Equals, "capture-3")
+	c.Assert(selectDrainTarget(targets, tableNums, 49, cf.placement), check.Equals, "capture-2")
+	c.Assert(selectDrainTarget(targets, tableNums, 49, nil), check.Equals, "capture-2")
+}
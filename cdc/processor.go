@@ -200,7 +200,7 @@ func newProcessor(
 		session:       session,
 		sinkManager:   sinkManager,
 		ddlPuller:     ddlPuller,
-		mounter:       entry.NewMounter(schemaStorage, changefeed.Config.Mounter.WorkerNum, changefeed.Config.EnableOldValue),
+		mounter:       entry.NewMounter(schemaStorage, changefeed.Config.Mounter.WorkerNum, changefeed.Config.EnableOldValue, filter),
 		schemaStorage: schemaStorage,
 		errCh:         errCh,
 
@@ -763,7 +763,7 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 
 	var tableName string
 	err := retry.Run(time.Millisecond*5, 3, func() error {
-		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(tableID); ok {
+		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(model.PhysicalTableID(tableID)); ok {
 			tableName = name.QuoteString()
 			return nil
 		}
@@ -813,6 +813,10 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 		// start table puller
 		enableOldValue := p.changefeed.Config.EnableOldValue
 		span := regionspan.GetTableSpan(tableID, enableOldValue)
+		if replicaInfo.Span != nil && tableID != replicaInfo.MarkTableID {
+			// only a key range of the table is replicated by a sub table
+			span = *replicaInfo.Span
+		}
 		kvStorage, err := util.KVStorageFromCtx(ctx)
 		if err != nil {
 			p.sendError(err)
@@ -874,7 +878,11 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 			p.pullerConsume(ctx, plr, sorter)
 		}()
 
-		tableSink := p.sinkManager.CreateTableSink(tableID, replicaInfo.StartTs)
+		tableSink, err := p.sinkManager.CreateTableSink(ctx, tableID, replicaInfo.StartTs)
+		if err != nil {
+			p.sendError(err)
+			return nil
+		}
 		go func() {
 			p.sorterConsume(ctx, tableID, tableName, sorter, pResolvedTs, pCheckpointTs, replicaInfo, tableSink)
 		}()
@@ -53,6 +53,13 @@ var (
 			Name:      "num_of_tables",
 			Help:      "number of synchronized table of processor",
 		}, []string{"changefeed", "capture"})
+	memoryUsageGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "memory_usage",
+			Help:      "estimated memory used by the table pipelines of processor",
+		}, []string{"changefeed", "capture"})
 	processorErrorCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "ticdc",
@@ -69,5 +76,6 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(checkpointTsGauge)
 	registry.MustRegister(checkpointTsLagGauge)
 	registry.MustRegister(syncTableNumGauge)
+	registry.MustRegister(memoryUsageGauge)
 	registry.MustRegister(processorErrorCounter)
 }
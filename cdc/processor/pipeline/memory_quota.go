@@ -0,0 +1,286 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+)
+
+// memoryQuotaCheckInterval is the interval of checking the memory quota when
+// the puller node stops pulling events, the memory used by the tables is
+// refreshed at most once in the interval
+const memoryQuotaCheckInterval = 50 * time.Millisecond
+
+// eventMemoryOverhead is the estimated memory used by an event besides the
+// key and the values
+var eventMemoryOverhead = uint64(unsafe.Sizeof(model.PolymorphicEvent{}) + unsafe.Sizeof(model.RawKVEntry{}))
+
+// eventMemorySize returns the estimated memory used by an event
+func eventMemorySize(raw *model.RawKVEntry) uint64 {
+	return uint64(raw.ApproximateSize()) + eventMemoryOverhead
+}
+
+// MemoryQuota limits the memory used by the table pipelines of a changefeed
+// on a capture. The memory of a table is the sum of the events buffered by the
+// kv client and the puller, the events buffered in the memory of the sorter,
+// and the sorted events not flushed by the sink yet.
+type MemoryQuota struct {
+	quota           uint64
+	refreshInterval time.Duration
+
+	mu       sync.Mutex
+	trackers map[*tableMemoryTracker]struct{}
+	// used and slowest are refreshed from the trackers periodically, slowest
+	// is the tracker of the table with the minimum resolved ts
+	used        uint64
+	slowest     *tableMemoryTracker
+	lastRefresh time.Time
+}
+
+// NewMemoryQuota creates a MemoryQuota, zero quota means the memory is
+// tracked but not limited.
+func NewMemoryQuota(quota uint64) *MemoryQuota {
+	return &MemoryQuota{
+		quota:           quota,
+		refreshInterval: memoryQuotaCheckInterval,
+		trackers:        make(map[*tableMemoryTracker]struct{}),
+	}
+}
+
+// Quota returns the memory quota in bytes
+func (q *MemoryQuota) Quota() uint64 {
+	return q.quota
+}
+
+// Used returns the memory used in bytes
+func (q *MemoryQuota) Used() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.refreshLocked()
+	return q.used
+}
+
+// IsExceeded returns whether the memory used reaches the quota
+func (q *MemoryQuota) IsExceeded() bool {
+	return q.quota != 0 && q.Used() >= q.quota
+}
+
+func (q *MemoryQuota) register(t *tableMemoryTracker) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.trackers[t] = struct{}{}
+	q.lastRefresh = time.Time{}
+}
+
+func (q *MemoryQuota) unregister(t *tableMemoryTracker) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.trackers, t)
+	q.lastRefresh = time.Time{}
+}
+
+func (q *MemoryQuota) refreshLocked() {
+	if time.Since(q.lastRefresh) < q.refreshInterval {
+		return
+	}
+	used := uint64(0)
+	var (
+		slowest       *tableMemoryTracker
+		minResolvedTs model.Ts
+	)
+	for t := range q.trackers {
+		used += t.used()
+		// the tables with the same resolved ts are ordered by the table ID,
+		// so only one of them proceeds
+		resolvedTs := t.getResolvedTs()
+		if slowest == nil || resolvedTs < minResolvedTs ||
+			(resolvedTs == minResolvedTs && t.tableID < slowest.tableID) {
+			slowest = t
+			minResolvedTs = resolvedTs
+		}
+	}
+	q.used = used
+	q.slowest = slowest
+	q.lastRefresh = time.Now()
+}
+
+// shouldWait returns whether the table of the tracker should stop pulling
+// events. All the tables wait once the quota is exceeded regardless of whether
+// they are resolved, except the table with the minimum resolved ts: the sinks
+// can't flush any event beyond the global resolved ts, so the memory is
+// released only after the slowest table advances, which is never throttled to
+// avoid the deadlock.
+func (q *MemoryQuota) shouldWait(t *tableMemoryTracker) bool {
+	if q.quota == 0 {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.refreshLocked()
+	return q.used >= q.quota && t != q.slowest
+}
+
+type memoryEvent struct {
+	commitTs model.Ts
+	size     uint64
+}
+
+// memoryEventHeap is a min heap of the events ordered by the commit ts
+type memoryEventHeap []memoryEvent
+
+func (h memoryEventHeap) Len() int            { return len(h) }
+func (h memoryEventHeap) Less(i, j int) bool  { return h[i].commitTs < h[j].commitTs }
+func (h memoryEventHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *memoryEventHeap) Push(x interface{}) { *h = append(*h, x.(memoryEvent)) }
+func (h *memoryEventHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// tableMemoryTracker tracks the memory used by the events of a table in three
+// stages:
+//  1. the events sent by the kv client are counted until the sorter node
+//     receives them, which covers the buffers of the kv client and the puller.
+//  2. the events in the sorter are measured by the sorter itself, so the events
+//     spilled to the disk are not counted.
+//  3. the events output by the sorter are counted until the sink flushes them.
+//     The sink flushes all the events whose commit ts are not greater than the
+//     checkpoint ts, so the memory is released by the commit ts.
+type tableMemoryTracker struct {
+	quota   *MemoryQuota
+	tableID model.TableID
+
+	// pulled and resolvedTs are accessed atomically, resolvedTs is the last
+	// resolved ts pulled from the puller
+	pulled     int64
+	resolvedTs uint64
+
+	mu     sync.Mutex
+	sorter puller.MemoryMeasurableSorter
+	events memoryEventHeap
+	sorted uint64
+	closed bool
+}
+
+func newTableMemoryTracker(quota *MemoryQuota, tableID model.TableID, startTs model.Ts) *tableMemoryTracker {
+	t := &tableMemoryTracker{
+		quota:      quota,
+		tableID:    tableID,
+		resolvedTs: startTs,
+	}
+	quota.register(t)
+	return t
+}
+
+var _ kv.EventMemoryTracker = (*tableMemoryTracker)(nil)
+
+// Consume implements the kv.EventMemoryTracker interface
+func (t *tableMemoryTracker) Consume(raw *model.RawKVEntry) {
+	atomic.AddInt64(&t.pulled, int64(eventMemorySize(raw)))
+}
+
+// ShouldPause implements the kv.EventMemoryTracker interface
+func (t *tableMemoryTracker) ShouldPause() bool {
+	return t.shouldWait()
+}
+
+// releasePulled releases the memory used by an event received by the sorter
+// node
+func (t *tableMemoryTracker) releasePulled(raw *model.RawKVEntry) {
+	atomic.AddInt64(&t.pulled, -int64(eventMemorySize(raw)))
+}
+
+// setSorter sets the sorter which measures the memory used by the events in it
+func (t *tableMemoryTracker) setSorter(sorter puller.MemoryMeasurableSorter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sorter = sorter
+}
+
+// consume records the memory used by an event output by the sorter
+func (t *tableMemoryTracker) consume(raw *model.RawKVEntry) {
+	size := eventMemorySize(raw)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	heap.Push(&t.events, memoryEvent{commitTs: raw.CRTs, size: size})
+	t.sorted += size
+}
+
+// updateResolvedTs records the last resolved ts pulled from the puller
+func (t *tableMemoryTracker) updateResolvedTs(ts model.Ts) {
+	if ts > t.getResolvedTs() {
+		atomic.StoreUint64(&t.resolvedTs, ts)
+	}
+}
+
+func (t *tableMemoryTracker) getResolvedTs() model.Ts {
+	return atomic.LoadUint64(&t.resolvedTs)
+}
+
+// release releases the memory used by the events flushed by the sink
+func (t *tableMemoryTracker) release(checkpointTs model.Ts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.events.Len() > 0 && t.events[0].commitTs <= checkpointTs {
+		t.sorted -= heap.Pop(&t.events).(memoryEvent).size
+	}
+}
+
+// releaseAll releases the memory used by all the events of the table, the
+// table is not tracked any more.
+func (t *tableMemoryTracker) releaseAll() {
+	t.mu.Lock()
+	t.sorter = nil
+	t.events = nil
+	t.sorted = 0
+	t.closed = true
+	t.mu.Unlock()
+	t.quota.unregister(t)
+}
+
+// used returns the memory used by the table
+func (t *tableMemoryTracker) used() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	used := t.sorted
+	if pulled := atomic.LoadInt64(&t.pulled); pulled > 0 {
+		used += uint64(pulled)
+	}
+	if t.sorter != nil {
+		if size := t.sorter.MemorySize(); size > 0 {
+			used += uint64(size)
+		}
+	}
+	return used
+}
+
+// shouldWait returns whether the puller should stop pulling events of the
+// table
+func (t *tableMemoryTracker) shouldWait() bool {
+	return t.quota.shouldWait(t)
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	stdContext "context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/context"
+	"github.com/pingcap/ticdc/pkg/pipeline"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type memoryQuotaSuite struct{}
+
+var _ = check.Suite(&memoryQuotaSuite{})
+
+func newRawKV(commitTs model.Ts, value string) *model.RawKVEntry {
+	return &model.RawKVEntry{OpType: model.OpTypePut, CRTs: commitTs, Key: []byte("k"), Value: []byte(value)}
+}
+
+type mockMeasurableSorter struct {
+	puller.EventSorter
+	memorySize int64
+}
+
+func (s *mockMeasurableSorter) MemorySize() int64 {
+	return s.memorySize
+}
+
+func (s *memoryQuotaSuite) TestTableMemoryTracker(c *check.C) {
+	defer testleak.AfterTest(c)()
+	raw := newRawKV(0, "value")
+	size := eventMemorySize(raw)
+	quota := NewMemoryQuota(4 * size)
+	quota.refreshInterval = 0
+	t1 := newTableMemoryTracker(quota, 1, 1)
+	t2 := newTableMemoryTracker(quota, 2, 1)
+	sorter := &mockMeasurableSorter{}
+	t2.setSorter(sorter)
+
+	// the events are pulled by the kv client during the incremental scans,
+	// no resolved ts is pulled yet
+	for _, commitTs := range []model.Ts{5, 3, 4} {
+		t1.Consume(newRawKV(commitTs, "value"))
+	}
+	t2.Consume(newRawKV(3, "value"))
+	c.Assert(quota.Used(), check.Equals, 4*size)
+	c.Assert(quota.IsExceeded(), check.IsTrue)
+	// only the table with the minimum resolved ts and table ID proceeds
+	c.Assert(t1.ShouldPause(), check.IsFalse)
+	c.Assert(t2.ShouldPause(), check.IsTrue)
+	t1.updateResolvedTs(4)
+	c.Assert(t1.ShouldPause(), check.IsTrue)
+	c.Assert(t2.ShouldPause(), check.IsFalse)
+
+	// the events received by the sorter are measured by the sorter
+	t2.releasePulled(newRawKV(3, "value"))
+	c.Assert(quota.Used(), check.Equals, 3*size)
+	c.Assert(quota.IsExceeded(), check.IsFalse)
+	c.Assert(t1.ShouldPause(), check.IsFalse)
+	sorter.memorySize = int64(size)
+	c.Assert(quota.Used(), check.Equals, 4*size)
+	// the events spilled to the disk are not counted
+	sorter.memorySize = 0
+	c.Assert(quota.Used(), check.Equals, 3*size)
+
+	// the sorted events are released once the sink flushes them
+	for _, commitTs := range []model.Ts{5, 3, 4} {
+		t1.releasePulled(newRawKV(commitTs, "value"))
+	}
+	for _, commitTs := range []model.Ts{3, 4} {
+		t1.consume(newRawKV(commitTs, "value"))
+	}
+	t2.consume(newRawKV(3, "value"))
+	c.Assert(quota.Used(), check.Equals, 3*size)
+	t1.release(3)
+	c.Assert(quota.Used(), check.Equals, 2*size)
+	t1.release(4)
+	c.Assert(quota.Used(), check.Equals, size)
+
+	// the events of the removed table are all released, and the table is not
+	// tracked any more
+	t2.updateResolvedTs(6)
+	t2.releaseAll()
+	c.Assert(quota.Used(), check.Equals, uint64(0))
+	t2.consume(newRawKV(7, "value"))
+	c.Assert(quota.Used(), check.Equals, uint64(0))
+	t1.releaseAll()
+}
+
+func (s *memoryQuotaSuite) TestUnlimitedQuota(c *check.C) {
+	defer testleak.AfterTest(c)()
+	quota := NewMemoryQuota(0)
+	quota.refreshInterval = 0
+	t := newTableMemoryTracker(quota, 1, 0)
+	t.Consume(newRawKV(2, "value"))
+	c.Assert(quota.Used(), check.Equals, eventMemorySize(newRawKV(2, "value")))
+	c.Assert(quota.IsExceeded(), check.IsFalse)
+	c.Assert(t.ShouldPause(), check.IsFalse)
+	t.releaseAll()
+}
+
+func (s *memoryQuotaSuite) TestSinkNodeReleaseMemory(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.NewContext(stdContext.Background(), &context.Vars{})
+	quota := NewMemoryQuota(0)
+	quota.refreshInterval = 0
+	tracker := newTableMemoryTracker(quota, 1, 0)
+	node := newSinkNode(&mockSink{}, 0, 10, 1, nil, tracker)
+	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
+
+	for _, commitTs := range []model.Ts{1, 2} {
+		raw := newRawKV(commitTs, "value")
+		tracker.consume(raw)
+		c.Assert(node.Receive(pipeline.MockNodeContext4Test(ctx,
+			pipeline.PolymorphicEventMessage(&model.PolymorphicEvent{
+				CRTs: commitTs, RawKV: raw, Row: &model.RowChangedEvent{CommitTs: commitTs},
+			}), nil)), check.IsNil)
+	}
+	c.Assert(node.Receive(pipeline.MockNodeContext4Test(ctx, pipeline.BarrierMessage(1), nil)), check.IsNil)
+	c.Assert(node.Receive(pipeline.MockNodeContext4Test(ctx,
+		pipeline.PolymorphicEventMessage(&model.PolymorphicEvent{CRTs: 2, RawKV: &model.RawKVEntry{OpType: model.OpTypeResolved}}), nil)), check.IsNil)
+	c.Assert(node.CheckpointTs(), check.Equals, uint64(1))
+	c.Assert(quota.Used(), check.Equals, eventMemorySize(newRawKV(2, "value")))
+
+	c.Assert(node.Destroy(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
+	c.Assert(quota.Used(), check.Equals, uint64(0))
+}
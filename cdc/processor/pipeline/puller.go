@@ -15,8 +15,11 @@ package pipeline
 
 import (
 	stdContext "context"
+	"sync/atomic"
+	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/puller"
 	"github.com/pingcap/ticdc/pkg/context"
@@ -30,9 +33,9 @@ import (
 )
 
 type pullerNode struct {
-	credential *security.Credential
-	kvStorage  tidbkv.Storage
-	limitter   *puller.BlurResourceLimitter
+	credential    *security.Credential
+	kvStorage     tidbkv.Storage
+	memoryTracker *tableMemoryTracker
 
 	changefeedID model.ChangeFeedID
 	tableName    string // quoted schema and table, used in metircs only
@@ -41,22 +44,27 @@ type pullerNode struct {
 	replicaInfo *model.TableReplicaInfo
 	cancel      stdContext.CancelFunc
 	wg          errgroup.Group
+
+	// eventCount and regionCount are accessed atomically, they are used to
+	// measure the workload of the table
+	eventCount  uint64
+	regionCount int64
 }
 
 func newPullerNode(
 	changefeedID model.ChangeFeedID,
 	credential *security.Credential,
 	kvStorage tidbkv.Storage,
-	limitter *puller.BlurResourceLimitter,
-	tableID model.TableID, replicaInfo *model.TableReplicaInfo, tableName string) pipeline.Node {
+	memoryTracker *tableMemoryTracker,
+	tableID model.TableID, replicaInfo *model.TableReplicaInfo, tableName string) *pullerNode {
 	return &pullerNode{
-		credential:   credential,
-		kvStorage:    kvStorage,
-		limitter:     limitter,
-		tableID:      tableID,
-		replicaInfo:  replicaInfo,
-		tableName:    tableName,
-		changefeedID: changefeedID,
+		credential:    credential,
+		kvStorage:     kvStorage,
+		memoryTracker: memoryTracker,
+		tableID:       tableID,
+		replicaInfo:   replicaInfo,
+		tableName:     tableName,
+		changefeedID:  changefeedID,
 	}
 }
 
@@ -64,7 +72,12 @@ func (n *pullerNode) tableSpan(ctx context.Context) []regionspan.Span {
 	// start table puller
 	enableOldValue := ctx.Vars().Config.EnableOldValue
 	spans := make([]regionspan.Span, 0, 4)
-	spans = append(spans, regionspan.GetTableSpan(n.tableID, enableOldValue))
+	if n.replicaInfo.Span != nil {
+		// only a key range of the table is replicated by a sub table
+		spans = append(spans, *n.replicaInfo.Span)
+	} else {
+		spans = append(spans, regionspan.GetTableSpan(n.tableID, enableOldValue))
+	}
 
 	if ctx.Vars().Config.Cyclic.IsEnabled() && n.replicaInfo.MarkTableID != 0 {
 		spans = append(spans, regionspan.GetTableSpan(n.replicaInfo.MarkTableID, enableOldValue))
@@ -77,14 +90,27 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 	enableOldValue := ctx.Vars().Config.EnableOldValue
 	ctxC, cancel := stdContext.WithCancel(ctx.StdContext())
 	ctxC = util.PutTableInfoInCtx(ctxC, n.tableID, n.tableName)
+	if n.memoryTracker != nil {
+		ctxC = kv.PutEventMemoryTrackerInCtx(ctxC, n.memoryTracker)
+	}
 	plr := puller.NewPuller(ctxC, ctx.Vars().PDClient, n.credential, n.kvStorage,
-		n.replicaInfo.StartTs, n.tableSpan(ctx), n.limitter, enableOldValue)
+		n.replicaInfo.StartTs, n.tableSpan(ctx), nil, enableOldValue)
 	n.wg.Go(func() error {
 		ctx.Throw(errors.Trace(plr.Run(ctxC)))
 		return nil
 	})
 	n.wg.Go(func() error {
 		for {
+			if n.memoryTracker != nil && n.memoryTracker.shouldWait() {
+				// stop pulling events until the memory is released, the kv
+				// client pauses the region feeds by itself
+				select {
+				case <-ctxC.Done():
+					return nil
+				case <-time.After(memoryQuotaCheckInterval):
+				}
+				continue
+			}
 			select {
 			case <-ctxC.Done():
 				return nil
@@ -94,6 +120,12 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 				}
 				if rawKV.OpType == model.OpTypeResolved {
 					metricTableResolvedTsGauge.Set(float64(oracle.ExtractPhysical(rawKV.CRTs)))
+					atomic.StoreInt64(&n.regionCount, int64(plr.RegionCount()))
+					if n.memoryTracker != nil {
+						n.memoryTracker.updateResolvedTs(rawKV.CRTs)
+					}
+				} else {
+					atomic.AddUint64(&n.eventCount, 1)
 				}
 				pEvent := model.NewPolymorphicEvent(rawKV)
 				ctx.SendToNextNode(pipeline.PolymorphicEventMessage(pEvent))
@@ -104,6 +136,16 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 	return nil
 }
 
+// EventCount returns the number of the kv events pulled
+func (n *pullerNode) EventCount() uint64 {
+	return atomic.LoadUint64(&n.eventCount)
+}
+
+// RegionCount returns the number of the regions of the table
+func (n *pullerNode) RegionCount() int {
+	return int(atomic.LoadInt64(&n.regionCount))
+}
+
 // Receive receives the message from the previous node
 func (n *pullerNode) Receive(ctx pipeline.NodeContext) error {
 	// just forward any messages to the next node
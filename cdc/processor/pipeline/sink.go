@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/cdc/sink"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/pipeline"
@@ -62,8 +63,13 @@ func (s *TableStatus) store(new TableStatus) {
 }
 
 type sinkNode struct {
-	sink   sink.Sink
-	status TableStatus
+	sink    sink.Sink
+	status  TableStatus
+	tableID model.TableID
+	// redoWriter is nil if the redo log is disabled
+	redoWriter *redo.LogWriter
+	// memoryTracker is nil if the memory is not tracked
+	memoryTracker *tableMemoryTracker
 
 	resolvedTs   model.Ts
 	checkpointTs model.Ts
@@ -74,18 +80,40 @@ type sinkNode struct {
 	rowBuffer   []*model.RowChangedEvent
 }
 
-func newSinkNode(sink sink.Sink, startTs model.Ts, targetTs model.Ts) *sinkNode {
+func newSinkNode(
+	sink sink.Sink, startTs model.Ts, targetTs model.Ts, tableID model.TableID,
+	redoWriter *redo.LogWriter, memoryTracker *tableMemoryTracker,
+) *sinkNode {
+	if redoWriter != nil {
+		redoWriter.AddTable(tableID, startTs)
+	}
 	return &sinkNode{
-		sink:         sink,
-		status:       TableStatusInitializing,
-		targetTs:     targetTs,
-		resolvedTs:   startTs,
-		checkpointTs: startTs,
-		barrierTs:    startTs,
+		sink:          sink,
+		status:        TableStatusInitializing,
+		tableID:       tableID,
+		redoWriter:    redoWriter,
+		memoryTracker: memoryTracker,
+		targetTs:      targetTs,
+		resolvedTs:    startTs,
+		checkpointTs:  startTs,
+		barrierTs:     startTs,
+	}
+}
+
+// ResolvedTs returns the resolved ts of the table, it never exceeds the
+// resolved ts persisted in the redo log if the redo log is enabled, so the
+// global resolved ts and the rows flushed to the downstream are always
+// covered by the redo log.
+func (n *sinkNode) ResolvedTs() model.Ts {
+	resolvedTs := atomic.LoadUint64(&n.resolvedTs)
+	if n.redoWriter != nil {
+		if redoResolvedTs := n.redoWriter.ResolvedTs(n.tableID); redoResolvedTs < resolvedTs {
+			return redoResolvedTs
+		}
 	}
+	return resolvedTs
 }
 
-func (n *sinkNode) ResolvedTs() model.Ts   { return atomic.LoadUint64(&n.resolvedTs) }
 func (n *sinkNode) CheckpointTs() model.Ts { return atomic.LoadUint64(&n.checkpointTs) }
 func (n *sinkNode) Status() TableStatus    { return n.status.load() }
 
@@ -130,6 +158,9 @@ func (n *sinkNode) flushSink(ctx pipeline.NodeContext, resolvedTs model.Ts) (err
 		return nil
 	}
 	atomic.StoreUint64(&n.checkpointTs, checkpointTs)
+	if n.memoryTracker != nil {
+		n.memoryTracker.release(checkpointTs)
+	}
 	return nil
 }
 
@@ -156,6 +187,11 @@ func (n *sinkNode) flushRow2Sink(ctx pipeline.NodeContext) error {
 		ev.Row.ReplicaID = ev.ReplicaID
 		n.rowBuffer = append(n.rowBuffer, ev.Row)
 	}
+	if n.redoWriter != nil {
+		if err := n.redoWriter.EmitRowChangedEvents(n.rowBuffer...); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	failpoint.Inject("ProcessorSyncResolvedPreEmit", func() {
 		log.Info("Prepare to panic for ProcessorSyncResolvedPreEmit")
 		time.Sleep(10 * time.Second)
@@ -183,6 +219,14 @@ func (n *sinkNode) Receive(ctx pipeline.NodeContext) error {
 			failpoint.Inject("ProcessorSyncResolvedError", func() {
 				failpoint.Return(errors.New("processor sync resolved injected error"))
 			})
+			if n.redoWriter != nil {
+				// all the rows before the resolved ts must be written to the
+				// redo log before the resolved ts is updated
+				if err := n.flushRow2Sink(ctx); err != nil {
+					return errors.Trace(err)
+				}
+				n.redoWriter.UpdateResolvedTs(n.tableID, msg.PolymorphicEvent.CRTs)
+			}
 			if err := n.flushSink(ctx, msg.PolymorphicEvent.CRTs); err != nil {
 				return errors.Trace(err)
 			}
@@ -216,5 +260,11 @@ func (n *sinkNode) Receive(ctx pipeline.NodeContext) error {
 
 func (n *sinkNode) Destroy(ctx pipeline.NodeContext) error {
 	n.status.store(TableStatusStopped)
+	if n.redoWriter != nil {
+		n.redoWriter.RemoveTable(n.tableID)
+	}
+	if n.memoryTracker != nil {
+		n.memoryTracker.releaseAll()
+	}
 	return n.sink.Close()
 }
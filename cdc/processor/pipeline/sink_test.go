@@ -15,10 +15,14 @@ package pipeline
 
 import (
 	stdContext "context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/redo"
+	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/context"
 	cerrors "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/pipeline"
@@ -90,7 +94,7 @@ func (s *outputSuite) TestStatus(c *check.C) {
 	ctx := context.NewContext(stdContext.Background(), &context.Vars{})
 
 	// test stop at targetTs
-	node := newSinkNode(&mockSink{}, 0, 10)
+	node := newSinkNode(&mockSink{}, 0, 10, 1, nil, nil)
 	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
 	c.Assert(node.Status(), check.Equals, TableStatusInitializing)
 
@@ -116,7 +120,7 @@ func (s *outputSuite) TestStatus(c *check.C) {
 	c.Assert(node.CheckpointTs(), check.Equals, uint64(10))
 
 	// test the stop at ts command
-	node = newSinkNode(&mockSink{}, 0, 10)
+	node = newSinkNode(&mockSink{}, 0, 10, 1, nil, nil)
 	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
 	c.Assert(node.Status(), check.Equals, TableStatusInitializing)
 
@@ -138,7 +142,7 @@ func (s *outputSuite) TestStatus(c *check.C) {
 	c.Assert(node.CheckpointTs(), check.Equals, uint64(6))
 
 	// test the stop at ts command is after then resolvedTs and checkpointTs is greater than stop ts
-	node = newSinkNode(&mockSink{}, 0, 10)
+	node = newSinkNode(&mockSink{}, 0, 10, 1, nil, nil)
 	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
 	c.Assert(node.Status(), check.Equals, TableStatusInitializing)
 
@@ -164,7 +168,7 @@ func (s *outputSuite) TestManyTs(c *check.C) {
 	defer testleak.AfterTest(c)()
 	ctx := context.NewContext(stdContext.Background(), &context.Vars{})
 	sink := &mockSink{}
-	node := newSinkNode(sink, 0, 10)
+	node := newSinkNode(sink, 0, 10, 1, nil, nil)
 	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
 	c.Assert(node.Status(), check.Equals, TableStatusInitializing)
 
@@ -209,3 +213,46 @@ func (s *outputSuite) TestManyTs(c *check.C) {
 	c.Assert(node.ResolvedTs(), check.Equals, uint64(2))
 	c.Assert(node.CheckpointTs(), check.Equals, uint64(2))
 }
+
+func (s *outputSuite) TestRedoLog(c *check.C) {
+	defer testleak.AfterTest(c)()
+	stdCtx, cancel := stdContext.WithCancel(stdContext.Background())
+	defer cancel()
+	ctx := context.NewContext(stdCtx, &context.Vars{})
+	writer, err := redo.NewLogWriter(stdCtx, "test-cf", "test-capture", &config.ConsistentConfig{
+		Level:             redo.ConsistentLevelEventual,
+		MaxLogSize:        1,
+		FlushIntervalInMs: 10,
+		Storage:           c.MkDir(),
+	})
+	c.Assert(err, check.IsNil)
+	sink := &mockSink{}
+	node := newSinkNode(sink, 0, 10, 1, writer, nil)
+	c.Assert(node.Init(pipeline.MockNodeContext4Test(ctx, nil, nil)), check.IsNil)
+
+	c.Assert(node.Receive(pipeline.MockNodeContext4Test(ctx,
+		pipeline.PolymorphicEventMessage(&model.PolymorphicEvent{CRTs: 1, RawKV: &model.RawKVEntry{OpType: model.OpTypePut}, Row: &model.RowChangedEvent{CommitTs: 1}}), nil)), check.IsNil)
+	c.Assert(node.Receive(pipeline.MockNodeContext4Test(ctx,
+		pipeline.PolymorphicEventMessage(&model.PolymorphicEvent{CRTs: 2, RawKV: &model.RawKVEntry{OpType: model.OpTypeResolved}}), nil)), check.IsNil)
+	// the rows are emitted, but the resolved ts is not persisted in the redo log
+	sink.Check(c, []struct {
+		resolvedTs model.Ts
+		row        *model.RowChangedEvent
+	}{
+		{row: &model.RowChangedEvent{CommitTs: 1}},
+	})
+	c.Assert(node.ResolvedTs(), check.Equals, uint64(0))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = writer.Run(stdCtx)
+	}()
+	for i := 0; i < 100 && node.ResolvedTs() != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(node.ResolvedTs(), check.Equals, uint64(2))
+	cancel()
+	wg.Wait()
+}
@@ -36,6 +36,8 @@ type sorterNode struct {
 	changeFeedID model.ChangeFeedID
 	tableID      model.TableID
 	tableName    string // quoted schema and table, used in metircs only
+	// memoryTracker is nil if the memory is not tracked
+	memoryTracker *tableMemoryTracker
 
 	wg     errgroup.Group
 	cancel context.CancelFunc
@@ -45,14 +47,16 @@ func newSorterNode(
 	sortEngine model.SortEngine,
 	sortDir string,
 	changeFeedID model.ChangeFeedID,
+	memoryTracker *tableMemoryTracker,
 	tableName string, tableID model.TableID) pipeline.Node {
 	return &sorterNode{
 		sortEngine: sortEngine,
 		sortDir:    sortDir,
 
-		changeFeedID: changeFeedID,
-		tableID:      tableID,
-		tableName:    tableName,
+		changeFeedID:  changeFeedID,
+		memoryTracker: memoryTracker,
+		tableID:       tableID,
+		tableName:     tableName,
 	}
 }
 
@@ -86,6 +90,13 @@ func (n *sorterNode) Init(ctx pipeline.NodeContext) error {
 	default:
 		return cerror.ErrUnknownSortEngine.GenWithStackByArgs(n.sortEngine)
 	}
+	if n.memoryTracker != nil {
+		// the file sorter keeps the events on the disk, so they are counted
+		// only after they are output
+		if measurable, ok := sorter.(puller.MemoryMeasurableSorter); ok {
+			n.memoryTracker.setSorter(measurable)
+		}
+	}
 	failpoint.Inject("ProcessorAddTableError", func() {
 		failpoint.Return(errors.New("processor add table injected error"))
 	})
@@ -102,6 +113,9 @@ func (n *sorterNode) Init(ctx pipeline.NodeContext) error {
 				if msg == nil {
 					continue
 				}
+				if n.memoryTracker != nil && msg.RawKV.OpType != model.OpTypeResolved {
+					n.memoryTracker.consume(msg.RawKV)
+				}
 				ctx.SendToNextNode(pipeline.PolymorphicEventMessage(msg))
 			}
 		}
@@ -115,6 +129,9 @@ func (n *sorterNode) Receive(ctx pipeline.NodeContext) error {
 	msg := ctx.Message()
 	switch msg.Tp {
 	case pipeline.MessageTypePolymorphicEvent:
+		if n.memoryTracker != nil && msg.PolymorphicEvent.RawKV.OpType != model.OpTypeResolved {
+			n.memoryTracker.releasePulled(msg.PolymorphicEvent.RawKV)
+		}
 		n.sorter.AddEntry(ctx.StdContext(), msg.PolymorphicEvent)
 	default:
 		ctx.SendToNextNode(msg)
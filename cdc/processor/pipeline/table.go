@@ -17,12 +17,12 @@ import (
 	stdContext "context"
 	"time"
 
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/cdc/sink"
 
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
-	"github.com/pingcap/ticdc/cdc/puller"
 	"github.com/pingcap/ticdc/pkg/context"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/pipeline"
@@ -62,8 +62,15 @@ type tablePipelineImpl struct {
 	markTableID int64
 	tableName   string // quoted schema and table, used in metircs only
 
-	sinkNode *sinkNode
-	cancel   stdContext.CancelFunc
+	pullerNode *pullerNode
+	sinkNode   *sinkNode
+	cancel     stdContext.CancelFunc
+
+	// the fields below are used to measure the event rate of the table
+	lastSampleTime       time.Time
+	lastSampleEventCount uint64
+	eventRate            uint64
+	eventRateSampled     bool
 }
 
 // ResolvedTs returns the resolved ts in this table pipeline
@@ -96,13 +103,30 @@ func (t *tablePipelineImpl) AsyncStop(targetTs model.Ts) {
 	}
 }
 
-var workload = model.WorkloadInfo{Workload: 1}
+// workloadSampleInterval is the min interval of sampling the event rate
+const workloadSampleInterval = 10 * time.Second
 
 // Workload returns the workload of this table
 func (t *tablePipelineImpl) Workload() model.WorkloadInfo {
-	// TODO(leoppro) calculate the workload of this table
-	// We temporarily set the value to constant 1
-	return workload
+	now := time.Now()
+	if elapsed := now.Sub(t.lastSampleTime); elapsed >= workloadSampleInterval {
+		eventCount := t.pullerNode.EventCount()
+		rate := uint64(float64(eventCount-t.lastSampleEventCount) / elapsed.Seconds())
+		if t.eventRateSampled {
+			// smooth the rate, so that a transient spike doesn't make the
+			// table look hot
+			rate = (t.eventRate + rate) / 2
+		}
+		t.eventRate = rate
+		t.eventRateSampled = true
+		t.lastSampleTime = now
+		t.lastSampleEventCount = eventCount
+	}
+	return model.WorkloadInfo{
+		Workload:    1,
+		EventRate:   t.eventRate,
+		RegionCount: uint64(t.pullerNode.RegionCount()),
+	}
 }
 
 // Status returns the status of this table pipeline
@@ -130,6 +154,27 @@ func (t *tablePipelineImpl) Wait() {
 	t.p.Wait()
 }
 
+const (
+	// tablePipelineGoroutineNum is the estimated number of the goroutines used
+	// by the nodes of a table pipeline, the puller and the sorter
+	tablePipelineGoroutineNum = 18
+	// spanGoroutineNum is the estimated number of the goroutines used by the
+	// puller and the kv client to pull a span, assuming that the regions of
+	// the span are on 3 TiKV stores
+	spanGoroutineNum = 16
+)
+
+// EstimateGoroutineNum returns the estimated number of the goroutines used by
+// a table pipeline, the mark table is pulled with the table if the cyclic
+// replication is enabled
+func EstimateGoroutineNum(cyclicEnabled bool) int {
+	spanNum := 1
+	if cyclicEnabled {
+		spanNum = 2
+	}
+	return tablePipelineGoroutineNum + spanNum*spanGoroutineNum
+}
+
 // NewTablePipeline creates a table pipeline
 // TODO(leoppro): the parameters in this function are too much, try to move some parameters into ctx.Vars().
 // TODO(leoppro): implement a mock kvclient to test the table pipeline
@@ -137,7 +182,7 @@ func NewTablePipeline(ctx context.Context,
 	changefeedID model.ChangeFeedID,
 	credential *security.Credential,
 	kvStorage tidbkv.Storage,
-	limitter *puller.BlurResourceLimitter,
+	memoryQuota *MemoryQuota,
 	mounter entry.Mounter,
 	sortEngine model.SortEngine,
 	sortDir string,
@@ -145,6 +190,7 @@ func NewTablePipeline(ctx context.Context,
 	tableName string,
 	replicaInfo *model.TableReplicaInfo,
 	sink sink.Sink,
+	redoWriter *redo.LogWriter,
 	targetTs model.Ts) TablePipeline {
 	ctx, cancel := context.WithCancel(ctx)
 	tablePipeline := &tablePipelineImpl{
@@ -152,17 +198,24 @@ func NewTablePipeline(ctx context.Context,
 		markTableID: replicaInfo.MarkTableID,
 		tableName:   tableName,
 		cancel:      cancel,
+
+		lastSampleTime: time.Now(),
 	}
 
+	var memoryTracker *tableMemoryTracker
+	if memoryQuota != nil {
+		memoryTracker = newTableMemoryTracker(memoryQuota, tableID, replicaInfo.StartTs)
+	}
 	p := pipeline.NewPipeline(ctx, 500*time.Millisecond)
-	p.AppendNode(ctx, "puller", newPullerNode(changefeedID, credential, kvStorage, limitter, tableID, replicaInfo, tableName))
-	p.AppendNode(ctx, "sorter", newSorterNode(sortEngine, sortDir, changefeedID, tableName, tableID))
+	tablePipeline.pullerNode = newPullerNode(changefeedID, credential, kvStorage, memoryTracker, tableID, replicaInfo, tableName)
+	p.AppendNode(ctx, "puller", tablePipeline.pullerNode)
+	p.AppendNode(ctx, "sorter", newSorterNode(sortEngine, sortDir, changefeedID, memoryTracker, tableName, tableID))
 	p.AppendNode(ctx, "mounter", newMounterNode(mounter))
 	config := ctx.Vars().Config
 	if config.Cyclic != nil && config.Cyclic.IsEnabled() {
 		p.AppendNode(ctx, "cyclic", newCyclicMarkNode(replicaInfo.MarkTableID))
 	}
-	tablePipeline.sinkNode = newSinkNode(sink, replicaInfo.StartTs, targetTs)
+	tablePipeline.sinkNode = newSinkNode(sink, replicaInfo.StartTs, targetTs, tableID, redoWriter, memoryTracker)
 	p.AppendNode(ctx, "sink", tablePipeline.sinkNode)
 	tablePipeline.p = p
 	return tablePipeline
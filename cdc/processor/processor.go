@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/model"
 	tablepipeline "github.com/pingcap/ticdc/cdc/processor/pipeline"
 	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/cdc/sink"
 	cdccontext "github.com/pingcap/ticdc/pkg/context"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
@@ -65,6 +66,13 @@ type processor struct {
 	filter        *filter.Filter
 	mounter       entry.Mounter
 	sinkManager   *sink.Manager
+	// redoWriter is nil if the redo log is disabled
+	redoWriter *redo.LogWriter
+	// memoryQuota limits the memory used by the table pipelines
+	memoryQuota *tablepipeline.MemoryQuota
+	// deadLetterCountBase is the dead letter count in the task position
+	// before the sink is created
+	deadLetterCountBase uint64
 
 	firstTick bool
 	errCh     chan error
@@ -79,6 +87,7 @@ type processor struct {
 	metricCheckpointTsGauge     prometheus.Gauge
 	metricCheckpointTsLagGauge  prometheus.Gauge
 	metricSyncTableNumGauge     prometheus.Gauge
+	metricMemoryUsageGauge      prometheus.Gauge
 	metricProcessorErrorCounter prometheus.Counter
 }
 
@@ -103,6 +112,7 @@ func newProcessor(
 		metricCheckpointTsGauge:     checkpointTsGauge.WithLabelValues(changefeedID, captureInfo.AdvertiseAddr),
 		metricCheckpointTsLagGauge:  checkpointTsLagGauge.WithLabelValues(changefeedID, captureInfo.AdvertiseAddr),
 		metricSyncTableNumGauge:     syncTableNumGauge.WithLabelValues(changefeedID, captureInfo.AdvertiseAddr),
+		metricMemoryUsageGauge:      memoryUsageGauge.WithLabelValues(changefeedID, captureInfo.AdvertiseAddr),
 		metricProcessorErrorCounter: processorErrorCounter.WithLabelValues(changefeedID, captureInfo.AdvertiseAddr),
 	}
 	p.createTablePipeline = p.createTablePipelineImpl
@@ -249,13 +259,25 @@ func (p *processor) lazyInitImpl(ctx context.Context) error {
 		return errors.Trace(err)
 	}
 
-	p.mounter = entry.NewMounter(p.schemaStorage, p.changefeed.Info.Config.Mounter.WorkerNum, p.changefeed.Info.Config.EnableOldValue)
+	p.mounter = entry.NewMounter(p.schemaStorage, p.changefeed.Info.Config.Mounter.WorkerNum, p.changefeed.Info.Config.EnableOldValue, p.filter)
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
 		p.sendError(p.mounter.Run(ctx))
 	}()
 
+	if redo.IsConsistentEnabled(p.changefeed.Info.Config.Consistent) {
+		p.redoWriter, err = redo.NewLogWriter(ctx, p.changefeed.ID, p.captureInfo.ID, p.changefeed.Info.Config.Consistent)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.sendError(p.redoWriter.Run(ctx))
+		}()
+	}
+
 	opts := make(map[string]string, len(p.changefeed.Info.Opts)+2)
 	for k, v := range p.changefeed.Info.Opts {
 		opts[k] = v
@@ -277,6 +299,10 @@ func (p *processor) lazyInitImpl(ctx context.Context) error {
 	}
 	checkpointTs := p.changefeed.Info.GetCheckpointTs(p.changefeed.Status)
 	p.sinkManager = sink.NewManager(ctx, s, errCh, checkpointTs)
+	p.memoryQuota = tablepipeline.NewMemoryQuota(p.changefeed.Info.Config.MemoryQuota)
+	if p.changefeed.TaskPosition != nil {
+		p.deadLetterCountBase = p.changefeed.TaskPosition.DeadLetterCount
+	}
 
 	// Clean up possible residual error states
 	p.changefeed.PatchTaskPosition(func(position *model.TaskPosition) (*model.TaskPosition, error) {
@@ -410,6 +436,14 @@ func (p *processor) handleTableOperation(ctx context.Context) error {
 				if replicaInfo.StartTs != opt.BoundaryTs {
 					log.Warn("the startTs and BoundaryTs of add table operation should be always equaled", zap.Any("replicaInfo", replicaInfo))
 				}
+				if !p.hasGoroutineQuota() {
+					// the operation is left dispatched and retried in the next
+					// tick, the owner doesn't advance the resolved ts until the
+					// table is added
+					log.Debug("the goroutine quota is exceeded, the table will be added later",
+						util.ZapFieldChangefeed(ctx), zap.Int64("tableID", tableID))
+					continue
+				}
 				err := p.addTable(ctx, tableID, replicaInfo)
 				if err != nil {
 					return errors.Trace(err)
@@ -513,6 +547,18 @@ func (p *processor) sendError(err error) {
 	}
 }
 
+// hasGoroutineQuota returns whether a new table pipeline can be created
+// within the goroutine quota. A table is always allowed if there is no table
+// pipeline, so that the changefeed can make progress with a small quota.
+func (p *processor) hasGoroutineQuota() bool {
+	quota := p.changefeed.Info.Config.GoroutineQuota
+	if quota <= 0 || len(p.tables) == 0 {
+		return true
+	}
+	goroutineNum := tablepipeline.EstimateGoroutineNum(p.changefeed.Info.Config.Cyclic.IsEnabled())
+	return (len(p.tables)+1)*goroutineNum <= quota
+}
+
 // checkTablesNum if the number of table pipelines is equal to the number of TaskStatus in etcd state.
 // if the table number is not right, create or remove the odd tables.
 func (p *processor) checkTablesNum(ctx context.Context) error {
@@ -585,9 +631,22 @@ func (p *processor) handlePosition() error {
 	p.metricCheckpointTsLagGauge.Set(float64(oracle.GetPhysical(time.Now())-checkpointPhyTs) / 1e3)
 	p.metricCheckpointTsGauge.Set(float64(checkpointPhyTs))
 
+	deadLetterCount := p.deadLetterCountBase
+	if p.sinkManager != nil {
+		deadLetterCount += p.sinkManager.DeadLetterCount()
+	}
+
+	var memoryUsage uint64
+	if p.memoryQuota != nil {
+		memoryUsage = p.memoryQuota.Used()
+	}
+	p.metricMemoryUsageGauge.Set(float64(memoryUsage))
+
 	// minResolvedTs and minCheckpointTs may less than global resolved ts and global checkpoint ts when a new table added, the startTs of the new table is less than global checkpoint ts.
 	if minResolvedTs != p.changefeed.TaskPosition.ResolvedTs ||
-		minCheckpointTs != p.changefeed.TaskPosition.CheckPointTs {
+		minCheckpointTs != p.changefeed.TaskPosition.CheckPointTs ||
+		deadLetterCount != p.changefeed.TaskPosition.DeadLetterCount ||
+		memoryUsage != p.changefeed.TaskPosition.MemoryUsage {
 		p.changefeed.PatchTaskPosition(func(position *model.TaskPosition) (*model.TaskPosition, error) {
 			failpoint.Inject("ProcessorUpdatePositionDelaying", nil)
 			if position == nil {
@@ -598,6 +657,8 @@ func (p *processor) handlePosition() error {
 			}
 			position.CheckPointTs = minCheckpointTs
 			position.ResolvedTs = minResolvedTs
+			position.DeadLetterCount = deadLetterCount
+			position.MemoryUsage = memoryUsage
 			return position, nil
 		})
 	}
@@ -677,7 +738,7 @@ func (p *processor) createTablePipelineImpl(ctx context.Context, tableID model.T
 	}
 	var tableName string
 	err = retry.Run(time.Millisecond*5, 3, func() error {
-		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(tableID); ok {
+		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(model.PhysicalTableID(tableID)); ok {
 			tableName = name.QuoteString()
 			return nil
 		}
@@ -687,14 +748,17 @@ func (p *processor) createTablePipelineImpl(ctx context.Context, tableID model.T
 		log.Warn("get table name for metric", zap.Error(err))
 		tableName = strconv.Itoa(int(tableID))
 	}
-	sink := p.sinkManager.CreateTableSink(tableID, replicaInfo.StartTs)
+	sink, err := p.sinkManager.CreateTableSink(ctx, tableID, replicaInfo.StartTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	table := tablepipeline.NewTablePipeline(
 		cdcCtx,
 		p.changefeed.ID,
 		p.credential,
 		kvStorage,
-		p.limitter,
+		p.memoryQuota,
 		p.mounter,
 		p.changefeed.Info.Engine,
 		p.changefeed.Info.SortDir,
@@ -702,6 +766,7 @@ func (p *processor) createTablePipelineImpl(ctx context.Context, tableID model.T
 		tableName,
 		replicaInfo,
 		sink,
+		p.redoWriter,
 		p.changefeed.Info.GetTargetTs(),
 	)
 	p.wg.Add(1)
@@ -765,6 +830,7 @@ func (p *processor) Close() error {
 	checkpointTsGauge.DeleteLabelValues(p.changefeed.ID, p.captureInfo.AdvertiseAddr)
 	checkpointTsLagGauge.DeleteLabelValues(p.changefeed.ID, p.captureInfo.AdvertiseAddr)
 	syncTableNumGauge.DeleteLabelValues(p.changefeed.ID, p.captureInfo.AdvertiseAddr)
+	memoryUsageGauge.DeleteLabelValues(p.changefeed.ID, p.captureInfo.AdvertiseAddr)
 	processorErrorCounter.DeleteLabelValues(p.changefeed.ID, p.captureInfo.AdvertiseAddr)
 	if p.sinkManager != nil {
 		return p.sinkManager.Close()
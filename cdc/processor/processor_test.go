@@ -538,6 +538,65 @@ func (s *processorSuite) TestHandleTableOperation4MultiTable(c *check.C) {
 	c.Assert(p.tables, check.HasLen, 0)
 }
 
+func (s *processorSuite) TestGoroutineQuota(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	p := newProcessor4Test()
+	// the quota is enough for two tables
+	p.changefeed.Info.Config.GoroutineQuota = 2 * tablepipeline.EstimateGoroutineNum(false)
+	var err error
+	// init tick
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	applyPatches(c, p.changefeed)
+	p.schemaStorage.AdvanceResolvedTs(200)
+	p.changefeed.Status.CheckpointTs = 90
+	p.changefeed.Status.ResolvedTs = 90
+	p.changefeed.TaskPosition.ResolvedTs = 100
+	p.changefeed.TaskPosition.CheckPointTs = 90
+
+	p.changefeed.TaskStatus.AddTable(1, &model.TableReplicaInfo{StartTs: 60}, 60)
+	p.changefeed.TaskStatus.AddTable(2, &model.TableReplicaInfo{StartTs: 60}, 60)
+	p.changefeed.TaskStatus.AddTable(3, &model.TableReplicaInfo{StartTs: 60}, 60)
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	applyPatches(c, p.changefeed)
+	c.Assert(p.tables, check.HasLen, 2)
+	// the operation of the table exceeding the quota is left dispatched
+	dispatched := make([]model.TableID, 0, 1)
+	for tableID, opt := range p.changefeed.TaskStatus.Operation {
+		if opt.Status == model.OperDispatched {
+			dispatched = append(dispatched, tableID)
+		}
+	}
+	c.Assert(dispatched, check.HasLen, 1)
+	_, exist := p.tables[dispatched[0]]
+	c.Assert(exist, check.IsFalse)
+
+	// the table is added after another table is removed
+	var removed model.TableID
+	for tableID := range p.tables {
+		removed = tableID
+		break
+	}
+	p.changefeed.TaskStatus.RemoveTable(removed, 100, false)
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	applyPatches(c, p.changefeed)
+	c.Assert(p.tables, check.HasLen, 2)
+	p.tables[removed].(*mockTablePipeline).status = pipeline.TableStatusStopped
+	for i := 0; i < 2; i++ {
+		_, err = p.Tick(ctx, p.changefeed)
+		c.Assert(err, check.IsNil)
+		applyPatches(c, p.changefeed)
+	}
+	c.Assert(p.tables, check.HasLen, 2)
+	_, exist = p.tables[removed]
+	c.Assert(exist, check.IsFalse)
+	_, exist = p.tables[dispatched[0]]
+	c.Assert(exist, check.IsTrue)
+}
+
 func (s *processorSuite) TestInitTable(c *check.C) {
 	defer testleak.AfterTest(c)()
 	p := newProcessor4Test()
@@ -111,6 +111,7 @@ func (s *stateSuite) TestChangefeedStateUpdate(c *check.C) {
 						Sink:             &config.SinkConfig{Protocol: "default"},
 						Cyclic:           &config.CyclicConfig{},
 						Scheduler:        &config.SchedulerConfig{Tp: "table-number", PollingTime: -1},
+						Consistent:       &config.ConsistentConfig{Level: "none", MaxLogSize: 64, FlushIntervalInMs: 1000},
 					},
 				},
 				Status: &model.ChangeFeedStatus{CheckpointTs: 421980719742451713, ResolvedTs: 421980720003809281},
@@ -176,6 +177,7 @@ func (s *stateSuite) TestChangefeedStateUpdate(c *check.C) {
 						Sink:             &config.SinkConfig{Protocol: "default"},
 						Cyclic:           &config.CyclicConfig{},
 						Scheduler:        &config.SchedulerConfig{Tp: "table-number", PollingTime: -1},
+						Consistent:       &config.ConsistentConfig{Level: "none", MaxLogSize: 64, FlushIntervalInMs: 1000},
 					},
 				},
 				Status: &model.ChangeFeedStatus{CheckpointTs: 421980719742451713, ResolvedTs: 421980720003809281},
@@ -35,6 +35,9 @@ type EntrySorter struct {
 	lock            sync.Mutex
 	resolvedTsGroup []uint64
 	closed          int32
+	// memorySize is the estimated memory used by the events not sent to the
+	// output channel, it's accessed atomically
+	memorySize int64
 
 	outputCh         chan *model.PolymorphicEvent
 	resolvedNotifier *notify.Notifier
@@ -94,6 +97,9 @@ func (es *EntrySorter) Run(ctx context.Context) error {
 			return
 		case es.outputCh <- entry:
 		}
+		if entry.RawKV.OpType != model.OpTypeResolved {
+			atomic.AddInt64(&es.memorySize, -entry.RawKV.ApproximateSize())
+		}
 	}
 
 	errg, ctx := errgroup.WithContext(ctx)
@@ -169,10 +175,17 @@ func (es *EntrySorter) AddEntry(ctx context.Context, entry *model.PolymorphicEve
 		es.resolvedNotifier.Notify()
 	} else {
 		es.unsorted = append(es.unsorted, entry)
+		atomic.AddInt64(&es.memorySize, entry.RawKV.ApproximateSize())
 	}
 	es.lock.Unlock()
 }
 
+// MemorySize implements the MemoryMeasurableSorter interface, all the events
+// not sent to the output channel are buffered in memory
+func (es *EntrySorter) MemorySize() int64 {
+	return atomic.LoadInt64(&es.memorySize)
+}
+
 // Output returns the sorted raw kv output channel
 func (es *EntrySorter) Output() <-chan *model.PolymorphicEvent {
 	return es.outputCh
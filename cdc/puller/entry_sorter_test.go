@@ -132,6 +132,37 @@ func (s *mockEntrySorterSuite) TestEntrySorter(c *check.C) {
 	wg.Wait()
 }
 
+func (s *mockEntrySorterSuite) TestEntrySorterMemorySize(c *check.C) {
+	defer testleak.AfterTest(c)()
+	es := NewEntrySorter()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := es.Run(ctx)
+		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+	}()
+
+	entries := []*model.RawKVEntry{
+		{CRTs: 2, OpType: model.OpTypePut, Key: []byte("k1"), Value: []byte("v1")},
+		{CRTs: 1, OpType: model.OpTypePut, Key: []byte("k2"), Value: []byte("v2")},
+	}
+	for _, entry := range entries {
+		es.AddEntry(ctx, model.NewPolymorphicEvent(entry))
+	}
+	c.Assert(es.MemorySize(), check.Equals, entries[0].ApproximateSize()+entries[1].ApproximateSize())
+
+	// the events output are not counted, the memory of an event is released
+	// before the next event is output
+	es.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 1))
+	c.Assert((<-es.Output()).RawKV.OpType, check.Equals, model.OpTypePut)
+	c.Assert((<-es.Output()).RawKV.OpType, check.Equals, model.OpTypeResolved)
+	c.Assert(es.MemorySize(), check.Equals, entries[0].ApproximateSize())
+	cancel()
+	wg.Wait()
+}
+
 func (s *mockEntrySorterSuite) TestEntrySorterRandomly(c *check.C) {
 	defer testleak.AfterTest(c)()
 	es := NewEntrySorter()
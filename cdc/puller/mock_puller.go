@@ -180,6 +180,10 @@ func (p *mockPuller) IsInitialized() bool {
 	return false
 }
 
+func (p *mockPuller) RegionCount() int {
+	return 0
+}
+
 // NewMockPullerManager creates and sets up a mock puller manager
 func NewMockPullerManager(c *check.C, newRowFormat bool) *MockPullerManager {
 	m := &MockPullerManager{
@@ -38,6 +38,8 @@ import (
 const (
 	defaultPullerEventChanSize  = 128
 	defaultPullerOutputChanSize = 128
+	// regionCountInterval is the interval of counting the regions
+	regionCountInterval = 10 * time.Second
 )
 
 // Puller pull data from tikv and push changes into a buffer
@@ -47,6 +49,9 @@ type Puller interface {
 	GetResolvedTs() uint64
 	Output() <-chan *model.RawKVEntry
 	IsInitialized() bool
+	// RegionCount returns the number of the regions sending resolved ts to
+	// the puller in the last count interval
+	RegionCount() int
 }
 
 type pullerImpl struct {
@@ -61,6 +66,7 @@ type pullerImpl struct {
 	resolvedTs     uint64
 	initialized    int64
 	enableOldValue bool
+	regionCount    int64
 }
 
 // NewPuller create a new Puller fetch event start from checkpointTs
@@ -177,6 +183,10 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 
 		start := time.Now()
 		initialized := false
+		// every region sends resolved ts periodically, so the regions sending
+		// resolved ts in an interval are all the regions of the spans
+		regions := make(map[uint64]struct{})
+		lastRegionCountTime := start
 		for {
 			var e *model.RegionFeedEvent
 			select {
@@ -194,6 +204,12 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 				}
 			} else if e.Resolved != nil {
 				metricTxnCollectCounterResolved.Inc()
+				regions[e.RegionID] = struct{}{}
+				if time.Since(lastRegionCountTime) >= regionCountInterval {
+					atomic.StoreInt64(&p.regionCount, int64(len(regions)))
+					regions = make(map[uint64]struct{}, len(regions))
+					lastRegionCountTime = time.Now()
+				}
 				if !regionspan.IsSubSpan(e.Resolved.Span, p.spans...) {
 					log.Panic("the resolved span is not in the total span",
 						zap.Reflect("resolved", e.Resolved),
@@ -243,3 +259,7 @@ func (p *pullerImpl) GetResolvedTs() uint64 {
 func (p *pullerImpl) IsInitialized() bool {
 	return atomic.LoadInt64(&p.initialized) > 0
 }
+
+func (p *pullerImpl) RegionCount() int {
+	return int(atomic.LoadInt64(&p.regionCount))
+}
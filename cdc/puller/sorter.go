@@ -26,3 +26,12 @@ type EventSorter interface {
 	AddEntry(ctx context.Context, entry *model.PolymorphicEvent)
 	Output() <-chan *model.PolymorphicEvent
 }
+
+// MemoryMeasurableSorter is an EventSorter which measures the memory used by
+// the events buffered by it
+type MemoryMeasurableSorter interface {
+	EventSorter
+	// MemorySize returns the estimated memory in bytes used by the events
+	// buffered in memory, the events spilled to the disk are not counted
+	MemorySize() int64
+}
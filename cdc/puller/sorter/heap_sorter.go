@@ -79,15 +79,19 @@ type heapSorter struct {
 
 	poolHandle    workerpool.EventHandle
 	internalState *heapSorterInternalState
+	// memorySize is shared by the heapSorters of a UnifiedSorter, it's the
+	// estimated memory used by the events not flushed to the file backends
+	memorySize *int64
 }
 
-func newHeapSorter(id int, out chan *flushTask) *heapSorter {
+func newHeapSorter(id int, out chan *flushTask, memorySize *int64) *heapSorter {
 	return &heapSorter{
-		id:        id,
-		inputCh:   make(chan *model.PolymorphicEvent, 1024*1024),
-		outputCh:  out,
-		heap:      make(sortHeap, 0, 65536),
-		canceller: new(asyncCanceller),
+		id:         id,
+		inputCh:    make(chan *model.PolymorphicEvent, 1024*1024),
+		outputCh:   out,
+		heap:       make(sortHeap, 0, 65536),
+		canceller:  new(asyncCanceller),
+		memorySize: memorySize,
 	}
 }
 
@@ -110,6 +114,16 @@ func (h *heapSorter) flush(ctx context.Context, maxResolvedTs uint64) error {
 
 	sorterFlushCountHistogram.WithLabelValues(captureAddr, changefeedID, tableName).Observe(float64(h.heap.Len()))
 
+	// the memory used by the events in the heap is released once they are
+	// written to a file backend, or the memory backend is deallocated
+	heapSize := h.internalState.heapSizeBytesEstimate
+	var memoryReleased int32
+	releaseMemory := func() {
+		if atomic.CompareAndSwapInt32(&memoryReleased, 0, 1) {
+			atomic.AddInt64(h.memorySize, -heapSize)
+		}
+	}
+
 	// We check if the heap contains only one entry and that entry is a ResolvedEvent.
 	// As an optimization, when the condition is true, we clear the heap and send an empty flush.
 	// Sending an empty flush saves CPU and potentially IO.
@@ -151,6 +165,7 @@ func (h *heapSorter) flush(ctx context.Context, maxResolvedTs uint64) error {
 	var oldHeap sortHeap
 	if !isEmptyFlush {
 		task.dealloc = func() error {
+			releaseMemory()
 			backEnd := task.GetBackEnd()
 			if backEnd != nil {
 				defer task.markDeallocated()
@@ -161,6 +176,7 @@ func (h *heapSorter) flush(ctx context.Context, maxResolvedTs uint64) error {
 		oldHeap = h.heap
 		h.heap = make(sortHeap, 0, 65536)
 	} else {
+		releaseMemory()
 		task.dealloc = func() error {
 			task.markDeallocated()
 			return nil
@@ -251,6 +267,9 @@ func (h *heapSorter) flush(ctx context.Context, maxResolvedTs uint64) error {
 			}
 
 			backEndFinal = nil
+			if _, ok := backEnd.(*fileBackEnd); ok {
+				releaseMemory()
+			}
 
 			failpoint.Inject("sorterDebug", func() {
 				tableID, tableName := util.TableIDFromCtx(ctx)
@@ -316,7 +335,9 @@ func (h *heapSorter) init(ctx context.Context, onError func(err error)) {
 		}
 
 		// 5 * 8 is for the 5 fields in PolymorphicEvent
-		state.heapSizeBytesEstimate += event.RawKV.ApproximateSize() + 40
+		eventSize := event.RawKV.ApproximateSize() + 40
+		state.heapSizeBytesEstimate += eventSize
+		atomic.AddInt64(h.memorySize, eventSize)
 		needFlush := state.heapSizeBytesEstimate >= int64(state.sorterConfig.ChunkSizeLimit) ||
 			(isResolvedEvent && state.rateCounter < flushRateLimitPerSecond)
 
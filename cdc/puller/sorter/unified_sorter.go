@@ -37,6 +37,9 @@ type UnifiedSorter struct {
 	dir         string
 	pool        *backEndPool
 	metricsInfo *metricsInfo
+	// memorySize is the estimated memory used by the events in the heaps and
+	// the memory backends, it's accessed atomically
+	memorySize int64
 }
 
 type metricsInfo struct {
@@ -150,7 +153,7 @@ func (s *UnifiedSorter) Run(ctx context.Context) error {
 	heapSorterErrOnce := &sync.Once{}
 	heapSorters := make([]*heapSorter, sorterConfig.NumConcurrentWorker)
 	for i := range heapSorters {
-		heapSorters[i] = newHeapSorter(i, heapSorterCollectCh)
+		heapSorters[i] = newHeapSorter(i, heapSorterCollectCh, &s.memorySize)
 		heapSorters[i].init(subctx, func(err error) {
 			heapSorterErrOnce.Do(func() {
 				heapSorterErrCh <- err
@@ -271,6 +274,12 @@ func (s *UnifiedSorter) AddEntry(ctx context.Context, entry *model.PolymorphicEv
 	}
 }
 
+// MemorySize implements the MemoryMeasurableSorter interface, the events
+// flushed to the file backends are not counted
+func (s *UnifiedSorter) MemorySize() int64 {
+	return atomic.LoadInt64(&s.memorySize)
+}
+
 // Output implements the EventSorter interface
 func (s *UnifiedSorter) Output() <-chan *model.PolymorphicEvent {
 	return s.outputCh
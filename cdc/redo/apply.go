@@ -0,0 +1,165 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"go.uber.org/zap"
+)
+
+const (
+	applyFlushCheckInterval = 50 * time.Millisecond
+	// applyBatchSize is the number of the rows emitted to the sink in a batch
+	applyBatchSize = 1024
+)
+
+// ApplyConfig is the config of applying the redo log to the downstream
+type ApplyConfig struct {
+	Storage      string
+	ChangefeedID string
+	SinkURI      string
+	// ReplicaConfig is the config of the changefeed, such as the route rules
+	// of the sink, the default config is used if it's nil
+	ReplicaConfig *config.ReplicaConfig
+}
+
+// Apply replays the redo log of the changefeed to the downstream, after that
+// the downstream is consistent at the resolved ts of the redo log.
+func Apply(ctx context.Context, cfg *ApplyConfig) (*Meta, error) {
+	reader, err := NewLogReader(ctx, cfg.Storage, cfg.ChangefeedID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	meta, err := reader.ReadMeta(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ddls, err := reader.ReadDDLs(ctx, meta.CheckpointTs, meta.ResolvedTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rows, err := reader.ReadRows(ctx, meta.CheckpointTs, meta.ResolvedTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer rows.Close() //nolint:errcheck
+	log.Info("redo log opened", zap.String("changefeed", cfg.ChangefeedID),
+		zap.Uint64("checkpointTs", meta.CheckpointTs), zap.Uint64("resolvedTs", meta.ResolvedTs),
+		zap.Int("ddls", len(ddls)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	replicaConfig := cfg.ReplicaConfig
+	if replicaConfig == nil {
+		replicaConfig = config.GetDefaultReplicaConfig()
+	}
+	f, err := filter.NewFilter(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	opts := map[string]string{sink.OptChangefeedID: cfg.ChangefeedID}
+	errCh := make(chan error, 16)
+	s, err := sink.NewSink(ctx, cfg.ChangefeedID, cfg.SinkURI, f, replicaConfig, opts, errCh)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer s.Close() //nolint:errcheck
+	if err := replay(ctx, s, errCh, meta, rows, ddls); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return meta, nil
+}
+
+// rowIterator iterates the rows sorted by the commit ts, it returns nil if
+// there are no more rows
+type rowIterator interface {
+	Next() (*model.RowChangedEvent, error)
+}
+
+// replay emits the rows and the DDLs sorted by the commit ts to the sink, the
+// rows committed before a DDL are flushed before the DDL is executed. The rows
+// are emitted in batches, and flushed once a batch is emitted, so the memory
+// used by the sink is bounded.
+func replay(ctx context.Context, s sink.Sink, errCh <-chan error, meta *Meta,
+	rows rowIterator, ddls []*model.DDLEvent) error {
+	flush := func(resolvedTs uint64) error {
+		for {
+			checkpointTs, err := s.FlushRowChangedEvents(ctx, resolvedTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if checkpointTs >= resolvedTs {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case err := <-errCh:
+				return errors.Trace(err)
+			case <-time.After(applyFlushCheckInterval):
+			}
+		}
+	}
+	next, err := rows.Next()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// emitRows emits all the rows committed before ts
+	emitRows := func(ts uint64) error {
+		batch := make([]*model.RowChangedEvent, 0, applyBatchSize)
+		for next != nil && next.CommitTs < ts {
+			batch = append(batch, next)
+			if next, err = rows.Next(); err != nil {
+				return errors.Trace(err)
+			}
+			// the transaction of the last row is not split between batches
+			if len(batch) >= applyBatchSize && (next == nil || next.CommitTs > batch[len(batch)-1].CommitTs) {
+				if err := s.EmitRowChangedEvents(ctx, batch...); err != nil {
+					return errors.Trace(err)
+				}
+				if err := flush(batch[len(batch)-1].CommitTs); err != nil {
+					return errors.Trace(err)
+				}
+				batch = batch[:0]
+			}
+		}
+		return errors.Trace(s.EmitRowChangedEvents(ctx, batch...))
+	}
+	for _, ddl := range ddls {
+		if err := emitRows(ddl.CommitTs); err != nil {
+			return errors.Trace(err)
+		}
+		if err := flush(ddl.CommitTs - 1); err != nil {
+			return errors.Trace(err)
+		}
+		err := s.EmitDDLEvent(ctx, ddl)
+		if err != nil && cerror.ErrDDLEventIgnored.NotEqual(err) {
+			return errors.Trace(err)
+		}
+		log.Info("redo DDL applied", zap.String("query", ddl.Query), zap.Uint64("commitTs", ddl.CommitTs))
+	}
+	if err := emitRows(meta.ResolvedTs + 1); err != nil {
+		return errors.Trace(err)
+	}
+	return flush(meta.ResolvedTs)
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// valueKind is the go type of a column value produced by the mounter, it's
+// recorded in the redo log to decode the value to the same type.
+type valueKind string
+
+const (
+	kindNull    valueKind = "null"
+	kindInt     valueKind = "int"
+	kindUint    valueKind = "uint"
+	kindFloat32 valueKind = "float32"
+	kindFloat64 valueKind = "float64"
+	kindString  valueKind = "string"
+	kindBytes   valueKind = "bytes"
+)
+
+type logColumn struct {
+	Name  string               `json:"name"`
+	Type  byte                 `json:"type"`
+	Flag  model.ColumnFlagType `json:"flag"`
+	Kind  valueKind            `json:"kind"`
+	Value string               `json:"value,omitempty"`
+}
+
+// logRow is the format of a row changed event in the redo log
+type logRow struct {
+	StartTs          uint64           `json:"start-ts"`
+	CommitTs         uint64           `json:"commit-ts"`
+	Table            *model.TableName `json:"table"`
+	TableInfoVersion uint64           `json:"table-info-version,omitempty"`
+	ReplicaID        uint64           `json:"replica-id"`
+	Columns          []*logColumn     `json:"columns"`
+	PreColumns       []*logColumn     `json:"pre-columns"`
+	IndexColumns     [][]int          `json:"index-columns"`
+}
+
+func encodeValue(value interface{}) (valueKind, string, error) {
+	switch v := value.(type) {
+	case nil:
+		return kindNull, "", nil
+	case int64:
+		return kindInt, strconv.FormatInt(v, 10), nil
+	case int:
+		return kindInt, strconv.FormatInt(int64(v), 10), nil
+	case uint64:
+		return kindUint, strconv.FormatUint(v, 10), nil
+	case float32:
+		return kindFloat32, strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return kindFloat64, strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return kindString, v, nil
+	case []byte:
+		return kindBytes, base64.StdEncoding.EncodeToString(v), nil
+	default:
+		return "", "", cerror.ErrMarshalFailed.GenWithStack("unsupported column value type %T", value)
+	}
+}
+
+func decodeValue(kind valueKind, value string) (interface{}, error) {
+	switch kind {
+	case kindNull:
+		return nil, nil
+	case kindInt:
+		return strconv.ParseInt(value, 10, 64)
+	case kindUint:
+		return strconv.ParseUint(value, 10, 64)
+	case kindFloat32:
+		v, err := strconv.ParseFloat(value, 32)
+		return float32(v), err
+	case kindFloat64:
+		return strconv.ParseFloat(value, 64)
+	case kindString:
+		return value, nil
+	case kindBytes:
+		return base64.StdEncoding.DecodeString(value)
+	default:
+		return nil, cerror.ErrUnmarshalFailed.GenWithStack("unknown column value kind %s", kind)
+	}
+}
+
+func encodeColumns(cols []*model.Column) ([]*logColumn, error) {
+	if cols == nil {
+		return nil, nil
+	}
+	logCols := make([]*logColumn, len(cols))
+	for i, col := range cols {
+		if col == nil {
+			// the column is filtered by the column rules
+			continue
+		}
+		kind, value, err := encodeValue(col.Value)
+		if err != nil {
+			return nil, err
+		}
+		logCols[i] = &logColumn{Name: col.Name, Type: col.Type, Flag: col.Flag, Kind: kind, Value: value}
+	}
+	return logCols, nil
+}
+
+func decodeColumns(logCols []*logColumn) ([]*model.Column, error) {
+	if logCols == nil {
+		return nil, nil
+	}
+	cols := make([]*model.Column, len(logCols))
+	for i, logCol := range logCols {
+		if logCol == nil {
+			continue
+		}
+		value, err := decodeValue(logCol.Kind, logCol.Value)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrUnmarshalFailed, err)
+		}
+		cols[i] = &model.Column{Name: logCol.Name, Type: logCol.Type, Flag: logCol.Flag, Value: value}
+	}
+	return cols, nil
+}
+
+// encodeRow encodes a row changed event to a line of the redo log
+func encodeRow(row *model.RowChangedEvent) ([]byte, error) {
+	columns, err := encodeColumns(row.Columns)
+	if err != nil {
+		return nil, err
+	}
+	preColumns, err := encodeColumns(row.PreColumns)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(&logRow{
+		StartTs:          row.StartTs,
+		CommitTs:         row.CommitTs,
+		Table:            row.Table,
+		TableInfoVersion: row.TableInfoVersion,
+		ReplicaID:        row.ReplicaID,
+		Columns:          columns,
+		PreColumns:       preColumns,
+		IndexColumns:     row.IndexColumns,
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	return data, nil
+}
+
+// decodeRow decodes a line of the redo log to a row changed event
+func decodeRow(data []byte) (*model.RowChangedEvent, error) {
+	var r logRow
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, cerror.WrapError(cerror.ErrUnmarshalFailed, err)
+	}
+	columns, err := decodeColumns(r.Columns)
+	if err != nil {
+		return nil, err
+	}
+	preColumns, err := decodeColumns(r.PreColumns)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RowChangedEvent{
+		StartTs:          r.StartTs,
+		CommitTs:         r.CommitTs,
+		Table:            r.Table,
+		TableInfoVersion: r.TableInfoVersion,
+		ReplicaID:        r.ReplicaID,
+		Columns:          columns,
+		PreColumns:       preColumns,
+		IndexColumns:     r.IndexColumns,
+	}, nil
+}
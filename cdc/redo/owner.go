@@ -0,0 +1,164 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/br/pkg/storage"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/backup"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// defaultGCInterval is the min interval between two GCs of the redo log
+const defaultGCInterval = time.Minute
+
+// fileRemover removes a log file from the storage of the redo log
+type fileRemover func(name string) error
+
+// newFileRemover returns the fileRemover of the local storage, or nil if the
+// storage is not a local directory. The external storage of BR can't remove
+// files, so the log files in S3 are left to the lifecycle rules of the bucket.
+func newFileRemover(backend *backup.StorageBackend) fileRemover {
+	local := backend.GetLocal()
+	if local == nil {
+		return nil
+	}
+	return func(name string) error {
+		err := os.Remove(filepath.Join(local.Path, name))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Trace(err)
+	}
+}
+
+// OwnerWriter writes the DDL events and the meta of a changefeed to the redo
+// log, it's used by the owner. The log files in the local storage which are
+// not needed by the meta any more are removed by the owner too.
+type OwnerWriter struct {
+	storage       storage.ExternalStorage
+	remover       fileRemover
+	changefeedID  string
+	flushInterval time.Duration
+	gcInterval    time.Duration
+
+	meta          Meta
+	lastFlushTime time.Time
+	lastGCTime    time.Time
+	// gcTs is the checkpoint ts of the last GC
+	gcTs uint64
+}
+
+// NewOwnerWriter creates a new OwnerWriter
+func NewOwnerWriter(ctx context.Context, changefeedID string, cfg *config.ConsistentConfig) (*OwnerWriter, error) {
+	s, backend, err := newStorage(ctx, cfg.Storage, changefeedID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &OwnerWriter{
+		storage:       s,
+		remover:       newFileRemover(backend),
+		changefeedID:  changefeedID,
+		flushInterval: time.Duration(cfg.FlushIntervalInMs) * time.Millisecond,
+		gcInterval:    defaultGCInterval,
+	}, nil
+}
+
+// EmitDDLEvent writes the DDL event to the redo log synchronously, it must be
+// called before the DDL event is applied to the downstream.
+func (w *OwnerWriter) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	data, err := json.Marshal(ddl)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	err = w.storage.WriteFile(ctx, makeDDLFileName(ddl.CommitTs), data)
+	return cerror.WrapError(cerror.ErrRedoStorage, err)
+}
+
+// FlushMeta writes the checkpoint ts and the resolved ts to the meta of the
+// redo log. The meta is written at most once in the flush interval, the
+// global resolved ts of the changefeed must not exceed ResolvedTs().
+func (w *OwnerWriter) FlushMeta(ctx context.Context, checkpointTs, resolvedTs uint64) error {
+	if checkpointTs < w.meta.CheckpointTs || resolvedTs < w.meta.ResolvedTs || checkpointTs > resolvedTs {
+		return nil
+	}
+	if checkpointTs == w.meta.CheckpointTs && resolvedTs == w.meta.ResolvedTs {
+		return nil
+	}
+	if time.Since(w.lastFlushTime) < w.flushInterval {
+		return nil
+	}
+	meta := Meta{CheckpointTs: checkpointTs, ResolvedTs: resolvedTs}
+	data, err := json.Marshal(&meta)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	if err := w.storage.WriteFile(ctx, metaFileName, data); err != nil {
+		return cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	w.meta = meta
+	w.lastFlushTime = time.Now()
+	if time.Since(w.lastGCTime) >= w.gcInterval {
+		w.gc(ctx)
+		w.lastGCTime = time.Now()
+	}
+	return nil
+}
+
+// gc removes the log files whose events are all committed before or at the
+// checkpoint ts in the meta, they are never read again. The replication is
+// not affected by the failure of GC, the files are removed by the next GC.
+func (w *OwnerWriter) gc(ctx context.Context) {
+	checkpointTs := w.meta.CheckpointTs
+	if w.remover == nil || checkpointTs <= w.gcTs {
+		return
+	}
+	var names []string
+	err := w.storage.WalkDir(ctx, &storage.WalkOption{}, func(name string, size int64) error {
+		// ts is the max commit ts of a row file or the commit ts of a DDL file
+		if _, ts, ok := parseFileName(name); ok && ts <= checkpointTs {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn("list the redo log files failed", zap.String("changefeed", w.changefeedID), zap.Error(err))
+		return
+	}
+	for _, name := range names {
+		if err := w.remover(name); err != nil {
+			log.Warn("remove the redo log file failed", zap.String("changefeed", w.changefeedID),
+				zap.String("name", name), zap.Error(err))
+			return
+		}
+	}
+	w.gcTs = checkpointTs
+	log.Info("redo log GC finished", zap.String("changefeed", w.changefeedID),
+		zap.Uint64("checkpointTs", checkpointTs), zap.Int("removedFiles", len(names)))
+}
+
+// ResolvedTs returns the resolved ts in the meta of the redo log
+func (w *OwnerWriter) ResolvedTs() uint64 {
+	return w.meta.ResolvedTs
+}
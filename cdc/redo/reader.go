@@ -0,0 +1,245 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pingcap/br/pkg/storage"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// LogReader reads the redo log of a changefeed
+type LogReader struct {
+	storage      storage.ExternalStorage
+	changefeedID string
+}
+
+// NewLogReader creates a new LogReader
+func NewLogReader(ctx context.Context, storageURI, changefeedID string) (*LogReader, error) {
+	s, _, err := newStorage(ctx, storageURI, changefeedID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &LogReader{storage: s, changefeedID: changefeedID}, nil
+}
+
+// ReadMeta reads the meta of the redo log
+func (r *LogReader) ReadMeta(ctx context.Context) (*Meta, error) {
+	exist, err := r.storage.FileExists(ctx, metaFileName)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	if !exist {
+		return nil, cerror.ErrRedoMetaNotFound.GenWithStackByArgs(r.changefeedID)
+	}
+	data, err := r.storage.ReadFile(ctx, metaFileName)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	meta := new(Meta)
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, cerror.ErrRedoDecodeFailed.Wrap(err).GenWithStackByArgs(metaFileName)
+	}
+	return meta, nil
+}
+
+// listFiles lists the row files which may contain the events committed after
+// startTs and the DDL files committed in (startTs, endTs]
+func (r *LogReader) listFiles(ctx context.Context, startTs, endTs uint64) (rowFiles, ddlFiles []string, err error) {
+	err = r.storage.WalkDir(ctx, &storage.WalkOption{}, func(name string, size int64) error {
+		isRow, ts, ok := parseFileName(name)
+		if !ok {
+			return nil
+		}
+		if isRow {
+			// ts is the max commit ts of the rows in the file
+			if ts > startTs {
+				rowFiles = append(rowFiles, name)
+			}
+		} else if ts > startTs && ts <= endTs {
+			ddlFiles = append(ddlFiles, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	// keep the order of the files written by the same writer
+	sort.Strings(rowFiles)
+	sort.Strings(ddlFiles)
+	return rowFiles, ddlFiles, nil
+}
+
+// ReadRows returns a RowIterator of the row changed events committed in
+// (startTs, endTs], the iterator must be closed after it's used.
+func (r *LogReader) ReadRows(ctx context.Context, startTs, endTs uint64) (*RowIterator, error) {
+	rowFiles, _, err := r.listFiles(ctx, startTs, endTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	it := &RowIterator{startTs: startTs, endTs: endTs}
+	for i, name := range rowFiles {
+		file, err := r.storage.Open(ctx, name)
+		if err != nil {
+			it.Close() //nolint:errcheck
+			return nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+		}
+		f := &rowFileReader{name: name, index: i, file: file, reader: bufio.NewReader(file)}
+		it.files = append(it.files, f)
+		if err := it.push(f); err != nil {
+			it.Close() //nolint:errcheck
+			return nil, errors.Trace(err)
+		}
+	}
+	return it, nil
+}
+
+// ReadDDLs reads the DDL events committed in (startTs, endTs] from the redo
+// log, the events are sorted by the commit ts.
+func (r *LogReader) ReadDDLs(ctx context.Context, startTs, endTs uint64) ([]*model.DDLEvent, error) {
+	_, ddlFiles, err := r.listFiles(ctx, startTs, endTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ddls := make([]*model.DDLEvent, 0, len(ddlFiles))
+	for _, name := range ddlFiles {
+		data, err := r.storage.ReadFile(ctx, name)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+		}
+		ddl := new(model.DDLEvent)
+		if err := json.Unmarshal(data, ddl); err != nil {
+			return nil, cerror.ErrRedoDecodeFailed.Wrap(err).GenWithStackByArgs(name)
+		}
+		ddls = append(ddls, ddl)
+	}
+	return ddls, nil
+}
+
+// rowFileReader reads the rows of a row file line by line
+type rowFileReader struct {
+	name string
+	// index is the order of the file, the rows with the same commit ts are
+	// returned in the order of the files
+	index  int
+	file   storage.ExternalFileReader
+	reader *bufio.Reader
+	// row is the next row of the file
+	row *model.RowChangedEvent
+}
+
+// next reads the next row of the file, row is nil at the end of the file
+func (f *rowFileReader) next() error {
+	f.row = nil
+	for {
+		line, err := f.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return cerror.WrapError(cerror.ErrRedoStorage, err)
+		}
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		if len(line) != 0 {
+			row, err := decodeRow(line)
+			if err != nil {
+				return cerror.ErrRedoDecodeFailed.Wrap(err).GenWithStackByArgs(f.name)
+			}
+			f.row = row
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+type rowFileHeap []*rowFileReader
+
+func (h rowFileHeap) Len() int { return len(h) }
+func (h rowFileHeap) Less(i, j int) bool {
+	if h[i].row.CommitTs != h[j].row.CommitTs {
+		return h[i].row.CommitTs < h[j].row.CommitTs
+	}
+	return h[i].index < h[j].index
+}
+func (h rowFileHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *rowFileHeap) Push(x interface{}) { *h = append(*h, x.(*rowFileReader)) }
+func (h *rowFileHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// RowIterator iterates the row changed events of the redo log in the order of
+// the commit ts. The rows of every file are sorted by the commit ts, so the
+// files are read line by line and merged, only a row of every file is kept in
+// memory.
+type RowIterator struct {
+	startTs uint64
+	endTs   uint64
+	files   []*rowFileReader
+	heap    rowFileHeap
+}
+
+// push reads the next row in (startTs, endTs] of the file and pushes the file
+// into the heap, the file is skipped if there is no such row.
+func (it *RowIterator) push(f *rowFileReader) error {
+	for {
+		if err := f.next(); err != nil {
+			return errors.Trace(err)
+		}
+		if f.row == nil || f.row.CommitTs > it.endTs {
+			// the rows after endTs are skipped since the file is sorted
+			return nil
+		}
+		if f.row.CommitTs > it.startTs {
+			heap.Push(&it.heap, f)
+			return nil
+		}
+	}
+}
+
+// Next returns the next row changed event, it returns nil if there are no
+// more events.
+func (it *RowIterator) Next() (*model.RowChangedEvent, error) {
+	if it.heap.Len() == 0 {
+		return nil, nil
+	}
+	f := heap.Pop(&it.heap).(*rowFileReader)
+	row := f.row
+	if err := it.push(f); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return row, nil
+}
+
+// Close closes all the files of the iterator
+func (it *RowIterator) Close() error {
+	var firstErr error
+	for _, f := range it.files {
+		if err := f.file.Close(); err != nil && firstErr == nil {
+			firstErr = cerror.WrapError(cerror.ErrRedoStorage, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redo implements the redo log of a changefeed. The row changed events
+// and the DDL events are persisted to an external storage before they are
+// applied to the downstream, so the downstream can be recovered to a globally
+// consistent state by replaying the redo log if the upstream is lost.
+//
+// The redo log of a changefeed is made of the following objects under the
+// <storage>/<changefeed-id>/ directory:
+//
+//	meta                                              the checkpoint ts and the resolved ts of the redo log
+//	row_<capture>_<writer>_<seq>_<max-commit-ts>.log  row changed events in JSON lines, written by processors
+//	ddl_<commit-ts>.log                               a DDL event in JSON, written by the owner
+//
+// The log files whose events are all committed before or at the checkpoint ts
+// in the meta are removed by the owner periodically if the storage is a local
+// directory. The external storage of BR can't remove files, so the log files
+// in S3 are left to the lifecycle rules of the bucket.
+package redo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/br/pkg/storage"
+	"github.com/pingcap/kvproto/pkg/backup"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+const (
+	// ConsistentLevelNone means the redo log is disabled
+	ConsistentLevelNone = "none"
+	// ConsistentLevelEventual means the redo log is written, the downstream
+	// can be recovered to a consistent state by `cdc redo apply`
+	ConsistentLevelEventual = "eventual"
+
+	metaFileName  = "meta"
+	rowFilePrefix = "row_"
+	ddlFilePrefix = "ddl_"
+	logFileSuffix = ".log"
+)
+
+// Meta is the meta of the redo log of a changefeed. All the events committed
+// before or at CheckpointTs are applied to the downstream, and all the events
+// committed in (CheckpointTs, ResolvedTs] are persisted in the redo log.
+type Meta struct {
+	CheckpointTs uint64 `json:"checkpoint-ts"`
+	ResolvedTs   uint64 `json:"resolved-ts"`
+}
+
+// IsConsistentEnabled returns true if the redo log is enabled by the config
+func IsConsistentEnabled(cfg *config.ConsistentConfig) bool {
+	return cfg != nil && cfg.Level == ConsistentLevelEventual
+}
+
+// ValidateConsistentConfig checks whether the consistent config is valid
+func ValidateConsistentConfig(cfg *config.ConsistentConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Level {
+	case "", ConsistentLevelNone:
+		return nil
+	case ConsistentLevelEventual:
+	default:
+		return cerror.ErrRedoConfigInvalid.GenWithStackByArgs(
+			fmt.Sprintf("unknown consistent level %s", cfg.Level))
+	}
+	if cfg.Storage == "" {
+		return cerror.ErrRedoConfigInvalid.GenWithStackByArgs("storage is required by the eventual consistent level")
+	}
+	if cfg.MaxLogSize <= 0 || cfg.FlushIntervalInMs <= 0 {
+		return cerror.ErrRedoConfigInvalid.GenWithStackByArgs("max-log-size and flush-interval must be positive")
+	}
+	_, err := storage.ParseBackend(cfg.Storage, nil)
+	if err != nil {
+		return cerror.ErrRedoConfigInvalid.GenWithStackByArgs(err.Error())
+	}
+	return nil
+}
+
+// newStorage creates the external storage of the redo log of a changefeed,
+// which is the changefeed-id directory under the storage URI. The storage
+// backend is returned too.
+func newStorage(
+	ctx context.Context, storageURI string, changefeedID string,
+) (storage.ExternalStorage, *backup.StorageBackend, error) {
+	u, err := storage.ParseRawURL(storageURI)
+	if err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	if u.Scheme == "" {
+		// a local path without scheme
+		u = &url.URL{Scheme: "local", Path: storageURI}
+	}
+	u.Path = path.Join(u.Path, changefeedID)
+	backend, err := storage.ParseBackend(u.String(), nil)
+	if err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	s, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
+		SendCredentials: false,
+		SkipCheckPath:   false,
+		HTTPClient:      nil,
+	})
+	if err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrRedoStorage, err)
+	}
+	return s, backend, nil
+}
+
+func makeRowFileName(captureID string, writerID int64, seq uint64, maxCommitTs uint64) string {
+	// the numbers are padded so the files of a writer are listed in order
+	return fmt.Sprintf("%s%s_%020d_%020d_%d%s", rowFilePrefix, captureID, writerID, seq, maxCommitTs, logFileSuffix)
+}
+
+func makeDDLFileName(commitTs uint64) string {
+	return fmt.Sprintf("%s%020d%s", ddlFilePrefix, commitTs, logFileSuffix)
+}
+
+// parseFileName returns the max commit ts of the row file or the commit ts
+// of the DDL file
+func parseFileName(name string) (isRow bool, ts uint64, ok bool) {
+	name = path.Base(name)
+	if !strings.HasSuffix(name, logFileSuffix) {
+		return false, 0, false
+	}
+	name = strings.TrimSuffix(name, logFileSuffix)
+	var tsStr string
+	switch {
+	case strings.HasPrefix(name, rowFilePrefix):
+		isRow = true
+		tsStr = name[strings.LastIndex(name, "_")+1:]
+	case strings.HasPrefix(name, ddlFilePrefix):
+		tsStr = strings.TrimPrefix(name, ddlFilePrefix)
+	default:
+		return false, 0, false
+	}
+	ts, err := strconv.ParseUint(tsStr, 10, 64)
+	if err != nil {
+		return false, 0, false
+	}
+	return isRow, ts, true
+}
@@ -0,0 +1,316 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pingcap/br/pkg/storage"
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type redoSuite struct{}
+
+var _ = check.Suite(&redoSuite{})
+
+func newConsistentConfig(storage string) *config.ConsistentConfig {
+	return &config.ConsistentConfig{
+		Level:             ConsistentLevelEventual,
+		MaxLogSize:        1,
+		FlushIntervalInMs: 10,
+		Storage:           storage,
+	}
+}
+
+func (s *redoSuite) TestValidateConsistentConfig(c *check.C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(ValidateConsistentConfig(nil), check.IsNil)
+	c.Assert(ValidateConsistentConfig(config.GetDefaultReplicaConfig().Consistent), check.IsNil)
+	c.Assert(ValidateConsistentConfig(newConsistentConfig("s3://bucket/prefix")), check.IsNil)
+	c.Assert(ValidateConsistentConfig(newConsistentConfig("")), check.ErrorMatches, ".*storage is required.*")
+	c.Assert(ValidateConsistentConfig(newConsistentConfig("hdfs://redo")), check.ErrorMatches, ".*not support.*")
+	c.Assert(ValidateConsistentConfig(&config.ConsistentConfig{Level: "strong"}), check.ErrorMatches, ".*unknown consistent level.*")
+}
+
+func (s *redoSuite) TestCodec(c *check.C) {
+	defer testleak.AfterTest(c)()
+	row := &model.RowChangedEvent{
+		StartTs:  1,
+		CommitTs: 2,
+		Table:    &model.TableName{Schema: "test", Table: "t", TableID: 10},
+		Columns: []*model.Column{
+			{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(-1)},
+			{Name: "b", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag, Value: uint64(18446744073709551615)},
+			{Name: "c", Type: mysql.TypeFloat, Value: float32(1.5)},
+			{Name: "d", Type: mysql.TypeDouble, Value: float64(0.1)},
+			{Name: "e", Type: mysql.TypeNewDecimal, Value: "3.14"},
+			{Name: "f", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0, 1, '\n'}},
+			{Name: "g", Type: mysql.TypeVarchar, Value: nil},
+			nil,
+		},
+		IndexColumns: [][]int{{0}},
+	}
+	data, err := encodeRow(row)
+	c.Assert(err, check.IsNil)
+	decoded, err := decodeRow(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, row)
+
+	row.Columns[0].Value = struct{}{}
+	_, err = encodeRow(row)
+	c.Assert(err, check.ErrorMatches, ".*unsupported column value type.*")
+}
+
+func (s *redoSuite) TestFileName(c *check.C) {
+	defer testleak.AfterTest(c)()
+	isRow, ts, ok := parseFileName(makeRowFileName("capture-1", 2, 3, 4))
+	c.Assert(ok, check.IsTrue)
+	c.Assert(isRow, check.IsTrue)
+	c.Assert(ts, check.Equals, uint64(4))
+	isRow, ts, ok = parseFileName("cf/" + makeDDLFileName(5))
+	c.Assert(ok, check.IsTrue)
+	c.Assert(isRow, check.IsFalse)
+	c.Assert(ts, check.Equals, uint64(5))
+	_, _, ok = parseFileName(metaFileName)
+	c.Assert(ok, check.IsFalse)
+}
+
+func newRow(tableID model.TableID, commitTs uint64) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		StartTs:  commitTs - 1,
+		CommitTs: commitTs,
+		Table:    &model.TableName{Schema: "test", Table: fmt.Sprintf("t%d", tableID), TableID: tableID},
+		Columns:  []*model.Column{{Name: "id", Type: mysql.TypeLong, Value: int64(commitTs)}},
+	}
+}
+
+func newDDL(commitTs uint64) *model.DDLEvent {
+	return &model.DDLEvent{
+		StartTs:   commitTs - 1,
+		CommitTs:  commitTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "ALTER TABLE test.t1 ADD COLUMN a INT",
+		Type:      timodel.ActionAddColumn,
+	}
+}
+
+func (s *redoSuite) TestWriteAndRead(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	cfg := newConsistentConfig(c.MkDir())
+
+	writer, err := NewLogWriter(ctx, "test-cf", "capture-1", cfg)
+	c.Assert(err, check.IsNil)
+	writer.AddTable(1, 10)
+	writer.AddTable(2, 10)
+	c.Assert(writer.EmitRowChangedEvents(newRow(1, 11), newRow(2, 12)), check.IsNil)
+	writer.UpdateResolvedTs(1, 12)
+	// the resolved ts takes effect after flush
+	c.Assert(writer.ResolvedTs(1), check.Equals, uint64(10))
+	c.Assert(writer.flush(ctx), check.IsNil)
+	c.Assert(writer.ResolvedTs(1), check.Equals, uint64(12))
+	c.Assert(writer.ResolvedTs(2), check.Equals, uint64(10))
+
+	// the rows in a file are sorted by the commit ts
+	c.Assert(writer.EmitRowChangedEvents(newRow(2, 16), newRow(1, 15), newRow(1, 20)), check.IsNil)
+	writer.UpdateResolvedTs(2, 16)
+	writer.RemoveTable(1)
+	c.Assert(writer.flush(ctx), check.IsNil)
+	c.Assert(writer.ResolvedTs(1), check.Equals, uint64(0))
+	c.Assert(writer.ResolvedTs(2), check.Equals, uint64(16))
+
+	ownerWriter, err := NewOwnerWriter(ctx, "test-cf", cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(ownerWriter.EmitDDLEvent(ctx, newDDL(14)), check.IsNil)
+	c.Assert(ownerWriter.EmitDDLEvent(ctx, newDDL(18)), check.IsNil)
+	c.Assert(ownerWriter.FlushMeta(ctx, 11, 16), check.IsNil)
+	c.Assert(ownerWriter.ResolvedTs(), check.Equals, uint64(16))
+	// the meta is not written again in the flush interval
+	ownerWriter.flushInterval = time.Hour
+	c.Assert(ownerWriter.FlushMeta(ctx, 12, 17), check.IsNil)
+	c.Assert(ownerWriter.ResolvedTs(), check.Equals, uint64(16))
+
+	reader, err := NewLogReader(ctx, cfg.Storage, "test-cf")
+	c.Assert(err, check.IsNil)
+	meta, err := reader.ReadMeta(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(meta, check.DeepEquals, &Meta{CheckpointTs: 11, ResolvedTs: 16})
+	c.Assert(readRows(ctx, c, reader, meta.CheckpointTs, meta.ResolvedTs), check.DeepEquals,
+		[]*model.RowChangedEvent{newRow(2, 12), newRow(1, 15), newRow(2, 16)})
+	ddls, err := reader.ReadDDLs(ctx, meta.CheckpointTs, meta.ResolvedTs)
+	c.Assert(err, check.IsNil)
+	c.Assert(ddls, check.DeepEquals, []*model.DDLEvent{newDDL(14)})
+
+	reader, err = NewLogReader(ctx, cfg.Storage, "other-cf")
+	c.Assert(err, check.IsNil)
+	_, err = reader.ReadMeta(ctx)
+	c.Assert(err, check.ErrorMatches, ".*redo log meta of changefeed other-cf is not found.*")
+}
+
+func readRows(ctx context.Context, c *check.C, reader *LogReader, startTs, endTs uint64) []*model.RowChangedEvent {
+	it, err := reader.ReadRows(ctx, startTs, endTs)
+	c.Assert(err, check.IsNil)
+	defer it.Close() //nolint:errcheck
+	var rows []*model.RowChangedEvent
+	for {
+		row, err := it.Next()
+		c.Assert(err, check.IsNil)
+		if row == nil {
+			return rows
+		}
+		rows = append(rows, row)
+	}
+}
+
+func (s *redoSuite) TestReadRowsMerged(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	cfg := newConsistentConfig(c.MkDir())
+
+	writer1, err := NewLogWriter(ctx, "test-cf", "capture-1", cfg)
+	c.Assert(err, check.IsNil)
+	writer2, err := NewLogWriter(ctx, "test-cf", "capture-2", cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(writer1.EmitRowChangedEvents(newRow(1, 13), newRow(1, 11)), check.IsNil)
+	c.Assert(writer1.flush(ctx), check.IsNil)
+	c.Assert(writer1.EmitRowChangedEvents(newRow(3, 12)), check.IsNil)
+	c.Assert(writer1.flush(ctx), check.IsNil)
+	c.Assert(writer2.EmitRowChangedEvents(newRow(2, 14), newRow(2, 12), newRow(2, 10)), check.IsNil)
+	c.Assert(writer2.flush(ctx), check.IsNil)
+
+	reader, err := NewLogReader(ctx, cfg.Storage, "test-cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(readRows(ctx, c, reader, 10, 13), check.DeepEquals, []*model.RowChangedEvent{
+		newRow(1, 11), newRow(3, 12), newRow(2, 12), newRow(1, 13),
+	})
+}
+
+func (s *redoSuite) TestGC(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	cfg := newConsistentConfig(c.MkDir())
+
+	writer, err := NewLogWriter(ctx, "test-cf", "capture-1", cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(writer.EmitRowChangedEvents(newRow(1, 11), newRow(2, 12)), check.IsNil)
+	c.Assert(writer.flush(ctx), check.IsNil)
+	c.Assert(writer.EmitRowChangedEvents(newRow(1, 13), newRow(2, 15)), check.IsNil)
+	c.Assert(writer.flush(ctx), check.IsNil)
+	ownerWriter, err := NewOwnerWriter(ctx, "test-cf", cfg)
+	c.Assert(err, check.IsNil)
+	ownerWriter.gcInterval = 0
+	c.Assert(ownerWriter.EmitDDLEvent(ctx, newDDL(12)), check.IsNil)
+	c.Assert(ownerWriter.EmitDDLEvent(ctx, newDDL(14)), check.IsNil)
+
+	listFiles := func() []string {
+		var names []string
+		err := ownerWriter.storage.WalkDir(ctx, &storage.WalkOption{}, func(name string, size int64) error {
+			if _, _, ok := parseFileName(name); ok {
+				names = append(names, name)
+			}
+			return nil
+		})
+		c.Assert(err, check.IsNil)
+		sort.Strings(names)
+		return names
+	}
+	c.Assert(listFiles(), check.HasLen, 4)
+	// the row file whose max commit ts is 12 and the DDL file at 12 are removed
+	c.Assert(ownerWriter.FlushMeta(ctx, 12, 15), check.IsNil)
+	c.Assert(listFiles(), check.DeepEquals, []string{
+		makeDDLFileName(14), makeRowFileName("capture-1", writer.writerID, 1, 15),
+	})
+
+	reader, err := NewLogReader(ctx, cfg.Storage, "test-cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(readRows(ctx, c, reader, 12, 15), check.DeepEquals,
+		[]*model.RowChangedEvent{newRow(1, 13), newRow(2, 15)})
+
+	// the log files in S3 are left to the lifecycle rules of the bucket
+	backend, err := storage.ParseBackend("s3://bucket/prefix", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(newFileRemover(backend), check.IsNil)
+}
+
+type mockSink struct {
+	ops []string
+}
+
+func (s *mockSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
+	return nil
+}
+
+func (s *mockSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	for _, row := range rows {
+		s.ops = append(s.ops, fmt.Sprintf("row %s %d", row.Table.Table, row.CommitTs))
+	}
+	return nil
+}
+
+func (s *mockSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	s.ops = append(s.ops, fmt.Sprintf("ddl %d", ddl.CommitTs))
+	return nil
+}
+
+func (s *mockSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	s.ops = append(s.ops, fmt.Sprintf("flush %d", resolvedTs))
+	return resolvedTs, nil
+}
+
+func (s *mockSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+	return nil
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+type sliceRowIterator struct {
+	rows []*model.RowChangedEvent
+}
+
+func (it *sliceRowIterator) Next() (*model.RowChangedEvent, error) {
+	if len(it.rows) == 0 {
+		return nil, nil
+	}
+	row := it.rows[0]
+	it.rows = it.rows[1:]
+	return row, nil
+}
+
+func (s *redoSuite) TestReplay(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	sink := &mockSink{}
+	rows := []*model.RowChangedEvent{newRow(1, 11), newRow(2, 12), newRow(1, 15), newRow(2, 16)}
+	ddls := []*model.DDLEvent{newDDL(13), newDDL(14)}
+	err := replay(ctx, sink, nil, &Meta{CheckpointTs: 10, ResolvedTs: 16}, &sliceRowIterator{rows: rows}, ddls)
+	c.Assert(err, check.IsNil)
+	c.Assert(sink.ops, check.DeepEquals, []string{
+		"row t1 11", "row t2 12", "flush 12", "ddl 13",
+		"flush 13", "ddl 14",
+		"row t1 15", "row t2 16", "flush 16",
+	})
+}
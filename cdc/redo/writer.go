@@ -0,0 +1,205 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/br/pkg/storage"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// LogWriter writes the row changed events of the tables in a processor to the
+// redo log. The events are buffered and flushed to the storage periodically,
+// and the resolved ts of a table is persisted only after all the events
+// before it are flushed. The events in a file are sorted by the commit ts, so
+// the files can be merged by the reader without loading them into memory.
+type LogWriter struct {
+	storage       storage.ExternalStorage
+	changefeedID  string
+	captureID     string
+	writerID      int64
+	seq           uint64
+	maxLogSize    int
+	flushInterval time.Duration
+
+	mu          sync.Mutex
+	buf         []encodedRow
+	bufSize     int
+	maxCommitTs uint64
+	// unflushedResolvedTs is the resolved ts of the tables updated after the
+	// last flush, it takes effect after the next flush
+	unflushedResolvedTs map[model.TableID]model.Ts
+	// resolvedTs is the resolved ts of the tables persisted in the redo log
+	resolvedTs map[model.TableID]model.Ts
+
+	flushNotifier chan struct{}
+}
+
+type encodedRow struct {
+	commitTs uint64
+	data     []byte
+}
+
+// NewLogWriter creates a new LogWriter
+func NewLogWriter(ctx context.Context, changefeedID, captureID string, cfg *config.ConsistentConfig) (*LogWriter, error) {
+	s, _, err := newStorage(ctx, cfg.Storage, changefeedID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &LogWriter{
+		storage:             s,
+		changefeedID:        changefeedID,
+		captureID:           captureID,
+		writerID:            time.Now().UnixNano(),
+		maxLogSize:          int(cfg.MaxLogSize * 1024 * 1024),
+		flushInterval:       time.Duration(cfg.FlushIntervalInMs) * time.Millisecond,
+		unflushedResolvedTs: make(map[model.TableID]model.Ts),
+		resolvedTs:          make(map[model.TableID]model.Ts),
+		flushNotifier:       make(chan struct{}, 1),
+	}, nil
+}
+
+// Run flushes the redo log periodically until the context is canceled or an
+// error occurs
+func (w *LogWriter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-ticker.C:
+		case <-w.flushNotifier:
+		}
+		if err := w.flush(ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// AddTable starts tracking the resolved ts of the table, the table is
+// replicated from startTs.
+func (w *LogWriter) AddTable(tableID model.TableID, startTs model.Ts) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.resolvedTs[tableID] = startTs
+	delete(w.unflushedResolvedTs, tableID)
+}
+
+// RemoveTable stops tracking the resolved ts of the table
+func (w *LogWriter) RemoveTable(tableID model.TableID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.resolvedTs, tableID)
+	delete(w.unflushedResolvedTs, tableID)
+}
+
+// EmitRowChangedEvents appends the row changed events to the redo log buffer
+func (w *LogWriter) EmitRowChangedEvents(rows ...*model.RowChangedEvent) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, row := range rows {
+		data, err := encodeRow(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		w.buf = append(w.buf, encodedRow{commitTs: row.CommitTs, data: data})
+		w.bufSize += len(data) + 1
+		if row.CommitTs > w.maxCommitTs {
+			w.maxCommitTs = row.CommitTs
+		}
+	}
+	if w.bufSize >= w.maxLogSize {
+		select {
+		case w.flushNotifier <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// UpdateResolvedTs updates the resolved ts of the table, all the row changed
+// events of the table before resolvedTs must have been emitted.
+func (w *LogWriter) UpdateResolvedTs(tableID model.TableID, resolvedTs model.Ts) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.resolvedTs[tableID]; !ok {
+		return
+	}
+	w.unflushedResolvedTs[tableID] = resolvedTs
+}
+
+// ResolvedTs returns the resolved ts of the table persisted in the redo log
+func (w *LogWriter) ResolvedTs(tableID model.TableID) model.Ts {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.resolvedTs[tableID]
+}
+
+func (w *LogWriter) flush(ctx context.Context) error {
+	w.mu.Lock()
+	rows, size, maxCommitTs, unflushedResolvedTs := w.buf, w.bufSize, w.maxCommitTs, w.unflushedResolvedTs
+	if len(rows) == 0 && len(unflushedResolvedTs) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	w.buf = nil
+	w.bufSize = 0
+	w.maxCommitTs = 0
+	w.unflushedResolvedTs = make(map[model.TableID]model.Ts)
+	w.mu.Unlock()
+
+	if len(rows) != 0 {
+		// the rows of a table are emitted in order, the order is kept for
+		// the rows with the same commit ts
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].commitTs < rows[j].commitTs
+		})
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		for _, row := range rows {
+			buf.Write(row.data)
+			buf.WriteByte('\n')
+		}
+		name := makeRowFileName(w.captureID, w.writerID, w.seq, maxCommitTs)
+		if err := w.storage.WriteFile(ctx, name, buf.Bytes()); err != nil {
+			return cerror.WrapError(cerror.ErrRedoStorage, err)
+		}
+		w.seq++
+		log.Debug("redo log flushed", zap.String("changefeed", w.changefeedID),
+			zap.String("name", name), zap.Int("size", buf.Len()))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for tableID, ts := range unflushedResolvedTs {
+		// the table may be removed during the flush
+		if resolvedTs, ok := w.resolvedTs[tableID]; ok && ts > resolvedTs {
+			w.resolvedTs[tableID] = ts
+		}
+	}
+	return nil
+}
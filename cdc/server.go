@@ -240,6 +240,7 @@ func (s *Server) run(ctx context.Context) (err error) {
 	opts := &captureOpts{
 		flushCheckpointInterval: time.Duration(conf.ProcessorFlushInterval),
 		captureSessionTTL:       conf.CaptureSessionTTL,
+		labels:                  conf.Labels,
 	}
 	capture, err := NewCapture(ctx, s.pdEndpoints, s.pdClient, conf.Security, conf.AdvertiseAddr, opts)
 	if err != nil {
@@ -15,6 +15,7 @@ package cdclog
 
 import (
 	"context"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -40,9 +41,10 @@ const (
 )
 
 type logPath struct {
-	root string
-	ddl  string
-	meta string
+	root      string
+	ddl       string
+	meta      string
+	syncpoint string
 }
 
 type tableStream struct {
@@ -230,6 +232,20 @@ func (f *fileSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	return f.flushLogMeta()
 }
 
+// EmitSyncpoint writes a marker file of the syncpoint
+func (f *fileSink) EmitSyncpoint(ctx context.Context, ts uint64) error {
+	log.Debug("[EmitSyncpoint]", zap.Uint64("ts", ts))
+	data, err := makeSyncpointContent(ts)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	if err := os.MkdirAll(f.logPath.syncpoint, defaultDirMode); err != nil {
+		return cerror.WrapError(cerror.ErrFileSinkCreateDir, err)
+	}
+	err = ioutil.WriteFile(filepath.Join(f.logPath.syncpoint, makeSyncpointFileName(ts)), data, defaultFileMode)
+	return cerror.WrapError(cerror.ErrFileSinkFileOp, err)
+}
+
 func (f *fileSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 	switch ddl.Type {
 	case parsemodel.ActionCreateTable:
@@ -351,9 +367,10 @@ func NewLocalFileSink(ctx context.Context, sinkURI *url.URL, errCh chan error) (
 	)
 	rootPath := sinkURI.Path + "/"
 	logPath := &logPath{
-		root: rootPath,
-		meta: rootPath + logMetaFile,
-		ddl:  rootPath + ddlEventsDir,
+		root:      rootPath,
+		meta:      rootPath + logMetaFile,
+		ddl:       rootPath + ddlEventsDir,
+		syncpoint: rootPath + syncpointsDir,
 	}
 	err := os.MkdirAll(logPath.ddl, defaultDirMode)
 	if err != nil {
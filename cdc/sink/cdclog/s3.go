@@ -236,6 +236,15 @@ func (s *s3Sink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	return s.flushLogMeta(ctx)
 }
 
+// EmitSyncpoint writes a marker object of the syncpoint
+func (s *s3Sink) EmitSyncpoint(ctx context.Context, ts uint64) error {
+	data, err := makeSyncpointContent(ts)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	return cerror.WrapError(cerror.ErrS3SinkWriteStorage, s.storage.WriteFile(ctx, makeSyncpointFileObject(ts), data))
+}
+
 // EmitDDLEvent write ddl event to S3 directory, all events split by '\n'
 // Because S3 doesn't support append-like write.
 // we choose a hack way to read origin file then write in place.
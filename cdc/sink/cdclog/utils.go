@@ -37,6 +37,9 @@ const (
 	ddlEventsDir    = "ddls"
 	ddlEventsPrefix = "ddl"
 
+	syncpointsDir   = "syncpoints"
+	syncpointPrefix = "syncpoint"
+
 	maxUint64 = ^uint64(0)
 )
 
@@ -243,6 +246,24 @@ func makeLogMetaContent(tableInfos []*model.SimpleTableInfo) *logMeta {
 	return meta
 }
 
+// syncpointMarker is the content of the syncpoint marker file, the data before
+// the syncpoint ts in the log is a consistent snapshot of the upstream.
+type syncpointMarker struct {
+	SyncpointTS uint64 `json:"syncpoint_ts"`
+}
+
+func makeSyncpointContent(ts uint64) ([]byte, error) {
+	return json.Marshal(&syncpointMarker{SyncpointTS: ts})
+}
+
+func makeSyncpointFileObject(ts uint64) string {
+	return fmt.Sprintf("%s/%s", syncpointsDir, makeSyncpointFileName(ts))
+}
+
+func makeSyncpointFileName(ts uint64) string {
+	return fmt.Sprintf("%s.%d", syncpointPrefix, ts)
+}
+
 func makeDDLFileObject(commitTS uint64) string {
 	return fmt.Sprintf("%s/%s", ddlEventsDir, makeDDLFileName(commitTS))
 }
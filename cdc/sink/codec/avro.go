@@ -24,8 +24,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/linkedin/goavro/v2"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -136,11 +138,58 @@ func (a *AvroEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, er
 	return nil, nil
 }
 
-// EncodeDDLEvent is no-op now
-func (a *AvroEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+// EncodeSyncpointEvent implements the EventBatchEncoder interface,
+// there is no such a corresponding type to the syncpoint event in the avro protocol,
+// therefore the event is ignored.
+func (a *AvroEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
 	return nil, nil
 }
 
+// avroDDLSchema is the schema of the DDL messages, which are registered under
+// the subject of the table avroDDLTableName.
+const avroDDLSchema = `{
+	"type": "record",
+	"name": "ddl",
+	"namespace": "com.pingcap.ticdc",
+	"fields": [
+		{"name": "schema", "type": "string"},
+		{"name": "table", "type": "string"},
+		{"name": "query", "type": "string"},
+		{"name": "type", "type": "int"},
+		{"name": "commit_ts", "type": "long"}
+	]
+}`
+
+var avroDDLTableName = model.TableName{Schema: "_ticdc", Table: "ddl"}
+
+// EncodeDDLEvent encodes the DDL event as a message without key, the value is
+// a record of avroDDLSchema
+func (a *AvroEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+	// TODO pass ctx from the upper function. Need to modify the EventBatchEncoder interface.
+	avroCodec, registryID, err := a.valueSchemaManager.GetCachedOrRegister(
+		context.Background(), avroDDLTableName, 1, func() (string, error) { return avroDDLSchema, nil })
+	if err != nil {
+		return nil, errors.Annotate(err, "AvroEventBatchEncoder: get-or-register failed")
+	}
+	bin, err := avroCodec.BinaryFromNative(nil, map[string]interface{}{
+		"schema":    e.TableInfo.Schema,
+		"table":     e.TableInfo.Table,
+		"query":     e.Query,
+		"type":      int32(e.Type),
+		"commit_ts": int64(e.CommitTs),
+	})
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroEncodeToBinary, err), "AvroEventBatchEncoder: converting to Avro binary failed")
+	}
+	res := &avroEncodeResult{data: bin, registryID: registryID}
+	value, err := res.toEnvelope()
+	if err != nil {
+		return nil, errors.Annotate(err, "EncodeDDLEvent could not construct Avro envelope")
+	}
+	return newDDLMQMessage(ProtocolAvro, nil, value, e), nil
+}
+
 // Build MQ Messages
 func (a *AvroEventBatchEncoder) Build() (mqMessages []*MQMessage) {
 	old := a.resultBuf
@@ -239,6 +288,10 @@ func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, er
 	}
 
 	for _, col := range columnInfo {
+		// the columns dropped by the column filter are nil
+		if col == nil {
+			continue
+		}
 		avroType, err := getAvroDataTypeFromColumn(col)
 		if err != nil {
 			return "", err
@@ -519,3 +572,263 @@ func (r *avroEncodeResult) toEnvelope() ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// AvroEventBatchDecoder decodes the messages encoded by AvroEventBatchEncoder.
+// The schemas are resolved through the registry schema IDs in the messages.
+// Avro messages carry no commit ts and the MySQL types are narrowed to the Avro types,
+// so the decoded row has a zero commit ts and columns typed by the Avro types.
+// A message without key is a DDL message.
+type AvroEventBatchDecoder struct {
+	row *model.RowChangedEvent
+	ddl *model.DDLEvent
+}
+
+// NewAvroEventBatchDecoder creates a new AvroEventBatchDecoder
+func NewAvroEventBatchDecoder(
+	ctx context.Context, key []byte, value []byte, schemaManager *AvroSchemaManager, tz *time.Location,
+) (EventBatchDecoder, error) {
+	if len(key) == 0 {
+		ddl, err := avroDecodeDDL(ctx, value, schemaManager)
+		if err != nil {
+			return nil, errors.Annotate(err, "AvroEventBatchDecoder: decoding ddl failed")
+		}
+		return &AvroEventBatchDecoder{ddl: ddl}, nil
+	}
+	keyTable, keyCols, err := avroDecode(ctx, key, schemaManager, tz)
+	if err != nil {
+		return nil, errors.Annotate(err, "AvroEventBatchDecoder: decoding key failed")
+	}
+	row := &model.RowChangedEvent{Table: keyTable}
+	// a message without value is a delete event, which only carries the handle key
+	if len(value) == 0 {
+		row.PreColumns = keyCols
+		return &AvroEventBatchDecoder{row: row}, nil
+	}
+	valueTable, valueCols, err := avroDecode(ctx, value, schemaManager, tz)
+	if err != nil {
+		return nil, errors.Annotate(err, "AvroEventBatchDecoder: decoding value failed")
+	}
+	row.Table = valueTable
+	row.Columns = valueCols
+	return &AvroEventBatchDecoder{row: row}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *AvroEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if b.row != nil {
+		return model.MqMessageTypeRow, true, nil
+	}
+	if b.ddl != nil {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	return model.MqMessageTypeUnknown, false, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface,
+// avro messages carry no resolved events
+func (b *AvroEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerror.ErrAvroDecodeFailed.GenWithStack("resolved event is not supported by avro protocol")
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface,
+// avro messages carry no syncpoint events
+func (b *AvroEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return 0, cerror.ErrAvroDecodeFailed.GenWithStack("syncpoint event is not supported by avro protocol")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *AvroEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if b.row == nil {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	row := b.row
+	b.row = nil
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *AvroEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if b.ddl == nil {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	ddl := b.ddl
+	b.ddl = nil
+	return ddl, nil
+}
+
+// avroDecodeEnvelope decodes the Avro envelope into a record, and returns the
+// codec of the record
+func avroDecodeEnvelope(ctx context.Context, data []byte, manager *AvroSchemaManager) (int, *goavro.Codec, map[string]interface{}, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("invalid avro envelope")
+	}
+	registryID := int(binary.BigEndian.Uint32(data[1:5]))
+	avroCodec, err := manager.LookupByID(ctx, registryID)
+	if err != nil {
+		return 0, nil, nil, errors.Trace(err)
+	}
+	native, _, err := avroCodec.NativeFromBinary(data[5:])
+	if err != nil {
+		return 0, nil, nil, cerror.WrapError(cerror.ErrAvroDecodeFailed, err)
+	}
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return 0, nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("unexpected avro data %v", native)
+	}
+	return registryID, avroCodec, record, nil
+}
+
+// avroDecodeDDL is the reverse of EncodeDDLEvent
+func avroDecodeDDL(ctx context.Context, data []byte, manager *AvroSchemaManager) (*model.DDLEvent, error) {
+	_, _, record, err := avroDecodeEnvelope(ctx, data, manager)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	schema, _ := record["schema"].(string)
+	table, _ := record["table"].(string)
+	query, _ := record["query"].(string)
+	tp, ok1 := record["type"].(int32)
+	commitTs, ok2 := record["commit_ts"].(int64)
+	if !ok1 || !ok2 {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("unexpected avro ddl record %v", record)
+	}
+	return &model.DDLEvent{
+		CommitTs:  uint64(commitTs),
+		TableInfo: &model.SimpleTableInfo{Schema: schema, Table: table},
+		Query:     query,
+		Type:      timodel.ActionType(tp),
+	}, nil
+}
+
+// avroDecode is the reverse of avroEncode plus toEnvelope
+func avroDecode(ctx context.Context, data []byte, manager *AvroSchemaManager, tz *time.Location) (*model.TableName, []*model.Column, error) {
+	registryID, avroCodec, record, err := avroDecodeEnvelope(ctx, data, manager)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var schema avroSchemaTop
+	if err := json.Unmarshal([]byte(avroCodec.Schema()), &schema); err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrAvroDecodeFailed, err)
+	}
+	// the record is named by the table, and the schema name is recovered from
+	// the subject which the schema is registered under
+	subject, err := manager.LookupSubjectByID(ctx, registryID)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	schemaName, ok := schemaSubjectToSchemaName(subject, schema.Name)
+	if !ok {
+		return nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("unexpected subject %s of table %s", subject, schema.Name)
+	}
+	table := &model.TableName{Schema: schemaName, Table: schema.Name}
+	cols := make([]*model.Column, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		name, _ := field["name"].(string)
+		col := &model.Column{Name: name}
+		value := record[name]
+		var typeName string
+		if union, ok := field["type"].([]interface{}); ok {
+			// nullable columns are encoded as unions, and the union value is keyed by the type name
+			if value != nil {
+				unionValue, ok := value.(map[string]interface{})
+				if !ok || len(unionValue) != 1 {
+					return nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("unexpected union value %v of column %s", value, name)
+				}
+				for k, v := range unionValue {
+					typeName, value = k, v
+				}
+			} else if len(union) > 1 {
+				typeName = avroTypeName(union[1])
+			}
+		} else {
+			col.Flag.SetIsHandleKey()
+			typeName = avroTypeName(field["type"])
+		}
+		if err := avroNativeToColumn(col, typeName, value, tz); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		cols = append(cols, col)
+	}
+	return table, cols, nil
+}
+
+// avroTypeName returns the name of the Avro type, which is the same as the key of union values
+func avroTypeName(tp interface{}) string {
+	switch t := tp.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		name, _ := t["type"].(string)
+		if logical, ok := t["logicalType"].(string); ok {
+			name += "." + logical
+		}
+		return name
+	default:
+		return ""
+	}
+}
+
+// avroNativeToColumn is the reverse of columnToAvroNativeData
+func avroNativeToColumn(col *model.Column, typeName string, value interface{}, tz *time.Location) error {
+	switch typeName {
+	case "int":
+		col.Type = mysql.TypeLong
+	case "long":
+		col.Type = mysql.TypeLonglong
+	case "float":
+		col.Type = mysql.TypeFloat
+	case "double":
+		col.Type = mysql.TypeDouble
+	case "string":
+		col.Type = mysql.TypeVarchar
+	case "bytes":
+		col.Type = mysql.TypeBlob
+		col.Flag.SetIsBinary()
+	case "long." + string(timestampMillis):
+		col.Type = mysql.TypeDatetime
+	case "int." + string(timeMillis):
+		col.Type = mysql.TypeDuration
+	case "bytes." + string(decimalType):
+		col.Type = mysql.TypeLonglong
+		col.Flag.SetIsUnsigned()
+	case "null", "":
+		col.Type = mysql.TypeNull
+	default:
+		return cerror.ErrAvroDecodeFailed.GenWithStack("unknown avro type %s of column %s", typeName, col.Name)
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case int32:
+		col.Value = int64(v)
+	case int64:
+		col.Value = v
+	case float32:
+		col.Value = float64(v)
+	case float64:
+		col.Value = v
+	case string:
+		col.Value = []byte(v)
+	case []byte:
+		col.Value = v
+	case time.Time:
+		if v.IsZero() {
+			col.Value = zeroTimeStr
+		} else {
+			col.Value = v.In(tz).Format(types.TimeFSPFormat)
+		}
+	case time.Duration:
+		col.Value = types.Duration{Duration: v, Fsp: types.MaxFsp}.String()
+	case *big.Rat:
+		if !v.IsInt() || !v.Num().IsUint64() {
+			return cerror.ErrAvroDecodeFailed.GenWithStack("unexpected decimal value %s of column %s", v.String(), col.Name)
+		}
+		col.Value = v.Num().Uint64()
+	default:
+		return cerror.ErrAvroDecodeFailed.GenWithStack("unexpected value %v of column %s", value, col.Name)
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
@@ -238,3 +240,144 @@ func (s *avroBatchEncoderSuite) TestAvroEncode(c *check.C) {
 	_, err = s.encoder.AppendRowChangedEvent(testCaseUpdate)
 	c.Check(err, check.IsNil)
 }
+
+func (s *avroBatchEncoderSuite) TestAvroSchemaWithoutNamespace(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// the schema registered by the existing changefeeds must not be changed
+	schema, err := ColumnInfoToAvroSchema("person", []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(schema, check.Equals, `{"type":"record","name":"person","fields":[{"name":"id","type":"int"}]}`)
+}
+
+func (s *avroBatchEncoderSuite) TestAvroDecode(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := &model.TableName{Schema: "testdb", Table: "decode1"}
+	columns := []*model.Column{
+		{Name: "id", Value: int64(1), Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+		{Name: "name", Value: []byte("Bob"), Type: mysql.TypeVarchar},
+		{Name: "score", Value: float64(95.5), Type: mysql.TypeDouble},
+		{Name: "tiny", Value: int64(7), Type: mysql.TypeTiny},
+		{Name: "note", Value: nil, Type: mysql.TypeVarchar},
+	}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: 417318403368288260, Table: table, Columns: columns},
+		{CommitTs: 417318403368288261, Table: table, PreColumns: columns},
+	}
+	for _, row := range rows {
+		_, err := s.encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := s.encoder.Build()
+	c.Assert(msgs, check.HasLen, len(rows))
+
+	keyColumns := []*model.Column{
+		{Name: "id", Value: int64(1), Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+	}
+	expected := []*model.RowChangedEvent{{
+		Table: table,
+		Columns: []*model.Column{
+			{Name: "id", Value: int64(1), Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+			{Name: "name", Value: []byte("Bob"), Type: mysql.TypeVarchar},
+			{Name: "score", Value: float64(95.5), Type: mysql.TypeDouble},
+			{Name: "tiny", Value: int64(7), Type: mysql.TypeLong},
+			{Name: "note", Value: nil, Type: mysql.TypeVarchar},
+		},
+	}, {
+		Table:      table,
+		PreColumns: keyColumns,
+	}}
+	for i, msg := range msgs {
+		decoder, err := NewAvroEventBatchDecoder(context.Background(), msg.Key, msg.Value, s.encoder.valueSchemaManager, time.UTC)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(row, check.DeepEquals, expected[i])
+		_, hasNext, err = decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsFalse)
+	}
+
+	_, err := NewAvroEventBatchDecoder(context.Background(), []byte{0x1}, nil, s.encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.ErrorMatches, ".*invalid avro envelope.*")
+}
+
+func (s *avroBatchEncoderSuite) TestAvroFilteredColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := &model.TableName{Schema: "testdb", Table: "filtered1"}
+	row := &model.RowChangedEvent{
+		CommitTs: 417318403368288260,
+		Table:    table,
+		Columns: []*model.Column{
+			{Name: "id", Value: int64(1), Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+			{Name: "name", Value: []byte("Bob"), Type: mysql.TypeVarchar},
+			{Name: "secret", Value: []byte("pwd"), Type: mysql.TypeVarchar},
+		},
+	}
+	f, err := filter.NewFilter(&config.ReplicaConfig{
+		Filter: &config.FilterConfig{
+			Rules: []string{"*.*"},
+			ColumnRules: []*config.ColumnRule{
+				{Matcher: []string{"testdb.filtered1"}, IgnoreColumns: []string{"secret"}},
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(f.FilterColumns(row), check.IsNil)
+	c.Assert(row.Columns[2], check.IsNil)
+
+	schema, err := ColumnInfoToAvroSchema(table.Table, row.Columns)
+	c.Assert(err, check.IsNil)
+	c.Assert(schema, check.Not(check.Matches), ".*secret.*")
+
+	_, err = s.encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	msgs := s.encoder.Build()
+	c.Assert(len(msgs), check.Greater, 0)
+	msg := msgs[len(msgs)-1]
+
+	decoder, err := NewAvroEventBatchDecoder(context.Background(), msg.Key, msg.Value, s.encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	decoded, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.Columns, check.DeepEquals, []*model.Column{
+		{Name: "id", Value: int64(1), Type: mysql.TypeLong, Flag: model.HandleKeyFlag},
+		{Name: "name", Value: []byte("Bob"), Type: mysql.TypeVarchar},
+	})
+}
+
+func (s *avroBatchEncoderSuite) TestAvroDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ddl := &model.DDLEvent{
+		CommitTs:  417318403368288260,
+		TableInfo: &model.SimpleTableInfo{Schema: "testdb", Table: "ddl1"},
+		Query:     "create table ddl1(id int primary key)",
+		Type:      model2.ActionCreateTable,
+	}
+	msg, err := s.encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Key, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeDDL)
+	c.Assert(msg.Ts, check.Equals, ddl.CommitTs)
+
+	decoder, err := NewAvroEventBatchDecoder(context.Background(), msg.Key, msg.Value, s.encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decoded, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, ddl)
+	_, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+}
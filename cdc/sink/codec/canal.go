@@ -328,6 +328,13 @@ func (d *CanalEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, e
 	return nil, nil
 }
 
+// EncodeSyncpointEvent implements the EventBatchEncoder interface,
+// there is no such a corresponding type to the syncpoint event in the canal protocol,
+// therefore the event is ignored.
+func (d *CanalEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
 // AppendRowChangedEvent implements the EventBatchEncoder interface
 func (d *CanalEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	entry, err := d.entryBuilder.FromRowEvent(e)
@@ -456,3 +463,242 @@ func NewCanalEventBatchEncoder() EventBatchEncoder {
 	encoder.resetPacket()
 	return encoder
 }
+
+// canalTypeNames maps the MySQL type names used in canal messages back to the column types
+var canalTypeNames = func() map[string]byte {
+	names := make(map[string]byte)
+	for tp := 0; tp <= 0xff; tp++ {
+		if name := parser_types.TypeStr(byte(tp)); name != "" {
+			names[name] = byte(tp)
+		}
+	}
+	return names
+}()
+
+// canalValueToColumn converts a column value in canal messages to the column of the RowChangedEvent,
+// it is the reverse of canalEntryBuilder.buildColumn
+func canalValueToColumn(name, mysqlType string, value string, isNull bool, isKey bool) (*model.Column, error) {
+	col := &model.Column{Name: name}
+	if isKey {
+		col.Flag.SetIsPrimaryKey()
+		col.Flag.SetIsHandleKey()
+	}
+	// binary types are recorded as blob and binary, see buildColumn
+	typeName := mysqlType
+	if strings.Contains(typeName, "blob") || strings.Contains(typeName, "binary") {
+		col.Flag.SetIsBinary()
+		typeName = strings.Replace(typeName, "blob", "text", 1)
+		typeName = strings.Replace(typeName, "binary", "char", 1)
+	}
+	tp, ok := canalTypeNames[typeName]
+	if !ok {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack("unknown mysql type %s of column %s", mysqlType, name)
+	}
+	col.Type = tp
+	if isNull {
+		return col, nil
+	}
+
+	var err error
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		col.Value, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// the signed flag is not recorded in canal messages, values out of the int64 range must be unsigned
+			col.Value, err = strconv.ParseUint(value, 10, 64)
+			col.Flag.SetIsUnsigned()
+		}
+	case mysql.TypeFloat, mysql.TypeDouble:
+		col.Value, err = strconv.ParseFloat(value, 64)
+	case mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+		col.Value, err = strconv.ParseUint(value, 10, 64)
+	case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		if col.Flag.IsBinary() {
+			col.Value, err = charmap.ISO8859_1.NewEncoder().Bytes([]byte(value))
+		} else {
+			col.Value = []byte(value)
+		}
+	default:
+		col.Value = value
+	}
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+	}
+	return col, nil
+}
+
+// canalDDLType guesses the DDL action type from the canal event type and the query,
+// the action type is needed by the MySQL sink to decide whether to switch the database
+func canalDDLType(tp canal.EventType, query string) mm.ActionType {
+	switch tp {
+	case canal.EventType_CREATE:
+		return mm.ActionCreateTable
+	case canal.EventType_ERASE:
+		return mm.ActionDropTable
+	case canal.EventType_TRUNCATE:
+		return mm.ActionTruncateTable
+	case canal.EventType_RENAME:
+		return mm.ActionRenameTable
+	case canal.EventType_CINDEX:
+		return mm.ActionAddIndex
+	case canal.EventType_DINDEX:
+		return mm.ActionDropIndex
+	case canal.EventType_ALTER:
+		return mm.ActionAddColumn
+	}
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) >= 2 && (fields[1] == "DATABASE" || fields[1] == "SCHEMA") {
+		switch fields[0] {
+		case "CREATE":
+			return mm.ActionCreateSchema
+		case "DROP":
+			return mm.ActionDropSchema
+		case "ALTER":
+			return mm.ActionModifySchemaCharsetAndCollate
+		}
+	}
+	return mm.ActionNone
+}
+
+// convert timestamp(in ms) in canal to ts in tidb, the logical part is lost
+func convertFromCanalTs(executeTime int64) uint64 {
+	return uint64(executeTime) << 18
+}
+
+// CanalEventBatchDecoder decodes the packets encoded by CanalEventBatchEncoder
+type CanalEventBatchDecoder struct {
+	entries []*canal.Entry
+	// rowChange is the decoded store value of entries[0]
+	rowChange *canal.RowChange
+}
+
+// NewCanalEventBatchDecoder creates a new CanalEventBatchDecoder
+func NewCanalEventBatchDecoder(data []byte) (EventBatchDecoder, error) {
+	packet := new(canal.Packet)
+	if err := proto.Unmarshal(data, packet); err != nil {
+		return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+	}
+	if packet.GetType() != canal.PacketType_MESSAGES {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack("unexpected packet type %s", packet.GetType())
+	}
+	messages := new(canal.Messages)
+	if err := proto.Unmarshal(packet.GetBody(), messages); err != nil {
+		return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+	}
+	entries := make([]*canal.Entry, 0, len(messages.GetMessages()))
+	for _, message := range messages.GetMessages() {
+		entry := new(canal.Entry)
+		if err := proto.Unmarshal(message, entry); err != nil {
+			return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+		}
+		entries = append(entries, entry)
+	}
+	return &CanalEventBatchDecoder{entries: entries}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *CanalEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if len(b.entries) == 0 {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+	if b.rowChange == nil {
+		b.rowChange = new(canal.RowChange)
+		if err := proto.Unmarshal(b.entries[0].GetStoreValue(), b.rowChange); err != nil {
+			return model.MqMessageTypeUnknown, false, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+		}
+	}
+	switch b.rowChange.GetEventType() {
+	case canal.EventType_INSERT, canal.EventType_UPDATE, canal.EventType_DELETE:
+		if !b.rowChange.GetIsDdl() {
+			return model.MqMessageTypeRow, true, nil
+		}
+	}
+	return model.MqMessageTypeDDL, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface,
+// canal messages carry no resolved events
+func (b *CanalEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerror.ErrCanalDecodeFailed.GenWithStack("resolved event is not supported by canal protocol")
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface,
+// canal messages carry no syncpoint events
+func (b *CanalEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return 0, cerror.ErrCanalDecodeFailed.GenWithStack("syncpoint event is not supported by canal protocol")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *CanalEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	tp, hasNext, err := b.HasNext()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !hasNext || tp != model.MqMessageTypeRow {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	header := b.entries[0].GetHeader()
+	rowDatas := b.rowChange.GetRowDatas()
+	if len(rowDatas) != 1 {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack("unexpected row data count %d", len(rowDatas))
+	}
+	row := &model.RowChangedEvent{
+		CommitTs: convertFromCanalTs(header.GetExecuteTime()),
+		Table: &model.TableName{
+			Schema: header.GetSchemaName(),
+			Table:  header.GetTableName(),
+		},
+	}
+	if row.PreColumns, err = canalColumnsToColumns(rowDatas[0].GetBeforeColumns()); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row.Columns, err = canalColumnsToColumns(rowDatas[0].GetAfterColumns()); err != nil {
+		return nil, errors.Trace(err)
+	}
+	b.next()
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *CanalEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	tp, hasNext, err := b.HasNext()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !hasNext || tp != model.MqMessageTypeDDL {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	header := b.entries[0].GetHeader()
+	ddl := &model.DDLEvent{
+		CommitTs: convertFromCanalTs(header.GetExecuteTime()),
+		TableInfo: &model.SimpleTableInfo{
+			Schema: header.GetSchemaName(),
+			Table:  header.GetTableName(),
+		},
+		Query: b.rowChange.GetSql(),
+		Type:  canalDDLType(b.rowChange.GetEventType(), b.rowChange.GetSql()),
+	}
+	b.next()
+	return ddl, nil
+}
+
+func (b *CanalEventBatchDecoder) next() {
+	b.entries = b.entries[1:]
+	b.rowChange = nil
+}
+
+func canalColumnsToColumns(canalColumns []*canal.Column) ([]*model.Column, error) {
+	if len(canalColumns) == 0 {
+		return nil, nil
+	}
+	cols := make([]*model.Column, 0, len(canalColumns))
+	for _, c := range canalColumns {
+		col, err := canalValueToColumn(c.GetName(), c.GetMysqlType(), c.GetValue(), c.GetIsNull(), c.GetIsKey())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
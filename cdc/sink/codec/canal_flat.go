@@ -15,6 +15,7 @@ package codec
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
@@ -168,6 +169,13 @@ func (c *CanalFlatEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessag
 	return nil, nil
 }
 
+// EncodeSyncpointEvent implements the EventBatchEncoder interface,
+// there is no such a corresponding type to the syncpoint event in the canal-json protocol,
+// therefore the event is ignored.
+func (c *CanalFlatEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
 // AppendRowChangedEvent implements the interface EventBatchEncoder
 func (c *CanalFlatEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	msg, err := c.newFlatMessageForDML(e)
@@ -247,3 +255,130 @@ func (c *CanalFlatEventBatchEncoder) SetParams(params map[string]string) error {
 	// no op
 	return nil
 }
+
+// CanalFlatEventBatchDecoder decodes the messages encoded by CanalFlatEventBatchEncoder,
+// each message contains exactly one event
+type CanalFlatEventBatchDecoder struct {
+	msg *canalFlatMessage
+}
+
+// NewCanalFlatEventBatchDecoder creates a new CanalFlatEventBatchDecoder
+func NewCanalFlatEventBatchDecoder(data []byte) (EventBatchDecoder, error) {
+	msg := new(canalFlatMessage)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, cerrors.WrapError(cerrors.ErrCanalDecodeFailed, err)
+	}
+	return &CanalFlatEventBatchDecoder{msg: msg}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *CanalFlatEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if b.msg == nil {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+	if b.msg.IsDDL || b.msg.Query != "" {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	return model.MqMessageTypeRow, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface,
+// canal-json messages carry no resolved events
+func (b *CanalFlatEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerrors.ErrCanalDecodeFailed.GenWithStack("resolved event is not supported by canal-json protocol")
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface,
+// canal-json messages carry no syncpoint events
+func (b *CanalFlatEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return 0, cerrors.ErrCanalDecodeFailed.GenWithStack("syncpoint event is not supported by canal-json protocol")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *CanalFlatEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if tp, hasNext, _ := b.HasNext(); !hasNext || tp != model.MqMessageTypeRow {
+		return nil, cerrors.ErrCanalDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	msg := b.msg
+	if len(msg.Data) != 1 || len(msg.Old) != 1 {
+		return nil, cerrors.ErrCanalDecodeFailed.GenWithStack("unexpected data count %d and old count %d", len(msg.Data), len(msg.Old))
+	}
+	row := &model.RowChangedEvent{
+		CommitTs: convertFromCanalTs(msg.ExecutionTime),
+		Table: &model.TableName{
+			Schema: msg.Schema,
+			Table:  msg.Table,
+		},
+	}
+	var err error
+	switch msg.EventType {
+	case canal.EventType_INSERT.String():
+		row.Columns, err = msg.toColumns(msg.Data[0])
+	case canal.EventType_UPDATE.String():
+		if row.PreColumns, err = msg.toColumns(msg.Old[0]); err != nil {
+			return nil, errors.Trace(err)
+		}
+		row.Columns, err = msg.toColumns(msg.Data[0])
+	case canal.EventType_DELETE.String():
+		row.PreColumns, err = msg.toColumns(msg.Data[0])
+	default:
+		return nil, cerrors.ErrCanalDecodeFailed.GenWithStack("unexpected event type %s", msg.EventType)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b.msg = nil
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *CanalFlatEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if tp, hasNext, _ := b.HasNext(); !hasNext || tp != model.MqMessageTypeDDL {
+		return nil, cerrors.ErrCanalDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	msg := b.msg
+	ddl := &model.DDLEvent{
+		CommitTs: convertFromCanalTs(msg.ExecutionTime),
+		TableInfo: &model.SimpleTableInfo{
+			Schema: msg.Schema,
+			Table:  msg.Table,
+		},
+		Query: msg.Query,
+		Type:  canalDDLType(canal.EventType(canal.EventType_value[msg.EventType]), msg.Query),
+	}
+	b.msg = nil
+	return ddl, nil
+}
+
+// toColumns converts the data of the message to columns ordered by the column names
+func (c *canalFlatMessage) toColumns(data map[string]interface{}) ([]*model.Column, error) {
+	if data == nil {
+		return nil, nil
+	}
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := make([]*model.Column, 0, len(data))
+	for _, name := range names {
+		isKey := false
+		for _, pkName := range c.PKNames {
+			if pkName == name {
+				isKey = true
+				break
+			}
+		}
+		value, isString := data[name].(string)
+		if data[name] != nil && !isString {
+			return nil, cerrors.ErrCanalDecodeFailed.GenWithStack("unexpected value %v of column %s", data[name], name)
+		}
+		col, err := canalValueToColumn(name, c.MySQLType[name], value, data[name] == nil, isKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
@@ -15,6 +15,7 @@ package codec
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/pingcap/check"
 	mm "github.com/pingcap/parser/model"
@@ -170,3 +171,65 @@ var testCaseDdl = &model.DDLEvent{
 	Query: "create table person(id int, name varchar(32), tiny tinyint unsigned, comment text, primary key(id))",
 	Type:  mm.ActionCreateTable,
 }
+
+func (s *canalFlatSuite) TestCanalFlatEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// the decoded columns are ordered by the column names
+	sortColumns := func(cols []*model.Column) []*model.Column {
+		if cols == nil {
+			return nil
+		}
+		sorted := make([]*model.Column, len(cols))
+		copy(sorted, cols)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		return sorted
+	}
+
+	rows := canalDecoderTestRows()
+	encoder := NewCanalFlatEventBatchEncoder()
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	_, err := encoder.AppendResolvedEvent(canalDecoderTestTs)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, len(rows))
+
+	for i, msg := range msgs {
+		expected := rows[i]
+		decoder, err := NewCanalFlatEventBatchDecoder(msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(row.CommitTs, check.Equals, expected.CommitTs)
+		c.Assert(row.Table, check.DeepEquals, expected.Table)
+		c.Assert(row.PreColumns, check.DeepEquals, sortColumns(expected.PreColumns))
+		c.Assert(row.Columns, check.DeepEquals, sortColumns(expected.Columns))
+		_, hasNext, err = decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsFalse)
+	}
+
+	expected := &model.DDLEvent{
+		CommitTs:  canalDecoderTestTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+		Type:      mm.ActionCreateTable,
+	}
+	msg, err := encoder.EncodeDDLEvent(expected)
+	c.Assert(err, check.IsNil)
+	decoder, err := NewCanalFlatEventBatchDecoder(msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	ddl, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ddl, check.DeepEquals, expected)
+}
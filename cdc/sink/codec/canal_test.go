@@ -368,3 +368,85 @@ func testDdl(c *check.C) {
 	c.Assert(rc.GetIsDdl(), check.IsTrue)
 	c.Assert(rc.GetDdlSchemaName(), check.Equals, testCaseDdl.TableInfo.Schema)
 }
+
+// canal messages keep the timestamp in milliseconds only
+var canalDecoderTestTs = uint64(1617000000000) << 18
+
+var canalDecoderTestColumns = []*model.Column{
+	{Name: "id", Type: mysql.TypeLong, Flag: model.PrimaryKeyFlag | model.HandleKeyFlag, Value: int64(1)},
+	{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Bob")},
+	{Name: "avatar", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0x00, 0xff, 0x10}},
+	{Name: "score", Type: mysql.TypeDouble, Value: 1.5},
+	{Name: "price", Type: mysql.TypeNewDecimal, Value: "12.30"},
+	{Name: "created", Type: mysql.TypeDatetime, Value: "2021-03-29 10:00:00"},
+	{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+}
+
+func canalDecoderTestRows() []*model.RowChangedEvent {
+	updated := make([]*model.Column, len(canalDecoderTestColumns))
+	copy(updated, canalDecoderTestColumns)
+	updated[1] = &model.Column{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Alice")}
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	return []*model.RowChangedEvent{
+		{CommitTs: canalDecoderTestTs, Table: table, Columns: canalDecoderTestColumns},
+		{CommitTs: canalDecoderTestTs, Table: table, PreColumns: canalDecoderTestColumns, Columns: updated},
+		{CommitTs: canalDecoderTestTs, Table: table, PreColumns: updated},
+	}
+}
+
+func (s *canalBatchSuite) TestCanalEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	rows := canalDecoderTestRows()
+	encoder := NewCanalEventBatchEncoder()
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+
+	decoder, err := NewCanalEventBatchDecoder(msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	for _, expected := range rows {
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(row.CommitTs, check.Equals, expected.CommitTs)
+		c.Assert(row.Table, check.DeepEquals, expected.Table)
+		c.Assert(row.PreColumns, check.DeepEquals, expected.PreColumns)
+		c.Assert(row.Columns, check.DeepEquals, expected.Columns)
+	}
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	ddls := []*model.DDLEvent{{
+		CommitTs:  canalDecoderTestTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test"},
+		Query:     "create database test",
+		Type:      mm.ActionCreateSchema,
+	}, {
+		CommitTs:  canalDecoderTestTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+		Type:      mm.ActionCreateTable,
+	}}
+	for _, expected := range ddls {
+		msg, err := encoder.EncodeDDLEvent(expected)
+		c.Assert(err, check.IsNil)
+		decoder, err := NewCanalEventBatchDecoder(msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+		_, err = decoder.NextRowChangedEvent()
+		c.Assert(err, check.NotNil)
+		ddl, err := decoder.NextDDLEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(ddl, check.DeepEquals, expected)
+	}
+}
@@ -0,0 +1,707 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/mysql"
+	parser_types "github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/version"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+const (
+	debeziumConnector         = "tidb"
+	debeziumDefaultServerName = "tidb"
+
+	debeziumOpCreate = "c"
+	debeziumOpUpdate = "u"
+	debeziumOpDelete = "d"
+
+	// schema names used by Debezium for the non-row events
+	debeziumSchemaChangeKeyName   = "io.debezium.connector.mysql.SchemaChangeKey"
+	debeziumSchemaChangeValueName = "io.debezium.connector.mysql.SchemaChangeValue"
+	debeziumHeartbeatKeyName      = "io.debezium.connector.common.ServerNameKey"
+	debeziumHeartbeatValueName    = "io.debezium.connector.common.Heartbeat"
+	debeziumSourceName            = "io.debezium.connector.tidb.Source"
+	debeziumEnvelopeSuffix        = ".Envelope"
+
+	// field schema parameters used to recover the column type and flag when decoding
+	debeziumParamColumnType = "__debezium.source.column.type"
+	debeziumParamTiDBType   = "tidb.column.type"
+	debeziumParamTiDBFlag   = "tidb.column.flag"
+)
+
+// debeziumSchema is the Kafka Connect schema attached to every Debezium message
+type debeziumSchema struct {
+	Type       string            `json:"type"`
+	Optional   bool              `json:"optional"`
+	Name       string            `json:"name,omitempty"`
+	Field      string            `json:"field,omitempty"`
+	Fields     []*debeziumSchema `json:"fields,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+type debeziumMessage struct {
+	Schema  *debeziumSchema `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type debeziumSource struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Table     string `json:"table,omitempty"`
+	CommitTs  uint64 `json:"commit_ts"`
+}
+
+type debeziumRowPayload struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source *debeziumSource        `json:"source"`
+	Op     string                 `json:"op"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+type debeziumDDLPayload struct {
+	Source       *debeziumSource `json:"source"`
+	DatabaseName string          `json:"databaseName"`
+	DDL          string          `json:"ddl"`
+	TsMs         int64           `json:"ts_ms"`
+}
+
+type debeziumHeartbeatPayload struct {
+	TsMs     int64  `json:"ts_ms"`
+	CommitTs uint64 `json:"commit_ts"`
+}
+
+type debeziumBufferedMessage struct {
+	key      []byte
+	value    []byte
+	schema   string
+	table    string
+	commitTs uint64
+}
+
+// DebeziumEventBatchEncoder encodes the events into the Debezium JSON format,
+// each event is encoded into one message with a schema and a payload
+type DebeziumEventBatchEncoder struct {
+	serverName    string
+	unresolvedBuf []*debeziumBufferedMessage
+	resolvedBuf   []*debeziumBufferedMessage
+}
+
+// NewDebeziumEventBatchEncoder creates a new DebeziumEventBatchEncoder
+func NewDebeziumEventBatchEncoder() EventBatchEncoder {
+	return &DebeziumEventBatchEncoder{
+		serverName:    debeziumDefaultServerName,
+		unresolvedBuf: make([]*debeziumBufferedMessage, 0),
+		resolvedBuf:   make([]*debeziumBufferedMessage, 0),
+	}
+}
+
+func (d *DebeziumEventBatchEncoder) newSource(commitTs uint64, schema, table string) *debeziumSource {
+	return &debeziumSource{
+		Version:   version.ReleaseVersion,
+		Connector: debeziumConnector,
+		Name:      d.serverName,
+		TsMs:      oracle.ExtractPhysical(commitTs),
+		Snapshot:  "false",
+		DB:        schema,
+		Table:     table,
+		CommitTs:  commitTs,
+	}
+}
+
+// EncodeCheckpointEvent encodes the checkpoint ts as a Debezium heartbeat message
+func (d *DebeziumEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
+	key, err := encodeDebeziumMessage(&debeziumSchema{
+		Type: "struct",
+		Name: debeziumHeartbeatKeyName,
+		Fields: []*debeziumSchema{
+			{Type: "string", Field: "serverName"},
+		},
+	}, map[string]string{"serverName": d.serverName})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := encodeDebeziumMessage(&debeziumSchema{
+		Type: "struct",
+		Name: debeziumHeartbeatValueName,
+		Fields: []*debeziumSchema{
+			{Type: "int64", Field: "ts_ms"},
+			{Type: "int64", Field: "commit_ts"},
+		},
+	}, &debeziumHeartbeatPayload{
+		TsMs:     oracle.ExtractPhysical(ts),
+		CommitTs: ts,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newResolvedMQMessage(ProtocolDebezium, key, value, ts), nil
+}
+
+// EncodeSyncpointEvent implements the EventBatchEncoder interface,
+// there is no such a corresponding type to the syncpoint event in the debezium protocol,
+// therefore the event is ignored.
+func (d *DebeziumEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// AppendRowChangedEvent implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
+	key, value, err := d.rowEventToDebeziumMessage(e)
+	if err != nil {
+		return EncoderNoOperation, errors.Trace(err)
+	}
+	d.unresolvedBuf = append(d.unresolvedBuf, &debeziumBufferedMessage{
+		key:      key,
+		value:    value,
+		schema:   e.Table.Schema,
+		table:    e.Table.Table,
+		commitTs: e.CommitTs,
+	})
+	return EncoderNoOperation, nil
+}
+
+// AppendResolvedEvent receives the latest resolvedTs
+func (d *DebeziumEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
+	nextIdx := 0
+	for _, msg := range d.unresolvedBuf {
+		if msg.commitTs > ts {
+			break
+		}
+		d.resolvedBuf = append(d.resolvedBuf, msg)
+		nextIdx++
+	}
+	d.unresolvedBuf = d.unresolvedBuf[nextIdx:]
+	if len(d.resolvedBuf) > 0 {
+		return EncoderNeedAsyncWrite, nil
+	}
+	return EncoderNoOperation, nil
+}
+
+// EncodeDDLEvent encodes the DDL event as a Debezium schema change message
+func (d *DebeziumEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+	key, err := encodeDebeziumMessage(&debeziumSchema{
+		Type: "struct",
+		Name: debeziumSchemaChangeKeyName,
+		Fields: []*debeziumSchema{
+			{Type: "string", Field: "databaseName"},
+		},
+	}, map[string]string{"databaseName": e.TableInfo.Schema})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := encodeDebeziumMessage(&debeziumSchema{
+		Type: "struct",
+		Name: debeziumSchemaChangeValueName,
+		Fields: []*debeziumSchema{
+			debeziumSourceSchema(),
+			{Type: "string", Field: "databaseName"},
+			{Type: "string", Field: "ddl"},
+			{Type: "int64", Field: "ts_ms"},
+		},
+	}, &debeziumDDLPayload{
+		Source:       d.newSource(e.CommitTs, e.TableInfo.Schema, e.TableInfo.Table),
+		DatabaseName: e.TableInfo.Schema,
+		DDL:          e.Query,
+		TsMs:         time.Now().UnixNano() / int64(time.Millisecond),
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newDDLMQMessage(ProtocolDebezium, key, value, e), nil
+}
+
+// Build implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) Build() []*MQMessage {
+	if len(d.resolvedBuf) == 0 {
+		return nil
+	}
+	ret := make([]*MQMessage, len(d.resolvedBuf))
+	for i, msg := range d.resolvedBuf {
+		ret[i] = NewMQMessage(ProtocolDebezium, msg.key, msg.value, msg.commitTs, model.MqMessageTypeRow, &msg.schema, &msg.table)
+	}
+	d.resolvedBuf = d.resolvedBuf[0:0]
+	return ret
+}
+
+// MixedBuild is not used here
+func (d *DebeziumEventBatchEncoder) MixedBuild(withVersion bool) []byte {
+	panic("MixedBuild not supported by DebeziumEventBatchEncoder")
+}
+
+// Size implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) Size() int {
+	return -1
+}
+
+// Reset is only supported by JSONEventBatchEncoder
+func (d *DebeziumEventBatchEncoder) Reset() {
+	panic("not supported")
+}
+
+// SetParams reads the logical server name which is used as the `source.name` and schema name prefix
+func (d *DebeziumEventBatchEncoder) SetParams(params map[string]string) error {
+	if name, ok := params["debezium-server-name"]; ok && name != "" {
+		d.serverName = name
+	}
+	return nil
+}
+
+func (d *DebeziumEventBatchEncoder) rowEventToDebeziumMessage(e *model.RowChangedEvent) (key []byte, value []byte, err error) {
+	payload := &debeziumRowPayload{
+		Source: d.newSource(e.CommitTs, e.Table.Schema, e.Table.Table),
+		TsMs:   time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	switch {
+	case e.IsDelete():
+		payload.Op = debeziumOpDelete
+	case len(e.PreColumns) != 0:
+		payload.Op = debeziumOpUpdate
+	default:
+		payload.Op = debeziumOpCreate
+	}
+
+	cols := e.Columns
+	if e.IsDelete() {
+		cols = e.PreColumns
+	}
+	if payload.Before, err = debeziumColumnValues(e.PreColumns); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if payload.After, err = debeziumColumnValues(e.Columns); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	prefix := d.serverName + "." + e.Table.Schema + "." + e.Table.Table
+	rowSchema := &debeziumSchema{
+		Type:     "struct",
+		Optional: true,
+		Name:     prefix + ".Value",
+		Fields:   debeziumColumnSchemas(cols),
+	}
+	before, after := *rowSchema, *rowSchema
+	before.Field, after.Field = "before", "after"
+	value, err = encodeDebeziumMessage(&debeziumSchema{
+		Type: "struct",
+		Name: prefix + debeziumEnvelopeSuffix,
+		Fields: []*debeziumSchema{
+			&before,
+			&after,
+			debeziumSourceSchema(),
+			{Type: "string", Field: "op"},
+			{Type: "int64", Field: "ts_ms", Optional: true},
+		},
+	}, payload)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var keyCols []*model.Column
+	for _, col := range cols {
+		if col != nil && col.Flag.IsHandleKey() {
+			keyCols = append(keyCols, col)
+		}
+	}
+	// tables without a handle key have no message key, just like Debezium does
+	if len(keyCols) == 0 {
+		return nil, value, nil
+	}
+	keyPayload, err := debeziumColumnValues(keyCols)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	key, err = encodeDebeziumMessage(&debeziumSchema{
+		Type:   "struct",
+		Name:   prefix + ".Key",
+		Fields: debeziumColumnSchemas(keyCols),
+	}, keyPayload)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return key, value, nil
+}
+
+func encodeDebeziumMessage(schema *debeziumSchema, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	data, err = json.Marshal(&debeziumMessage{Schema: schema, Payload: data})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	return data, nil
+}
+
+func debeziumSourceSchema() *debeziumSchema {
+	return &debeziumSchema{
+		Type:  "struct",
+		Name:  debeziumSourceName,
+		Field: "source",
+		Fields: []*debeziumSchema{
+			{Type: "string", Field: "version"},
+			{Type: "string", Field: "connector"},
+			{Type: "string", Field: "name"},
+			{Type: "int64", Field: "ts_ms"},
+			{Type: "string", Field: "snapshot", Optional: true},
+			{Type: "string", Field: "db"},
+			{Type: "string", Field: "table", Optional: true},
+			{Type: "int64", Field: "commit_ts"},
+		},
+	}
+}
+
+func debeziumColumnSchemas(cols []*model.Column) []*debeziumSchema {
+	fields := make([]*debeziumSchema, 0, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		field := &debeziumSchema{
+			Type:     debeziumColumnType(col),
+			Optional: !col.Flag.IsHandleKey(),
+			Field:    col.Name,
+			Parameters: map[string]string{
+				debeziumParamColumnType: strings.ToUpper(parser_types.TypeStr(col.Type)),
+				debeziumParamTiDBType:   strconv.Itoa(int(col.Type)),
+				debeziumParamTiDBFlag:   strconv.FormatUint(uint64(col.Flag), 10),
+			},
+		}
+		switch col.Type {
+		case mysql.TypeJSON:
+			field.Name = "io.debezium.data.Json"
+		case mysql.TypeYear:
+			field.Name = "io.debezium.time.Year"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// debeziumColumnType maps the column type to the Kafka Connect schema type
+func debeziumColumnType(col *model.Column) string {
+	switch col.Type {
+	case mysql.TypeTiny, mysql.TypeShort:
+		if col.Flag.IsUnsigned() && col.Type == mysql.TypeShort {
+			return "int32"
+		}
+		return "int16"
+	case mysql.TypeInt24, mysql.TypeYear:
+		return "int32"
+	case mysql.TypeLong:
+		if col.Flag.IsUnsigned() {
+			return "int64"
+		}
+		return "int32"
+	case mysql.TypeLonglong, mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+		return "int64"
+	case mysql.TypeFloat:
+		return "float32"
+	case mysql.TypeDouble:
+		return "float64"
+	case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		if col.Flag.IsBinary() {
+			return "bytes"
+		}
+		return "string"
+	default:
+		return "string"
+	}
+}
+
+func debeziumColumnValues(cols []*model.Column) (map[string]interface{}, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		if col.Value == nil {
+			values[col.Name] = nil
+			continue
+		}
+		switch col.Type {
+		case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar,
+			mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+			var b []byte
+			switch v := col.Value.(type) {
+			case []byte:
+				b = v
+			case string:
+				b = []byte(v)
+			default:
+				return nil, cerror.ErrDebeziumEncodeFailed.GenWithStack(
+					"unexpected value type %T of column %s", col.Value, col.Name)
+			}
+			if col.Flag.IsBinary() {
+				values[col.Name] = base64.StdEncoding.EncodeToString(b)
+			} else {
+				values[col.Name] = string(b)
+			}
+		case mysql.TypeFloat, mysql.TypeDouble, mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24,
+			mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear, mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+			values[col.Name] = col.Value
+		default:
+			values[col.Name] = model.ColumnValueString(col.Value)
+		}
+	}
+	return values, nil
+}
+
+// DebeziumEventBatchDecoder decodes the messages encoded by DebeziumEventBatchEncoder,
+// each message contains exactly one event
+type DebeziumEventBatchDecoder struct {
+	key     []byte
+	value   *debeziumMessage
+	msgType model.MqMessageType
+	hasNext bool
+}
+
+// NewDebeziumEventBatchDecoder creates a new DebeziumEventBatchDecoder
+func NewDebeziumEventBatchDecoder(key []byte, value []byte) (EventBatchDecoder, error) {
+	msg := new(debeziumMessage)
+	if err := json.Unmarshal(value, msg); err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	if msg.Schema == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("schema is missing in the message")
+	}
+	var msgType model.MqMessageType
+	switch {
+	case msg.Schema.Name == debeziumHeartbeatValueName:
+		msgType = model.MqMessageTypeResolved
+	case msg.Schema.Name == debeziumSchemaChangeValueName:
+		msgType = model.MqMessageTypeDDL
+	case strings.HasSuffix(msg.Schema.Name, debeziumEnvelopeSuffix):
+		msgType = model.MqMessageTypeRow
+	default:
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("unknown schema name %s", msg.Schema.Name)
+	}
+	return &DebeziumEventBatchDecoder{
+		key:     key,
+		value:   msg,
+		msgType: msgType,
+		hasNext: true,
+	}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if !b.hasNext {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+	return b.msgType, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	if err := b.checkNext(model.MqMessageTypeResolved); err != nil {
+		return 0, errors.Trace(err)
+	}
+	payload := new(debeziumHeartbeatPayload)
+	if err := json.Unmarshal(b.value.Payload, payload); err != nil {
+		return 0, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	b.hasNext = false
+	return payload.CommitTs, nil
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface,
+// debezium messages carry no syncpoint events
+func (b *DebeziumEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return 0, cerror.ErrDebeziumDecodeFailed.GenWithStack("syncpoint event is not supported by debezium protocol")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if err := b.checkNext(model.MqMessageTypeRow); err != nil {
+		return nil, errors.Trace(err)
+	}
+	payload := new(debeziumRowPayload)
+	decoder := json.NewDecoder(bytes.NewReader(b.value.Payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(payload); err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	if payload.Source == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("source is missing in the message")
+	}
+	var rowSchema *debeziumSchema
+	for _, field := range b.value.Schema.Fields {
+		if field.Field == "after" || field.Field == "before" {
+			rowSchema = field
+			break
+		}
+	}
+	if rowSchema == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("row schema is missing in the message")
+	}
+
+	row := &model.RowChangedEvent{
+		CommitTs: payload.Source.CommitTs,
+		Table: &model.TableName{
+			Schema: payload.Source.DB,
+			Table:  payload.Source.Table,
+		},
+	}
+	var err error
+	if payload.Op != debeziumOpCreate {
+		if row.PreColumns, err = debeziumValuesToColumns(rowSchema.Fields, payload.Before); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if payload.Op != debeziumOpDelete {
+		if row.Columns, err = debeziumValuesToColumns(rowSchema.Fields, payload.After); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	b.hasNext = false
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if err := b.checkNext(model.MqMessageTypeDDL); err != nil {
+		return nil, errors.Trace(err)
+	}
+	payload := new(debeziumDDLPayload)
+	if err := json.Unmarshal(b.value.Payload, payload); err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	if payload.Source == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("source is missing in the message")
+	}
+	b.hasNext = false
+	return &model.DDLEvent{
+		CommitTs: payload.Source.CommitTs,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: payload.Source.DB,
+			Table:  payload.Source.Table,
+		},
+		Query: payload.DDL,
+	}, nil
+}
+
+func (b *DebeziumEventBatchDecoder) checkNext(tp model.MqMessageType) error {
+	if !b.hasNext || b.msgType != tp {
+		return cerror.ErrDebeziumDecodeFailed.GenWithStack("not found %s message", tp)
+	}
+	return nil
+}
+
+func debeziumValuesToColumns(fields []*debeziumSchema, values map[string]interface{}) ([]*model.Column, error) {
+	if values == nil {
+		return nil, nil
+	}
+	cols := make([]*model.Column, 0, len(fields))
+	for _, field := range fields {
+		value, ok := values[field.Field]
+		if !ok {
+			continue
+		}
+		tp, err := strconv.Atoi(field.Parameters[debeziumParamTiDBType])
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+		}
+		flag, err := strconv.ParseUint(field.Parameters[debeziumParamTiDBFlag], 10, 64)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+		}
+		col := &model.Column{
+			Name: field.Field,
+			Type: byte(tp),
+			Flag: model.ColumnFlagType(flag),
+		}
+		if col.Value, err = debeziumValueToColumnValue(col, value); err != nil {
+			return nil, errors.Trace(err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func debeziumValueToColumnValue(col *model.Column, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch col.Type {
+	case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		str, ok := value.(string)
+		if !ok {
+			break
+		}
+		if !col.Flag.IsBinary() {
+			return []byte(str), nil
+		}
+		b, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+		}
+		return b, nil
+	case mysql.TypeFloat, mysql.TypeDouble:
+		num, ok := value.(json.Number)
+		if !ok {
+			break
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+		}
+		return f, nil
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear,
+		mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+		num, ok := value.(json.Number)
+		if !ok {
+			break
+		}
+		if col.Flag.IsUnsigned() || col.Type == mysql.TypeBit || col.Type == mysql.TypeEnum || col.Type == mysql.TypeSet {
+			v, err := strconv.ParseUint(num.String(), 10, 64)
+			if err != nil {
+				return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+			}
+			return v, nil
+		}
+		v, err := num.Int64()
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+		}
+		return v, nil
+	default:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+	}
+	return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack(
+		"unexpected value %v of column %s", value, col.Name)
+}
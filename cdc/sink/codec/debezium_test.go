@@ -0,0 +1,160 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type debeziumSuite struct{}
+
+var _ = check.Suite(&debeziumSuite{})
+
+var debeziumTestColumns = []*model.Column{
+	{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+	{Name: "age", Type: mysql.TypeLong, Flag: model.UnsignedFlag, Value: uint64(18)},
+	{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Bob")},
+	{Name: "avatar", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0x00, 0xff, 0x10}},
+	{Name: "score", Type: mysql.TypeDouble, Value: 95.5},
+	{Name: "price", Type: mysql.TypeNewDecimal, Value: "12.30"},
+	{Name: "created", Type: mysql.TypeDatetime, Value: "2021-03-01 10:00:00"},
+	{Name: "bits", Type: mysql.TypeBit, Value: uint64(5)},
+	{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+}
+
+func (s *debeziumSuite) TestRowRoundTrip(c *check.C) {
+	defer testleak.AfterTest(c)()
+	updatedColumns := make([]*model.Column, len(debeziumTestColumns))
+	copy(updatedColumns, debeziumTestColumns)
+	updatedColumns[2] = &model.Column{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Alice")}
+
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	testCases := []struct {
+		row *model.RowChangedEvent
+		op  string
+	}{
+		{&model.RowChangedEvent{CommitTs: 417318403368288260, Table: table, Columns: debeziumTestColumns}, "c"},
+		{&model.RowChangedEvent{CommitTs: 417318403368288261, Table: table, PreColumns: debeziumTestColumns, Columns: updatedColumns}, "u"},
+		{&model.RowChangedEvent{CommitTs: 417318403368288262, Table: table, PreColumns: updatedColumns}, "d"},
+	}
+
+	encoder := NewDebeziumEventBatchEncoder()
+	c.Assert(encoder.SetParams(map[string]string{"debezium-server-name": "cluster1"}), check.IsNil)
+	for _, tc := range testCases {
+		result, err := encoder.AppendRowChangedEvent(tc.row)
+		c.Assert(err, check.IsNil)
+		c.Assert(result, check.Equals, EncoderNoOperation)
+	}
+	// no event is resolved yet
+	result, err := encoder.AppendResolvedEvent(417318403368288259)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.Equals, EncoderNoOperation)
+	c.Assert(encoder.Build(), check.IsNil)
+
+	result, err = encoder.AppendResolvedEvent(417318403368288262)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.Equals, EncoderNeedAsyncWrite)
+	messages := encoder.Build()
+	c.Assert(messages, check.HasLen, len(testCases))
+
+	for i, msg := range messages {
+		expected := testCases[i].row
+		c.Assert(msg.Type, check.Equals, model.MqMessageTypeRow)
+		c.Assert(msg.Ts, check.Equals, expected.CommitTs)
+		c.Assert(msg.Protocol, check.Equals, ProtocolDebezium)
+
+		// check the Debezium envelope
+		envelope := make(map[string]interface{})
+		c.Assert(json.Unmarshal(msg.Value, &envelope), check.IsNil)
+		schema := envelope["schema"].(map[string]interface{})
+		c.Assert(schema["name"], check.Equals, "cluster1.test.t1.Envelope")
+		payload := envelope["payload"].(map[string]interface{})
+		c.Assert(payload["op"], check.Equals, testCases[i].op)
+		source := payload["source"].(map[string]interface{})
+		c.Assert(source["connector"], check.Equals, "tidb")
+		c.Assert(source["name"], check.Equals, "cluster1")
+		c.Assert(source["db"], check.Equals, "test")
+		c.Assert(source["table"], check.Equals, "t1")
+
+		key := make(map[string]interface{})
+		c.Assert(json.Unmarshal(msg.Key, &key), check.IsNil)
+		c.Assert(key["payload"], check.DeepEquals, map[string]interface{}{"id": float64(1)})
+
+		decoder, err := NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(row.CommitTs, check.Equals, expected.CommitTs)
+		c.Assert(row.Table, check.DeepEquals, expected.Table)
+		c.Assert(row.PreColumns, check.DeepEquals, expected.PreColumns)
+		c.Assert(row.Columns, check.DeepEquals, expected.Columns)
+		_, hasNext, err = decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsFalse)
+	}
+}
+
+func (s *debeziumSuite) TestDDLAndCheckpointRoundTrip(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewDebeziumEventBatchEncoder()
+	ddl := &model.DDLEvent{
+		CommitTs:  417318403368288260,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+		Type:      3,
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeDDL)
+	c.Assert(msg.Ts, check.Equals, ddl.CommitTs)
+
+	decoder, err := NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	_, err = decoder.NextRowChangedEvent()
+	c.Assert(err, check.ErrorMatches, ".*not found.*")
+	decoded, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.CommitTs, check.Equals, ddl.CommitTs)
+	c.Assert(decoded.TableInfo, check.DeepEquals, ddl.TableInfo)
+	c.Assert(decoded.Query, check.Equals, ddl.Query)
+
+	msg, err = encoder.EncodeCheckpointEvent(417318403368288261)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeResolved)
+	decoder, err = NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+	ts, err := decoder.NextResolvedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(417318403368288261))
+
+	_, err = NewDebeziumEventBatchDecoder(nil, []byte(`{"schema":{"type":"struct","name":"unknown"},"payload":{}}`))
+	c.Assert(err, check.ErrorMatches, ".*unknown schema name.*")
+}
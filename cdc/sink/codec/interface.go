@@ -28,6 +28,10 @@ type EventBatchEncoder interface {
 	// EncodeCheckpointEvent appends a checkpoint event into the batch.
 	// This event will be broadcast to all partitions to signal a global checkpoint.
 	EncodeCheckpointEvent(ts uint64) (*MQMessage, error)
+	// EncodeSyncpointEvent encodes a syncpoint event.
+	// This event will be broadcast to all partitions to signal that the data
+	// before ts in all partitions is a consistent snapshot of the upstream.
+	EncodeSyncpointEvent(ts uint64) (*MQMessage, error)
 	// AppendRowChangedEvent appends a row changed event into the batch
 	AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error)
 	// AppendResolvedEvent appends a resolved event into the batch.
@@ -117,6 +121,8 @@ type EventBatchDecoder interface {
 	HasNext() (model.MqMessageType, bool, error)
 	// NextResolvedEvent returns the next resolved event if exists
 	NextResolvedEvent() (uint64, error)
+	// NextSyncpointEvent returns the next syncpoint event if exists
+	NextSyncpointEvent() (uint64, error)
 	// NextRowChangedEvent returns the next row changed event if exists
 	NextRowChangedEvent() (*model.RowChangedEvent, error)
 	// NextDDLEvent returns the next DDL event if exists
@@ -143,6 +149,7 @@ const (
 	ProtocolAvro
 	ProtocolMaxwell
 	ProtocolCanalJSON
+	ProtocolDebezium
 )
 
 // FromString converts the protocol from string to Protocol enum type
@@ -158,6 +165,8 @@ func (p *Protocol) FromString(protocol string) {
 		*p = ProtocolMaxwell
 	case "canal-json":
 		*p = ProtocolCanalJSON
+	case "debezium":
+		*p = ProtocolDebezium
 	default:
 		*p = ProtocolDefault
 		log.Warn("can't support codec protocol, using default protocol", zap.String("protocol", protocol))
@@ -177,6 +186,8 @@ func NewEventBatchEncoder(p Protocol) func() EventBatchEncoder {
 		return NewMaxwellEventBatchEncoder
 	case ProtocolCanalJSON:
 		return NewCanalFlatEventBatchEncoder
+	case ProtocolDebezium:
+		return NewDebeziumEventBatchEncoder
 	default:
 		log.Warn("unknown codec protocol value of EventBatchEncoder", zap.Int("protocol_value", int(p)))
 		return NewJSONEventBatchEncoder
@@ -187,6 +187,13 @@ func (m *mqMessageDDL) Decode(data []byte) error {
 	return cerror.WrapError(cerror.ErrUnmarshalFailed, json.Unmarshal(data, m))
 }
 
+func tsEventName(tp model.MqMessageType) string {
+	if tp == model.MqMessageTypeSyncpoint {
+		return "syncpoint"
+	}
+	return "resolved"
+}
+
 func newResolvedMessage(ts uint64) *mqMessageKey {
 	return &mqMessageKey{
 		Ts:   ts,
@@ -335,7 +342,17 @@ func (d *JSONEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, e
 
 // EncodeCheckpointEvent implements the EventBatchEncoder interface
 func (d *JSONEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
-	keyMsg := newResolvedMessage(ts)
+	return d.encodeTsEvent(newResolvedMessage(ts))
+}
+
+// EncodeSyncpointEvent implements the EventBatchEncoder interface
+func (d *JSONEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
+	return d.encodeTsEvent(&mqMessageKey{Ts: ts, Type: model.MqMessageTypeSyncpoint})
+}
+
+// encodeTsEvent encodes an event carrying only the ts in the key
+func (d *JSONEventBatchEncoder) encodeTsEvent(keyMsg *mqMessageKey) (*MQMessage, error) {
+	ts := keyMsg.Ts
 	key, err := keyMsg.Encode()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -363,7 +380,7 @@ func (d *JSONEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, er
 	valueBuf := new(bytes.Buffer)
 	valueBuf.Write(valueLenByte[:])
 
-	ret := newResolvedMQMessage(ProtocolDefault, keyBuf.Bytes(), valueBuf.Bytes(), ts)
+	ret := NewMQMessage(ProtocolDefault, keyBuf.Bytes(), valueBuf.Bytes(), ts, keyMsg.Type, nil, nil)
 	return ret, nil
 }
 
@@ -592,14 +609,23 @@ func (b *JSONEventBatchMixedDecoder) HasNext() (model.MqMessageType, bool, error
 
 // NextResolvedEvent implements the EventBatchDecoder interface
 func (b *JSONEventBatchMixedDecoder) NextResolvedEvent() (uint64, error) {
+	return b.nextTsEvent(model.MqMessageTypeResolved)
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface
+func (b *JSONEventBatchMixedDecoder) NextSyncpointEvent() (uint64, error) {
+	return b.nextTsEvent(model.MqMessageTypeSyncpoint)
+}
+
+func (b *JSONEventBatchMixedDecoder) nextTsEvent(tp model.MqMessageType) (uint64, error) {
 	if b.nextKey == nil {
 		if err := b.decodeNextKey(); err != nil {
 			return 0, err
 		}
 	}
 	b.mixedBytes = b.mixedBytes[b.nextKeyLen+8:]
-	if b.nextKey.Type != model.MqMessageTypeResolved {
-		return 0, cerror.ErrJSONCodecInvalidData.GenWithStack("not found resolved event message")
+	if b.nextKey.Type != tp {
+		return 0, cerror.ErrJSONCodecInvalidData.GenWithStack("not found %s event message", tsEventName(tp))
 	}
 	valueLen := binary.BigEndian.Uint64(b.mixedBytes[:8])
 	b.mixedBytes = b.mixedBytes[valueLen+8:]
@@ -693,14 +719,23 @@ func (b *JSONEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
 
 // NextResolvedEvent implements the EventBatchDecoder interface
 func (b *JSONEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return b.nextTsEvent(model.MqMessageTypeResolved)
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface
+func (b *JSONEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return b.nextTsEvent(model.MqMessageTypeSyncpoint)
+}
+
+func (b *JSONEventBatchDecoder) nextTsEvent(tp model.MqMessageType) (uint64, error) {
 	if b.nextKey == nil {
 		if err := b.decodeNextKey(); err != nil {
 			return 0, err
 		}
 	}
 	b.keyBytes = b.keyBytes[b.nextKeyLen+8:]
-	if b.nextKey.Type != model.MqMessageTypeResolved {
-		return 0, cerror.ErrJSONCodecInvalidData.GenWithStack("not found resolved event message")
+	if b.nextKey.Type != tp {
+		return 0, cerror.ErrJSONCodecInvalidData.GenWithStack("not found %s event message", tsEventName(tp))
 	}
 	valueLen := binary.BigEndian.Uint64(b.valueBytes[:8])
 	b.valueBytes = b.valueBytes[valueLen+8:]
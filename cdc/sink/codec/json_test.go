@@ -386,3 +386,30 @@ func (s *columnSuite) TestVarBinaryCol(c *check.C) {
 	col2 := jsonCol2.ToSinkColumn("test")
 	c.Assert(col2, check.DeepEquals, col)
 }
+
+func (s *batchSuite) TestSyncpointEvent(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewJSONEventBatchEncoder()
+	msg, err := encoder.EncodeSyncpointEvent(417318403368288260)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeSyncpoint)
+	c.Assert(msg.Ts, check.Equals, uint64(417318403368288260))
+
+	decoder, err := NewJSONEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeSyncpoint)
+	_, err = decoder.NextResolvedEvent()
+	c.Assert(err, check.ErrorMatches, ".*not found resolved event message.*")
+
+	decoder, err = NewJSONEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	ts, err := decoder.NextSyncpointEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(417318403368288260))
+	_, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+}
@@ -17,12 +17,15 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"sort"
+	"strconv"
 
 	"github.com/pingcap/errors"
 	model2 "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/tikv/pd/pkg/tsoutil"
 )
 
@@ -65,6 +68,13 @@ func (d *MaxwellEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage,
 	return nil, nil
 }
 
+// EncodeSyncpointEvent implements the EventBatchEncoder interface,
+// there is no such a corresponding type to the syncpoint event in the maxwell protocol,
+// therefore the event is ignored.
+func (d *MaxwellEventBatchEncoder) EncodeSyncpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
 // AppendResolvedEvent implements the EventBatchEncoder interface
 func (d *MaxwellEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
 	return EncoderNoOperation, nil
@@ -95,6 +105,9 @@ func rowEventToMaxwellMessage(e *model.RowChangedEvent) (*mqMessageKey, *maxwell
 	if e.IsDelete() {
 		value.Type = "delete"
 		for _, v := range e.PreColumns {
+			if v == nil {
+				continue
+			}
 			switch v.Type {
 			case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
 				if v.Value == nil {
@@ -110,6 +123,9 @@ func rowEventToMaxwellMessage(e *model.RowChangedEvent) (*mqMessageKey, *maxwell
 		}
 	} else {
 		for _, v := range e.Columns {
+			if v == nil {
+				continue
+			}
 			switch v.Type {
 			case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
 				if v.Value == nil {
@@ -128,6 +144,9 @@ func rowEventToMaxwellMessage(e *model.RowChangedEvent) (*mqMessageKey, *maxwell
 		} else {
 			value.Type = "update"
 			for _, v := range e.PreColumns {
+				if v == nil {
+					continue
+				}
 				switch v.Type {
 				case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
 					if v.Value == nil {
@@ -368,3 +387,181 @@ func columnToMaxwellType(columnType byte) (string, error) {
 		return "", cerror.ErrMaxwellInvalidData.GenWithStack("unsupported column type - %v", columnType)
 	}
 }
+
+// maxwellDDLType is the reverse of ddlToMaxwellType
+func maxwellDDLType(tp string) model2.ActionType {
+	switch tp {
+	case "table-create":
+		return model2.ActionCreateTable
+	case "table-drop":
+		return model2.ActionDropTable
+	case "table-alter":
+		return model2.ActionAddColumn
+	case "database-create":
+		return model2.ActionCreateSchema
+	case "database-drop":
+		return model2.ActionDropSchema
+	case "database-alter":
+		return model2.ActionModifySchemaCharsetAndCollate
+	default:
+		return model2.ActionNone
+	}
+}
+
+// MaxwellEventBatchDecoder decodes the messages encoded by MaxwellEventBatchEncoder.
+// Maxwell row messages carry neither the column types nor the handle key,
+// so the decoded columns are typed by their JSON values and have no flags.
+type MaxwellEventBatchDecoder struct {
+	ddl     *DdlMaxwellMessage
+	decoder *json.Decoder
+}
+
+// NewMaxwellEventBatchDecoder creates a new MaxwellEventBatchDecoder
+func NewMaxwellEventBatchDecoder(key []byte, value []byte) (EventBatchDecoder, error) {
+	// DDL messages have a JSON key, while the key of row batches only contains the batch version
+	if len(key) > 0 && key[0] == '{' {
+		keyMsg := new(mqMessageKey)
+		if err := keyMsg.Decode(key); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+		}
+		if keyMsg.Type != model.MqMessageTypeDDL {
+			return nil, cerror.ErrMaxwellInvalidData.GenWithStack("unexpected message type %d", keyMsg.Type)
+		}
+		ddl := new(DdlMaxwellMessage)
+		if err := json.Unmarshal(value, ddl); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+		}
+		return &MaxwellEventBatchDecoder{ddl: ddl}, nil
+	}
+	if len(key) != 8 || binary.BigEndian.Uint64(key) != BatchVersion1 {
+		return nil, cerror.ErrMaxwellInvalidData.GenWithStack("unexpected batch version")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	return &MaxwellEventBatchDecoder{decoder: decoder}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *MaxwellEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if b.ddl != nil {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	if b.decoder != nil && b.decoder.More() {
+		return model.MqMessageTypeRow, true, nil
+	}
+	return model.MqMessageTypeUnknown, false, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface,
+// maxwell messages carry no resolved events
+func (b *MaxwellEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerror.ErrMaxwellInvalidData.GenWithStack("resolved event is not supported by maxwell protocol")
+}
+
+// NextSyncpointEvent implements the EventBatchDecoder interface,
+// maxwell messages carry no syncpoint events
+func (b *MaxwellEventBatchDecoder) NextSyncpointEvent() (uint64, error) {
+	return 0, cerror.ErrMaxwellInvalidData.GenWithStack("syncpoint event is not supported by maxwell protocol")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *MaxwellEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if tp, hasNext, _ := b.HasNext(); !hasNext || tp != model.MqMessageTypeRow {
+		return nil, cerror.ErrMaxwellInvalidData.GenWithStack("not found row changed event message")
+	}
+	msg := new(maxwellMessage)
+	if err := b.decoder.Decode(msg); err != nil {
+		return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+	}
+	row := &model.RowChangedEvent{
+		// the ts of maxwell messages is in seconds
+		CommitTs: oracle.ComposeTS(msg.Ts*1000, 0),
+		Table: &model.TableName{
+			Schema: msg.Database,
+			Table:  msg.Table,
+		},
+	}
+	var err error
+	switch msg.Type {
+	case "insert":
+		row.Columns, err = maxwellDataToColumns(msg.Data)
+	case "update":
+		if row.Columns, err = maxwellDataToColumns(msg.Data); err != nil {
+			return nil, errors.Trace(err)
+		}
+		// only the changed columns are recorded in the old data
+		old := make(map[string]interface{}, len(msg.Data))
+		for name, value := range msg.Data {
+			old[name] = value
+		}
+		for name, value := range msg.Old {
+			old[name] = value
+		}
+		row.PreColumns, err = maxwellDataToColumns(old)
+	case "delete":
+		row.PreColumns, err = maxwellDataToColumns(msg.Old)
+	default:
+		return nil, cerror.ErrMaxwellInvalidData.GenWithStack("unexpected row type %s", msg.Type)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *MaxwellEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if b.ddl == nil {
+		return nil, cerror.ErrMaxwellInvalidData.GenWithStack("not found ddl event message")
+	}
+	ddl := &model.DDLEvent{
+		CommitTs: b.ddl.Ts,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: b.ddl.Database,
+			Table:  b.ddl.Table,
+		},
+		Query: b.ddl.SQL,
+		Type:  maxwellDDLType(b.ddl.Type),
+	}
+	b.ddl = nil
+	return ddl, nil
+}
+
+func maxwellDataToColumns(data map[string]interface{}) ([]*model.Column, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := make([]*model.Column, 0, len(data))
+	for _, name := range names {
+		col := &model.Column{Name: name}
+		switch v := data[name].(type) {
+		case nil:
+			col.Type = mysql.TypeNull
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				col.Type, col.Value = mysql.TypeLonglong, i
+			} else if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+				col.Type, col.Value = mysql.TypeLonglong, u
+				col.Flag.SetIsUnsigned()
+			} else {
+				f, err := v.Float64()
+				if err != nil {
+					return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+				}
+				col.Type, col.Value = mysql.TypeDouble, f
+			}
+		case string:
+			col.Type, col.Value = mysql.TypeVarchar, []byte(v)
+		default:
+			return nil, cerror.ErrMaxwellInvalidData.GenWithStack("unexpected value %v of column %s", v, name)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
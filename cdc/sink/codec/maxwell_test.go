@@ -15,8 +15,11 @@ package codec
 
 import (
 	"github.com/pingcap/check"
+	model2 "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 )
 
 type maxwellbatchSuite struct {
@@ -96,3 +99,80 @@ func (s *maxwellcolumnSuite) TestMaxwellFormatCol(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(rowEncode, check.NotNil)
 }
+
+func (s *maxwellbatchSuite) TestMaxwellEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// maxwell messages keep the timestamp in seconds only
+	commitTs := oracle.ComposeTS(1617000000000, 5)
+	decodedTs := oracle.ComposeTS(1617000000000, 0)
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	columns := []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Bob")},
+		{Name: "score", Type: mysql.TypeDouble, Value: 1.5},
+	}
+	updated := []*model.Column{columns[0], columns[1], {Name: "score", Type: mysql.TypeDouble, Value: 2.5}}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: commitTs, Table: table, Columns: columns},
+		{CommitTs: commitTs, Table: table, PreColumns: columns, Columns: updated},
+		{CommitTs: commitTs, Table: table, PreColumns: updated},
+	}
+
+	// the json values carry no column types and flags
+	decodedColumns := func(score float64) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Bob")},
+			{Name: "score", Type: mysql.TypeDouble, Value: score},
+		}
+	}
+	expected := []*model.RowChangedEvent{
+		{CommitTs: decodedTs, Table: table, Columns: decodedColumns(1.5)},
+		{CommitTs: decodedTs, Table: table, PreColumns: decodedColumns(1.5), Columns: decodedColumns(2.5)},
+		{CommitTs: decodedTs, Table: table, PreColumns: decodedColumns(2.5)},
+	}
+
+	encoder := NewMaxwellEventBatchEncoder()
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+
+	decoder, err := NewMaxwellEventBatchDecoder(msgs[0].Key, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	for _, row := range expected {
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		decoded, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded, check.DeepEquals, row)
+	}
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	ddl := &model.DDLEvent{
+		CommitTs:  commitTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+		Type:      model2.ActionCreateTable,
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	decoder, err = NewMaxwellEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decodedDDL, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decodedDDL, check.DeepEquals, ddl)
+
+	_, err = NewMaxwellEventBatchDecoder([]byte{0x1}, nil)
+	c.Assert(err, check.NotNil)
+}
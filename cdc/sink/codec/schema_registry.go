@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -47,6 +48,10 @@ type AvroSchemaManager struct {
 
 	cacheRWLock sync.RWMutex
 	cache       map[string]*schemaCacheEntry
+	// idCache caches the codecs looked up by the registry schema ID
+	idCache map[int]*goavro.Codec
+	// subjectCache caches the subjects looked up by the registry schema ID
+	subjectCache map[int]string
 }
 
 type schemaCacheEntry struct {
@@ -65,6 +70,11 @@ type registerResponse struct {
 	ID int `json:"id"`
 }
 
+type subjectVersion struct {
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
 type lookupResponse struct {
 	Name       string `json:"name"`
 	RegistryID int    `json:"id"`
@@ -107,6 +117,8 @@ func NewAvroSchemaManager(
 	return &AvroSchemaManager{
 		registryURL:   registryURL,
 		cache:         make(map[string]*schemaCacheEntry, 1),
+		idCache:       make(map[int]*goavro.Codec),
+		subjectCache:  make(map[int]string),
 		subjectSuffix: subjectSuffix,
 		credential:    credential,
 	}, nil
@@ -265,6 +277,117 @@ func (m *AvroSchemaManager) Lookup(ctx context.Context, tableName model.TableNam
 	return cacheEntry.codec, cacheEntry.registryID, nil
 }
 
+// LookupByID fetches the schema with the given registry schema ID from the Registry,
+// it is used to decode the messages which carry the registry schema ID only.
+func (m *AvroSchemaManager) LookupByID(ctx context.Context, registryID int) (*goavro.Codec, error) {
+	m.cacheRWLock.RLock()
+	if codec, exists := m.idCache[registryID]; exists {
+		m.cacheRWLock.RUnlock()
+		return codec, nil
+	}
+	m.cacheRWLock.RUnlock()
+
+	uri := m.registryURL + "/schemas/ids/" + strconv.Itoa(registryID)
+	log.Debug("Querying for schema by ID", zap.String("uri", uri))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Error constructing request for Registry lookup")
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json, application/vnd.schemaregistry+json, application/json")
+
+	resp, err := httpRetry(ctx, m.credential, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to read response from Registry")
+	}
+	if resp.StatusCode == 404 {
+		log.Warn("Specified schema not found in Registry", zap.Int("registryID", registryID))
+		return nil, cerror.ErrAvroSchemaAPIError.GenWithStackByArgs("Schema not found in Registry")
+	}
+
+	var jsonResp lookupResponse
+	err = json.Unmarshal(body, &jsonResp)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to parse result from Registry")
+	}
+	codec, err := goavro.NewCodec(jsonResp.Schema)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Creating Avro codec failed")
+	}
+
+	m.cacheRWLock.Lock()
+	m.idCache[registryID] = codec
+	m.cacheRWLock.Unlock()
+
+	log.Info("Avro schema lookup by ID successful",
+		zap.Int("registryID", registryID),
+		zap.String("schema", codec.Schema()))
+	return codec, nil
+}
+
+// LookupSubjectByID fetches the subject which the schema with the given registry
+// schema ID is registered under, it requires the Registry to support the
+// /schemas/ids/{id}/versions API.
+func (m *AvroSchemaManager) LookupSubjectByID(ctx context.Context, registryID int) (string, error) {
+	m.cacheRWLock.RLock()
+	if subject, exists := m.subjectCache[registryID]; exists {
+		m.cacheRWLock.RUnlock()
+		return subject, nil
+	}
+	m.cacheRWLock.RUnlock()
+
+	uri := m.registryURL + "/schemas/ids/" + strconv.Itoa(registryID) + "/versions"
+	log.Debug("Querying for subject by ID", zap.String("uri", uri))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return "", errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Error constructing request for Registry lookup")
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json, application/vnd.schemaregistry+json, application/json")
+
+	resp, err := httpRetry(ctx, m.credential, req, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to read response from Registry")
+	}
+	if resp.StatusCode == 404 {
+		log.Warn("Specified schema not found in Registry", zap.Int("registryID", registryID))
+		return "", cerror.ErrAvroSchemaAPIError.GenWithStackByArgs("Schema not found in Registry")
+	}
+
+	var versions []subjectVersion
+	err = json.Unmarshal(body, &versions)
+	if err != nil {
+		return "", errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to parse result from Registry")
+	}
+	if len(versions) == 0 {
+		return "", cerror.ErrAvroSchemaAPIError.GenWithStackByArgs("Schema is not registered under any subject")
+	}
+
+	m.cacheRWLock.Lock()
+	m.subjectCache[registryID] = versions[0].Subject
+	m.cacheRWLock.Unlock()
+	return versions[0].Subject, nil
+}
+
 // SchemaGenerator represents a function that returns an Avro schema in JSON.
 // Used for lazy evaluation
 type SchemaGenerator func() (string, error)
@@ -402,6 +525,17 @@ func httpRetry(ctx context.Context, credential *security.Credential, r *http.Req
 	return resp, nil
 }
 
+// schemaSubjectToSchemaName returns the schema name in the subject of the table,
+// it is the reverse of tableNameToSchemaSubject, the subject suffix is ignored
+// so that both the key and the value subjects are supported.
+func schemaSubjectToSchemaName(subject string, table string) (string, bool) {
+	idx := strings.LastIndex(subject, "_"+table)
+	if idx < 0 {
+		return "", false
+	}
+	return subject[:idx], true
+}
+
 func (m *AvroSchemaManager) tableNameToSchemaSubject(tableName model.TableName) string {
 	// We should guarantee unique names for subjects
 	return tableName.Schema + "_" + tableName.Table + m.subjectSuffix
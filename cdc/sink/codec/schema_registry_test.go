@@ -123,6 +123,40 @@ func startHTTPInterceptForTestingRegistry(c *check.C) {
 			return httpmock.NewJsonResponse(200, &respData)
 		})
 
+	httpmock.RegisterResponder("GET", `=~^http://127.0.0.1:8081/schemas/ids/(\d+)/versions$`,
+		func(req *http.Request) (*http.Response, error) {
+			id, err := httpmock.GetSubmatchAsInt(req, 1)
+			if err != nil {
+				return httpmock.NewStringResponse(500, "Internal Server Error"), err
+			}
+
+			registry.mu.Lock()
+			defer registry.mu.Unlock()
+			for subject, item := range registry.subjects {
+				if item.ID == int(id) {
+					return httpmock.NewJsonResponse(200, []subjectVersion{{Subject: subject, Version: item.version}})
+				}
+			}
+			return httpmock.NewStringResponse(404, ""), nil
+		})
+
+	httpmock.RegisterResponder("GET", `=~^http://127.0.0.1:8081/schemas/ids/(\d+)$`,
+		func(req *http.Request) (*http.Response, error) {
+			id, err := httpmock.GetSubmatchAsInt(req, 1)
+			if err != nil {
+				return httpmock.NewStringResponse(500, "Internal Server Error"), err
+			}
+
+			registry.mu.Lock()
+			defer registry.mu.Unlock()
+			for _, item := range registry.subjects {
+				if item.ID == int(id) {
+					return httpmock.NewJsonResponse(200, &lookupResponse{Schema: item.content})
+				}
+			}
+			return httpmock.NewStringResponse(404, ""), nil
+		})
+
 	httpmock.RegisterResponder("DELETE", `=~^http://127.0.0.1:8081/subjects/(.+)`,
 		func(req *http.Request) (*http.Response, error) {
 			subject, err := httpmock.GetSubmatch(req, 1)
@@ -241,6 +275,36 @@ func (s *AvroSchemaRegistrySuite) TestSchemaRegistry(c *check.C) {
 	c.Assert(codec.CanonicalSchema(), check.Equals, codec2.CanonicalSchema())
 }
 
+func (s *AvroSchemaRegistrySuite) TestLookupSubjectByID(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := model.TableName{
+		Schema: "test_db",
+		Table:  "test_subject",
+	}
+
+	manager, err := NewAvroSchemaManager(getTestingContext(), &security.Credential{}, "http://127.0.0.1:8081", "-value")
+	c.Assert(err, check.IsNil)
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test_subject", "fields": [{"type": "string", "name": "field1"}]}`)
+	c.Assert(err, check.IsNil)
+	id, err := manager.Register(getTestingContext(), table, codec)
+	c.Assert(err, check.IsNil)
+
+	subject, err := manager.LookupSubjectByID(getTestingContext(), id)
+	c.Assert(err, check.IsNil)
+	c.Assert(subject, check.Equals, "test_db_test_subject-value")
+	schemaName, ok := schemaSubjectToSchemaName(subject, table.Table)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(schemaName, check.Equals, table.Schema)
+	schemaName, ok = schemaSubjectToSchemaName("test_db_test_subject-key", table.Table)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(schemaName, check.Equals, table.Schema)
+	_, ok = schemaSubjectToSchemaName(subject, "other")
+	c.Assert(ok, check.IsFalse)
+
+	_, err = manager.LookupSubjectByID(getTestingContext(), 99999)
+	c.Assert(err, check.ErrorMatches, `.*not\sfound.*`)
+}
+
 func (s *AvroSchemaRegistrySuite) TestSchemaRegistryBad(c *check.C) {
 	defer testleak.AfterTest(c)()
 	_, err := NewAvroSchemaManager(getTestingContext(), &security.Credential{}, "http://127.0.0.1:808", "-value")
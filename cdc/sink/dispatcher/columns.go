@@ -0,0 +1,82 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/hash"
+)
+
+// columnsDispatcher dispatches rows by the values of the configured columns
+type columnsDispatcher struct {
+	partitionNum int32
+	hasher       *hash.PositionInertia
+	columns      []string
+}
+
+func newColumnsDispatcher(partitionNum int32, columns []string) *columnsDispatcher {
+	return &columnsDispatcher{
+		partitionNum: partitionNum,
+		hasher:       hash.NewPositionInertia(),
+		columns:      columns,
+	}
+}
+
+func (d *columnsDispatcher) Dispatch(row *model.RowChangedEvent) (int32, error) {
+	d.hasher.Reset()
+	d.hasher.Write([]byte(row.Table.Schema), []byte(row.Table.Table))
+
+	dispatchCols := row.Columns
+	if len(row.Columns) == 0 {
+		dispatchCols = row.PreColumns
+	}
+	for _, name := range d.columns {
+		col := findColumn(dispatchCols, name)
+		if col == nil {
+			return 0, cerror.ErrDispatcherColumnNotFound.GenWithStackByArgs(name, row.Table.Schema, row.Table.Table)
+		}
+		d.hasher.Write([]byte(name), []byte(model.ColumnValueString(col.Value)))
+	}
+	return int32(d.hasher.Sum32() % uint32(d.partitionNum)), nil
+}
+
+// verifyColumns checks whether all configured columns exist in the table
+func (d *columnsDispatcher) verifyColumns(table *model.SimpleTableInfo) error {
+	for _, name := range d.columns {
+		found := false
+		for _, col := range table.ColumnInfo {
+			// column names are case insensitive in TiDB
+			if col != nil && strings.EqualFold(col.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return cerror.ErrDispatcherColumnNotFound.GenWithStackByArgs(name, table.Schema, table.Table)
+		}
+	}
+	return nil
+}
+
+func findColumn(cols []*model.Column, name string) *model.Column {
+	for _, col := range cols {
+		if col != nil && strings.EqualFold(col.Name, name) {
+			return col
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type ColumnsDispatcherSuite struct{}
+
+var _ = check.Suite(&ColumnsDispatcherSuite{})
+
+func (s ColumnsDispatcherSuite) TestColumnsDispatcher(c *check.C) {
+	defer testleak.AfterTest(c)()
+	newRow := func(id, tenantID interface{}) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			Table: &model.TableName{
+				Schema: "test",
+				Table:  "t1",
+			},
+			Columns: []*model.Column{
+				{Name: "id", Value: id, Flag: model.HandleKeyFlag},
+				{Name: "tenant_id", Value: tenantID},
+			},
+		}
+	}
+	p := newColumnsDispatcher(16, []string{"TENANT_ID"})
+	p1, err := p.Dispatch(newRow(1, 100))
+	c.Assert(err, check.IsNil)
+	// rows of the same tenant are dispatched to the same partition
+	p2, err := p.Dispatch(newRow(2, 100))
+	c.Assert(err, check.IsNil)
+	c.Assert(p1, check.Equals, p2)
+
+	// delete events are dispatched by the pre columns
+	deleteRow := newRow(3, 100)
+	deleteRow.PreColumns, deleteRow.Columns = deleteRow.Columns, nil
+	p3, err := p.Dispatch(deleteRow)
+	c.Assert(err, check.IsNil)
+	c.Assert(p1, check.Equals, p3)
+
+	partitions := make(map[int32]struct{})
+	for i := 0; i < 100; i++ {
+		partition, err := p.Dispatch(newRow(1, i))
+		c.Assert(err, check.IsNil)
+		c.Assert(partition, check.Less, int32(16))
+		partitions[partition] = struct{}{}
+	}
+	c.Assert(len(partitions), check.Greater, 1)
+
+	p = newColumnsDispatcher(16, []string{"tenant_id", "region"})
+	_, err = p.Dispatch(newRow(1, 100))
+	c.Assert(cerror.ErrDispatcherColumnNotFound.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*column region required by the columns dispatcher is not found in table test.t1.*")
+}
+
+func (s ColumnsDispatcherSuite) TestVerifyColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "columns", Columns: []string{"tenant_id"}},
+	}
+	d, err := NewDispatcher(cfg, 4)
+	c.Assert(err, check.IsNil)
+	verifier, ok := d.(ColumnsVerifier)
+	c.Assert(ok, check.IsTrue)
+
+	err = verifier.VerifyColumns(&model.SimpleTableInfo{
+		Schema: "test", Table: "t1",
+		ColumnInfo: []*model.ColumnInfo{{Name: "id"}, {Name: "Tenant_ID"}},
+	})
+	c.Assert(err, check.IsNil)
+	err = verifier.VerifyColumns(&model.SimpleTableInfo{
+		Schema: "test", Table: "t1",
+		ColumnInfo: []*model.ColumnInfo{{Name: "id"}},
+	})
+	c.Assert(cerror.ErrDispatcherColumnNotFound.Equal(err), check.IsTrue)
+	// tables which are not dispatched by columns are not verified
+	err = verifier.VerifyColumns(&model.SimpleTableInfo{
+		Schema: "other", Table: "t1",
+		ColumnInfo: []*model.ColumnInfo{{Name: "id"}},
+	})
+	c.Assert(err, check.IsNil)
+
+	cfg.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "columns"},
+	}
+	_, err = NewDispatcher(cfg, 4)
+	c.Assert(cerror.ErrDispatcherInvalidRule.Equal(err), check.IsTrue)
+}
@@ -33,7 +33,7 @@ func newDefaultDispatcher(partitionNum int32, enableOldValue bool) *defaultDispa
 	}
 }
 
-func (d *defaultDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (d *defaultDispatcher) Dispatch(row *model.RowChangedEvent) (int32, error) {
 	if d.enableOldValue {
 		return d.tbd.Dispatch(row)
 	}
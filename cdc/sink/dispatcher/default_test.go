@@ -197,6 +197,8 @@ func (s DefaultDispatcherSuite) TestDefaultDispatcher(c *check.C) {
 	}
 	p := newDefaultDispatcher(16, false)
 	for _, tc := range testCases {
-		c.Assert(p.Dispatch(tc.row), check.Equals, tc.exceptPartition)
+		partition, err := p.Dispatch(tc.row)
+		c.Assert(err, check.IsNil)
+		c.Assert(partition, check.Equals, tc.exceptPartition)
 	}
 }
@@ -30,7 +30,7 @@ func newIndexValueDispatcher(partitionNum int32) *indexValueDispatcher {
 	}
 }
 
-func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent) (int32, error) {
 	r.hasher.Reset()
 	r.hasher.Write([]byte(row.Table.Schema), []byte(row.Table.Table))
 	// FIXME(leoppro): if the row events includes both pre-cols and cols
@@ -49,5 +49,5 @@ func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
 			r.hasher.Write([]byte(col.Name), []byte(model.ColumnValueString(col.Value)))
 		}
 	}
-	return int32(r.hasher.Sum32() % uint32(r.partitionNum))
+	return int32(r.hasher.Sum32() % uint32(r.partitionNum)), nil
 }
@@ -151,6 +151,8 @@ func (s IndexValueDispatcherSuite) TestIndexValueDispatcher(c *check.C) {
 	}
 	p := newIndexValueDispatcher(16)
 	for _, tc := range testCases {
-		c.Assert(p.Dispatch(tc.row), check.Equals, tc.exceptPartition)
+		partition, err := p.Dispatch(tc.row)
+		c.Assert(err, check.IsNil)
+		c.Assert(partition, check.Equals, tc.exceptPartition)
 	}
 }
@@ -27,7 +27,23 @@ import (
 // Dispatcher is an abstraction for dispatching rows into different partitions
 type Dispatcher interface {
 	// Dispatch returns a index of partitions according to RowChangedEvent
-	Dispatch(row *model.RowChangedEvent) int32
+	Dispatch(row *model.RowChangedEvent) (int32, error)
+}
+
+// ColumnsVerifier verifies whether a table contains the columns required by
+// the dispatchers
+type ColumnsVerifier interface {
+	// VerifyColumns returns an error if any column required by the dispatcher
+	// of the table doesn't exist
+	VerifyColumns(table *model.SimpleTableInfo) error
+}
+
+// TablePartitionDispatcher is implemented by the dispatchers which can tell
+// the partition of the rows of a table without the rows
+type TablePartitionDispatcher interface {
+	// DispatchTable returns the partition all rows of the table are dispatched
+	// to, and false if the rows of the table are spread over the partitions
+	DispatchTable(schema, table string) (int32, bool)
 }
 
 type dispatchRule int
@@ -38,6 +54,7 @@ const (
 	dispatchRuleTS
 	dispatchRuleTable
 	dispatchRuleIndexValue
+	dispatchRuleColumns
 )
 
 func (r *dispatchRule) fromString(rule string) {
@@ -52,6 +69,8 @@ func (r *dispatchRule) fromString(rule string) {
 		*r = dispatchRuleTable
 	case "index-value":
 		*r = dispatchRuleIndexValue
+	case "columns":
+		*r = dispatchRuleColumns
 	default:
 		*r = dispatchRuleDefault
 		log.Warn("can't support dispatch rule, using default rule", zap.String("rule", rule))
@@ -65,13 +84,35 @@ type dispatcherSwitcher struct {
 	}
 }
 
-func (s *dispatcherSwitcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (s *dispatcherSwitcher) Dispatch(row *model.RowChangedEvent) (int32, error) {
 	return s.matchDispatcher(row).Dispatch(row)
 }
 
+// VerifyColumns implements the ColumnsVerifier interface
+func (s *dispatcherSwitcher) VerifyColumns(table *model.SimpleTableInfo) error {
+	d, ok := s.matchTableDispatcher(table.Schema, table.Table).(*columnsDispatcher)
+	if !ok {
+		return nil
+	}
+	return d.verifyColumns(table)
+}
+
+// DispatchTable implements the TablePartitionDispatcher interface
+func (s *dispatcherSwitcher) DispatchTable(schema, table string) (int32, bool) {
+	d, ok := s.matchTableDispatcher(schema, table).(*tableDispatcher)
+	if !ok {
+		return 0, false
+	}
+	return d.dispatchTable(schema, table), true
+}
+
 func (s *dispatcherSwitcher) matchDispatcher(row *model.RowChangedEvent) Dispatcher {
+	return s.matchTableDispatcher(row.Table.Schema, row.Table.Table)
+}
+
+func (s *dispatcherSwitcher) matchTableDispatcher(schema, table string) Dispatcher {
 	for _, rule := range s.rules {
-		if !rule.MatchTable(row.Table.Schema, row.Table.Table) {
+		if !rule.MatchTable(schema, table) {
 			continue
 		}
 		return rule.Dispatcher
@@ -114,6 +155,12 @@ func NewDispatcher(cfg *config.ReplicaConfig, partitionNum int32) (Dispatcher, e
 			d = newTsDispatcher(partitionNum)
 		case dispatchRuleTable:
 			d = newTableDispatcher(partitionNum)
+		case dispatchRuleColumns:
+			if len(ruleConfig.Columns) == 0 {
+				return nil, cerror.ErrDispatcherInvalidRule.GenWithStackByArgs(
+					"columns dispatcher requires at least one column")
+			}
+			d = newColumnsDispatcher(partitionNum, ruleConfig.Columns)
 		case dispatchRuleDefault:
 			d = newDefaultDispatcher(partitionNum, cfg.EnableOldValue)
 		}
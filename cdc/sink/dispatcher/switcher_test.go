@@ -77,3 +77,27 @@ func (s SwitcherSuite) TestSwitcher(c *check.C) {
 		},
 	}), check.FitsTypeOf, &indexValueDispatcher{})
 }
+
+func (s SwitcherSuite) TestDispatchTable(c *check.C) {
+	defer testleak.AfterTest(c)()
+	d, err := NewDispatcher(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test_table.*"}, Dispatcher: "table"},
+			},
+		},
+	}, 16)
+	c.Assert(err, check.IsNil)
+	td := d.(TablePartitionDispatcher)
+
+	row := &model.RowChangedEvent{Table: &model.TableName{Schema: "test_table", Table: "t1"}}
+	expected, err := d.Dispatch(row)
+	c.Assert(err, check.IsNil)
+	partition, ok := td.DispatchTable("test_table", "t1")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(partition, check.Equals, expected)
+
+	// the rows of the tables using the default dispatcher are spread over the partitions
+	_, ok = td.DispatchTable("test", "t1")
+	c.Assert(ok, check.IsFalse)
+}
@@ -30,9 +30,13 @@ func newTableDispatcher(partitionNum int32) *tableDispatcher {
 	}
 }
 
-func (t *tableDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (t *tableDispatcher) Dispatch(row *model.RowChangedEvent) (int32, error) {
+	return t.dispatchTable(row.Table.Schema, row.Table.Table), nil
+}
+
+func (t *tableDispatcher) dispatchTable(schema, table string) int32 {
 	t.hasher.Reset()
 	// distribute partition by table
-	t.hasher.Write([]byte(row.Table.Schema), []byte(row.Table.Table))
+	t.hasher.Write([]byte(schema), []byte(table))
 	return int32(t.hasher.Sum32() % uint32(t.partitionNum))
 }
@@ -0,0 +1,126 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+const (
+	schemaPlaceholder = "{schema}"
+	tablePlaceholder  = "{table}"
+
+	// kafkaTopicNameMaxLength is the max length of a Kafka topic name
+	kafkaTopicNameMaxLength = 249
+)
+
+var (
+	// validTopicExpression matches the expressions which only contain legal
+	// topic characters and placeholders
+	validTopicExpression = regexp.MustCompile(`\A([A-Za-z0-9._-]|\{schema\}|\{table\})+\z`)
+	// invalidTopicChar matches the characters which are not allowed in a topic name
+	invalidTopicChar = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+)
+
+// TopicDispatcher is an abstraction for dispatching events into different topics
+type TopicDispatcher interface {
+	// DispatchTopic returns the topic which the events of the given table
+	// should be sent to. The table can be empty for schema level events, in
+	// this case the returned topic is only valid if it doesn't depend on table.
+	DispatchTopic(schema, table string) string
+}
+
+// topicExpression is a topic name which may contain {schema} and {table} placeholders
+type topicExpression string
+
+func (e topicExpression) validate() error {
+	if !validTopicExpression.MatchString(string(e)) {
+		return cerror.ErrInvalidTopicExpression.GenWithStackByArgs(string(e))
+	}
+	return nil
+}
+
+// hasTablePlaceholder returns true if the topic depends on the table name
+func (e topicExpression) hasTablePlaceholder() bool {
+	return strings.Contains(string(e), tablePlaceholder)
+}
+
+func (e topicExpression) substitute(schema, table string) string {
+	topic := strings.ReplaceAll(string(e), schemaPlaceholder, schema)
+	topic = strings.ReplaceAll(topic, tablePlaceholder, table)
+	// schema and table names may contain characters which are illegal in
+	// topic names, replace them with underscores.
+	topic = invalidTopicChar.ReplaceAllString(topic, "_")
+	if len(topic) > kafkaTopicNameMaxLength {
+		topic = topic[:kafkaTopicNameMaxLength]
+	}
+	return topic
+}
+
+type topicDispatcherSwitcher struct {
+	defaultTopic string
+	rules        []struct {
+		topicExpression
+		filter.Filter
+	}
+}
+
+func (s *topicDispatcherSwitcher) DispatchTopic(schema, table string) string {
+	for _, rule := range s.rules {
+		if !rule.MatchTable(schema, table) {
+			continue
+		}
+		if table == "" && rule.hasTablePlaceholder() {
+			return s.defaultTopic
+		}
+		return rule.substitute(schema, table)
+	}
+	return s.defaultTopic
+}
+
+// NewTopicDispatcher creates a new topic dispatcher, the events of the tables
+// which don't match any topic rule are sent to the default topic.
+func NewTopicDispatcher(cfg *config.ReplicaConfig, defaultTopic string) (TopicDispatcher, error) {
+	rules := make([]struct {
+		topicExpression
+		filter.Filter
+	}, 0, len(cfg.Sink.TopicRules))
+
+	for _, ruleConfig := range cfg.Sink.TopicRules {
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		expression := topicExpression(ruleConfig.Topic)
+		if err := expression.validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, struct {
+			topicExpression
+			filter.Filter
+		}{topicExpression: expression, Filter: f})
+	}
+	return &topicDispatcherSwitcher{
+		defaultTopic: defaultTopic,
+		rules:        rules,
+	}, nil
+}
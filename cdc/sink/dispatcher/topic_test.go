@@ -0,0 +1,84 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type TopicSuite struct{}
+
+var _ = check.Suite(&TopicSuite{})
+
+func (s TopicSuite) TestTopicDispatcher(c *check.C) {
+	defer testleak.AfterTest(c)()
+	d, err := NewTopicDispatcher(config.GetDefaultReplicaConfig(), "default-topic")
+	c.Assert(err, check.IsNil)
+	c.Assert(d.DispatchTopic("test", "t1"), check.Equals, "default-topic")
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.CaseSensitive = false
+	cfg.Sink.TopicRules = []*config.TopicRule{
+		{Matcher: []string{"test1.*"}, Topic: "{schema}_{table}"},
+		{Matcher: []string{"test2.*"}, Topic: "topic-{schema}"},
+		{Matcher: []string{"test3.t1"}, Topic: "fixed.topic"},
+	}
+	d, err = NewTopicDispatcher(cfg, "default-topic")
+	c.Assert(err, check.IsNil)
+	testCases := []struct {
+		schema   string
+		table    string
+		expected string
+	}{
+		{"test1", "t1", "test1_t1"},
+		{"TEST1", "T2", "TEST1_T2"},
+		{"test1", "表", "test1__"},
+		{"test1", "", "default-topic"},
+		{"test2", "t1", "topic-test2"},
+		{"test2", "", "topic-test2"},
+		{"test3", "t1", "fixed.topic"},
+		{"test3", "t2", "default-topic"},
+		{"test4", "t1", "default-topic"},
+	}
+	for _, tc := range testCases {
+		c.Assert(d.DispatchTopic(tc.schema, tc.table), check.Equals, tc.expected)
+	}
+
+	long := strings.Repeat("a", 300)
+	c.Assert(d.DispatchTopic("test1", long), check.HasLen, kafkaTopicNameMaxLength)
+}
+
+func (s TopicSuite) TestInvalidTopicExpression(c *check.C) {
+	defer testleak.AfterTest(c)()
+	for _, expression := range []string{"", "{db}_{table}", "topic?", "{schema}/{table}"} {
+		cfg := config.GetDefaultReplicaConfig()
+		cfg.Sink.TopicRules = []*config.TopicRule{
+			{Matcher: []string{"test.*"}, Topic: expression},
+		}
+		_, err := NewTopicDispatcher(cfg, "default-topic")
+		c.Assert(cerror.ErrInvalidTopicExpression.Equal(err), check.IsTrue)
+	}
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.TopicRules = []*config.TopicRule{
+		{Matcher: []string{"[test.*"}, Topic: "{schema}"},
+	}
+	_, err := NewTopicDispatcher(cfg, "default-topic")
+	c.Assert(err, check.ErrorMatches, ".*CDC:ErrFilterRuleInvalid.*")
+}
@@ -78,6 +78,8 @@ func (s TsDispatcherSuite) TestTsDispatcher(c *check.C) {
 	}
 	p := &tsDispatcher{partitionNum: 16}
 	for _, tc := range testCases {
-		c.Assert(p.Dispatch(tc.row), check.Equals, tc.exceptPartition)
+		partition, err := p.Dispatch(tc.row)
+		c.Assert(err, check.IsNil)
+		c.Assert(partition, check.Equals, tc.exceptPartition)
 	}
 }
@@ -0,0 +1,407 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/security"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHTTPMaxRetries = 8
+	defaultHTTPTimeout    = 10 * time.Second
+)
+
+// Headers of the requests sent by the http sink, the body of a request is the
+// value of the message encoded by the configured protocol.
+const (
+	HTTPHeaderProtocol    = "X-Ticdc-Protocol"
+	HTTPHeaderMessageType = "X-Ticdc-Message-Type"
+	HTTPHeaderMessageKey  = "X-Ticdc-Message-Key"
+	HTTPHeaderSchema      = "X-Ticdc-Schema"
+	HTTPHeaderTable       = "X-Ticdc-Table"
+	HTTPHeaderCommitTs    = "X-Ticdc-Commit-Ts"
+)
+
+var httpMessageTypes = map[model.MqMessageType]string{
+	model.MqMessageTypeRow:      "row",
+	model.MqMessageTypeDDL:      "ddl",
+	model.MqMessageTypeResolved: "resolved",
+}
+
+// httpSinkParams are the parameters of sink uri consumed by the http sink,
+// they are removed from the uri before sending requests.
+var httpSinkParams = []string{
+	"protocol", "max-retries", "timeout", "ca", "cert", "key",
+	"max-message-bytes", "max-batch-size", "debezium-server-name",
+}
+
+// httpSink sends the events to a http endpoint. The row changed events are
+// buffered by table and POSTed when they are resolved, the DDL and checkpoint
+// events are POSTed in separate requests.
+type httpSink struct {
+	endpoint   string
+	client     *http.Client
+	protocol   string
+	newEncoder func() codec.EventBatchEncoder
+	filter     *filter.Filter
+	maxRetries uint64
+
+	rowsMu sync.Mutex
+	rows   map[model.TableName][]*model.RowChangedEvent
+	// pendingMsgs are the messages of a table not acknowledged by the last
+	// failed flush, they are sent first by the next flush, so that the messages
+	// acknowledged already are not sent again
+	pendingMsgs []*codec.MQMessage
+
+	checkpointTs uint64
+	statistics   *Statistics
+}
+
+func newHTTPSink(
+	ctx context.Context, sinkURI *url.URL, filter *filter.Filter, replicaConfig *config.ReplicaConfig, opts map[string]string,
+) (*httpSink, error) {
+	scheme := strings.ToLower(sinkURI.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return nil, cerror.ErrHTTPSinkInvalidConfig.GenWithStack("can't create http sink with unsupported scheme: %s", scheme)
+	}
+	query := sinkURI.Query()
+
+	s := query.Get("protocol")
+	if s != "" {
+		replicaConfig.Sink.Protocol = s
+	}
+	var protocol codec.Protocol
+	protocol.FromString(replicaConfig.Sink.Protocol)
+	switch protocol {
+	case codec.ProtocolAvro:
+		return nil, cerror.ErrHTTPSinkInvalidConfig.GenWithStack("avro protocol is not supported by http sink")
+	case codec.ProtocolCanal, codec.ProtocolCanalJSON:
+		if !replicaConfig.EnableOldValue {
+			return nil, cerror.ErrHTTPSinkInvalidConfig.GenWithStack("canal requires old value to be enabled")
+		}
+	}
+
+	maxRetries := uint64(defaultHTTPMaxRetries)
+	s = query.Get("max-retries")
+	if s != "" {
+		c, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrHTTPSinkInvalidConfig, err)
+		}
+		maxRetries = c
+	}
+
+	timeout := defaultHTTPTimeout
+	s = query.Get("timeout")
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrHTTPSinkInvalidConfig, err)
+		}
+		timeout = d
+	}
+
+	// the parameters of the encoders
+	for _, param := range []string{"max-message-bytes", "max-batch-size", "debezium-server-name"} {
+		s = query.Get(param)
+		if s != "" {
+			opts[param] = s
+		}
+	}
+	newEncoder := codec.NewEventBatchEncoder(protocol)
+	if err := newEncoder().SetParams(opts); err != nil {
+		return nil, cerror.WrapError(cerror.ErrHTTPSinkInvalidConfig, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if scheme == "https" {
+		credential := &security.Credential{
+			CAPath:   query.Get("ca"),
+			CertPath: query.Get("cert"),
+			KeyPath:  query.Get("key"),
+		}
+		if credential.CAPath != "" {
+			tlsConfig, err := credential.ToTLSConfig()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+	}
+
+	endpoint := *sinkURI
+	for _, param := range httpSinkParams {
+		query.Del(param)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	return &httpSink{
+		endpoint: endpoint.String(),
+		client:   &http.Client{Transport: transport, Timeout: timeout},
+		protocol: strings.ToLower(replicaConfig.Sink.Protocol),
+		newEncoder: func() codec.EventBatchEncoder {
+			encoder := newEncoder()
+			if err := encoder.SetParams(opts); err != nil {
+				log.Panic("HTTP Encoder could not parse parameters", zap.Error(err))
+			}
+			return encoder
+		},
+		filter:     filter,
+		maxRetries: maxRetries,
+		rows:       make(map[model.TableName][]*model.RowChangedEvent),
+		statistics: NewStatistics(ctx, "HTTP", opts),
+	}, nil
+}
+
+func (s *httpSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	s.rowsMu.Lock()
+	defer s.rowsMu.Unlock()
+	rowsCount := 0
+	for _, row := range rows {
+		if s.filter.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+			log.Info("Row changed event ignored", zap.Uint64("start-ts", row.StartTs))
+			continue
+		}
+		s.rows[*row.Table] = append(s.rows[*row.Table], row)
+		rowsCount++
+	}
+	s.statistics.AddRowsCount(rowsCount)
+	return nil
+}
+
+// FlushRowChangedEvents POSTs the resolved rows table by table, the rows are
+// acknowledged only if all the requests are responded with 2xx. The messages
+// are delivered at least once: a failed flush doesn't resend the messages
+// acknowledged already, but the sink is recreated from the checkpoint if the
+// changefeed restarts.
+func (s *httpSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	if resolvedTs <= s.checkpointTs {
+		return s.checkpointTs, nil
+	}
+	if err := s.sendMessages(ctx, s.pendingMsgs); err != nil {
+		return 0, errors.Trace(err)
+	}
+	resolved := s.takeResolvedRows(resolvedTs)
+	tables := make([]model.TableName, 0, len(resolved))
+	for table := range resolved {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].String() < tables[j].String()
+	})
+
+	err := s.statistics.RecordBatchExecution(func() (int, error) {
+		rowsCount := 0
+		for i, table := range tables {
+			msgs, err := s.encodeRows(resolved[table], resolvedTs)
+			if err != nil {
+				s.restoreRows(tables[i:], resolved)
+				return 0, errors.Trace(err)
+			}
+			if err := s.sendMessages(ctx, msgs); err != nil {
+				// the unacknowledged messages of the table are kept in
+				// pendingMsgs, and the rows of the unsent tables are kept, so
+				// that they are sent by the next flush
+				s.restoreRows(tables[i+1:], resolved)
+				return 0, errors.Trace(err)
+			}
+			rowsCount += len(resolved[table])
+		}
+		return rowsCount, nil
+	})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	s.checkpointTs = resolvedTs
+	s.statistics.PrintStatus(ctx)
+	return s.checkpointTs, nil
+}
+
+func (s *httpSink) takeResolvedRows(resolvedTs uint64) map[model.TableName][]*model.RowChangedEvent {
+	s.rowsMu.Lock()
+	defer s.rowsMu.Unlock()
+	resolved := make(map[model.TableName][]*model.RowChangedEvent)
+	for table, rows := range s.rows {
+		i := sort.Search(len(rows), func(i int) bool {
+			return rows[i].CommitTs > resolvedTs
+		})
+		if i == 0 {
+			continue
+		}
+		resolved[table] = rows[:i]
+		if i == len(rows) {
+			delete(s.rows, table)
+		} else {
+			s.rows[table] = rows[i:]
+		}
+	}
+	return resolved
+}
+
+func (s *httpSink) restoreRows(tables []model.TableName, resolved map[model.TableName][]*model.RowChangedEvent) {
+	s.rowsMu.Lock()
+	defer s.rowsMu.Unlock()
+	for _, table := range tables {
+		s.rows[table] = append(resolved[table], s.rows[table]...)
+	}
+}
+
+func (s *httpSink) encodeRows(rows []*model.RowChangedEvent, resolvedTs uint64) ([]*codec.MQMessage, error) {
+	encoder := s.newEncoder()
+	for _, row := range rows {
+		if _, err := encoder.AppendRowChangedEvent(row); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	// some encoders only output the rows after they are resolved
+	if _, err := encoder.AppendResolvedEvent(resolvedTs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return encoder.Build(), nil
+}
+
+// sendMessages sends the messages in order, the messages not acknowledged are
+// kept in pendingMsgs if any of them fails.
+func (s *httpSink) sendMessages(ctx context.Context, msgs []*codec.MQMessage) error {
+	for i, msg := range msgs {
+		if err := s.send(ctx, msg); err != nil {
+			s.pendingMsgs = msgs[i:]
+			return errors.Trace(err)
+		}
+	}
+	s.pendingMsgs = nil
+	return nil
+}
+
+func (s *httpSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+	msg, err := s.newEncoder().EncodeCheckpointEvent(ts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg == nil {
+		return nil
+	}
+	return errors.Trace(s.send(ctx, msg))
+}
+
+func (s *httpSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	if s.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+		log.Info(
+			"DDL event ignored",
+			zap.String("query", ddl.Query),
+			zap.Uint64("startTs", ddl.StartTs),
+			zap.Uint64("commitTs", ddl.CommitTs),
+		)
+		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
+	}
+	msg, err := s.newEncoder().EncodeDDLEvent(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg == nil {
+		return nil
+	}
+	log.Debug("emit ddl event", zap.String("query", ddl.Query), zap.Uint64("commit-ts", ddl.CommitTs))
+	return errors.Trace(s.send(ctx, msg))
+}
+
+// send POSTs the message to the endpoint, retrying until a 2xx response is
+// received or the retry limit is reached.
+func (s *httpSink) send(ctx context.Context, msg *codec.MQMessage) error {
+	// backoff treats zero max retries as unlimited
+	if s.maxRetries == 0 {
+		return s.doSend(ctx, msg)
+	}
+	return retry.Run(500*time.Millisecond, s.maxRetries, func() error {
+		err := s.doSend(ctx, msg)
+		// the error of a canceled request is a *url.Error, which is not
+		// context.Canceled, so the context is checked instead
+		if err != nil && ctx.Err() != nil {
+			return backoff.Permanent(errors.Trace(ctx.Err()))
+		}
+		if err != nil {
+			log.Warn("send http request failed, retry later", zap.String("endpoint", s.endpoint), zap.Error(err))
+		}
+		return err
+	})
+}
+
+func (s *httpSink) doSend(ctx context.Context, msg *codec.MQMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(msg.Value))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrHTTPSinkSendRequest, err)
+	}
+	if msg.Protocol == codec.ProtocolCanal {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(HTTPHeaderProtocol, s.protocol)
+	req.Header.Set(HTTPHeaderMessageType, httpMessageTypes[msg.Type])
+	req.Header.Set(HTTPHeaderCommitTs, strconv.FormatUint(msg.Ts, 10))
+	if len(msg.Key) != 0 {
+		req.Header.Set(HTTPHeaderMessageKey, base64.StdEncoding.EncodeToString(msg.Key))
+	}
+	if msg.Schema != nil {
+		req.Header.Set(HTTPHeaderSchema, *msg.Schema)
+	}
+	if msg.Table != nil {
+		req.Header.Set(HTTPHeaderTable, *msg.Table)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrHTTPSinkSendRequest, err)
+	}
+	defer resp.Body.Close()
+	// drain the body so that the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return cerror.ErrHTTPSinkSendRequest.GenWithStack("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Initialize is no-op for http sink
+func (s *httpSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type httpSinkSuite struct{}
+
+var _ = check.Suite(&httpSinkSuite{})
+
+type httpRequest struct {
+	header http.Header
+	query  url.Values
+	body   []byte
+}
+
+// mockHTTPServer records the requests, and responds with 500 to the first
+// `failures` requests.
+type mockHTTPServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*httpRequest
+	failures int
+}
+
+func newMockHTTPServer(c *check.C) *mockHTTPServer {
+	s := &mockHTTPServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, http.MethodPost)
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.requests = append(s.requests, &httpRequest{header: r.Header, query: r.URL.Query(), body: body})
+	}))
+	return s
+}
+
+func (s *mockHTTPServer) setFailures(failures int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = failures
+}
+
+func (s *mockHTTPServer) takeRequests() []*httpRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func decodeHTTPRequest(c *check.C, req *httpRequest) codec.EventBatchDecoder {
+	key, err := base64.StdEncoding.DecodeString(req.header.Get(HTTPHeaderMessageKey))
+	c.Assert(err, check.IsNil)
+	decoder, err := codec.NewJSONEventBatchDecoder(key, req.body)
+	c.Assert(err, check.IsNil)
+	return decoder
+}
+
+func newHTTPSinkForTest(c *check.C, ctx context.Context, uri string) *httpSink {
+	sinkURI, err := url.Parse(uri)
+	c.Assert(err, check.IsNil)
+	replicaConfig := config.GetDefaultReplicaConfig()
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	sink, err := newHTTPSink(ctx, sinkURI, fr, replicaConfig, map[string]string{})
+	c.Assert(err, check.IsNil)
+	return sink
+}
+
+func newHTTPTestRow(schema, table string, commitTs uint64) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		CommitTs: commitTs,
+		Table:    &model.TableName{Schema: schema, Table: table},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(commitTs)},
+		},
+	}
+}
+
+func (s httpSinkSuite) TestFlushRowChangedEvents(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := newMockHTTPServer(c)
+	defer server.Close()
+
+	sink := newHTTPSinkForTest(c, ctx, server.URL+"/events?token=abc&protocol=default&max-retries=0")
+	defer sink.Close()
+
+	err := sink.EmitRowChangedEvents(ctx,
+		newHTTPTestRow("test", "t1", 100),
+		newHTTPTestRow("test", "t1", 110),
+		newHTTPTestRow("test", "t2", 105),
+		newHTTPTestRow("test", "t1", 120),
+	)
+	c.Assert(err, check.IsNil)
+
+	checkpointTs, err := sink.FlushRowChangedEvents(ctx, 110)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(110))
+
+	// the rows are sent table by table
+	requests := server.takeRequests()
+	c.Assert(requests, check.HasLen, 2)
+	expected := []struct {
+		table     string
+		commitTss []uint64
+	}{
+		{"t1", []uint64{100, 110}},
+		{"t2", []uint64{105}},
+	}
+	for i, req := range requests {
+		// the parameters of the sink are not sent to the endpoint
+		c.Assert(req.query, check.DeepEquals, url.Values{"token": []string{"abc"}})
+		c.Assert(req.header.Get(HTTPHeaderProtocol), check.Equals, "default")
+		c.Assert(req.header.Get(HTTPHeaderSchema), check.Equals, "test")
+		c.Assert(req.header.Get(HTTPHeaderTable), check.Equals, expected[i].table)
+
+		decoder := decodeHTTPRequest(c, req)
+		var commitTss []uint64
+		for {
+			tp, hasNext, err := decoder.HasNext()
+			c.Assert(err, check.IsNil)
+			if !hasNext {
+				break
+			}
+			if tp == model.MqMessageTypeResolved {
+				ts, err := decoder.NextResolvedEvent()
+				c.Assert(err, check.IsNil)
+				c.Assert(ts, check.Equals, uint64(110))
+				continue
+			}
+			row, err := decoder.NextRowChangedEvent()
+			c.Assert(err, check.IsNil)
+			c.Assert(row.Table.Table, check.Equals, expected[i].table)
+			commitTss = append(commitTss, row.CommitTs)
+		}
+		c.Assert(commitTss, check.DeepEquals, expected[i].commitTss)
+	}
+
+	// a flush is acknowledged only after the endpoint responds with 2xx
+	server.setFailures(100)
+	_, err = sink.FlushRowChangedEvents(ctx, 120)
+	c.Assert(err, check.ErrorMatches, ".*unexpected status 500.*")
+	c.Assert(server.takeRequests(), check.HasLen, 0)
+	server.setFailures(0)
+
+	checkpointTs, err = sink.FlushRowChangedEvents(ctx, 120)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(120))
+	requests = server.takeRequests()
+	c.Assert(requests, check.HasLen, 1)
+	c.Assert(requests[0].header.Get(HTTPHeaderTable), check.Equals, "t1")
+
+	// nothing to send
+	checkpointTs, err = sink.FlushRowChangedEvents(ctx, 130)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(130))
+	c.Assert(server.takeRequests(), check.HasLen, 0)
+}
+
+func (s httpSinkSuite) TestRetry(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := newMockHTTPServer(c)
+	defer server.Close()
+
+	sink := newHTTPSinkForTest(c, ctx, server.URL+"?max-retries=2")
+	defer sink.Close()
+
+	server.setFailures(2)
+	err := sink.EmitRowChangedEvents(ctx, newHTTPTestRow("test", "t1", 100))
+	c.Assert(err, check.IsNil)
+	checkpointTs, err := sink.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(100))
+	c.Assert(server.takeRequests(), check.HasLen, 1)
+}
+
+func (s httpSinkSuite) TestFlushNotResendAcknowledgedMessages(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the server rejects the requests of table t2 until it's recovered
+	var (
+		mu        sync.Mutex
+		recovered bool
+		tables    []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		table := r.Header.Get(HTTPHeaderTable)
+		if table == "t2" && !recovered {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		tables = append(tables, table)
+	}))
+	defer server.Close()
+
+	sink := newHTTPSinkForTest(c, ctx, server.URL+"?max-retries=0")
+	defer sink.Close()
+
+	err := sink.EmitRowChangedEvents(ctx,
+		newHTTPTestRow("test", "t1", 100),
+		newHTTPTestRow("test", "t2", 100),
+		newHTTPTestRow("test", "t3", 100),
+	)
+	c.Assert(err, check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.ErrorMatches, ".*unexpected status 500.*")
+
+	mu.Lock()
+	recovered = true
+	mu.Unlock()
+	checkpointTs, err := sink.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(100))
+	// t1 is acknowledged by the first flush, so it's not sent again
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(tables, check.DeepEquals, []string{"t1", "t2", "t3"})
+}
+
+func (s httpSinkSuite) TestSendCanceled(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the server hangs until the request is canceled or the test ends
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	sink := newHTTPSinkForTest(c, ctx, server.URL+"?max-retries=10")
+	defer sink.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sink.EmitCheckpointTs(ctx, 100)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	// the canceled request is not retried
+	select {
+	case err := <-errCh:
+		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+	case <-time.After(time.Second):
+		c.Fatal("the canceled request is retried")
+	}
+}
+
+func (s httpSinkSuite) TestEmitDDLAndCheckpoint(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := newMockHTTPServer(c)
+	defer server.Close()
+
+	sink := newHTTPSinkForTest(c, ctx, server.URL)
+	defer sink.Close()
+
+	ddl := &model.DDLEvent{
+		CommitTs:  100,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+		Type:      timodel.ActionCreateTable,
+	}
+	c.Assert(sink.EmitDDLEvent(ctx, ddl), check.IsNil)
+	c.Assert(sink.EmitCheckpointTs(ctx, 110), check.IsNil)
+
+	requests := server.takeRequests()
+	c.Assert(requests, check.HasLen, 2)
+
+	c.Assert(requests[0].header.Get(HTTPHeaderMessageType), check.Equals, "ddl")
+	c.Assert(requests[0].header.Get(HTTPHeaderCommitTs), check.Equals, "100")
+	decoder := decodeHTTPRequest(c, requests[0])
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decoded, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.Query, check.Equals, ddl.Query)
+
+	c.Assert(requests[1].header.Get(HTTPHeaderMessageType), check.Equals, "resolved")
+	decoder = decodeHTTPRequest(c, requests[1])
+	tp, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+	ts, err := decoder.NextResolvedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(110))
+}
+
+func (s httpSinkSuite) TestInvalidConfig(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replicaConfig := config.GetDefaultReplicaConfig()
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+
+	for _, uri := range []string{
+		"http://127.0.0.1:8080?protocol=avro",
+		"http://127.0.0.1:8080?max-retries=abc",
+		"http://127.0.0.1:8080?timeout=abc",
+	} {
+		sinkURI, err := url.Parse(uri)
+		c.Assert(err, check.IsNil)
+		_, err = newHTTPSink(ctx, sinkURI, fr, config.GetDefaultReplicaConfig(), map[string]string{})
+		c.Assert(err, check.ErrorMatches, ".*ErrHTTPSinkInvalidConfig.*")
+	}
+}
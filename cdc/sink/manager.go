@@ -39,25 +39,34 @@ const (
 
 // Manager manages table sinks, maintains the relationship between table sinks and backendSink
 type Manager struct {
-	backendSink  Sink
-	checkpointTs model.Ts
-	tableSinks   map[model.TableID]*tableSink
-	tableSinksMu sync.Mutex
+	backendSink       Sink
+	deadLetterCounter DeadLetterCounter
+	checkpointLoader  TableCheckpointLoader
+	checkpointTs      model.Ts
+	tableSinks        map[model.TableID]*tableSink
+	tableSinksMu      sync.Mutex
 
 	flushMu sync.Mutex
 }
 
 // NewManager creates a new Sink manager
 func NewManager(ctx context.Context, backendSink Sink, errCh chan error, checkpointTs model.Ts) *Manager {
+	deadLetterCounter, _ := backendSink.(DeadLetterCounter)
+	checkpointLoader, _ := backendSink.(TableCheckpointLoader)
 	return &Manager{
-		backendSink:  newBufferSink(ctx, backendSink, errCh, checkpointTs),
-		checkpointTs: checkpointTs,
-		tableSinks:   make(map[model.TableID]*tableSink),
+		backendSink:       newBufferSink(ctx, backendSink, errCh, checkpointTs),
+		deadLetterCounter: deadLetterCounter,
+		checkpointLoader:  checkpointLoader,
+		checkpointTs:      checkpointTs,
+		tableSinks:        make(map[model.TableID]*tableSink),
 	}
 }
 
-// CreateTableSink creates a table sink
-func (m *Manager) CreateTableSink(tableID model.TableID, checkpointTs model.Ts) Sink {
+// CreateTableSink creates a table sink, the rows written to the downstream
+// before are skipped if the backend Sink records the checkpoints of the tables.
+// The checkpoints are recorded by the physical tables, so they are not loaded
+// for the sub tables, whose rows are all written again.
+func (m *Manager) CreateTableSink(ctx context.Context, tableID model.TableID, checkpointTs model.Ts) (Sink, error) {
 	if _, exist := m.tableSinks[tableID]; exist {
 		log.Panic("the table sink already exists", zap.Uint64("tableID", uint64(tableID)))
 	}
@@ -67,10 +76,30 @@ func (m *Manager) CreateTableSink(tableID model.TableID, checkpointTs model.Ts)
 		buffer:    make([]*model.RowChangedEvent, 0, 128),
 		emittedTs: checkpointTs,
 	}
+	if m.checkpointLoader != nil && !model.IsSubTableID(tableID) {
+		appliedTs, err := m.checkpointLoader.LoadTableCheckpoint(ctx, tableID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if appliedTs > checkpointTs {
+			log.Info("the rows written to the downstream before will be skipped",
+				zap.Int64("tableID", tableID), zap.Uint64("checkpointTs", checkpointTs), zap.Uint64("appliedTs", appliedTs))
+			sink.appliedTs = appliedTs
+		}
+	}
 	m.tableSinksMu.Lock()
 	defer m.tableSinksMu.Unlock()
 	m.tableSinks[tableID] = sink
-	return sink
+	return sink, nil
+}
+
+// DeadLetterCount returns the number of the rows written to the dead letter by
+// the backend Sink
+func (m *Manager) DeadLetterCount() uint64 {
+	if m.deadLetterCounter == nil {
+		return 0
+	}
+	return m.deadLetterCounter.DeadLetterCount()
 }
 
 // Close closes the Sink manager and backend Sink
@@ -122,6 +151,10 @@ type tableSink struct {
 	buffer  []*model.RowChangedEvent
 	// emittedTs means all of events which of commitTs less than or equal to emittedTs is sent to backendSink
 	emittedTs model.Ts
+	// appliedTs is the commit ts of the last transaction written to the
+	// downstream before the table sink is created, the rows of the
+	// transactions not after it are skipped
+	appliedTs model.Ts
 }
 
 func (t *tableSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
@@ -149,10 +182,21 @@ func (t *tableSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64
 	}
 	resolvedRows := t.buffer[:i]
 	t.buffer = append(make([]*model.RowChangedEvent, 0, len(t.buffer[i:])), t.buffer[i:]...)
+	if t.appliedTs != 0 {
+		j := sort.Search(len(resolvedRows), func(j int) bool {
+			return resolvedRows[j].CommitTs > t.appliedTs
+		})
+		resolvedRows = resolvedRows[j:]
+		if len(resolvedRows) != 0 {
+			t.appliedTs = 0
+		}
+	}
 
-	err := t.manager.backendSink.EmitRowChangedEvents(ctx, resolvedRows...)
-	if err != nil {
-		return t.manager.getCheckpointTs(), errors.Trace(err)
+	if len(resolvedRows) != 0 {
+		err := t.manager.backendSink.EmitRowChangedEvents(ctx, resolvedRows...)
+		if err != nil {
+			return t.manager.getCheckpointTs(), errors.Trace(err)
+		}
 	}
 	atomic.StoreUint64(&t.emittedTs, resolvedTs)
 	return t.manager.flushBackendSink(ctx)
@@ -98,7 +98,9 @@ func (s *managerSuite) TestManagerRandom(c *check.C) {
 	var wg sync.WaitGroup
 	tableSinks := make([]Sink, goroutineNum)
 	for i := 0; i < goroutineNum; i++ {
-		tableSinks[i] = manager.CreateTableSink(model.TableID(i), 0)
+		tableSink, err := manager.CreateTableSink(ctx, model.TableID(i), 0)
+		c.Assert(err, check.IsNil)
+		tableSinks[i] = tableSink
 	}
 	for i := 0; i < goroutineNum; i++ {
 		i := i
@@ -187,7 +189,8 @@ func (s *managerSuite) TestManagerAddRemoveTable(c *check.C) {
 		for i := 0; i < 200; i++ {
 			if i%4 != 3 {
 				// add table
-				table := manager.CreateTableSink(model.TableID(i), maxResolvedTs)
+				table, err := manager.CreateTableSink(ctx, model.TableID(i), maxResolvedTs)
+				c.Assert(err, check.IsNil)
 				close := make(chan struct{})
 				tableSinks = append(tableSinks, table)
 				closeChs = append(closeChs, close)
@@ -251,8 +254,9 @@ func (s *managerSuite) TestManagerError(c *check.C) {
 	errCh := make(chan error, 16)
 	manager := NewManager(ctx, &errorSink{C: c}, errCh, 0)
 	defer manager.Close()
-	sink := manager.CreateTableSink(1, 0)
-	err := sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
+	sink, err := manager.CreateTableSink(ctx, 1, 0)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
 		CommitTs: 1,
 	})
 	c.Assert(err, check.IsNil)
@@ -261,3 +265,64 @@ func (s *managerSuite) TestManagerError(c *check.C) {
 	err = <-errCh
 	c.Assert(err.Error(), check.Equals, "error in emit row changed events")
 }
+
+// recordSink records all rows and the checkpoints of the tables
+type recordSink struct {
+	checkSink
+	checkpoints map[model.TableID]uint64
+}
+
+func (r *recordSink) LoadTableCheckpoint(ctx context.Context, tableID model.TableID) (uint64, error) {
+	return r.checkpoints[tableID], nil
+}
+
+func (r *recordSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	return resolvedTs, nil
+}
+
+func (s *managerSuite) TestManagerSkipAppliedRows(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 16)
+	backendSink := &recordSink{
+		checkSink:   checkSink{C: c},
+		checkpoints: map[model.TableID]uint64{1: 5, 2: 1},
+	}
+	manager := NewManager(ctx, backendSink, errCh, 0)
+	defer manager.Close()
+
+	// the table 1 has been written to the downstream until 5, but the table 2
+	// is behind the checkpoint
+	sink1, err := manager.CreateTableSink(ctx, 1, 2)
+	c.Assert(err, check.IsNil)
+	sink2, err := manager.CreateTableSink(ctx, 2, 2)
+	c.Assert(err, check.IsNil)
+	for _, sink := range []Sink{sink1, sink2} {
+		for _, commitTs := range []uint64{3, 5, 6} {
+			err := sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{CommitTs: commitTs})
+			c.Assert(err, check.IsNil)
+		}
+	}
+	_, err = sink1.FlushRowChangedEvents(ctx, 4)
+	c.Assert(err, check.IsNil)
+	_, err = sink1.FlushRowChangedEvents(ctx, 7)
+	c.Assert(err, check.IsNil)
+	_, err = sink2.FlushRowChangedEvents(ctx, 7)
+	c.Assert(err, check.IsNil)
+
+	var commitTs []uint64
+	for i := 0; i < 100; i++ {
+		backendSink.rowsMu.Lock()
+		commitTs = commitTs[:0]
+		for _, row := range backendSink.rows {
+			commitTs = append(commitTs, row.CommitTs)
+		}
+		backendSink.rowsMu.Unlock()
+		if len(commitTs) == 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(commitTs, check.DeepEquals, []uint64{6, 3, 5, 6})
+}
@@ -16,13 +16,16 @@ package sink
 import (
 	"context"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/sink/codec"
 	"github.com/pingcap/ticdc/cdc/sink/dispatcher"
@@ -40,11 +43,27 @@ import (
 )
 
 type mqSink struct {
-	mqProducer producer.Producer
-	dispatcher dispatcher.Dispatcher
-	newEncoder func() codec.EventBatchEncoder
-	filter     *filter.Filter
-	protocol   codec.Protocol
+	mqProducer      producer.Producer
+	dispatcher      dispatcher.Dispatcher
+	topicDispatcher dispatcher.TopicDispatcher
+	newEncoder      func() codec.EventBatchEncoder
+	filter          *filter.Filter
+	protocol        codec.Protocol
+
+	// activeTopics records all topics the events have been routed to, the
+	// checkpoint and resolved events are broadcast to all of them.
+	activeTopicsLock sync.RWMutex
+	activeTopics     map[string]struct{}
+
+	// txnProducer is set if the messages are sent in transactions. The rows
+	// are held in pendingRows until they are resolved, so a transaction never
+	// contains the rows after the ts committed with it. tableTs records the
+	// commit ts of the last rows of the tables sent in the ongoing transaction.
+	txnProducer    producer.TransactionalProducer
+	pendingRows    []*model.RowChangedEvent
+	tableTs        map[model.TableID]uint64
+	ddlCommittedTs uint64
+	ddlLoaded      bool
 
 	partitionNum   int32
 	partitionInput []chan struct {
@@ -60,7 +79,7 @@ type mqSink struct {
 }
 
 func newMqSink(
-	ctx context.Context, credential *security.Credential, mqProducer producer.Producer,
+	ctx context.Context, credential *security.Credential, mqProducer producer.Producer, defaultTopic string,
 	filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error,
 ) (*mqSink, error) {
 	partitionNum := mqProducer.GetPartitionNum()
@@ -78,6 +97,10 @@ func newMqSink(
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	topicDispatcher, err := dispatcher.NewTopicDispatcher(config, defaultTopic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	notifier := new(notify.Notifier)
 	var protocol codec.Protocol
 	protocol.FromString(config.Sink.Protocol)
@@ -132,12 +155,17 @@ func newMqSink(
 	if err != nil {
 		return nil, err
 	}
+	txnProducer, _ := mqProducer.(producer.TransactionalProducer)
 	k := &mqSink{
-		mqProducer: mqProducer,
-		dispatcher: d,
-		newEncoder: newEncoder,
-		filter:     filter,
-		protocol:   protocol,
+		mqProducer:      mqProducer,
+		txnProducer:     txnProducer,
+		tableTs:         make(map[model.TableID]uint64),
+		dispatcher:      d,
+		topicDispatcher: topicDispatcher,
+		newEncoder:      newEncoder,
+		filter:          filter,
+		protocol:        protocol,
+		activeTopics:    map[string]struct{}{defaultTopic: {}},
 
 		partitionNum:        partitionNum,
 		partitionInput:      partitionInput,
@@ -169,26 +197,64 @@ func (k *mqSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowCha
 			log.Info("Row changed event ignored", zap.Uint64("start-ts", row.StartTs))
 			continue
 		}
-		partition := k.dispatcher.Dispatch(row)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case k.partitionInput[partition] <- struct {
-			row        *model.RowChangedEvent
-			resolvedTs uint64
-		}{row: row}:
-		}
+		k.addActiveTopic(k.dispatchTopic(row.Table.Schema, row.Table.Table))
 		rowsCount++
+		if k.txnProducer != nil {
+			k.pendingRows = append(k.pendingRows, row)
+			continue
+		}
+		if err := k.dispatchRow(ctx, row); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	k.statistics.AddRowsCount(rowsCount)
 	return nil
 }
 
+func (k *mqSink) dispatchRow(ctx context.Context, row *model.RowChangedEvent) error {
+	partition, err := k.dispatcher.Dispatch(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case k.partitionInput[partition] <- struct {
+		row        *model.RowChangedEvent
+		resolvedTs uint64
+	}{row: row}:
+	}
+	return nil
+}
+
+// dispatchResolvedRows dispatches the pending rows whose commit ts are not
+// greater than the resolved ts
+func (k *mqSink) dispatchResolvedRows(ctx context.Context, resolvedTs uint64) error {
+	var unresolved []*model.RowChangedEvent
+	for _, row := range k.pendingRows {
+		if row.CommitTs > resolvedTs {
+			unresolved = append(unresolved, row)
+			continue
+		}
+		if err := k.dispatchRow(ctx, row); err != nil {
+			return errors.Trace(err)
+		}
+		k.tableTs[row.Table.TableID] = row.CommitTs
+	}
+	k.pendingRows = unresolved
+	return nil
+}
+
 func (k *mqSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
 	if resolvedTs <= k.checkpointTs {
 		return k.checkpointTs, nil
 	}
 
+	if k.txnProducer != nil {
+		if err := k.dispatchResolvedRows(ctx, resolvedTs); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
 	for i := 0; i < int(k.partitionNum); i++ {
 		select {
 		case <-ctx.Done():
@@ -219,6 +285,10 @@ flushLoop:
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
+	err = k.commitTransaction(ctx, resolvedTs)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
 	k.checkpointTs = resolvedTs
 	k.statistics.PrintStatus(ctx)
 	return k.checkpointTs, nil
@@ -233,8 +303,63 @@ func (k *mqSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	if msg == nil {
 		return nil
 	}
-	err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, -1)
-	return errors.Trace(err)
+	for _, topic := range k.getActiveTopics() {
+		err = k.writeToProducer(ctx, topic, msg, codec.EncoderNeedSyncWrite, -1)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return k.commitTransaction(ctx, 0)
+}
+
+// EmitSyncpoint broadcasts a syncpoint event to all partitions, the consumers
+// get a consistent snapshot of the upstream once they receive the event from
+// all partitions.
+func (k *mqSink) EmitSyncpoint(ctx context.Context, ts uint64) error {
+	encoder := k.newEncoder()
+	msg, err := encoder.EncodeSyncpointEvent(ts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg == nil {
+		log.Warn("syncpoint event is not supported by the protocol, ignore it",
+			zap.Uint64("ts", ts), zap.Int("protocol", int(k.protocol)))
+		return nil
+	}
+	for _, topic := range k.getActiveTopics() {
+		err = k.writeToProducer(ctx, topic, msg, codec.EncoderNeedSyncWrite, -1)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return k.commitTransaction(ctx, 0)
+}
+
+// commitTransaction commits the ongoing transaction if the producer is
+// transactional, the messages sent before are visible to the read_committed
+// consumers after that. The ts is committed with the transaction if it's not
+// zero, so are the commit ts of the last rows of the tables, the messages
+// before them are skipped after the sink or the tables are restarted.
+func (k *mqSink) commitTransaction(ctx context.Context, ts uint64) error {
+	if k.txnProducer == nil {
+		return nil
+	}
+	if err := k.txnProducer.CommitTransaction(ctx, ts, k.tableTs); err != nil {
+		return errors.Trace(err)
+	}
+	k.tableTs = make(map[model.TableID]uint64)
+	return nil
+}
+
+// LoadTableCheckpoint implements the TableCheckpointLoader interface, it
+// returns the commit ts of the last rows of the table committed by the
+// transactions of the changefeed if the messages are sent in transactions.
+func (k *mqSink) LoadTableCheckpoint(ctx context.Context, tableID model.TableID) (uint64, error) {
+	if k.txnProducer == nil {
+		return 0, nil
+	}
+	ts, err := k.txnProducer.CommittedTs(ctx, tableID)
+	return ts, errors.Trace(err)
 }
 
 func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
@@ -247,6 +372,26 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		)
 		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
 	}
+	// the columns required by the dispatcher may be dropped by the DDL
+	if ddl.TableInfo.Table != "" && ddl.Type != timodel.ActionDropTable {
+		if err := k.verifyColumns(ddl.TableInfo); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if k.txnProducer != nil {
+		if !k.ddlLoaded {
+			ts, err := k.txnProducer.CommittedTs(ctx, producer.NoTableID)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			k.ddlCommittedTs, k.ddlLoaded = ts, true
+		}
+		if ddl.CommitTs <= k.ddlCommittedTs {
+			log.Info("skip the DDL event committed before",
+				zap.String("query", ddl.Query), zap.Uint64("commitTs", ddl.CommitTs))
+			return nil
+		}
+	}
 	encoder := k.newEncoder()
 	msg, err := encoder.EncodeDDLEvent(ddl)
 	if err != nil {
@@ -257,16 +402,99 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		return nil
 	}
 	log.Debug("emit ddl event", zap.String("query", ddl.Query), zap.Uint64("commit-ts", ddl.CommitTs))
-	err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, -1)
-	return errors.Trace(err)
+	// A schema level DDL, such as CREATE DATABASE, is sent to all topics,
+	// a table level DDL is only sent to the topic of the affected table.
+	// If all rows of the table are dispatched to one partition, the DDL is
+	// only sent to the partition, otherwise it's broadcast to all partitions.
+	// The DDL is emitted after all rows before it are flushed, so it's always
+	// behind these rows in the partitions. The DDLs affecting more than one
+	// table, such as RENAME TABLE, are always broadcast, because the rows of
+	// the other tables may be dispatched to other partitions.
+	topics := k.getActiveTopics()
+	partition := int32(-1)
+	if ddl.TableInfo.Table != "" {
+		topic := k.dispatchTopic(ddl.TableInfo.Schema, ddl.TableInfo.Table)
+		k.addActiveTopic(topic)
+		topics = []string{topic}
+		if td, ok := k.dispatcher.(dispatcher.TablePartitionDispatcher); ok && !isMultiTableDDL(ddl.Type) {
+			if p, ok := td.DispatchTable(ddl.TableInfo.Schema, ddl.TableInfo.Table); ok {
+				partition = p
+			}
+		}
+	}
+	for _, topic := range topics {
+		err = k.writeToProducer(ctx, topic, msg, codec.EncoderNeedSyncWrite, partition)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := k.commitTransaction(ctx, ddl.CommitTs); err != nil {
+		return errors.Trace(err)
+	}
+	k.ddlCommittedTs = ddl.CommitTs
+	return nil
 }
 
-// Initialize registers Avro schemas for all tables
+// isMultiTableDDL returns whether the DDL affects more than one table
+func isMultiTableDDL(tp timodel.ActionType) bool {
+	switch tp {
+	case timodel.ActionRenameTable, timodel.ActionRenameTables, timodel.ActionExchangeTablePartition:
+		return true
+	}
+	return false
+}
+
+// Initialize verifies the columns required by the dispatchers and registers
+// the topics of all tables, so that the checkpoint events are broadcast to
+// them even if no event of the tables has been routed.
 func (k *mqSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
-	// No longer need it for now
+	for _, table := range tableInfo {
+		if table == nil {
+			continue
+		}
+		if err := k.verifyColumns(table); err != nil {
+			return errors.Trace(err)
+		}
+		k.addActiveTopic(k.dispatchTopic(table.Schema, table.Table))
+	}
 	return nil
 }
 
+func (k *mqSink) verifyColumns(table *model.SimpleTableInfo) error {
+	verifier, ok := k.dispatcher.(dispatcher.ColumnsVerifier)
+	if !ok {
+		return nil
+	}
+	return verifier.VerifyColumns(table)
+}
+
+func (k *mqSink) dispatchTopic(schema, table string) string {
+	return k.topicDispatcher.DispatchTopic(schema, table)
+}
+
+func (k *mqSink) addActiveTopic(topic string) {
+	k.activeTopicsLock.RLock()
+	_, ok := k.activeTopics[topic]
+	k.activeTopicsLock.RUnlock()
+	if ok {
+		return
+	}
+	k.activeTopicsLock.Lock()
+	defer k.activeTopicsLock.Unlock()
+	k.activeTopics[topic] = struct{}{}
+}
+
+func (k *mqSink) getActiveTopics() []string {
+	k.activeTopicsLock.RLock()
+	defer k.activeTopicsLock.RUnlock()
+	topics := make([]string, 0, len(k.activeTopics))
+	for topic := range k.activeTopics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
 func (k *mqSink) Close() error {
 	err := k.mqProducer.Close()
 	return errors.Trace(err)
@@ -288,20 +516,30 @@ const batchSizeLimit = 4 * 1024 * 1024 // 4MB
 
 func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 	input := k.partitionInput[partition]
-	encoder := k.newEncoder()
+	// every topic has its own encoder since a batch of messages can only be
+	// sent to one topic
+	encoders := make(map[string]codec.EventBatchEncoder)
+	getEncoder := func(topic string) codec.EventBatchEncoder {
+		encoder, ok := encoders[topic]
+		if !ok {
+			encoder = k.newEncoder()
+			encoders[topic] = encoder
+		}
+		return encoder
+	}
 	tick := time.NewTicker(500 * time.Millisecond)
 	defer tick.Stop()
 
-	flushToProducer := func(op codec.EncoderResult) error {
+	flushToProducer := func(topic string, op codec.EncoderResult) error {
 		return k.statistics.RecordBatchExecution(func() (int, error) {
-			messages := encoder.Build()
+			messages := getEncoder(topic).Build()
 			thisBatchSize := len(messages)
 			if thisBatchSize == 0 {
 				return 0, nil
 			}
 
 			for _, msg := range messages {
-				err := k.writeToProducer(ctx, msg, codec.EncoderNeedAsyncWrite, partition)
+				err := k.writeToProducer(ctx, topic, msg, codec.EncoderNeedAsyncWrite, partition)
 				if err != nil {
 					return 0, err
 				}
@@ -313,7 +551,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 					return 0, err
 				}
 			}
-			log.Debug("MQSink flushed", zap.Int("thisBatchSize", thisBatchSize))
+			log.Debug("MQSink flushed", zap.Int("thisBatchSize", thisBatchSize), zap.String("topic", topic))
 			return thisBatchSize, nil
 		})
 	}
@@ -326,21 +564,26 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-tick.C:
-			if err := flushToProducer(codec.EncoderNeedAsyncWrite); err != nil {
-				return errors.Trace(err)
+			for topic := range encoders {
+				if err := flushToProducer(topic, codec.EncoderNeedAsyncWrite); err != nil {
+					return errors.Trace(err)
+				}
 			}
 			continue
 		case e = <-input:
 		}
 		if e.row == nil {
 			if e.resolvedTs != 0 {
-				op, err := encoder.AppendResolvedEvent(e.resolvedTs)
-				if err != nil {
-					return errors.Trace(err)
-				}
-
-				if err := flushToProducer(op); err != nil {
-					return errors.Trace(err)
+				// resolved events are broadcast to all active topics
+				for _, topic := range k.getActiveTopics() {
+					op, err := getEncoder(topic).AppendResolvedEvent(e.resolvedTs)
+					if err != nil {
+						return errors.Trace(err)
+					}
+
+					if err := flushToProducer(topic, op); err != nil {
+						return errors.Trace(err)
+					}
 				}
 
 				atomic.StoreUint64(&k.partitionResolvedTs[partition], e.resolvedTs)
@@ -348,6 +591,8 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 			}
 			continue
 		}
+		topic := k.dispatchTopic(e.row.Table.Schema, e.row.Table.Table)
+		encoder := getEncoder(topic)
 		op, err := encoder.AppendRowChangedEvent(e.row)
 		if err != nil {
 			return errors.Trace(err)
@@ -358,32 +603,33 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 		}
 
 		if encoder.Size() >= batchSizeLimit || op != codec.EncoderNoOperation {
-			if err := flushToProducer(op); err != nil {
+			if err := flushToProducer(topic, op); err != nil {
 				return errors.Trace(err)
 			}
 		}
 	}
 }
 
-func (k *mqSink) writeToProducer(ctx context.Context, message *codec.MQMessage, op codec.EncoderResult, partition int32) error {
+func (k *mqSink) writeToProducer(ctx context.Context, topic string, message *codec.MQMessage, op codec.EncoderResult, partition int32) error {
 	switch op {
 	case codec.EncoderNeedAsyncWrite:
 		if partition >= 0 {
-			return k.mqProducer.SendMessage(ctx, message, partition)
+			return k.mqProducer.SendMessage(ctx, topic, message, partition)
 		}
 		return cerror.ErrAsyncBroadcaseNotSupport.GenWithStackByArgs()
 	case codec.EncoderNeedSyncWrite:
 		if partition >= 0 {
-			err := k.mqProducer.SendMessage(ctx, message, partition)
+			err := k.mqProducer.SendMessage(ctx, topic, message, partition)
 			if err != nil {
 				return err
 			}
 			return k.mqProducer.Flush(ctx)
 		}
-		return k.mqProducer.SyncBroadcastMessage(ctx, message)
+		return k.mqProducer.SyncBroadcastMessage(ctx, topic, message)
 	}
 
 	log.Warn("writeToProducer called with no-op",
+		zap.String("topic", topic),
 		zap.ByteString("key", message.Key),
 		zap.ByteString("value", message.Value),
 		zap.Int32("partition", partition))
@@ -440,6 +686,11 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		config.Compression = s
 	}
 
+	s = sinkURI.Query().Get("debezium-server-name")
+	if s != "" {
+		opts["debezium-server-name"] = s
+	}
+
 	config.ClientID = sinkURI.Query().Get("kafka-client-id")
 
 	s = sinkURI.Query().Get("protocol")
@@ -462,6 +713,43 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		config.Credential.KeyPath = s
 	}
 
+	s = sinkURI.Query().Get("sasl-mechanism")
+	if s != "" {
+		mechanism, err := security.SASLMechanismFromString(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		config.SASL.SASLMechanism = mechanism
+	}
+
+	config.SASL.SASLUser = sinkURI.Query().Get("sasl-user")
+	config.SASL.SASLPassword = sinkURI.Query().Get("sasl-password")
+
+	s = sinkURI.Query().Get("sasl-gssapi-auth-type")
+	if s != "" {
+		authType, err := security.GSSAPIAuthTypeFromString(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		config.SASL.GSSAPI.AuthType = authType
+	}
+
+	config.SASL.GSSAPI.KeyTabPath = sinkURI.Query().Get("sasl-gssapi-keytab-path")
+	config.SASL.GSSAPI.KerberosConfigPath = sinkURI.Query().Get("sasl-gssapi-kerberos-config-path")
+	config.SASL.GSSAPI.ServiceName = sinkURI.Query().Get("sasl-gssapi-service-name")
+	config.SASL.GSSAPI.Username = sinkURI.Query().Get("sasl-gssapi-user")
+	config.SASL.GSSAPI.Password = sinkURI.Query().Get("sasl-gssapi-password")
+	config.SASL.GSSAPI.Realm = sinkURI.Query().Get("sasl-gssapi-realm")
+
+	s = sinkURI.Query().Get("sasl-gssapi-disable-pafxfast")
+	if s != "" {
+		disablePAFXFAST, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		config.SASL.GSSAPI.DisablePAFXFAST = disablePAFXFAST
+	}
+
 	s = sinkURI.Query().Get("auto-create-topic")
 	if s != "" {
 		autoCreate, err := strconv.ParseBool(s)
@@ -471,14 +759,29 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		config.TopicPreProcess = autoCreate
 	}
 
+	s = sinkURI.Query().Get("transactional")
+	if s != "" {
+		transactional, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		config.Transactional = transactional
+	}
+
 	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
 		return r == '/'
 	})
-	producer, err := kafka.NewKafkaSaramaProducer(ctx, sinkURI.Host, topic, config, errCh)
+	var mqProducer producer.Producer
+	var err error
+	if config.Transactional {
+		mqProducer, err = kafka.NewKafkaTransactionalProducer(ctx, sinkURI.Host, topic, config)
+	} else {
+		mqProducer, err = kafka.NewKafkaSaramaProducer(ctx, sinkURI.Host, topic, config, errCh)
+	}
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	sink, err := newMqSink(ctx, config.Credential, producer, filter, replicaConfig, opts, errCh)
+	sink, err := newMqSink(ctx, config.Credential, mqProducer, topic, filter, replicaConfig, opts, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -486,6 +789,9 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 }
 
 func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter, replicaConfig *config.ReplicaConfig, opts map[string]string, errCh chan error) (*mqSink, error) {
+	if len(replicaConfig.Sink.TopicRules) != 0 {
+		return nil, cerror.ErrPulsarNewProducer.GenWithStack("topic rules are not supported by pulsar sink")
+	}
 	producer, err := pulsar.NewProducer(sinkURI, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -507,7 +813,12 @@ func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
 	// For now, it's a place holder. Avro format have to make connection to Schema Registery,
 	// and it may needs credential.
 	credential := &security.Credential{}
-	sink, err := newMqSink(ctx, credential, producer, filter, replicaConfig, opts, errCh)
+	// pulsar producer is bound to the topic in sink uri, so the topic used by
+	// mq sink is just a placeholder.
+	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
+		return r == '/'
+	})
+	sink, err := newMqSink(ctx, credential, producer, topic, filter, replicaConfig, opts, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -17,17 +17,22 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"sort"
+	"sync"
 
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
 
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
 )
 
@@ -217,3 +222,386 @@ func (s mqSinkSuite) TestPulsarSinkEncoderConfig(c *check.C) {
 	c.Assert(encoder.(*codec.JSONEventBatchEncoder).GetMaxBatchSize(), check.Equals, 1)
 	c.Assert(encoder.(*codec.JSONEventBatchEncoder).GetMaxKafkaMessageSize(), check.Equals, 4194304)
 }
+
+func (s mqSinkSuite) TestKafkaSinkInvalidSASLParams(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	for _, uri := range []string{
+		"kafka://127.0.0.1:9092/kafka-test?sasl-mechanism=unknown",
+		"kafka://127.0.0.1:9092/kafka-test?sasl-mechanism=gssapi&sasl-gssapi-auth-type=unknown",
+		"kafka://127.0.0.1:9092/kafka-test?sasl-mechanism=gssapi&sasl-gssapi-disable-pafxfast=not-bool",
+	} {
+		sinkURI, err := url.Parse(uri)
+		c.Assert(err, check.IsNil)
+		_, err = newKafkaSaramaSink(ctx, sinkURI, fr, replicaConfig, map[string]string{}, make(chan error, 1))
+		c.Assert(err, check.ErrorMatches, ".*CDC:ErrKafkaInvalidConfig.*")
+	}
+}
+
+// mockProducer records the topics and partitions of all messages it received
+type mockProducer struct {
+	mu           sync.Mutex
+	partitionNum int32
+	sent         map[string][]int32
+	broadcast    map[string]int
+}
+
+func newMockProducer(partitionNum int32) *mockProducer {
+	return &mockProducer{
+		partitionNum: partitionNum,
+		sent:         make(map[string][]int32),
+		broadcast:    make(map[string]int),
+	}
+}
+
+func (p *mockProducer) SendMessage(ctx context.Context, topic string, message *codec.MQMessage, partition int32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent[topic] = append(p.sent[topic], partition)
+	return nil
+}
+
+func (p *mockProducer) SyncBroadcastMessage(ctx context.Context, topic string, message *codec.MQMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.broadcast[topic]++
+	return nil
+}
+
+func (p *mockProducer) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (p *mockProducer) GetPartitionNum() int32 {
+	return p.partitionNum
+}
+
+func (p *mockProducer) Close() error {
+	return nil
+}
+
+func (p *mockProducer) topics() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topics := make(map[string]int, len(p.sent))
+	for topic, partitions := range p.sent {
+		topics[topic] = len(partitions)
+	}
+	return topics
+}
+
+func (p *mockProducer) broadcastTopics() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topics := make(map[string]int, len(p.broadcast))
+	for topic, count := range p.broadcast {
+		topics[topic] = count
+	}
+	return topics
+}
+
+func (s mqSinkSuite) TestMQSinkTopicRules(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.TopicRules = []*config.TopicRule{
+		{Matcher: []string{"test1.*"}, Topic: "{schema}_{table}"},
+		{Matcher: []string{"test2.*"}, Topic: "{schema}"},
+	}
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	producer := newMockProducer(2)
+	sink, err := newMqSink(ctx, &security.Credential{}, producer, "default-topic",
+		fr, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	err = sink.Initialize(ctx, []*model.SimpleTableInfo{
+		{Schema: "test1", Table: "t4"}, nil,
+	})
+	c.Assert(err, check.IsNil)
+
+	rows := []*model.RowChangedEvent{
+		{Table: &model.TableName{Schema: "test1", Table: "t1"}, CommitTs: 100},
+		{Table: &model.TableName{Schema: "test1", Table: "t2"}, CommitTs: 100},
+		{Table: &model.TableName{Schema: "test2", Table: "t1"}, CommitTs: 100},
+		{Table: &model.TableName{Schema: "test2", Table: "t2"}, CommitTs: 100},
+	}
+	err = sink.EmitRowChangedEvents(ctx, rows...)
+	c.Assert(err, check.IsNil)
+	checkpointTs, err := sink.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(100))
+
+	// every row is sent to the topic of its table
+	topics := make([]string, 0)
+	for topic := range producer.topics() {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	c.Assert(topics, check.DeepEquals, []string{"test1_t1", "test1_t2", "test2"})
+
+	// table level DDL is only sent to the topic of the table
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs:  110,
+		TableInfo: &model.SimpleTableInfo{Schema: "test1", Table: "t3"},
+		Query:     "create table test1.t3(id int primary key)",
+		Type:      timodel.ActionCreateTable,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.broadcastTopics(), check.DeepEquals, map[string]int{"test1_t3": 1})
+
+	// schema level DDL and checkpoint event are sent to all active topics
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs:  120,
+		TableInfo: &model.SimpleTableInfo{Schema: "test4"},
+		Query:     "create database test4",
+		Type:      timodel.ActionCreateSchema,
+	})
+	c.Assert(err, check.IsNil)
+	err = sink.EmitCheckpointTs(ctx, 120)
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.broadcastTopics(), check.DeepEquals, map[string]int{
+		"test1_t1":      2,
+		"test1_t2":      2,
+		"test1_t3":      3,
+		"test1_t4":      2,
+		"test2":         2,
+		"default-topic": 2,
+	})
+}
+
+func (s mqSinkSuite) TestMQSinkColumnsDispatcher(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "columns", Columns: []string{"tenant_id"}},
+	}
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	sink, err := newMqSink(ctx, &security.Credential{}, newMockProducer(4), "default-topic",
+		fr, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	err = sink.Initialize(ctx, []*model.SimpleTableInfo{{
+		Schema: "test", Table: "t1",
+		ColumnInfo: []*model.ColumnInfo{{Name: "id"}, {Name: "tenant_id"}},
+	}})
+	c.Assert(err, check.IsNil)
+
+	err = sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
+		Table:    &model.TableName{Schema: "test", Table: "t1"},
+		CommitTs: 100,
+		Columns:  []*model.Column{{Name: "id", Value: 1}, {Name: "tenant_id", Value: 2}},
+	})
+	c.Assert(err, check.IsNil)
+
+	// the column required by the dispatcher is dropped
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs: 110,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: "test", Table: "t1",
+			ColumnInfo: []*model.ColumnInfo{{Name: "id"}},
+		},
+		Query: "alter table test.t1 drop column tenant_id",
+		Type:  timodel.ActionDropColumn,
+	})
+	c.Assert(cerror.ErrDispatcherColumnNotFound.Equal(err), check.IsTrue)
+
+	err = sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
+		Table:    &model.TableName{Schema: "test", Table: "t1"},
+		CommitTs: 120,
+		Columns:  []*model.Column{{Name: "id", Value: 1}},
+	})
+	c.Assert(cerror.ErrDispatcherColumnNotFound.Equal(err), check.IsTrue)
+}
+
+func (s mqSinkSuite) TestMQSinkTableDispatcherDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "table"},
+	}
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	producer := newMockProducer(4)
+	sink, err := newMqSink(ctx, &security.Credential{}, producer, "default-topic",
+		fr, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	row := &model.RowChangedEvent{Table: &model.TableName{Schema: "test", Table: "t1"}, CommitTs: 100}
+	partition, err := sink.dispatcher.Dispatch(row)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvents(ctx, row)
+	c.Assert(err, check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.IsNil)
+
+	// the DDL of the table is only sent to the partition of its rows
+	sent := producer.topics()["default-topic"]
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs:  110,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "alter table test.t1 add column c1 int",
+		Type:      timodel.ActionAddColumn,
+	})
+	c.Assert(err, check.IsNil)
+	producer.mu.Lock()
+	partitions := producer.sent["default-topic"]
+	producer.mu.Unlock()
+	c.Assert(partitions, check.HasLen, sent+1)
+	c.Assert(partitions[sent], check.Equals, partition)
+	c.Assert(producer.broadcastTopics(), check.HasLen, 0)
+
+	// the DDL of the tables using other dispatchers is broadcast
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs:  120,
+		TableInfo: &model.SimpleTableInfo{Schema: "other", Table: "t1"},
+		Query:     "alter table other.t1 add column c1 int",
+		Type:      timodel.ActionAddColumn,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.broadcastTopics(), check.DeepEquals, map[string]int{"default-topic": 1})
+
+	// the DDLs affecting more than one table are broadcast
+	for i, ddl := range []*model.DDLEvent{{
+		CommitTs:  130,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t2"},
+		Query:     "rename table test.t1 to test.t2",
+		Type:      timodel.ActionRenameTable,
+	}, {
+		CommitTs:  140,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t3"},
+		Query:     "rename table test.t2 to test.t3, test.t4 to test.t5",
+		Type:      timodel.ActionRenameTables,
+	}, {
+		CommitTs:  150,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t3"},
+		Query:     "alter table test.t3 exchange partition p0 with table test.t6",
+		Type:      timodel.ActionExchangeTablePartition,
+	}} {
+		err = sink.EmitDDLEvent(ctx, ddl)
+		c.Assert(err, check.IsNil)
+		c.Assert(producer.broadcastTopics(), check.DeepEquals, map[string]int{"default-topic": i + 2})
+	}
+	c.Assert(producer.topics()["default-topic"], check.Equals, sent+1)
+}
+
+// mockTxnProducer records the messages sent in the transactions
+type mockTxnProducer struct {
+	*mockProducer
+	committedTs map[int64]uint64
+	// pending and committed record the number of the row messages sent in
+	// the ongoing transaction and committed
+	pending   int
+	committed int
+}
+
+func newMockTxnProducer(partitionNum int32, committedTs map[int64]uint64) *mockTxnProducer {
+	return &mockTxnProducer{
+		mockProducer: newMockProducer(partitionNum),
+		committedTs:  committedTs,
+	}
+}
+
+func (p *mockTxnProducer) SendMessage(ctx context.Context, topic string, message *codec.MQMessage, partition int32) error {
+	if message.Type == model.MqMessageTypeRow {
+		p.mu.Lock()
+		p.pending++
+		p.mu.Unlock()
+	}
+	return p.mockProducer.SendMessage(ctx, topic, message, partition)
+}
+
+func (p *mockTxnProducer) CommittedTs(ctx context.Context, tableID int64) (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.committedTs[tableID], nil
+}
+
+func (p *mockTxnProducer) CommitTransaction(ctx context.Context, ts uint64, tableTs map[int64]uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.committed += p.pending
+	p.pending = 0
+	for tableID, ts := range tableTs {
+		p.committedTs[tableID] = ts
+	}
+	if ts != 0 {
+		p.committedTs[producer.NoTableID] = ts
+	}
+	return nil
+}
+
+func (s mqSinkSuite) TestMQSinkTransactional(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	txnProducer := newMockTxnProducer(2, map[int64]uint64{1: 90, producer.NoTableID: 80})
+	sink, err := newMqSink(ctx, &security.Credential{}, txnProducer, "default-topic",
+		fr, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	ts, err := sink.LoadTableCheckpoint(ctx, 1)
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(90))
+
+	table1 := &model.TableName{Schema: "test", Table: "t1", TableID: 1}
+	table2 := &model.TableName{Schema: "test", Table: "t2", TableID: 2}
+	err = sink.EmitRowChangedEvents(ctx,
+		&model.RowChangedEvent{Table: table1, CommitTs: 100, Columns: []*model.Column{{Name: "id", Value: 1}}},
+		&model.RowChangedEvent{Table: table2, CommitTs: 100, Columns: []*model.Column{{Name: "id", Value: 1}}},
+		// the row is not resolved yet, it's not sent in the transaction
+		&model.RowChangedEvent{Table: table2, CommitTs: 120, Columns: []*model.Column{{Name: "id", Value: 2}}},
+	)
+	c.Assert(err, check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 110)
+	c.Assert(err, check.IsNil)
+	// the commit ts of the last rows of the tables are committed
+	txnProducer.mu.Lock()
+	committed := txnProducer.committed
+	c.Assert(committed, check.Greater, 0)
+	c.Assert(txnProducer.pending, check.Equals, 0)
+	c.Assert(txnProducer.committedTs, check.DeepEquals, map[int64]uint64{1: 100, 2: 100, producer.NoTableID: 110})
+	txnProducer.mu.Unlock()
+	c.Assert(sink.pendingRows, check.HasLen, 1)
+
+	_, err = sink.FlushRowChangedEvents(ctx, 120)
+	c.Assert(err, check.IsNil)
+	txnProducer.mu.Lock()
+	c.Assert(txnProducer.committed, check.Greater, committed)
+	c.Assert(txnProducer.pending, check.Equals, 0)
+	c.Assert(txnProducer.committedTs, check.DeepEquals, map[int64]uint64{1: 100, 2: 120, producer.NoTableID: 120})
+	txnProducer.mu.Unlock()
+	c.Assert(sink.pendingRows, check.HasLen, 0)
+
+	// the DDL committed before is skipped
+	ddl := &model.DDLEvent{
+		CommitTs:  80,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "alter table test.t1 add column c1 int",
+		Type:      timodel.ActionAddColumn,
+	}
+	c.Assert(sink.EmitDDLEvent(ctx, ddl), check.IsNil)
+	c.Assert(txnProducer.broadcastTopics(), check.HasLen, 0)
+	ddl.CommitTs = 130
+	c.Assert(sink.EmitDDLEvent(ctx, ddl), check.IsNil)
+	c.Assert(txnProducer.broadcastTopics(), check.DeepEquals, map[string]int{"default-topic": 1})
+	c.Assert(sink.ddlCommittedTs, check.Equals, uint64(130))
+}
@@ -60,6 +60,7 @@ const (
 	defaultFlushInterval       = time.Millisecond * 50
 	defaultBatchReplaceEnabled = true
 	defaultBatchReplaceSize    = 20
+	defaultBatchDMLSize        = 64
 	defaultReadTimeout         = "2m"
 	defaultWriteTimeout        = "2m"
 	defaultDialTimeout         = "2m"
@@ -69,6 +70,11 @@ const (
 // SyncpointTableName is the name of table where all syncpoint maps sit
 const syncpointTableName string = "syncpoint_v1"
 
+// syncpointPosTableName is the name of table where the syncpoints of the MySQL
+// downstream sit, the position of a syncpoint is the GTID set or the binlog
+// position of the downstream instead of a TSO.
+const syncpointPosTableName string = "syncpoint_pos_v1"
+
 const tidbVersionString string = "TiDB"
 
 var validSchemes = map[string]bool{
@@ -80,6 +86,8 @@ var validSchemes = map[string]bool{
 
 type mysqlSyncpointStore struct {
 	db *sql.DB
+	// isTiDB is false if the downstream is MySQL, which has no TSO
+	isTiDB bool
 }
 
 type mysqlSink struct {
@@ -105,6 +113,19 @@ type mysqlSink struct {
 	metricBucketSizeCounters        []prometheus.Counter
 
 	forceReplicate bool
+
+	// conflict resolves the conflicts if the on-conflict policy is configured
+	conflict *conflictResolver
+	// deadLetter records the rows rejected by the downstream if it's enabled
+	deadLetter *deadLetterWriter
+	// stmtCache caches the prepared statements of the batched DMLs if it's enabled
+	stmtCache *stmtCache
+	// router maps the upstream tables to the downstream ones if route rules
+	// are configured
+	router *tableRouter
+	// ddlTransformer skips or rewrites the DDLs if DDL rules are configured
+	// or the downstream is MySQL
+	ddlTransformer *ddlTransformer
 }
 
 func (s *mysqlSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
@@ -181,7 +202,26 @@ func (s *mysqlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error
 		)
 		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
 	}
-	err := s.execDDLWithMaxRetries(ctx, ddl, defaultDDLMaxRetryTime)
+	query, err := s.ddlTransformer.transform(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if query == "" {
+		log.Info(
+			"DDL event skipped by the DDL rules or the downstream type",
+			zap.String("query", ddl.Query),
+			zap.Uint64("startTs", ddl.StartTs),
+			zap.Uint64("commitTs", ddl.CommitTs),
+		)
+		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
+	}
+	if query != ddl.Query {
+		log.Info("DDL event transformed", zap.String("query", ddl.Query), zap.String("transformed", query))
+		transformed := *ddl
+		transformed.Query = query
+		ddl = &transformed
+	}
+	err = s.execDDLWithMaxRetries(ctx, ddl, defaultDDLMaxRetryTime)
 	return errors.Trace(err)
 }
 
@@ -209,7 +249,16 @@ func (s *mysqlSink) execDDLWithMaxRetries(ctx context.Context, ddl *model.DDLEve
 }
 
 func (s *mysqlSink) execDDL(ctx context.Context, ddl *model.DDLEvent) error {
-	shouldSwitchDB := len(ddl.TableInfo.Schema) > 0 && ddl.Type != timodel.ActionCreateSchema
+	schema, query, err := s.router.routeDDL(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if query == "" {
+		log.Warn("DDL event skipped since it drops or renames a downstream table shared by other upstream tables",
+			zap.String("query", ddl.Query), zap.Uint64("commitTs", ddl.CommitTs))
+		return backoff.Permanent(cerror.ErrDDLEventIgnored.GenWithStackByArgs())
+	}
+	shouldSwitchDB := len(schema) > 0 && ddl.Type != timodel.ActionCreateSchema
 
 	failpoint.Inject("MySQLSinkExecDDLDelay", func() {
 		select {
@@ -226,7 +275,7 @@ func (s *mysqlSink) execDDL(ctx context.Context, ddl *model.DDLEvent) error {
 	}
 
 	if shouldSwitchDB {
-		_, err = tx.ExecContext(ctx, "USE "+quotes.QuoteName(ddl.TableInfo.Schema)+";")
+		_, err = tx.ExecContext(ctx, "USE "+quotes.QuoteName(schema)+";")
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
 				log.Error("Failed to rollback", zap.Error(err))
@@ -235,9 +284,9 @@ func (s *mysqlSink) execDDL(ctx context.Context, ddl *model.DDLEvent) error {
 		}
 	}
 
-	if _, err = tx.ExecContext(ctx, ddl.Query); err != nil {
+	if _, err = tx.ExecContext(ctx, query); err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
-			log.Error("Failed to rollback", zap.String("sql", ddl.Query), zap.Error(err))
+			log.Error("Failed to rollback", zap.String("sql", query), zap.Error(err))
 		}
 		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
 	}
@@ -246,7 +295,7 @@ func (s *mysqlSink) execDDL(ctx context.Context, ddl *model.DDLEvent) error {
 		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
 	}
 
-	log.Info("Exec DDL succeeded", zap.String("sql", ddl.Query))
+	log.Info("Exec DDL succeeded", zap.String("sql", query))
 	return nil
 }
 
@@ -272,7 +321,10 @@ func (s *mysqlSink) adjustSQLMode(ctx context.Context) error {
 	return nil
 }
 
-var _ Sink = &mysqlSink{}
+var (
+	_ Sink                  = &mysqlSink{}
+	_ TableCheckpointLoader = &mysqlSink{}
+)
 
 type sinkParams struct {
 	workerCount         int
@@ -289,6 +341,11 @@ type sinkParams struct {
 	safeMode            bool
 	timezone            string
 	tls                 string
+	checkpointTable     bool
+	batchDMLEnabled     bool
+	batchDMLSize        int
+	preparedStmtEnabled bool
+	downstreamType      string
 }
 
 func (s *sinkParams) Clone() *sinkParams {
@@ -306,6 +363,7 @@ var defaultParams = &sinkParams{
 	writeTimeout:        defaultWriteTimeout,
 	dialTimeout:         defaultDialTimeout,
 	safeMode:            defaultSafeMode,
+	downstreamType:      downstreamTypeTiDB,
 }
 
 func checkIsTiDB(ctx context.Context, db *sql.DB) (bool, error) {
@@ -477,6 +535,51 @@ func parseSinkURI(ctx context.Context, sinkURI *url.URL, opts map[string]string)
 		params.batchReplaceSize = size
 	}
 
+	// The consecutive rows on the same table are merged into multi-row
+	// statements, which reduces the round trips to the downstream.
+	s = sinkURI.Query().Get("batch-dml-enable")
+	if s != "" {
+		enable, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.batchDMLEnabled = enable
+	}
+	if params.batchDMLEnabled {
+		params.batchDMLSize = defaultBatchDMLSize
+		if s = sinkURI.Query().Get("batch-dml-size"); s != "" {
+			size, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+			}
+			if size <= 0 {
+				return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("invalid batch-dml-size %d", size)
+			}
+			params.batchDMLSize = size
+		}
+	}
+	// The batched statements are executed by the server-side prepared
+	// statements, which are cached per table schema version.
+	s = sinkURI.Query().Get("prepared-stmt-enable")
+	if s != "" {
+		enable, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.preparedStmtEnabled = enable
+	}
+
+	// The TiDB specific syntax is stripped from the DDLs if the downstream is
+	// MySQL.
+	s = sinkURI.Query().Get("downstream-type")
+	if s != "" {
+		s = strings.ToLower(s)
+		if s != downstreamTypeTiDB && s != downstreamTypeMySQL {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("invalid downstream-type %s, should be tidb or mysql", s)
+		}
+		params.downstreamType = s
+	}
+
 	// TODO: force safe mode in startup phase
 	s = sinkURI.Query().Get("safe-mode")
 	if s != "" {
@@ -487,6 +590,18 @@ func parseSinkURI(ctx context.Context, sinkURI *url.URL, opts map[string]string)
 		params.safeMode = safeModeEnabled
 	}
 
+	// The commit ts of the applied transactions are recorded in the downstream,
+	// so the transactions applied before are skipped after restarting, which
+	// makes it possible to replicate without safe mode.
+	s = sinkURI.Query().Get("checkpoint-table")
+	if s != "" {
+		enabled, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.checkpointTable = enabled
+	}
+
 	if _, ok := sinkURI.Query()["time-zone"]; ok {
 		s = sinkURI.Query().Get("time-zone")
 		if s == "" {
@@ -555,6 +670,18 @@ func newMySQLSink(
 	}
 
 	params.enableOldValue = replicaConfig.EnableOldValue
+	conflict, err := newConflictResolver(replicaConfig.Sink, params, replicaConfig.ForceReplicate)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	router, err := newTableRouter(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ddlTransformer, err := newDDLTransformer(replicaConfig, params.downstreamType)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	// dsn format of the driver:
 	// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
@@ -584,6 +711,9 @@ func newMySQLSink(
 	dsn.Params["readTimeout"] = params.readTimeout
 	dsn.Params["writeTimeout"] = params.writeTimeout
 	dsn.Params["timeout"] = params.dialTimeout
+	// the conflicts are detected by the rows affected, which should be the
+	// number of the rows matched rather than changed by an UPDATE
+	dsn.ClientFoundRows = conflict != nil
 	testDB, err := getDBConnImpl(ctx, dsn.FormatDSN())
 	if err != nil {
 		return nil, err
@@ -622,6 +752,9 @@ func newMySQLSink(
 		metricBucketSizeCounters:        metricBucketSizeCounters,
 		errCh:                           make(chan error, 1),
 		forceReplicate:                  replicaConfig.ForceReplicate,
+		conflict:                        conflict,
+		router:                          router,
+		ddlTransformer:                  ddlTransformer,
 	}
 
 	if val, ok := opts[mark.OptCyclicConfig]; ok {
@@ -638,6 +771,25 @@ func newMySQLSink(
 		}
 	}
 
+	err = sink.initCheckpointTable(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if conflict != nil {
+		err = conflict.createConflictLogTable(ctx, db)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	sink.deadLetter, err = newDeadLetterWriter(ctx, replicaConfig.Sink, params.changefeedID, db)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if params.batchDMLEnabled && params.preparedStmtEnabled && conflict == nil {
+		sink.stmtCache = newStmtCache(db, maxCachedStmts(params.workerCount))
+	}
+
 	sink.execWaitNotifier = new(notify.Notifier)
 	sink.resolvedNotifier = new(notify.Notifier)
 	err = sink.createSinkWorkers(ctx)
@@ -714,6 +866,13 @@ func (s *mysqlSink) dispatchAndExecTxns(ctx context.Context, txnsGroup map[model
 		s.workers[idx].appendTxn(ctx, txn)
 	}
 	resolveConflict := func(txn *model.SingleTableTxn) {
+		// The transactions of a table are executed by the same worker in order
+		// if the checkpoint table is enabled, so that the commit ts recorded for
+		// a table never exceeds the unapplied transactions of it.
+		if s.params.checkpointTable {
+			s.workers[uint64(txn.Table.TableID)%uint64(nWorkers)].appendTxn(ctx, txn)
+			return
+		}
 		keys := genTxnKeys(txn)
 		if conflict, idx := causality.detectConflict(keys); conflict {
 			if idx >= 0 {
@@ -868,10 +1027,26 @@ func (w *mysqlSinkWorker) run(ctx context.Context) (err error) {
 func (s *mysqlSink) Close() error {
 	s.execWaitNotifier.Close()
 	s.resolvedNotifier.Close()
+	if s.stmtCache != nil {
+		s.stmtCache.Close()
+	}
+	if s.deadLetter != nil {
+		if err := s.deadLetter.Close(); err != nil {
+			log.Warn("close dead letter failed", zap.Error(err))
+		}
+	}
 	err := s.db.Close()
 	return cerror.WrapError(cerror.ErrMySQLConnectionError, err)
 }
 
+// DeadLetterCount implements DeadLetterCounter
+func (s *mysqlSink) DeadLetterCount() uint64 {
+	if s.deadLetter == nil {
+		return 0
+	}
+	return s.deadLetter.Count()
+}
+
 func (s *mysqlSink) execDMLWithMaxRetries(
 	ctx context.Context, dmls *preparedDMLs, maxRetries uint64, bucket int,
 ) error {
@@ -895,6 +1070,7 @@ func (s *mysqlSink) execDMLWithMaxRetries(
 			failpoint.Inject("MySQLSinkHangLongTime", func() {
 				time.Sleep(time.Hour)
 			})
+			var conflictTypes []string
 			err := s.statistics.RecordBatchExecution(func() (int, error) {
 				tx, err := s.db.BeginTx(ctx, nil)
 				if err != nil {
@@ -903,10 +1079,27 @@ func (s *mysqlSink) execDMLWithMaxRetries(
 				for i, query := range dmls.sqls {
 					args := dmls.values[i]
 					log.Debug("exec row", zap.String("sql", query), zap.Any("args", args))
-					if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+					var result sql.Result
+					var err error
+					if i < len(dmls.stmtKeys) {
+						result, err = s.stmtCache.exec(ctx, tx, dmls.stmtKeys[i], query, args)
+					} else {
+						result, err = tx.ExecContext(ctx, query, args...)
+					}
+					if err == nil && i < len(dmls.conflictDMLs) {
+						var conflictType string
+						conflictType, err = s.conflict.resolve(ctx, tx, dmls.conflictDMLs[i], result)
+						if conflictType != "" {
+							conflictTypes = append(conflictTypes, conflictType)
+						}
+					}
+					if err != nil {
 						if rbErr := tx.Rollback(); rbErr != nil {
 							log.Warn("failed to rollback txn", zap.Error(err))
 						}
+						if cerror.ErrMySQLConflict.Equal(err) {
+							return 0, err
+						}
 						return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
 					}
 				}
@@ -925,8 +1118,16 @@ func (s *mysqlSink) execDMLWithMaxRetries(
 				return dmls.rowCount, nil
 			})
 			if err != nil {
+				// neither the conflict nor the rows rejected by the downstream
+				// are resolved by retrying
+				if cerror.ErrMySQLConflict.Equal(err) || (s.deadLetter != nil && isDeadLetterError(err)) {
+					return backoff.Permanent(err)
+				}
 				return errors.Trace(err)
 			}
+			if s.conflict != nil {
+				s.conflict.recordConflicts(conflictTypes)
+			}
 			log.Debug("Exec Rows succeeded",
 				zap.String("changefeed", s.params.changefeedID),
 				zap.Int("num of Rows", dmls.rowCount),
@@ -937,8 +1138,14 @@ func (s *mysqlSink) execDMLWithMaxRetries(
 }
 
 type preparedDMLs struct {
-	sqls     []string
-	values   [][]interface{}
+	sqls   []string
+	values [][]interface{}
+	// conflictDMLs are the first statements of sqls whose conflicts are
+	// resolved by the conflict policy
+	conflictDMLs []*conflictDML
+	// stmtKeys are the keys of the first statements of sqls which are
+	// executed by the cached prepared statements
+	stmtKeys []stmtKey
 	markSQL  string
 	rowCount int
 }
@@ -948,8 +1155,13 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 	sqls := make([]string, 0, len(rows))
 	values := make([][]interface{}, 0, len(rows))
 	replaces := make(map[string][][]interface{})
+	var conflictDMLs []*conflictDML
 	rowCount := 0
 	translateToInsert := s.params.enableOldValue && !s.params.safeMode
+	var batcher *dmlBatcher
+	if s.params.batchDMLEnabled && s.conflict == nil {
+		batcher = newDMLBatcher(translateToInsert, s.forceReplicate, s.params.batchDMLSize)
+	}
 
 	// flush cached batch replace or insert, to keep the sequence of DMLs
 	flushCacheDMLs := func() {
@@ -964,7 +1176,26 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 	for _, row := range rows {
 		var query string
 		var args []interface{}
-		quoteTable := quotes.QuoteSchema(row.Table.Schema, row.Table.Table)
+		quoteTable := s.router.quoteTable(row.Table)
+
+		// Prepare a statement detecting the conflict for each row if the
+		// on-conflict policy is configured
+		if s.conflict != nil {
+			query, args, dml := s.conflict.prepareDML(quoteTable, row)
+			if query != "" {
+				sqls = append(sqls, query)
+				values = append(values, args)
+				conflictDMLs = append(conflictDMLs, dml)
+				rowCount++
+			}
+			continue
+		}
+
+		// Merge the consecutive rows on the same table if batching is enabled
+		if batcher != nil {
+			batcher.append(quoteTable, row)
+			continue
+		}
 
 		// Translate to UPDATE if old value is enabled, not in safe mode and is update event
 		if translateToInsert && len(row.PreColumns) != 0 && len(row.Columns) != 0 {
@@ -1015,16 +1246,35 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 		}
 	}
 	flushCacheDMLs()
+	var stmtKeys []stmtKey
+	if batcher != nil {
+		batcher.flush()
+		sqls = append(sqls, batcher.sqls...)
+		values = append(values, batcher.values...)
+		rowCount += batcher.rowCount
+		if s.stmtCache != nil {
+			stmtKeys = batcher.stmtKeys
+		}
+	}
+	if s.params.checkpointTable && len(rows) > 0 {
+		checkpointSqls, checkpointValues := s.prepareCheckpointDMLs(rows)
+		sqls = append(sqls, checkpointSqls...)
+		values = append(values, checkpointValues...)
+	}
 
 	dmls := &preparedDMLs{
-		sqls:   sqls,
-		values: values,
+		sqls:         sqls,
+		values:       values,
+		conflictDMLs: conflictDMLs,
+		stmtKeys:     stmtKeys,
 	}
 	if s.cyclic != nil && len(rows) > 0 {
 		// Write mark table with the current replica ID.
+		// The mark table is named after the downstream table.
 		row := rows[0]
+		schema, table := s.router.route(row.Table.Schema, row.Table.Table)
 		updateMark := s.cyclic.UdpateSourceTableCyclicMark(
-			row.Table.Schema, row.Table.Table, uint64(bucket), replicaID, row.StartTs)
+			schema, table, uint64(bucket), replicaID, row.StartTs)
 		dmls.markSQL = updateMark
 		// rowCount is used in statistics, and for simplicity,
 		// we do not count mark table rows in rowCount.
@@ -1046,7 +1296,13 @@ func (s *mysqlSink) execDMLs(ctx context.Context, rows []*model.RowChangedEvent,
 	})
 	dmls := s.prepareDMLs(rows, replicaID, bucket)
 	log.Debug("prepare DMLs", zap.Any("rows", rows), zap.Strings("sqls", dmls.sqls), zap.Any("values", dmls.values))
-	if err := s.execDMLWithMaxRetries(ctx, dmls, defaultDMLMaxRetryTime, bucket); err != nil {
+	err := s.execDMLWithMaxRetries(ctx, dmls, defaultDMLMaxRetryTime, bucket)
+	if err != nil && s.deadLetter != nil && isDeadLetterError(err) {
+		log.Warn("some rows are rejected by the downstream, execute the rows one by one",
+			zap.String("changefeed", s.params.changefeedID), zap.Int("bucket", bucket), zap.Error(err))
+		err = s.execDMLsRowByRow(ctx, rows, replicaID, bucket)
+	}
+	if err != nil {
 		ts := make([]uint64, 0, len(rows))
 		for _, row := range rows {
 			if len(ts) == 0 || ts[len(ts)-1] != row.CommitTs {
@@ -1059,6 +1315,25 @@ func (s *mysqlSink) execDMLs(ctx context.Context, rows []*model.RowChangedEvent,
 	return nil
 }
 
+// execDMLsRowByRow executes every row in its own transaction, the rows rejected
+// by the downstream are written to the dead letter and the rest rows continue.
+func (s *mysqlSink) execDMLsRowByRow(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+	for _, row := range rows {
+		dmls := s.prepareDMLs([]*model.RowChangedEvent{row}, replicaID, bucket)
+		err := s.execDMLWithMaxRetries(ctx, dmls, defaultDMLMaxRetryTime, bucket)
+		if err == nil {
+			continue
+		}
+		if !isDeadLetterError(err) {
+			return errors.Trace(err)
+		}
+		if err := s.deadLetter.write(ctx, row, err); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func prepareReplace(
 	quoteTable string,
 	cols []*model.Column,
@@ -1353,9 +1628,15 @@ func newMySQLSyncpointStore(ctx context.Context, id string, sinkURI *url.URL) (S
 		return nil, errors.Annotate(err, "fail to open MySQL connection")
 	}
 
-	log.Info("Start mysql syncpoint sink")
+	isTiDB, err := checkIsTiDB(ctx, syncDB)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	log.Info("Start mysql syncpoint sink", zap.Bool("isTiDB", isTiDB))
 	syncpointStore := &mysqlSyncpointStore{
-		db: syncDB,
+		db:     syncDB,
+		isTiDB: isTiDB,
 	}
 
 	return syncpointStore, nil
@@ -1384,7 +1665,13 @@ func (s *mysqlSyncpointStore) CreateSynctable(ctx context.Context) error {
 		}
 		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
 	}
-	_, err = tx.Exec("CREATE TABLE  IF NOT EXISTS " + syncpointTableName + " (cf varchar(255),primary_ts varchar(18),secondary_ts varchar(18),PRIMARY KEY ( `cf`, `primary_ts` ) )")
+	createTableSQL := "CREATE TABLE  IF NOT EXISTS " + syncpointTableName + " (cf varchar(255),primary_ts varchar(18),secondary_ts varchar(18),PRIMARY KEY ( `cf`, `primary_ts` ) )"
+	if !s.isTiDB {
+		createTableSQL = "CREATE TABLE IF NOT EXISTS " + syncpointPosTableName +
+			" (cf varchar(255),primary_ts varchar(18),gtid_set text,binlog_file varchar(255),binlog_pos bigint unsigned," +
+			"PRIMARY KEY ( `cf`, `primary_ts` ) )"
+	}
+	_, err = tx.Exec(createTableSQL)
 	if err != nil {
 		err2 := tx.Rollback()
 		if err2 != nil {
@@ -1397,6 +1684,9 @@ func (s *mysqlSyncpointStore) CreateSynctable(ctx context.Context) error {
 }
 
 func (s *mysqlSyncpointStore) SinkSyncpoint(ctx context.Context, id string, checkpointTs uint64) error {
+	if !s.isTiDB {
+		return s.sinkSyncpointPos(ctx, id, checkpointTs)
+	}
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Error("sync table: begin Tx fail", zap.Error(err))
@@ -1425,6 +1715,64 @@ func (s *mysqlSyncpointStore) SinkSyncpoint(ctx context.Context, id string, chec
 	return cerror.WrapError(cerror.ErrMySQLTxnError, err)
 }
 
+// sinkSyncpointPos records the syncpoint with the binlog position of the MySQL
+// downstream, the changes are paused at a syncpoint, so the position matches
+// the syncpoint unless other clients write the downstream.
+func (s *mysqlSyncpointStore) sinkSyncpointPos(ctx context.Context, id string, checkpointTs uint64) error {
+	file, pos, gtidSet, err := queryBinlogPos(ctx, s.db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = s.db.ExecContext(ctx, "insert ignore into "+mark.SchemaName+"."+syncpointPosTableName+
+		"(cf, primary_ts, gtid_set, binlog_file, binlog_pos) VALUES (?,?,?,?,?)", id, checkpointTs, gtidSet, file, pos)
+	return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+// queryBinlogPos returns the binlog position and the executed GTID set of the
+// downstream, the GTID set is empty if GTID is not enabled.
+func queryBinlogPos(ctx context.Context, db *sql.DB) (file string, pos uint64, gtidSet string, err error) {
+	rows, err := db.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		return "", 0, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", 0, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", 0, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		return "", 0, "", cerror.ErrMySQLQueryError.GenWithStack(
+			"binlog is not enabled in the downstream, the syncpoint can't be recorded")
+	}
+	// the columns differ between MySQL versions and MariaDB
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", 0, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	for i, column := range columns {
+		switch column {
+		case "File":
+			file = values[i].String
+		case "Position":
+			pos, err = strconv.ParseUint(values[i].String, 10, 64)
+			if err != nil {
+				return "", 0, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+			}
+		case "Executed_Gtid_Set":
+			// the GTID set is split into multiple lines if it's long
+			gtidSet = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+	return file, pos, gtidSet, nil
+}
+
 func (s *mysqlSyncpointStore) Close() error {
 	err := s.db.Close()
 	return cerror.WrapError(cerror.ErrMySQLConnectionError, err)
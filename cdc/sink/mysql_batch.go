@@ -0,0 +1,259 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"strings"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/quotes"
+)
+
+type batchDMLType int
+
+const (
+	batchDMLNone batchDMLType = iota
+	batchDMLDelete
+	batchDMLInsert
+	batchDMLUpsert
+)
+
+// dmlBatch is a group of consecutive rows of the same table and the same kind,
+// which are merged into one statement
+type dmlBatch struct {
+	tp         batchDMLType
+	quoteTable string
+	version    uint64
+	// prefix is the statement before the values, e.g.
+	// "INSERT INTO `test`.`t`(`a`,`b`) VALUES " or "DELETE FROM `test`.`t` WHERE (`a`) IN ("
+	prefix   string
+	colNames []string
+	args     [][]interface{}
+}
+
+// dmlBatcher merges the consecutive rows of a transaction on the same table
+// into multi-row statements, the order of the rows is kept.
+type dmlBatcher struct {
+	translateToInsert bool
+	forceReplicate    bool
+	batchSize         int
+
+	cur *dmlBatch
+
+	sqls   []string
+	values [][]interface{}
+	// stmtKeys are the tables and the table info versions of the statements
+	stmtKeys []stmtKey
+	rowCount int
+}
+
+func newDMLBatcher(translateToInsert, forceReplicate bool, batchSize int) *dmlBatcher {
+	if batchSize <= 0 {
+		batchSize = defaultBatchDMLSize
+	}
+	return &dmlBatcher{
+		translateToInsert: translateToInsert,
+		forceReplicate:    forceReplicate,
+		batchSize:         batchSize,
+	}
+}
+
+// isSafeUpsert returns true if the update can be executed as an upsert, which
+// requires the handle key is unchanged and no other unique key exists, so
+// the upsert never hits any row other than the updated one.
+func isSafeUpsert(preCols, cols []*model.Column) bool {
+	preHandles := make(map[string]interface{})
+	for _, col := range preCols {
+		if col != nil && col.Flag.IsHandleKey() {
+			preHandles[col.Name] = col.Value
+		}
+	}
+	if len(preHandles) == 0 {
+		return false
+	}
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		if !col.Flag.IsHandleKey() {
+			if col.Flag.IsPrimaryKey() || col.Flag.IsUniqueKey() {
+				return false
+			}
+			continue
+		}
+		preValue, ok := preHandles[col.Name]
+		if !ok || preValue == nil || col.Value == nil || !isSameValue(preValue, col.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSameValue(a, b interface{}) bool {
+	if ab, ok := a.([]byte); ok {
+		bb, ok := b.([]byte)
+		return ok && string(ab) == string(bb)
+	}
+	if _, ok := b.([]byte); ok {
+		return false
+	}
+	return a == b
+}
+
+// handleKeyArgs returns the names and the values of the handle key columns,
+// it returns false if the row can't be located by the handle key in an IN list.
+func handleKeyArgs(cols []*model.Column) ([]string, []interface{}, bool) {
+	var names []string
+	var args []interface{}
+	for _, col := range cols {
+		if col == nil || !col.Flag.IsHandleKey() {
+			continue
+		}
+		if col.Value == nil {
+			return nil, nil, false
+		}
+		names = append(names, col.Name)
+		args = append(args, col.Value)
+	}
+	return names, args, len(names) != 0
+}
+
+// append adds a row to the batcher, the current batch is flushed if the row
+// can't be merged into it.
+func (b *dmlBatcher) append(quoteTable string, row *model.RowChangedEvent) {
+	switch {
+	case len(row.PreColumns) != 0 && len(row.Columns) != 0:
+		if !isSafeUpsert(row.PreColumns, row.Columns) {
+			b.appendSingleUpdate(quoteTable, row)
+			return
+		}
+		if b.translateToInsert {
+			b.appendInsert(batchDMLUpsert, quoteTable, row)
+		} else {
+			// REPLACE of the row with the same handle key is the same as
+			// DELETE + REPLACE in safe mode
+			b.appendInsert(batchDMLInsert, quoteTable, row)
+		}
+	case len(row.PreColumns) != 0:
+		names, args, ok := handleKeyArgs(row.PreColumns)
+		if !ok {
+			b.flush()
+			query, args := prepareDelete(quoteTable, row.PreColumns, b.forceReplicate)
+			b.appendSQL(query, args, quoteTable, row.TableInfoVersion)
+			return
+		}
+		prefix := "DELETE FROM " + quoteTable + " WHERE (" + buildColumnList(names) + ") IN ("
+		b.appendBatch(batchDMLDelete, quoteTable, row.TableInfoVersion, prefix, names, args)
+	default:
+		b.appendInsert(batchDMLInsert, quoteTable, row)
+	}
+}
+
+func (b *dmlBatcher) appendInsert(tp batchDMLType, quoteTable string, row *model.RowChangedEvent) {
+	translateToInsert := b.translateToInsert || tp == batchDMLUpsert
+	prefix, args := prepareReplace(quoteTable, row.Columns, false /* appendPlaceHolder */, translateToInsert)
+	if prefix == "" {
+		return
+	}
+	var names []string
+	if tp == batchDMLUpsert {
+		names = make([]string, 0, len(args))
+		for _, col := range row.Columns {
+			if col == nil || col.Flag.IsGeneratedColumn() {
+				continue
+			}
+			names = append(names, col.Name)
+		}
+	}
+	b.appendBatch(tp, quoteTable, row.TableInfoVersion, prefix, names, args)
+}
+
+// appendSingleUpdate adds an update which can't be merged, it's converted in
+// the same way as prepareDMLs without batching.
+func (b *dmlBatcher) appendSingleUpdate(quoteTable string, row *model.RowChangedEvent) {
+	b.flush()
+	if b.translateToInsert {
+		query, args := prepareUpdate(quoteTable, row.PreColumns, row.Columns, b.forceReplicate)
+		b.appendSQL(query, args, quoteTable, row.TableInfoVersion)
+		return
+	}
+	query, args := prepareDelete(quoteTable, row.PreColumns, b.forceReplicate)
+	b.appendSQL(query, args, quoteTable, row.TableInfoVersion)
+	query, args = prepareReplace(quoteTable, row.Columns, true /* appendPlaceHolder */, false /* translateToInsert */)
+	b.appendSQL(query, args, quoteTable, row.TableInfoVersion)
+}
+
+func (b *dmlBatcher) appendBatch(
+	tp batchDMLType, quoteTable string, version uint64, prefix string, colNames []string, args []interface{},
+) {
+	cur := b.cur
+	if cur == nil || cur.tp != tp || cur.prefix != prefix || cur.version != version || len(cur.args) >= b.batchSize {
+		b.flush()
+		b.cur = &dmlBatch{
+			tp:         tp,
+			quoteTable: quoteTable,
+			version:    version,
+			prefix:     prefix,
+			colNames:   colNames,
+		}
+	}
+	b.cur.args = append(b.cur.args, args)
+	b.rowCount++
+}
+
+func (b *dmlBatcher) appendSQL(query string, args []interface{}, quoteTable string, version uint64) {
+	if query == "" {
+		return
+	}
+	b.sqls = append(b.sqls, query)
+	b.values = append(b.values, args)
+	b.stmtKeys = append(b.stmtKeys, stmtKey{quoteTable: quoteTable, version: version})
+	b.rowCount++
+}
+
+// flush converts the current batch to a statement
+func (b *dmlBatcher) flush() {
+	cur := b.cur
+	if cur == nil {
+		return
+	}
+	b.cur = nil
+
+	var builder strings.Builder
+	args := make([]interface{}, 0, len(cur.args)*len(cur.args[0]))
+	builder.WriteString(cur.prefix)
+	for i, rowArgs := range cur.args {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("(" + model.HolderString(len(rowArgs)) + ")")
+		args = append(args, rowArgs...)
+	}
+	switch cur.tp {
+	case batchDMLDelete:
+		builder.WriteString(")")
+	case batchDMLUpsert:
+		builder.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, name := range cur.colNames {
+			if i > 0 {
+				builder.WriteString(",")
+			}
+			quoteName := quotes.QuoteName(name)
+			builder.WriteString(quoteName + "=VALUES(" + quoteName + ")")
+		}
+	}
+	b.sqls = append(b.sqls, builder.String())
+	b.values = append(b.values, args)
+	b.stmtKeys = append(b.stmtKeys, stmtKey{quoteTable: cur.quoteTable, version: cur.version})
+}
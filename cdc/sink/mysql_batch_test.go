@@ -0,0 +1,212 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func newBatchTestColumns(id interface{}, name string) []*model.Column {
+	return []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+		{Name: "name", Type: mysql.TypeVarchar, Value: name},
+	}
+}
+
+func newBatchTestRow(table string, preColumns, columns []*model.Column) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		StartTs:          1,
+		CommitTs:         2,
+		Table:            &model.TableName{Schema: "test", Table: table},
+		TableInfoVersion: 1,
+		PreColumns:       preColumns,
+		Columns:          columns,
+	}
+}
+
+func (s MySQLSinkSuite) TestIsSafeUpsert(c *check.C) {
+	defer testleak.AfterTest(c)()
+	uniqueCols := append(newBatchTestColumns(1, "b"),
+		&model.Column{Name: "uk", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 1})
+	testCases := []struct {
+		preCols  []*model.Column
+		cols     []*model.Column
+		expected bool
+	}{
+		{newBatchTestColumns(1, "a"), newBatchTestColumns(1, "b"), true},
+		{newBatchTestColumns([]byte("1"), "a"), newBatchTestColumns([]byte("1"), "b"), true},
+		// the handle key is changed
+		{newBatchTestColumns(1, "a"), newBatchTestColumns(2, "b"), false},
+		{newBatchTestColumns(nil, "a"), newBatchTestColumns(nil, "b"), false},
+		// other unique keys may be hit
+		{uniqueCols, uniqueCols, false},
+		// no handle key
+		{
+			[]*model.Column{{Name: "a", Type: mysql.TypeLong, Value: 1}},
+			[]*model.Column{{Name: "a", Type: mysql.TypeLong, Value: 2}},
+			false,
+		},
+	}
+	for i, tc := range testCases {
+		c.Assert(isSafeUpsert(tc.preCols, tc.cols), check.Equals, tc.expected, check.Commentf("%d", i))
+	}
+}
+
+func (s MySQLSinkSuite) TestPrepareBatchDMLs(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows := []*model.RowChangedEvent{
+		newBatchTestRow("t1", newBatchTestColumns(1, "a"), nil),
+		newBatchTestRow("t1", newBatchTestColumns(2, "b"), nil),
+		newBatchTestRow("t1", nil, newBatchTestColumns(3, "c")),
+		newBatchTestRow("t1", nil, newBatchTestColumns(4, "d")),
+		newBatchTestRow("t1", nil, newBatchTestColumns(5, "e")),
+		newBatchTestRow("t2", nil, newBatchTestColumns(1, "a")),
+		newBatchTestRow("t1", newBatchTestColumns(3, "c"), newBatchTestColumns(3, "cc")),
+		newBatchTestRow("t1", newBatchTestColumns(4, "d"), newBatchTestColumns(4, "dd")),
+		// the handle key is changed
+		newBatchTestRow("t1", newBatchTestColumns(5, "e"), newBatchTestColumns(6, "e")),
+		newBatchTestRow("t1", newBatchTestColumns(6, "e"), nil),
+	}
+	testCases := []struct {
+		safeMode bool
+		expected *preparedDMLs
+	}{{
+		safeMode: false,
+		expected: &preparedDMLs{
+			sqls: []string{
+				"DELETE FROM `test`.`t1` WHERE (`id`) IN ((?),(?))",
+				"INSERT INTO `test`.`t1`(`id`,`name`) VALUES (?,?),(?,?)",
+				"INSERT INTO `test`.`t1`(`id`,`name`) VALUES (?,?)",
+				"INSERT INTO `test`.`t2`(`id`,`name`) VALUES (?,?)",
+				"INSERT INTO `test`.`t1`(`id`,`name`) VALUES (?,?),(?,?)" +
+					" ON DUPLICATE KEY UPDATE `id`=VALUES(`id`),`name`=VALUES(`name`)",
+				"UPDATE `test`.`t1` SET `id`=?,`name`=? WHERE `id`=? LIMIT 1;",
+				"DELETE FROM `test`.`t1` WHERE (`id`) IN ((?))",
+			},
+			values: [][]interface{}{
+				{1, 2},
+				{3, "c", 4, "d"},
+				{5, "e"},
+				{1, "a"},
+				{3, "cc", 4, "dd"},
+				{6, "e", 5},
+				{6},
+			},
+			rowCount: 10,
+		},
+	}, {
+		safeMode: true,
+		expected: &preparedDMLs{
+			sqls: []string{
+				"DELETE FROM `test`.`t1` WHERE (`id`) IN ((?),(?))",
+				"REPLACE INTO `test`.`t1`(`id`,`name`) VALUES (?,?),(?,?)",
+				"REPLACE INTO `test`.`t1`(`id`,`name`) VALUES (?,?)",
+				"REPLACE INTO `test`.`t2`(`id`,`name`) VALUES (?,?)",
+				"REPLACE INTO `test`.`t1`(`id`,`name`) VALUES (?,?),(?,?)",
+				"DELETE FROM `test`.`t1` WHERE `id` = ? LIMIT 1;",
+				"REPLACE INTO `test`.`t1`(`id`,`name`) VALUES (?,?);",
+				"DELETE FROM `test`.`t1` WHERE (`id`) IN ((?))",
+			},
+			values: [][]interface{}{
+				{1, 2},
+				{3, "c", 4, "d"},
+				{5, "e"},
+				{1, "a"},
+				{3, "cc", 4, "dd"},
+				{5},
+				{6, "e"},
+				{6},
+			},
+			rowCount: 11,
+		},
+	}}
+	for i, tc := range testCases {
+		ms := newMySQLSink4Test(ctx, c)
+		ms.params.enableOldValue = true
+		ms.params.safeMode = tc.safeMode
+		ms.params.batchDMLEnabled = true
+		ms.params.batchDMLSize = 2
+		dmls := ms.prepareDMLs(rows, 0, 0)
+		c.Assert(dmls, check.DeepEquals, tc.expected, check.Commentf("%d", i))
+	}
+}
+
+func (s MySQLSinkSuite) TestStmtCache(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+
+	query := "INSERT INTO `test`.`t1`(`id`,`name`) VALUES (?,?)"
+	cache := newStmtCache(db, 2)
+	key := stmtKey{quoteTable: "`test`.`t1`", version: 1}
+
+	mock.ExpectPrepare(query).WillBeClosed()
+	stmt, err := cache.prepare(ctx, key, query)
+	c.Assert(err, check.IsNil)
+	c.Assert(stmt, check.NotNil)
+	// the statement is cached
+	cached, err := cache.prepare(ctx, key, query)
+	c.Assert(err, check.IsNil)
+	c.Assert(cached, check.Equals, stmt)
+
+	// the statements of the older schema are never prepared
+	stmt, err = cache.prepare(ctx, stmtKey{quoteTable: key.quoteTable, version: 0}, query)
+	c.Assert(err, check.IsNil)
+	c.Assert(stmt, check.IsNil)
+
+	deleteQuery := "DELETE FROM `test`.`t2` WHERE (`id`) IN ((?))"
+	mock.ExpectPrepare(deleteQuery)
+	_, err = cache.prepare(ctx, stmtKey{quoteTable: "`test`.`t2`", version: 1}, deleteQuery)
+	c.Assert(err, check.IsNil)
+
+	// the statements of t1 are closed once a newer version comes
+	key.version = 2
+	mock.ExpectPrepare(query)
+	_, err = cache.prepare(ctx, key, query)
+	c.Assert(err, check.IsNil)
+	c.Assert(cache.count, check.Equals, 2)
+	c.Assert(cache.tables[key.quoteTable].version, check.Equals, uint64(2))
+
+	// the statements exceeding the limit are executed without being prepared
+	stmt, err = cache.prepare(ctx, key, query+",(?,?)")
+	c.Assert(err, check.IsNil)
+	c.Assert(stmt, check.IsNil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(1, "a").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query+",(?,?)").WithArgs(1, "a", 2, "b").WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	c.Assert(err, check.IsNil)
+	_, err = cache.exec(ctx, tx, key, query, []interface{}{1, "a"})
+	c.Assert(err, check.IsNil)
+	_, err = cache.exec(ctx, tx, key, query+",(?,?)", []interface{}{1, "a", 2, "b"})
+	c.Assert(err, check.IsNil)
+	c.Assert(tx.Commit(), check.IsNil)
+
+	cache.Close()
+	c.Assert(cache.count, check.Equals, 0)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
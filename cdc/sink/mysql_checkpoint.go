@@ -0,0 +1,103 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// checkpointTableName is the name of the downstream table recording the commit
+// ts of the last applied transaction of every table, it sits in mark.SchemaName.
+const checkpointTableName string = "checkpoint_v1"
+
+func quoteCheckpointTable() string {
+	return quotes.QuoteSchema(mark.SchemaName, checkpointTableName)
+}
+
+// createCheckpointTable creates the checkpoint table in the downstream if it
+// does not exist.
+func (s *mysqlSink) createCheckpointTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+quotes.QuoteName(mark.SchemaName))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	_, err = s.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+quoteCheckpointTable()+
+		" (changefeed_id VARCHAR(255) NOT NULL, table_id BIGINT NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL,"+
+		" PRIMARY KEY (changefeed_id, table_id))")
+	return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+// LoadTableCheckpoint implements TableCheckpointLoader, it reads the commit ts
+// of the last applied transaction of the table back from the checkpoint table,
+// and returns 0 if there is none or the checkpoint table is disabled.
+func (s *mysqlSink) LoadTableCheckpoint(ctx context.Context, tableID model.TableID) (uint64, error) {
+	if !s.params.checkpointTable {
+		return 0, nil
+	}
+	var commitTs uint64
+	row := s.db.QueryRowContext(ctx,
+		"SELECT commit_ts FROM "+quoteCheckpointTable()+" WHERE changefeed_id = ? AND table_id = ?",
+		s.params.changefeedID, tableID)
+	if err := row.Scan(&commitTs); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	log.Info("table checkpoint loaded from downstream",
+		zap.String("changefeed", s.params.changefeedID), zap.Int64("tableID", tableID), zap.Uint64("commitTs", commitTs))
+	return commitTs, nil
+}
+
+// prepareCheckpointDMLs returns the statements updating the commit ts of the
+// tables of the rows, which are executed in the same transaction as the rows.
+func (s *mysqlSink) prepareCheckpointDMLs(rows []*model.RowChangedEvent) ([]string, [][]interface{}) {
+	// the rows of a table are in the order of the commit ts
+	var tableIDs []model.TableID
+	commitTs := make(map[model.TableID]uint64)
+	for _, row := range rows {
+		tableID := row.Table.TableID
+		if _, ok := commitTs[tableID]; !ok {
+			tableIDs = append(tableIDs, tableID)
+		}
+		commitTs[tableID] = row.CommitTs
+	}
+	query := fmt.Sprintf("INSERT INTO %s (changefeed_id, table_id, commit_ts) VALUES (?,?,?)"+
+		" ON DUPLICATE KEY UPDATE commit_ts = VALUES(commit_ts)", quoteCheckpointTable())
+	sqls := make([]string, 0, len(tableIDs))
+	values := make([][]interface{}, 0, len(tableIDs))
+	for _, tableID := range tableIDs {
+		sqls = append(sqls, query)
+		values = append(values, []interface{}{s.params.changefeedID, tableID, commitTs[tableID]})
+	}
+	return sqls, values
+}
+
+// initCheckpointTable prepares the checkpoint table if it's enabled
+func (s *mysqlSink) initCheckpointTable(ctx context.Context) error {
+	if !s.params.checkpointTable {
+		return nil
+	}
+	return errors.Trace(s.createCheckpointTable(ctx))
+}
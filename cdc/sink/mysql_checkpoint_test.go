@@ -0,0 +1,265 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func (s MySQLSinkSuite) TestNewMySQLSinkCheckpointTable(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	changefeed := "test-changefeed"
+	checkpointSQL := "INSERT INTO `tidb_cdc`.`checkpoint_v1` (changefeed_id, table_id, commit_ts) VALUES (?,?,?)" +
+		" ON DUPLICATE KEY UPDATE commit_ts = VALUES(commit_ts)"
+	dbIndex := 0
+	mockGetDBConn := func(ctx context.Context, dsnStr string) (*sql.DB, error) {
+		defer func() {
+			dbIndex++
+		}()
+		if dbIndex == 0 {
+			// test db
+			db, err := mockTestDB()
+			c.Assert(err, check.IsNil)
+			return db, nil
+		}
+		// normal db
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		c.Assert(err, check.IsNil)
+		mock.ExpectExec("CREATE DATABASE IF NOT EXISTS `tidb_cdc`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS `tidb_cdc`.`checkpoint_v1`" +
+			" (changefeed_id VARCHAR(255) NOT NULL, table_id BIGINT NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL," +
+			" PRIMARY KEY (changefeed_id, table_id))").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		// t2 is added when the sink starts and has no checkpoint
+		mock.ExpectQuery("SELECT commit_ts FROM `tidb_cdc`.`checkpoint_v1` WHERE changefeed_id = ? AND table_id = ?").
+			WithArgs(changefeed, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"commit_ts"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t2`(`a`) VALUES (?)").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(checkpointSQL).
+			WithArgs(changefeed, 2, 4).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// t1 is added after the sink starts, the transaction of t1 at 2 has
+		// been applied before
+		mock.ExpectQuery("SELECT commit_ts FROM `tidb_cdc`.`checkpoint_v1` WHERE changefeed_id = ? AND table_id = ?").
+			WithArgs(changefeed, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"commit_ts"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`) VALUES (?)").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(checkpointSQL).
+			WithArgs(changefeed, 1, 6).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectClose()
+		return db, nil
+	}
+	backupGetDBConn := getDBConnImpl
+	getDBConnImpl = mockGetDBConn
+	defer func() {
+		getDBConnImpl = backupGetDBConn
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:4000/?time-zone=UTC&worker-count=4" +
+		"&checkpoint-table=true&safe-mode=false")
+	c.Assert(err, check.IsNil)
+	rc := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(rc)
+	c.Assert(err, check.IsNil)
+	sink, err := newMySQLSink(ctx, changefeed, sinkURI, f, rc, map[string]string{})
+	c.Assert(err, check.IsNil)
+
+	newRow := func(table string, tableID int64, commitTs uint64, value int) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			StartTs:  commitTs - 1,
+			CommitTs: commitTs,
+			Table:    &model.TableName{Schema: "s1", Table: table, TableID: tableID},
+			Columns: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: value},
+			},
+		}
+	}
+	errCh := make(chan error, 16)
+	manager := NewManager(ctx, sink, errCh, 1)
+	flush := func(resolvedTs uint64, tableSinks ...Sink) {
+		err := retry.Run(time.Millisecond*20, 10, func() error {
+			checkpointTs := resolvedTs
+			for _, tableSink := range tableSinks {
+				ts, err := tableSink.FlushRowChangedEvents(ctx, resolvedTs)
+				c.Assert(err, check.IsNil)
+				if ts < checkpointTs {
+					checkpointTs = ts
+				}
+			}
+			if checkpointTs < resolvedTs {
+				return errors.Errorf("checkpoint ts %d less than resolved ts %d", checkpointTs, resolvedTs)
+			}
+			return nil
+		})
+		c.Assert(err, check.IsNil)
+	}
+
+	t2, err := manager.CreateTableSink(ctx, 2, 1)
+	c.Assert(err, check.IsNil)
+	err = t2.EmitRowChangedEvents(ctx, newRow("t2", 2, 4, 1))
+	c.Assert(err, check.IsNil)
+	flush(4, t2)
+
+	t1, err := manager.CreateTableSink(ctx, 1, 1)
+	c.Assert(err, check.IsNil)
+	err = t1.EmitRowChangedEvents(ctx, newRow("t1", 1, 2, 1), newRow("t1", 1, 6, 3))
+	c.Assert(err, check.IsNil)
+	flush(6, t1, t2)
+
+	err = manager.Close()
+	c.Assert(err, check.IsNil)
+	select {
+	case err := <-errCh:
+		c.Fatal(err)
+	default:
+	}
+}
+
+func (s MySQLSinkSuite) TestMySQLSinkCheckpointTableSubTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	changefeed := "test-changefeed"
+	checkpointSQL := "INSERT INTO `tidb_cdc`.`checkpoint_v1` (changefeed_id, table_id, commit_ts) VALUES (?,?,?)" +
+		" ON DUPLICATE KEY UPDATE commit_ts = VALUES(commit_ts)"
+	dbIndex := 0
+	mockGetDBConn := func(ctx context.Context, dsnStr string) (*sql.DB, error) {
+		defer func() {
+			dbIndex++
+		}()
+		if dbIndex == 0 {
+			// test db
+			db, err := mockTestDB()
+			c.Assert(err, check.IsNil)
+			return db, nil
+		}
+		// normal db
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		c.Assert(err, check.IsNil)
+		mock.ExpectExec("CREATE DATABASE IF NOT EXISTS `tidb_cdc`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS `tidb_cdc`.`checkpoint_v1`" +
+			" (changefeed_id VARCHAR(255) NOT NULL, table_id BIGINT NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL," +
+			" PRIMARY KEY (changefeed_id, table_id))").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		// the checkpoints are not loaded for the sub tables
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`) VALUES (?)").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(checkpointSQL).
+			WithArgs(changefeed, 1, 4).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// the row of the second sub table is behind the checkpoint of the
+		// physical table written by the first sub table, but it's not skipped
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`) VALUES (?)").
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(checkpointSQL).
+			WithArgs(changefeed, 1, 3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectClose()
+		return db, nil
+	}
+	backupGetDBConn := getDBConnImpl
+	getDBConnImpl = mockGetDBConn
+	defer func() {
+		getDBConnImpl = backupGetDBConn
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:4000/?time-zone=UTC&worker-count=4" +
+		"&checkpoint-table=true&safe-mode=false")
+	c.Assert(err, check.IsNil)
+	rc := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(rc)
+	c.Assert(err, check.IsNil)
+	sink, err := newMySQLSink(ctx, changefeed, sinkURI, f, rc, map[string]string{})
+	c.Assert(err, check.IsNil)
+
+	newRow := func(commitTs uint64, value int) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			StartTs:  commitTs - 1,
+			CommitTs: commitTs,
+			Table:    &model.TableName{Schema: "s1", Table: "t1", TableID: 1},
+			Columns: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: value},
+			},
+		}
+	}
+	errCh := make(chan error, 16)
+	manager := NewManager(ctx, sink, errCh, 1)
+	flush := func(resolvedTs uint64, tableSink Sink) {
+		err := retry.Run(time.Millisecond*20, 10, func() error {
+			checkpointTs, err := tableSink.FlushRowChangedEvents(ctx, resolvedTs)
+			c.Assert(err, check.IsNil)
+			if checkpointTs < resolvedTs {
+				return errors.Errorf("checkpoint ts %d less than resolved ts %d", checkpointTs, resolvedTs)
+			}
+			return nil
+		})
+		c.Assert(err, check.IsNil)
+	}
+
+	// the two sub tables of the table 1 are replicated at different positions
+	sub0, err := manager.CreateTableSink(ctx, model.SubTableID(1, 0), 1)
+	c.Assert(err, check.IsNil)
+	err = sub0.EmitRowChangedEvents(ctx, newRow(4, 1))
+	c.Assert(err, check.IsNil)
+	flush(4, sub0)
+	c.Assert(sub0.Close(), check.IsNil)
+
+	sub1, err := manager.CreateTableSink(ctx, model.SubTableID(1, 1), 1)
+	c.Assert(err, check.IsNil)
+	err = sub1.EmitRowChangedEvents(ctx, newRow(3, 2))
+	c.Assert(err, check.IsNil)
+	flush(5, sub1)
+
+	err = manager.Close()
+	c.Assert(err, check.IsNil)
+	select {
+	case err := <-errCh:
+		c.Fatal(err)
+	default:
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// conflictLogTableName is the name of the downstream table recording the
+// conflicts, it sits in mark.SchemaName.
+const conflictLogTableName string = "conflict_log_v1"
+
+// The types of the conflicts
+const (
+	conflictTypeDuplicateKey   = "duplicate-key"
+	conflictTypeNoRowsAffected = "no-rows-affected"
+)
+
+type conflictDMLType int
+
+const (
+	conflictDMLInsert conflictDMLType = iota
+	conflictDMLUpdate
+	conflictDMLDelete
+)
+
+// conflictDML is a statement whose conflict is detected by the rows affected
+type conflictDML struct {
+	tp  conflictDMLType
+	row *model.RowChangedEvent
+	// quoteTable is the quoted downstream name of the table
+	quoteTable string
+	// lastWriteWins is true if the statement is conditioned by the timestamp
+	// column, it's false if the table has no such column
+	lastWriteWins bool
+}
+
+// conflictResolver generates the statements detecting the conflicts, and
+// resolves the conflicts by the configured policy
+type conflictResolver struct {
+	policy          string
+	timestampColumn string
+	logEnabled      bool
+	changefeedID    string
+	forceReplicate  bool
+
+	metricConflictCount map[string]prometheus.Counter
+}
+
+func newConflictResolver(cfg *config.SinkConfig, params *sinkParams, forceReplicate bool) (*conflictResolver, error) {
+	switch cfg.OnConflict {
+	case "":
+		return nil, nil
+	case config.ConflictPolicyOverwrite, config.ConflictPolicyIgnore, config.ConflictPolicyError:
+	case config.ConflictPolicyLastWriteWins:
+		if cfg.ConflictTimestampColumn == "" {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack(
+				"conflict-timestamp-column is required by the %s policy", cfg.OnConflict)
+		}
+	default:
+		return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("unknown on-conflict policy %s", cfg.OnConflict)
+	}
+	if !params.enableOldValue {
+		return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("on-conflict requires enable-old-value")
+	}
+	r := &conflictResolver{
+		policy:              cfg.OnConflict,
+		timestampColumn:     cfg.ConflictTimestampColumn,
+		logEnabled:          cfg.ConflictLog,
+		changefeedID:        params.changefeedID,
+		forceReplicate:      forceReplicate,
+		metricConflictCount: make(map[string]prometheus.Counter, 2),
+	}
+	for _, tp := range []string{conflictTypeDuplicateKey, conflictTypeNoRowsAffected} {
+		r.metricConflictCount[tp] = conflictCounter.WithLabelValues(params.captureAddr, params.changefeedID, tp)
+	}
+	return r, nil
+}
+
+func quoteConflictLogTable() string {
+	return quotes.QuoteSchema(mark.SchemaName, conflictLogTableName)
+}
+
+// createConflictLogTable creates the conflict log table in the downstream if
+// it's enabled and does not exist.
+func (r *conflictResolver) createConflictLogTable(ctx context.Context, db *sql.DB) error {
+	if !r.logEnabled {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+quotes.QuoteName(mark.SchemaName))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+quoteConflictLogTable()+
+		" (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, changefeed_id VARCHAR(255) NOT NULL,"+
+		" schema_name VARCHAR(255) NOT NULL, table_name VARCHAR(255) NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL,"+
+		" conflict_type VARCHAR(32) NOT NULL, policy VARCHAR(32) NOT NULL, row_data LONGTEXT,"+
+		" created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+	return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+func (r *conflictResolver) timestampValue(cols []*model.Column) (interface{}, bool) {
+	if r.policy != config.ConflictPolicyLastWriteWins {
+		return nil, false
+	}
+	for _, col := range cols {
+		if col != nil && col.Name == r.timestampColumn {
+			return col.Value, true
+		}
+	}
+	return nil, false
+}
+
+// withTimestampCondition appends the condition of the timestamp column to the
+// WHERE clause of an UPDATE or DELETE statement generated by prepareUpdate or
+// prepareDelete.
+func (r *conflictResolver) withTimestampCondition(query string, args []interface{}, ts interface{}) (string, []interface{}) {
+	query = strings.TrimSuffix(query, " LIMIT 1;") +
+		" AND " + quotes.QuoteName(r.timestampColumn) + " <= ? LIMIT 1;"
+	return query, append(args, ts)
+}
+
+func prepareInsertIgnore(quoteTable string, cols []*model.Column) (string, []interface{}) {
+	query, args := prepareReplace(quoteTable, cols, true /* appendPlaceHolder */, true /* translateToInsert */)
+	if query == "" {
+		return "", nil
+	}
+	return "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT"), args
+}
+
+// prepareDML converts the row to a statement whose conflict can be detected by
+// the rows affected.
+func (r *conflictResolver) prepareDML(quoteTable string, row *model.RowChangedEvent) (string, []interface{}, *conflictDML) {
+	var query string
+	var args []interface{}
+	dml := &conflictDML{row: row, quoteTable: quoteTable}
+	switch {
+	case len(row.PreColumns) != 0 && len(row.Columns) != 0:
+		dml.tp = conflictDMLUpdate
+		query, args = prepareUpdate(quoteTable, row.PreColumns, row.Columns, r.forceReplicate)
+		if ts, ok := r.timestampValue(row.Columns); ok && query != "" {
+			query, args = r.withTimestampCondition(query, args, ts)
+			dml.lastWriteWins = true
+		}
+	case len(row.PreColumns) != 0:
+		dml.tp = conflictDMLDelete
+		query, args = prepareDelete(quoteTable, row.PreColumns, r.forceReplicate)
+		if ts, ok := r.timestampValue(row.PreColumns); ok && query != "" {
+			query, args = r.withTimestampCondition(query, args, ts)
+			dml.lastWriteWins = true
+		}
+	default:
+		dml.tp = conflictDMLInsert
+		query, args = prepareInsertIgnore(quoteTable, row.Columns)
+		_, dml.lastWriteWins = r.timestampValue(row.Columns)
+	}
+	return query, args, dml
+}
+
+// resolve checks the result of a statement and resolves the conflict in the
+// transaction, it returns the type of the conflict, or an empty string if no
+// conflict happens.
+func (r *conflictResolver) resolve(
+	ctx context.Context, tx *sql.Tx, dml *conflictDML, result sql.Result,
+) (string, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if affected != 0 {
+		return "", nil
+	}
+	conflictType := conflictTypeNoRowsAffected
+	if dml.tp == conflictDMLInsert {
+		conflictType = conflictTypeDuplicateKey
+	}
+	row := dml.row
+	log.Debug("conflict detected", zap.String("type", conflictType), zap.String("policy", r.policy),
+		zap.Reflect("row", row))
+	if r.policy == config.ConflictPolicyError {
+		return "", cerror.ErrMySQLConflict.GenWithStackByArgs(
+			row.Table.Schema, row.Table.Table, row.CommitTs, conflictType)
+	}
+
+	var query string
+	var args []interface{}
+	quoteTable := dml.quoteTable
+	switch {
+	case r.policy == config.ConflictPolicyIgnore || dml.tp == conflictDMLDelete:
+		// nothing to do, the deleted row is absent or newer than the change
+	case !dml.lastWriteWins:
+		query, args = prepareReplace(quoteTable, row.Columns, true /* appendPlaceHolder */, false /* translateToInsert */)
+	case dml.tp == conflictDMLInsert:
+		// the duplicate row is overwritten only if it is older than the change
+		ts, _ := r.timestampValue(row.Columns)
+		query, args = prepareUpdate(quoteTable, row.Columns, row.Columns, r.forceReplicate)
+		if query != "" {
+			query, args = r.withTimestampCondition(query, args, ts)
+		}
+	default:
+		// the updated row is missing or newer than the change, it's inserted
+		// only if it's missing
+		query, args = prepareInsertIgnore(quoteTable, row.Columns)
+	}
+	if query != "" {
+		log.Debug("resolve conflict", zap.String("sql", query), zap.Any("args", args))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	if r.logEnabled {
+		if err := r.writeConflictLog(ctx, tx, row, conflictType); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	return conflictType, nil
+}
+
+func columnsToMap(cols []*model.Column) map[string]interface{} {
+	if len(cols) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		if b, ok := col.Value.([]byte); ok {
+			m[col.Name] = string(b)
+			continue
+		}
+		m[col.Name] = col.Value
+	}
+	return m
+}
+
+func (r *conflictResolver) writeConflictLog(
+	ctx context.Context, tx *sql.Tx, row *model.RowChangedEvent, conflictType string,
+) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"old": columnsToMap(row.PreColumns),
+		"new": columnsToMap(row.Columns),
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+quoteConflictLogTable()+
+		" (changefeed_id, schema_name, table_name, commit_ts, conflict_type, policy, row_data) VALUES (?,?,?,?,?,?,?)",
+		r.changefeedID, row.Table.Schema, row.Table.Table, row.CommitTs, conflictType, r.policy, string(data))
+	return errors.Trace(err)
+}
+
+// recordConflicts updates the metrics of the conflicts resolved in a
+// committed transaction
+func (r *conflictResolver) recordConflicts(conflictTypes []string) {
+	for _, tp := range conflictTypes {
+		r.metricConflictCount[tp].Inc()
+	}
+}
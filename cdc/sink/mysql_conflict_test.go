@@ -0,0 +1,184 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func newConflictTestRow(preColumns, columns []*model.Column) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		CommitTs:   10,
+		Table:      &model.TableName{Schema: "test", Table: "t1", TableID: 1},
+		PreColumns: preColumns,
+		Columns:    columns,
+	}
+}
+
+func newConflictTestColumns(id int, ts string) []*model.Column {
+	return []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+		{Name: "updated_at", Type: mysql.TypeVarchar, Value: ts},
+	}
+}
+
+func newConflictResolver4Test(c *check.C, policy string, conflictLog bool) *conflictResolver {
+	cfg := &config.SinkConfig{OnConflict: policy, ConflictTimestampColumn: "updated_at", ConflictLog: conflictLog}
+	params := defaultParams.Clone()
+	params.enableOldValue = true
+	params.changefeedID = "test-cf"
+	r, err := newConflictResolver(cfg, params, false)
+	c.Assert(err, check.IsNil)
+	return r
+}
+
+func (s MySQLSinkSuite) TestNewConflictResolver(c *check.C) {
+	defer testleak.AfterTest(c)()
+	params := defaultParams.Clone()
+	params.enableOldValue = true
+	r, err := newConflictResolver(&config.SinkConfig{}, params, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(r, check.IsNil)
+
+	_, err = newConflictResolver(&config.SinkConfig{OnConflict: "unknown"}, params, false)
+	c.Assert(err, check.ErrorMatches, ".*unknown on-conflict policy unknown.*")
+	_, err = newConflictResolver(&config.SinkConfig{OnConflict: config.ConflictPolicyLastWriteWins}, params, false)
+	c.Assert(err, check.ErrorMatches, ".*conflict-timestamp-column is required.*")
+
+	params.enableOldValue = false
+	_, err = newConflictResolver(&config.SinkConfig{OnConflict: config.ConflictPolicyIgnore}, params, false)
+	c.Assert(err, check.ErrorMatches, ".*on-conflict requires enable-old-value.*")
+}
+
+func (s MySQLSinkSuite) TestConflictPrepareDML(c *check.C) {
+	defer testleak.AfterTest(c)()
+	testCases := []struct {
+		policy   string
+		row      *model.RowChangedEvent
+		expected string
+		args     []interface{}
+	}{
+		{
+			config.ConflictPolicyOverwrite,
+			newConflictTestRow(nil, newConflictTestColumns(1, "t2")),
+			"INSERT IGNORE INTO `test`.`t1`(`id`,`updated_at`) VALUES (?,?);",
+			[]interface{}{1, "t2"},
+		},
+		{
+			config.ConflictPolicyOverwrite,
+			newConflictTestRow(newConflictTestColumns(1, "t1"), newConflictTestColumns(1, "t2")),
+			"UPDATE `test`.`t1` SET `id`=?,`updated_at`=? WHERE `id`=? LIMIT 1;",
+			[]interface{}{1, "t2", 1},
+		},
+		{
+			config.ConflictPolicyLastWriteWins,
+			newConflictTestRow(newConflictTestColumns(1, "t1"), newConflictTestColumns(1, "t2")),
+			"UPDATE `test`.`t1` SET `id`=?,`updated_at`=? WHERE `id`=? AND `updated_at` <= ? LIMIT 1;",
+			[]interface{}{1, "t2", 1, "t2"},
+		},
+		{
+			config.ConflictPolicyLastWriteWins,
+			newConflictTestRow(newConflictTestColumns(1, "t1"), nil),
+			"DELETE FROM `test`.`t1` WHERE `id` = ? AND `updated_at` <= ? LIMIT 1;",
+			[]interface{}{1, "t1"},
+		},
+	}
+	for _, tc := range testCases {
+		r := newConflictResolver4Test(c, tc.policy, false)
+		query, args, _ := r.prepareDML("`test`.`t1`", tc.row)
+		c.Assert(query, check.Equals, tc.expected)
+		c.Assert(args, check.DeepEquals, tc.args)
+	}
+}
+
+func (s MySQLSinkSuite) TestConflictResolve(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+
+	insert := newConflictTestRow(nil, newConflictTestColumns(1, "t2"))
+	update := newConflictTestRow(newConflictTestColumns(1, "t1"), newConflictTestColumns(1, "t2"))
+	testCases := []struct {
+		policy       string
+		conflictLog  bool
+		row          *model.RowChangedEvent
+		conflictType string
+		// the statement resolving the conflict
+		expected string
+		args     []interface{}
+	}{
+		{
+			config.ConflictPolicyOverwrite, true, insert, conflictTypeDuplicateKey,
+			"REPLACE INTO `test`.`t1`(`id`,`updated_at`) VALUES (?,?);", []interface{}{1, "t2"},
+		},
+		{config.ConflictPolicyIgnore, false, update, conflictTypeNoRowsAffected, "", nil},
+		{
+			config.ConflictPolicyLastWriteWins, false, insert, conflictTypeDuplicateKey,
+			"UPDATE `test`.`t1` SET `id`=?,`updated_at`=? WHERE `id`=? AND `updated_at` <= ? LIMIT 1;",
+			[]interface{}{1, "t2", 1, "t2"},
+		},
+		{
+			config.ConflictPolicyLastWriteWins, false, update, conflictTypeNoRowsAffected,
+			"INSERT IGNORE INTO `test`.`t1`(`id`,`updated_at`) VALUES (?,?);", []interface{}{1, "t2"},
+		},
+	}
+	for _, tc := range testCases {
+		r := newConflictResolver4Test(c, tc.policy, tc.conflictLog)
+		_, _, dml := r.prepareDML("`test`.`t1`", tc.row)
+		mock.ExpectBegin()
+		if tc.expected != "" {
+			mock.ExpectExec(tc.expected).WithArgs(toDriverValues(tc.args)...).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		if tc.conflictLog {
+			mock.ExpectExec("INSERT INTO `tidb_cdc`.`conflict_log_v1` (changefeed_id, schema_name, table_name,"+
+				" commit_ts, conflict_type, policy, row_data) VALUES (?,?,?,?,?,?,?)").
+				WithArgs("test-cf", "test", "t1", 10, tc.conflictType, tc.policy,
+					`{"new":{"id":1,"updated_at":"t2"},"old":null}`).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		tx, err := db.Begin()
+		c.Assert(err, check.IsNil)
+		// no conflict
+		conflictType, err := r.resolve(ctx, tx, dml, sqlmock.NewResult(0, 1))
+		c.Assert(err, check.IsNil)
+		c.Assert(conflictType, check.Equals, "")
+		conflictType, err = r.resolve(ctx, tx, dml, sqlmock.NewResult(0, 0))
+		c.Assert(err, check.IsNil)
+		c.Assert(conflictType, check.Equals, tc.conflictType)
+		c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+	}
+
+	r := newConflictResolver4Test(c, config.ConflictPolicyError, false)
+	_, _, dml := r.prepareDML("`test`.`t1`", update)
+	_, err = r.resolve(ctx, nil, dml, sqlmock.NewResult(0, 0))
+	c.Assert(err, check.ErrorMatches, ".*ErrMySQLConflict.*conflict of table test.t1 at commit ts 10: no-rows-affected.*")
+}
+
+func toDriverValues(args []interface{}) []driver.Value {
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg)
+	}
+	return values
+}
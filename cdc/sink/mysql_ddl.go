@@ -0,0 +1,253 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+// The types of the downstream of the MySQL sink
+const (
+	downstreamTypeTiDB  = "tidb"
+	downstreamTypeMySQL = "mysql"
+)
+
+// ddlActionTypes maps the names of the DDL types to the action types
+var ddlActionTypes = func() map[string]timodel.ActionType {
+	types := make(map[string]timodel.ActionType)
+	for tp := timodel.ActionNone + 1; tp < timodel.ActionType(255); tp++ {
+		if name := tp.String(); name != "none" {
+			types[name] = tp
+		}
+	}
+	return types
+}()
+
+type ddlRule struct {
+	filter.Filter
+	types       map[timodel.ActionType]struct{}
+	skip        bool
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func (r *ddlRule) match(ddl *model.DDLEvent) bool {
+	if !r.MatchTable(ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+		return false
+	}
+	if len(r.types) == 0 {
+		return true
+	}
+	_, ok := r.types[ddl.Type]
+	return ok
+}
+
+// ddlTransformer transforms the DDLs before they are executed by the MySQL
+// sink. The DDL rules are applied first, then the TiDB specific syntax is
+// stripped if the downstream is MySQL. A nil transformer keeps all the DDLs.
+type ddlTransformer struct {
+	rules []*ddlRule
+	// stripTiDBSyntax is true if the downstream is MySQL
+	stripTiDBSyntax bool
+}
+
+// newDDLTransformer creates a ddlTransformer, it returns nil if the DDLs need
+// no transformation.
+func newDDLTransformer(cfg *config.ReplicaConfig, downstreamType string) (*ddlTransformer, error) {
+	stripTiDBSyntax := downstreamType == downstreamTypeMySQL
+	if len(cfg.Sink.DDLRules) == 0 && !stripTiDBSyntax {
+		return nil, nil
+	}
+	rules := make([]*ddlRule, 0, len(cfg.Sink.DDLRules))
+	for _, ruleConfig := range cfg.Sink.DDLRules {
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		rule := &ddlRule{Filter: f, skip: ruleConfig.Skip, replacement: ruleConfig.Replacement}
+		if len(ruleConfig.DDLTypes) != 0 {
+			rule.types = make(map[timodel.ActionType]struct{}, len(ruleConfig.DDLTypes))
+			for _, name := range ruleConfig.DDLTypes {
+				tp, ok := ddlActionTypes[strings.ToLower(name)]
+				if !ok {
+					return nil, cerror.ErrInvalidDDLRule.GenWithStackByArgs(ruleConfig.Matcher, "unknown DDL type "+name)
+				}
+				rule.types[tp] = struct{}{}
+			}
+		}
+		switch {
+		case rule.skip && ruleConfig.Pattern != "":
+			return nil, cerror.ErrInvalidDDLRule.GenWithStackByArgs(ruleConfig.Matcher, "skip and pattern are exclusive")
+		case !rule.skip && ruleConfig.Pattern == "":
+			return nil, cerror.ErrInvalidDDLRule.GenWithStackByArgs(ruleConfig.Matcher, "either skip or pattern is required")
+		case !rule.skip:
+			rule.pattern, err = regexp.Compile(ruleConfig.Pattern)
+			if err != nil {
+				return nil, cerror.ErrInvalidDDLRule.GenWithStackByArgs(ruleConfig.Matcher, err.Error())
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return &ddlTransformer{rules: rules, stripTiDBSyntax: stripTiDBSyntax}, nil
+}
+
+// transform returns the query to execute in the downstream, it returns an
+// empty query if the DDL should be skipped.
+func (t *ddlTransformer) transform(ddl *model.DDLEvent) (string, error) {
+	if t == nil {
+		return ddl.Query, nil
+	}
+	query := ddl.Query
+	for _, rule := range t.rules {
+		if !rule.match(ddl) {
+			continue
+		}
+		if rule.skip {
+			return "", nil
+		}
+		query = rule.pattern.ReplaceAllString(query, rule.replacement)
+	}
+	if !t.stripTiDBSyntax {
+		return query, nil
+	}
+	return stripTiDBSyntax(query)
+}
+
+// stripTiDBSyntax removes the TiDB specific options from the DDL, such as
+// AUTO_RANDOM and SHARD_ROW_ID_BITS, so that it can be executed by MySQL. It
+// returns an empty query if nothing is left to execute in MySQL.
+func stripTiDBSyntax(query string) (string, error) {
+	stmt, err := parser.New().ParseOneStmt(query, "", "")
+	if err != nil {
+		return "", cerror.ErrMySQLTransformDDL.Wrap(err).GenWithStackByArgs(query)
+	}
+	v := &tidbSyntaxStripper{}
+	stmt.Accept(v)
+	if v.skip {
+		return "", nil
+	}
+	if !v.stripped {
+		return query, nil
+	}
+	var sb strings.Builder
+	if err := stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", cerror.ErrMySQLTransformDDL.Wrap(err).GenWithStackByArgs(query)
+	}
+	return sb.String(), nil
+}
+
+// tidbSyntaxStripper removes the TiDB specific syntax from a DDL statement
+type tidbSyntaxStripper struct {
+	stripped bool
+	// skip is true if the whole statement is TiDB specific
+	skip bool
+}
+
+func (v *tidbSyntaxStripper) stripTableOptions(options []*ast.TableOption) []*ast.TableOption {
+	kept := options[:0]
+	for _, option := range options {
+		switch option.Tp {
+		case ast.TableOptionShardRowID, ast.TableOptionPreSplitRegion,
+			ast.TableOptionAutoIdCache, ast.TableOptionAutoRandomBase:
+			v.stripped = true
+		default:
+			kept = append(kept, option)
+		}
+	}
+	return kept
+}
+
+func (v *tidbSyntaxStripper) stripAlterTableSpecs(specs []*ast.AlterTableSpec) []*ast.AlterTableSpec {
+	kept := specs[:0]
+	for _, spec := range specs {
+		switch spec.Tp {
+		case ast.AlterTableOption:
+			spec.Options = v.stripTableOptions(spec.Options)
+			if len(spec.Options) == 0 {
+				continue
+			}
+		case ast.AlterTableSetTiFlashReplica, ast.AlterTablePlacement,
+			ast.AlterTableAddStatistics, ast.AlterTableDropStatistics:
+			v.stripped = true
+			continue
+		case ast.AlterTableAlterPartition:
+			if len(spec.PlacementSpecs) != 0 {
+				v.stripped = true
+				continue
+			}
+		}
+		kept = append(kept, spec)
+	}
+	return kept
+}
+
+func (v *tidbSyntaxStripper) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.CreateSequenceStmt, *ast.AlterSequenceStmt, *ast.DropSequenceStmt,
+		*ast.RecoverTableStmt, *ast.FlashBackTableStmt:
+		v.skip = true
+		return n, true
+	case *ast.CreateTableStmt:
+		node.Options = v.stripTableOptions(node.Options)
+	case *ast.AlterTableStmt:
+		node.Specs = v.stripAlterTableSpecs(node.Specs)
+		if len(node.Specs) == 0 {
+			v.skip = true
+			return n, true
+		}
+	case *ast.ColumnDef:
+		kept := node.Options[:0]
+		for _, option := range node.Options {
+			if option.Tp == ast.ColumnOptionAutoRandom {
+				v.stripped = true
+				continue
+			}
+			kept = append(kept, option)
+		}
+		node.Options = kept
+	case *ast.ColumnOption:
+		if node.PrimaryKeyTp != timodel.PrimaryKeyTypeDefault {
+			node.PrimaryKeyTp = timodel.PrimaryKeyTypeDefault
+			v.stripped = true
+		}
+	case *ast.Constraint:
+		option := node.Option
+		if option != nil && option.PrimaryKeyTp != timodel.PrimaryKeyTypeDefault {
+			option.PrimaryKeyTp = timodel.PrimaryKeyTypeDefault
+			v.stripped = true
+			if option.KeyBlockSize == 0 && option.Tp == timodel.IndexTypeInvalid && option.Comment == "" &&
+				option.ParserName.L == "" && option.Visibility == ast.IndexVisibilityDefault {
+				node.Option = nil
+			}
+		}
+	}
+	return n, false
+}
+
+func (v *tidbSyntaxStripper) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
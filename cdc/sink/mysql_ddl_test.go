@@ -0,0 +1,208 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func (s MySQLSinkSuite) TestNewDDLTransformer(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	t, err := newDDLTransformer(cfg, downstreamTypeTiDB)
+	c.Assert(err, check.IsNil)
+	c.Assert(t, check.IsNil)
+	t, err = newDDLTransformer(cfg, downstreamTypeMySQL)
+	c.Assert(err, check.IsNil)
+	c.Assert(t, check.NotNil)
+
+	testCases := []struct {
+		rule     *config.DDLRule
+		errorMsg string
+	}{
+		{&config.DDLRule{Matcher: []string{"test.*"}, DDLTypes: []string{"add foo"}, Skip: true}, ".*unknown DDL type add foo.*"},
+		{&config.DDLRule{Matcher: []string{"test.*"}, Skip: true, Pattern: "a"}, ".*skip and pattern are exclusive.*"},
+		{&config.DDLRule{Matcher: []string{"test.*"}}, ".*either skip or pattern is required.*"},
+		{&config.DDLRule{Matcher: []string{"test.*"}, Pattern: "("}, ".*missing closing.*"},
+		{&config.DDLRule{Matcher: []string{"[test.*"}, Skip: true}, ".*ErrFilterRuleInvalid.*"},
+	}
+	for _, tc := range testCases {
+		cfg.Sink.DDLRules = []*config.DDLRule{tc.rule}
+		_, err = newDDLTransformer(cfg, downstreamTypeTiDB)
+		c.Assert(err, check.ErrorMatches, tc.errorMsg)
+	}
+}
+
+func (s MySQLSinkSuite) TestDDLRules(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.DDLRules = []*config.DDLRule{
+		{Matcher: []string{"test.*"}, DDLTypes: []string{"Add Index", "drop index"}, Skip: true},
+		{Matcher: []string{"test.*"}, DDLTypes: []string{"create table"}, Pattern: `(?i)ENGINE\s*=\s*\w+`, Replacement: "ENGINE=InnoDB"},
+		{Matcher: []string{"test.*"}, Pattern: `/\*.*?\*/\s*`},
+	}
+	t, err := newDDLTransformer(cfg, downstreamTypeTiDB)
+	c.Assert(err, check.IsNil)
+
+	testCases := []struct {
+		schema, table string
+		tp            timodel.ActionType
+		query         string
+		expected      string
+	}{
+		{"test", "t1", timodel.ActionAddIndex, "ALTER TABLE t1 ADD INDEX idx(a)", ""},
+		{"test", "t1", timodel.ActionCreateTable, "CREATE TABLE t1 (a INT) /* comment */ ENGINE = MyISAM", "CREATE TABLE t1 (a INT) ENGINE=InnoDB"},
+		{"other", "t1", timodel.ActionAddIndex, "ALTER TABLE t1 ADD INDEX idx(a)", "ALTER TABLE t1 ADD INDEX idx(a)"},
+		{"test", "", timodel.ActionCreateSchema, "CREATE DATABASE /* comment */ test", "CREATE DATABASE test"},
+	}
+	for _, tc := range testCases {
+		query, err := t.transform(&model.DDLEvent{
+			TableInfo: &model.SimpleTableInfo{Schema: tc.schema, Table: tc.table},
+			Type:      tc.tp,
+			Query:     tc.query,
+		})
+		c.Assert(err, check.IsNil)
+		c.Assert(query, check.Equals, tc.expected, check.Commentf("%s", tc.query))
+	}
+}
+
+func (s MySQLSinkSuite) TestStripTiDBSyntax(c *check.C) {
+	defer testleak.AfterTest(c)()
+	testCases := []struct {
+		query    string
+		expected string
+	}{{
+		query:    "CREATE TABLE t1 (id BIGINT PRIMARY KEY AUTO_RANDOM(5), a INT) AUTO_RANDOM_BASE = 100",
+		expected: "CREATE TABLE `t1` (`id` BIGINT PRIMARY KEY,`a` INT)",
+	}, {
+		query:    "CREATE TABLE t1 (id INT, a INT, PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */) SHARD_ROW_ID_BITS = 4 PRE_SPLIT_REGIONS = 2",
+		expected: "CREATE TABLE `t1` (`id` INT,`a` INT,PRIMARY KEY(`id`))",
+	}, {
+		query:    "CREATE TABLE t1 (id INT PRIMARY KEY NONCLUSTERED) AUTO_ID_CACHE 100 COMMENT 'test'",
+		expected: "CREATE TABLE `t1` (`id` INT PRIMARY KEY) COMMENT = 'test'",
+	}, {
+		query:    "ALTER TABLE t1 SHARD_ROW_ID_BITS = 4, ADD COLUMN b INT",
+		expected: "ALTER TABLE `t1` ADD COLUMN `b` INT",
+	}, {
+		query:    "ALTER TABLE t1 SET TIFLASH REPLICA 1",
+		expected: "",
+	}, {
+		query:    "CREATE SEQUENCE seq START 10",
+		expected: "",
+	}, {
+		query:    "RECOVER TABLE t1",
+		expected: "",
+	}, {
+		// the query is kept if there is no TiDB specific syntax
+		query:    "create table t1 (id int primary key, a varchar(10) not null default '')",
+		expected: "create table t1 (id int primary key, a varchar(10) not null default '')",
+	}}
+	for _, tc := range testCases {
+		query, err := stripTiDBSyntax(tc.query)
+		c.Assert(err, check.IsNil)
+		c.Assert(query, check.Equals, tc.expected, check.Commentf("%s", tc.query))
+	}
+	_, err := stripTiDBSyntax("CREATE TABLE")
+	c.Assert(err, check.ErrorMatches, ".*transform the DDL CREATE TABLE failed.*")
+}
+
+// TestStripTiDBSyntaxCorpus checks the DDLs of the ddl_sequence integration
+// test, which are compatible with MySQL and should be kept as they are.
+func (s MySQLSinkSuite) TestStripTiDBSyntaxCorpus(c *check.C) {
+	defer testleak.AfterTest(c)()
+	data, err := ioutil.ReadFile("../../tests/ddl_sequence/data/prepare.sql")
+	c.Assert(err, check.IsNil)
+	p := parser.New()
+	ddlCount := 0
+	for _, query := range strings.Split(string(data), ";") {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			continue
+		}
+		stmt, err := p.ParseOneStmt(query, "", "")
+		c.Assert(err, check.IsNil)
+		if _, ok := stmt.(ast.DDLNode); !ok {
+			continue
+		}
+		ddlCount++
+		stripped, err := stripTiDBSyntax(query)
+		c.Assert(err, check.IsNil)
+		c.Assert(stripped, check.Equals, query)
+	}
+	c.Assert(ddlCount, check.Greater, 0)
+}
+
+func (s MySQLSinkSuite) TestParseDownstreamType(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:3306/?downstream-type=MySQL")
+	c.Assert(err, check.IsNil)
+	params, err := parseSinkURI(ctx, sinkURI, map[string]string{})
+	c.Assert(err, check.IsNil)
+	c.Assert(params.downstreamType, check.Equals, downstreamTypeMySQL)
+
+	sinkURI, err = url.Parse("mysql://127.0.0.1:3306/")
+	c.Assert(err, check.IsNil)
+	params, err = parseSinkURI(ctx, sinkURI, map[string]string{})
+	c.Assert(err, check.IsNil)
+	c.Assert(params.downstreamType, check.Equals, downstreamTypeTiDB)
+
+	sinkURI, err = url.Parse("mysql://127.0.0.1:3306/?downstream-type=oracle")
+	c.Assert(err, check.IsNil)
+	_, err = parseSinkURI(ctx, sinkURI, map[string]string{})
+	c.Assert(err, check.ErrorMatches, ".*invalid downstream-type oracle.*")
+}
+
+func (s MySQLSinkSuite) TestEmitTransformedDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+	mock.ExpectBegin()
+	mock.ExpectExec("USE `test`;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE `t1` (`id` BIGINT PRIMARY KEY)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ms := newMySQLSink4Test(ctx, c)
+	ms.db = db
+	ms.ddlTransformer, err = newDDLTransformer(config.GetDefaultReplicaConfig(), downstreamTypeMySQL)
+	c.Assert(err, check.IsNil)
+	err = ms.EmitDDLEvent(ctx, &model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "CREATE TABLE t1 (id BIGINT PRIMARY KEY AUTO_RANDOM)",
+		Type:      timodel.ActionCreateTable,
+	})
+	c.Assert(err, check.IsNil)
+	err = ms.EmitDDLEvent(ctx, &model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "ALTER TABLE t1 SET TIFLASH REPLICA 1",
+		Type:      timodel.ActionSetTiFlashReplica,
+	})
+	c.Assert(cerror.ErrDDLEventIgnored.Equal(err), check.IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
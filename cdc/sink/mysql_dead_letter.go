@@ -0,0 +1,296 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// deadLetterTableName is the name of the downstream table recording the rows
+// rejected by the downstream, it sits in mark.SchemaName.
+const deadLetterTableName string = "_cdc_dead_letter"
+
+// deadLetterErrCodes are the MySQL error codes caused by the data of a row,
+// retrying the row never succeeds. The duplicate entry error is not included,
+// it's a conflict which is resolved by the on-conflict policy or stops the
+// replication, writing the row to the dead letter loses data silently.
+var deadLetterErrCodes = map[errors.ErrCode]struct{}{
+	mysql.ErrDataTooLong:                 {},
+	mysql.ErrTruncatedWrongValue:         {},
+	mysql.ErrTruncatedWrongValueForField: {},
+	mysql.ErrWarnDataOutOfRange:          {},
+	mysql.ErrDataOutOfRange:              {},
+	mysql.ErrInvalidCharacterString:      {},
+	mysql.ErrBadNull:                     {},
+	mysql.ErrNoDefaultForField:           {},
+	mysql.ErrNoReferencedRow:             {},
+	mysql.ErrRowIsReferenced:             {},
+	mysql.ErrNoReferencedRow2:            {},
+	mysql.ErrRowIsReferenced2:            {},
+}
+
+// isDeadLetterError returns true if the row is rejected by the downstream with
+// a non-retryable error
+func isDeadLetterError(err error) bool {
+	code, ok := getSQLErrCode(err)
+	if !ok {
+		return false
+	}
+	_, ok = deadLetterErrCodes[code]
+	return ok
+}
+
+// deadLetterRecord is a row written to the dead letter
+type deadLetterRecord struct {
+	Changefeed string                 `json:"changefeed"`
+	Schema     string                 `json:"schema"`
+	Table      string                 `json:"table"`
+	CommitTs   uint64                 `json:"commit-ts"`
+	ErrorCode  int                    `json:"error-code"`
+	Error      string                 `json:"error"`
+	Old        map[string]interface{} `json:"old"`
+	New        map[string]interface{} `json:"new"`
+	RowKey     string                 `json:"row-key"`
+}
+
+// deadLetterRowKey identifies a row in the dead letter together with the
+// changefeed and the commit ts, it's the hash of the table and the handle key
+// of the row, or the whole row if the table has no handle key. The rows of the
+// transactions replayed after a restart are rejected again, they are skipped
+// by the key instead of being written and counted twice.
+func deadLetterRowKey(row *model.RowChangedEvent) (string, error) {
+	cols := row.Columns
+	if len(cols) == 0 {
+		cols = row.PreColumns
+	}
+	var keyCols []*model.Column
+	for _, col := range cols {
+		if col != nil && col.Flag.IsHandleKey() {
+			keyCols = append(keyCols, col)
+		}
+	}
+	if len(keyCols) == 0 {
+		keyCols = cols
+	}
+	data, err := json.Marshal([]interface{}{row.Table.Schema, row.Table.Table, columnsToMap(keyCols)})
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// deadLetterWriter writes the rows rejected by the downstream to a downstream
+// table or a local file
+type deadLetterWriter struct {
+	tp           string
+	changefeedID string
+	db           *sql.DB
+
+	fileMu sync.Mutex
+	file   *os.File
+	// fileKeys are the rows of the changefeed in the dead letter file
+	fileKeys map[deadLetterFileKey]struct{}
+
+	count uint64
+}
+
+func newDeadLetterWriter(
+	ctx context.Context, cfg *config.SinkConfig, changefeedID string, db *sql.DB,
+) (*deadLetterWriter, error) {
+	w := &deadLetterWriter{tp: cfg.DeadLetter, changefeedID: changefeedID, db: db}
+	switch cfg.DeadLetter {
+	case "":
+		return nil, nil
+	case config.DeadLetterTypeTable:
+		if err := w.createTable(ctx); err != nil {
+			return nil, errors.Trace(err)
+		}
+	case config.DeadLetterTypeFile:
+		if cfg.DeadLetterFile == "" {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("dead-letter-file is required by the file dead letter")
+		}
+		file, err := os.OpenFile(cfg.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		w.file = file
+		if w.fileKeys, err = loadDeadLetterFileKeys(cfg.DeadLetterFile, changefeedID); err != nil {
+			_ = file.Close()
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+	default:
+		return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("unknown dead-letter type %s", cfg.DeadLetter)
+	}
+	return w, nil
+}
+
+type deadLetterFileKey struct {
+	commitTs uint64
+	rowKey   string
+}
+
+// loadDeadLetterFileKeys reads the rows of the changefeed written to the dead
+// letter file before
+func loadDeadLetterFileKeys(path string, changefeedID string) (map[deadLetterFileKey]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer file.Close()
+	keys := make(map[deadLetterFileKey]struct{})
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		record := new(deadLetterRecord)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			log.Warn("skip the invalid line in the dead letter file",
+				zap.String("file", path), zap.Error(err))
+			continue
+		}
+		if record.Changefeed == changefeedID && record.RowKey != "" {
+			keys[deadLetterFileKey{commitTs: record.CommitTs, rowKey: record.RowKey}] = struct{}{}
+		}
+	}
+	return keys, errors.Trace(scanner.Err())
+}
+
+func quoteDeadLetterTable() string {
+	return quotes.QuoteSchema(mark.SchemaName, deadLetterTableName)
+}
+
+// createTable creates the dead letter table in the downstream if it does not
+// exist.
+func (w *deadLetterWriter) createTable(ctx context.Context) error {
+	_, err := w.db.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+quotes.QuoteName(mark.SchemaName))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	_, err = w.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+quoteDeadLetterTable()+
+		" (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, changefeed_id VARCHAR(255) NOT NULL,"+
+		" schema_name VARCHAR(255) NOT NULL, table_name VARCHAR(255) NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL,"+
+		" row_key CHAR(64) NOT NULL, error_code INT NOT NULL, error TEXT, row_data LONGTEXT,"+
+		" created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+		" UNIQUE KEY row_key (changefeed_id, commit_ts, row_key))")
+	return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+// write records a row rejected by the downstream with the error, the row
+// already in the dead letter is skipped.
+func (w *deadLetterWriter) write(ctx context.Context, row *model.RowChangedEvent, rowErr error) error {
+	code, _ := getSQLErrCode(rowErr)
+	rowKey, err := deadLetterRowKey(row)
+	if err != nil {
+		return cerror.ErrMySQLDeadLetter.Wrap(err).GenWithStackByCause()
+	}
+	record := &deadLetterRecord{
+		Changefeed: w.changefeedID,
+		Schema:     row.Table.Schema,
+		Table:      row.Table.Table,
+		CommitTs:   row.CommitTs,
+		ErrorCode:  int(code),
+		Error:      errors.Cause(rowErr).Error(),
+		Old:        columnsToMap(row.PreColumns),
+		New:        columnsToMap(row.Columns),
+		RowKey:     rowKey,
+	}
+	log.Warn("write the row rejected by the downstream to the dead letter",
+		zap.String("changefeed", w.changefeedID), zap.String("type", w.tp),
+		zap.Reflect("row", row), zap.Error(rowErr))
+	var written bool
+	if w.tp == config.DeadLetterTypeTable {
+		written, err = w.writeTable(ctx, record)
+	} else {
+		written, err = w.writeFile(record)
+	}
+	if err != nil {
+		return cerror.ErrMySQLDeadLetter.Wrap(err).GenWithStackByCause()
+	}
+	if written {
+		atomic.AddUint64(&w.count, 1)
+	}
+	return nil
+}
+
+// writeTable inserts the record into the dead letter table, it returns false
+// if the row is already in the table.
+func (w *deadLetterWriter) writeTable(ctx context.Context, record *deadLetterRecord) (bool, error) {
+	data, err := json.Marshal(map[string]interface{}{"old": record.Old, "new": record.New})
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	result, err := w.db.ExecContext(ctx, "INSERT IGNORE INTO "+quoteDeadLetterTable()+
+		" (changefeed_id, schema_name, table_name, commit_ts, row_key, error_code, error, row_data) VALUES (?,?,?,?,?,?,?,?)",
+		record.Changefeed, record.Schema, record.Table, record.CommitTs, record.RowKey,
+		record.ErrorCode, record.Error, string(data))
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return affected > 0, nil
+}
+
+// writeFile appends the record to the dead letter file, it returns false if
+// the row is already in the file.
+func (w *deadLetterWriter) writeFile(record *deadLetterRecord) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	w.fileMu.Lock()
+	defer w.fileMu.Unlock()
+	key := deadLetterFileKey{commitTs: record.CommitTs, rowKey: record.RowKey}
+	if _, ok := w.fileKeys[key]; ok {
+		return false, nil
+	}
+	if _, err = w.file.Write(append(data, '\n')); err != nil {
+		return false, errors.Trace(err)
+	}
+	w.fileKeys[key] = struct{}{}
+	return true, nil
+}
+
+// Count returns the number of the rows written to the dead letter
+func (w *deadLetterWriter) Count() uint64 {
+	return atomic.LoadUint64(&w.count)
+}
+
+// Close closes the dead letter file
+func (w *deadLetterWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return errors.Trace(w.file.Close())
+}
@@ -0,0 +1,278 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func (s MySQLSinkSuite) TestIsDeadLetterError(c *check.C) {
+	defer testleak.AfterTest(c)()
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("test"), false},
+		{&dmysql.MySQLError{Number: mysql.ErrDataTooLong}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrNoReferencedRow2}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrLockDeadlock}, false},
+		{&dmysql.MySQLError{Number: mysql.ErrDupEntry}, false},
+		{errors.Trace(&dmysql.MySQLError{Number: mysql.ErrBadNull}), true},
+		{cerror.WrapError(cerror.ErrMySQLTxnError, &dmysql.MySQLError{Number: mysql.ErrBadNull}), true},
+		{dmysql.ErrInvalidConn, false},
+	}
+	for _, tc := range testCases {
+		c.Assert(isDeadLetterError(tc.err), check.Equals, tc.expected, check.Commentf("%v", tc.err))
+	}
+}
+
+func (s MySQLSinkSuite) TestNewDeadLetterWriter(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	w, err := newDeadLetterWriter(ctx, &config.SinkConfig{}, "test-cf", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(w, check.IsNil)
+
+	_, err = newDeadLetterWriter(ctx, &config.SinkConfig{DeadLetter: "unknown"}, "test-cf", nil)
+	c.Assert(err, check.ErrorMatches, ".*unknown dead-letter type unknown.*")
+	_, err = newDeadLetterWriter(ctx, &config.SinkConfig{DeadLetter: config.DeadLetterTypeFile}, "test-cf", nil)
+	c.Assert(err, check.ErrorMatches, ".*dead-letter-file is required.*")
+}
+
+func (s MySQLSinkSuite) TestDeadLetterFile(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	path := filepath.Join(c.MkDir(), "dead_letter.log")
+	w, err := newDeadLetterWriter(ctx, &config.SinkConfig{
+		DeadLetter:     config.DeadLetterTypeFile,
+		DeadLetterFile: path,
+	}, "test-cf", nil)
+	c.Assert(err, check.IsNil)
+
+	row := &model.RowChangedEvent{
+		CommitTs: 10,
+		Table:    &model.TableName{Schema: "test", Table: "t1", TableID: 1},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Value: 1},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte("too long")},
+		},
+	}
+	rowErr := &dmysql.MySQLError{Number: mysql.ErrDataTooLong, Message: "Data too long for column 'name' at row 1"}
+	c.Assert(w.write(ctx, row, errors.Trace(rowErr)), check.IsNil)
+	// the same row is skipped
+	c.Assert(w.write(ctx, row, rowErr), check.IsNil)
+	c.Assert(w.Count(), check.Equals, uint64(1))
+	row2 := *row
+	row2.CommitTs = 11
+	c.Assert(w.write(ctx, &row2, rowErr), check.IsNil)
+	c.Assert(w.Count(), check.Equals, uint64(2))
+	c.Assert(w.Close(), check.IsNil)
+
+	// the rows written before the restart are skipped
+	w, err = newDeadLetterWriter(ctx, &config.SinkConfig{
+		DeadLetter:     config.DeadLetterTypeFile,
+		DeadLetterFile: path,
+	}, "test-cf", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(w.write(ctx, row, rowErr), check.IsNil)
+	c.Assert(w.Count(), check.Equals, uint64(0))
+	c.Assert(w.Close(), check.IsNil)
+	// the rows of the other changefeeds are not
+	w, err = newDeadLetterWriter(ctx, &config.SinkConfig{
+		DeadLetter:     config.DeadLetterTypeFile,
+		DeadLetterFile: path,
+	}, "test-cf-2", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(w.write(ctx, row, rowErr), check.IsNil)
+	c.Assert(w.Count(), check.Equals, uint64(1))
+	c.Assert(w.Close(), check.IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(lines, check.HasLen, 3)
+	rowKey, err := deadLetterRowKey(row)
+	c.Assert(err, check.IsNil)
+	record := new(deadLetterRecord)
+	c.Assert(json.Unmarshal([]byte(lines[0]), record), check.IsNil)
+	c.Assert(record, check.DeepEquals, &deadLetterRecord{
+		Changefeed: "test-cf",
+		Schema:     "test",
+		Table:      "t1",
+		CommitTs:   10,
+		ErrorCode:  mysql.ErrDataTooLong,
+		Error:      rowErr.Error(),
+		New:        map[string]interface{}{"id": float64(1), "name": "too long"},
+		RowKey:     rowKey,
+	})
+}
+
+func (s MySQLSinkSuite) TestDeadLetterRowKey(c *check.C) {
+	defer testleak.AfterTest(c)()
+	newRow := func(table string, a int, b string) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			CommitTs: 10,
+			Table:    &model.TableName{Schema: "test", Table: table},
+			Columns: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: a},
+				{Name: "b", Type: mysql.TypeVarchar, Value: b},
+			},
+		}
+	}
+	rowKey := func(row *model.RowChangedEvent) string {
+		key, err := deadLetterRowKey(row)
+		c.Assert(err, check.IsNil)
+		c.Assert(key, check.HasLen, 64)
+		return key
+	}
+	key := rowKey(newRow("t1", 1, "a"))
+	// only the handle key is used
+	c.Assert(rowKey(newRow("t1", 1, "b")), check.Equals, key)
+	c.Assert(rowKey(newRow("t1", 2, "a")), check.Not(check.Equals), key)
+	c.Assert(rowKey(newRow("t2", 1, "a")), check.Not(check.Equals), key)
+	// the deleted rows use the old values
+	deleted := newRow("t1", 1, "c")
+	deleted.PreColumns, deleted.Columns = deleted.Columns, nil
+	c.Assert(rowKey(deleted), check.Equals, key)
+	// the whole row is used without the handle key
+	row := newRow("t1", 1, "a")
+	row.Columns[0].Flag = 0
+	c.Assert(rowKey(row), check.Not(check.Equals), key)
+	row2 := newRow("t1", 1, "b")
+	row2.Columns[0].Flag = 0
+	c.Assert(rowKey(row2), check.Not(check.Equals), rowKey(row))
+}
+
+func (s MySQLSinkSuite) TestMySQLSinkDeadLetterTable(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	changefeed := "test-changefeed"
+	rowErr := &dmysql.MySQLError{Number: mysql.ErrDataTooLong, Message: "Data too long for column 'b' at row 1"}
+	dbIndex := 0
+	mockGetDBConn := func(ctx context.Context, dsnStr string) (*sql.DB, error) {
+		defer func() {
+			dbIndex++
+		}()
+		if dbIndex == 0 {
+			// test db
+			db, err := mockTestDB()
+			c.Assert(err, check.IsNil)
+			return db, nil
+		}
+		// normal db
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		c.Assert(err, check.IsNil)
+		mock.ExpectExec("CREATE DATABASE IF NOT EXISTS `tidb_cdc`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS `tidb_cdc`.`_cdc_dead_letter`" +
+			" (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, changefeed_id VARCHAR(255) NOT NULL," +
+			" schema_name VARCHAR(255) NOT NULL, table_name VARCHAR(255) NOT NULL, commit_ts BIGINT UNSIGNED NOT NULL," +
+			" row_key CHAR(64) NOT NULL, error_code INT NOT NULL, error TEXT, row_data LONGTEXT," +
+			" created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			" UNIQUE KEY row_key (changefeed_id, commit_ts, row_key))").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		// the transaction is rejected and never retried
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`,`b`) VALUES (?,?),(?,?),(?,?)").
+			WithArgs(1, "a", 2, "too long", 3, "c").
+			WillReturnError(rowErr)
+		mock.ExpectRollback()
+		// the rows are executed one by one
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`,`b`) VALUES (?,?)").
+			WithArgs(1, "a").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`,`b`) VALUES (?,?)").
+			WithArgs(2, "too long").
+			WillReturnError(rowErr)
+		mock.ExpectRollback()
+		mock.ExpectExec("INSERT IGNORE INTO `tidb_cdc`.`_cdc_dead_letter` (changefeed_id, schema_name, table_name,"+
+			" commit_ts, row_key, error_code, error, row_data) VALUES (?,?,?,?,?,?,?,?)").
+			WithArgs(changefeed, "s1", "t1", 2, sqlmock.AnyArg(), mysql.ErrDataTooLong, rowErr.Error(),
+				`{"new":{"a":2,"b":"too long"},"old":null}`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`,`b`) VALUES (?,?)").
+			WithArgs(3, "c").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectClose()
+		return db, nil
+	}
+	backupGetDBConn := getDBConnImpl
+	getDBConnImpl = mockGetDBConn
+	defer func() {
+		getDBConnImpl = backupGetDBConn
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:4000/?time-zone=UTC&worker-count=1&safe-mode=false")
+	c.Assert(err, check.IsNil)
+	rc := config.GetDefaultReplicaConfig()
+	rc.Sink.DeadLetter = config.DeadLetterTypeTable
+	f, err := filter.NewFilter(rc)
+	c.Assert(err, check.IsNil)
+	sink, err := newMySQLSink(ctx, changefeed, sinkURI, f, rc, map[string]string{})
+	c.Assert(err, check.IsNil)
+
+	newRow := func(a int, b string) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			StartTs:  1,
+			CommitTs: 2,
+			Table:    &model.TableName{Schema: "s1", Table: "t1", TableID: 1},
+			Columns: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: a},
+				{Name: "b", Type: mysql.TypeVarchar, Value: b},
+			},
+		}
+	}
+	err = sink.EmitRowChangedEvents(ctx, newRow(1, "a"), newRow(2, "too long"), newRow(3, "c"))
+	c.Assert(err, check.IsNil)
+
+	err = retry.Run(time.Millisecond*20, 10, func() error {
+		ts, err := sink.FlushRowChangedEvents(ctx, 2)
+		c.Assert(err, check.IsNil)
+		if ts < 2 {
+			return errors.Errorf("checkpoint ts %d less than resolved ts 2", ts)
+		}
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(sink.(DeadLetterCounter).DeadLetterCount(), check.Equals, uint64(1))
+
+	err = sink.Close()
+	c.Assert(err, check.IsNil)
+}
@@ -0,0 +1,277 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"strings"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+const (
+	routeSchemaPlaceholder = "{schema}"
+	routeTablePlaceholder  = "{table}"
+)
+
+type routeRule struct {
+	filter.Filter
+	targetSchema string
+	targetTable  string
+	// sharedTable is true if more than one upstream table may be routed to a
+	// downstream table by the rule, and sharedSchema is true if more than one
+	// upstream schema may be routed to a downstream schema
+	sharedTable  bool
+	sharedSchema bool
+}
+
+// tableRouter maps the upstream tables to the downstream schemas and tables
+// by the route rules, a nil router keeps all the upstream names.
+type tableRouter struct {
+	rules []*routeRule
+}
+
+// newTableRouter creates a tableRouter, it returns nil if there is no route rule
+func newTableRouter(cfg *config.ReplicaConfig) (*tableRouter, error) {
+	if len(cfg.Sink.RouteRules) == 0 {
+		return nil, nil
+	}
+	rules := make([]*routeRule, 0, len(cfg.Sink.RouteRules))
+	for _, ruleConfig := range cfg.Sink.RouteRules {
+		if ruleConfig.TargetSchema == "" && ruleConfig.TargetTable == "" {
+			return nil, cerror.ErrInvalidRouteRule.GenWithStackByArgs(
+				ruleConfig.Matcher, "either target-schema or target-table is required")
+		}
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		rules = append(rules, &routeRule{
+			Filter:       f,
+			targetSchema: ruleConfig.TargetSchema,
+			targetTable:  ruleConfig.TargetTable,
+		})
+	}
+	markSharedTargets(cfg.Sink.RouteRules, rules)
+	return &tableRouter{rules: rules}, nil
+}
+
+// isExactMatcher returns true if the matcher matches the exact tables only,
+// the tables are returned.
+func isExactMatcher(matcher []string) ([][2]string, bool) {
+	tables := make([][2]string, 0, len(matcher))
+	for _, pattern := range matcher {
+		if strings.ContainsAny(pattern, "*?[]!@`\\\"") {
+			return nil, false
+		}
+		parts := strings.Split(pattern, ".")
+		if len(parts) != 2 {
+			return nil, false
+		}
+		tables = append(tables, [2]string{parts[0], parts[1]})
+	}
+	return tables, true
+}
+
+// markSharedTargets marks the rules routing more than one upstream table or
+// schema to the same downstream one. A rule shares the downstream tables if
+// the target doesn't contain both the schema and the table of the upstream
+// table, unless it only matches an exact table. The rules with the same fixed
+// target share it too.
+func markSharedTargets(configs []*config.RouteRule, rules []*routeRule) {
+	tableTargets := make(map[[2]string]int)
+	schemaTargets := make(map[string]int)
+	for i, ruleConfig := range configs {
+		rule := rules[i]
+		target := ruleConfig.TargetSchema + ruleConfig.TargetTable
+		keepSchema := ruleConfig.TargetSchema == "" || strings.Contains(target, routeSchemaPlaceholder)
+		keepTable := ruleConfig.TargetTable == "" || strings.Contains(target, routeTablePlaceholder)
+		schemaFixed := ruleConfig.TargetSchema != "" &&
+			!strings.Contains(ruleConfig.TargetSchema, routeSchemaPlaceholder) &&
+			!strings.Contains(ruleConfig.TargetSchema, routeTablePlaceholder)
+		tables, exact := isExactMatcher(ruleConfig.Matcher)
+		schemas := make(map[string]struct{})
+		for _, t := range tables {
+			schemas[t[0]] = struct{}{}
+		}
+		rule.sharedTable = !(keepSchema && keepTable) && (!exact || len(tables) > 1)
+		rule.sharedSchema = schemaFixed && (!exact || len(schemas) > 1)
+		if schemaFixed {
+			schemaTargets[ruleConfig.TargetSchema]++
+			if ruleConfig.TargetTable != "" && !strings.Contains(target, "{") {
+				tableTargets[[2]string{ruleConfig.TargetSchema, ruleConfig.TargetTable}]++
+			}
+		}
+	}
+	for i, ruleConfig := range configs {
+		if schemaTargets[ruleConfig.TargetSchema] > 1 {
+			rules[i].sharedSchema = true
+		}
+		if tableTargets[[2]string{ruleConfig.TargetSchema, ruleConfig.TargetTable}] > 1 {
+			rules[i].sharedTable = true
+		}
+	}
+}
+
+// substitute fills the placeholders of the target expression, the upstream
+// name is kept if the expression is empty.
+func substitute(expression, name, schema, table string) string {
+	if expression == "" {
+		return name
+	}
+	target := strings.ReplaceAll(expression, routeSchemaPlaceholder, schema)
+	return strings.ReplaceAll(target, routeTablePlaceholder, table)
+}
+
+// matchRule returns the first rule matching the upstream table, the table is
+// empty for the schema. The internal tables of TiCDC, such as the syncpoint
+// and cyclic mark tables, are never routed.
+func (r *tableRouter) matchRule(schema, table string) *routeRule {
+	if r == nil || schema == mark.SchemaName {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if rule.MatchTable(schema, table) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// isShared returns true if the downstream table of the upstream table may be
+// shared by other upstream tables, the table is empty for the schema.
+func (r *tableRouter) isShared(schema, table string) bool {
+	rule := r.matchRule(schema, table)
+	if rule == nil {
+		return false
+	}
+	if table == "" {
+		return rule.sharedSchema
+	}
+	return rule.sharedTable
+}
+
+// route returns the downstream schema and table of the upstream table, the
+// table is empty for the schema.
+func (r *tableRouter) route(schema, table string) (string, string) {
+	if rule := r.matchRule(schema, table); rule != nil {
+		if table == "" {
+			if strings.Contains(rule.targetSchema, routeTablePlaceholder) {
+				// the target schema depends on the tables
+				return schema, table
+			}
+			return substitute(rule.targetSchema, schema, schema, table), table
+		}
+		return substitute(rule.targetSchema, schema, schema, table), substitute(rule.targetTable, table, schema, table)
+	}
+	return schema, table
+}
+
+// quoteTable returns the quoted downstream name of the table
+func (r *tableRouter) quoteTable(table *model.TableName) string {
+	schema, name := r.route(table.Schema, table.Table)
+	return quotes.QuoteSchema(schema, name)
+}
+
+// routeDDL returns the downstream schema the DDL is executed in and the DDL
+// query with all the schema and table names routed. The query is empty if the
+// DDL drops, truncates or renames a downstream table or schema shared by
+// other upstream tables, which must be skipped to keep the data of them.
+func (r *tableRouter) routeDDL(ddl *model.DDLEvent) (string, string, error) {
+	schema, _ := r.route(ddl.TableInfo.Schema, ddl.TableInfo.Table)
+	if r == nil {
+		return schema, ddl.Query, nil
+	}
+	stmt, err := parser.New().ParseOneStmt(ddl.Query, "", "")
+	if err != nil {
+		return "", "", cerror.ErrMySQLRouteDDL.Wrap(err).GenWithStackByArgs(ddl.Query)
+	}
+	v := &routeVisitor{router: r, defaultSchema: ddl.TableInfo.Schema}
+	stmt.Accept(v)
+	if v.shared {
+		return "", "", nil
+	}
+	if !v.routed {
+		return ddl.TableInfo.Schema, ddl.Query, nil
+	}
+	var sb strings.Builder
+	if err := stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", "", cerror.ErrMySQLRouteDDL.Wrap(err).GenWithStackByArgs(ddl.Query)
+	}
+	return schema, sb.String(), nil
+}
+
+// routeVisitor routes the schema and table names in a DDL statement. All the
+// table names are qualified by the schemas, because the DDL may be executed
+// in another schema after being routed.
+type routeVisitor struct {
+	router        *tableRouter
+	defaultSchema string
+	routed        bool
+	// destructive is true if the DDL drops, truncates or renames the tables
+	// or the schema, and shared is true if any of them is shared by other
+	// upstream tables in the downstream
+	destructive bool
+	shared      bool
+}
+
+func (v *routeVisitor) routeSchema(name string) string {
+	if name == "" {
+		name = v.defaultSchema
+	}
+	target, _ := v.router.route(name, "")
+	v.routed = v.routed || target != name
+	v.shared = v.shared || (v.destructive && v.router.isShared(name, ""))
+	return target
+}
+
+func (v *routeVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.DropTableStmt, *ast.TruncateTableStmt, *ast.RenameTableStmt:
+		v.destructive = true
+	case *ast.TableName:
+		schema := node.Schema.O
+		if schema == "" {
+			schema = v.defaultSchema
+		}
+		v.shared = v.shared || (v.destructive && v.router.isShared(schema, node.Name.O))
+		targetSchema, targetTable := v.router.route(schema, node.Name.O)
+		v.routed = v.routed || targetSchema != schema || targetTable != node.Name.O
+		node.Schema = timodel.NewCIStr(targetSchema)
+		node.Name = timodel.NewCIStr(targetTable)
+	case *ast.CreateDatabaseStmt:
+		node.Name = v.routeSchema(node.Name)
+	case *ast.AlterDatabaseStmt:
+		node.Name = v.routeSchema(node.Name)
+	case *ast.DropDatabaseStmt:
+		v.destructive = true
+		node.Name = v.routeSchema(node.Name)
+	}
+	return n, false
+}
+
+func (v *routeVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/cyclic"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func newTableRouter4Test(c *check.C) *tableRouter {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.CaseSensitive = false
+	cfg.Sink.RouteRules = []*config.RouteRule{
+		{Matcher: []string{"shard_*.orders_*"}, TargetSchema: "merged", TargetTable: "orders"},
+		{Matcher: []string{"app.*"}, TargetSchema: "{schema}_bak"},
+		{Matcher: []string{"log.*"}, TargetTable: "{schema}_{table}"},
+		{Matcher: []string{"*.*"}, TargetSchema: "{table}_db"},
+	}
+	r, err := newTableRouter(cfg)
+	c.Assert(err, check.IsNil)
+	return r
+}
+
+func (s MySQLSinkSuite) TestNewTableRouter(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	r, err := newTableRouter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(r, check.IsNil)
+
+	cfg.Sink.RouteRules = []*config.RouteRule{{Matcher: []string{"test.*"}}}
+	_, err = newTableRouter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*either target-schema or target-table is required.*")
+	cfg.Sink.RouteRules = []*config.RouteRule{{Matcher: []string{"[test.*"}, TargetSchema: "test"}}
+	_, err = newTableRouter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*ErrFilterRuleInvalid.*")
+}
+
+func (s MySQLSinkSuite) TestTableRoute(c *check.C) {
+	defer testleak.AfterTest(c)()
+	r := newTableRouter4Test(c)
+	testCases := []struct {
+		schema, table             string
+		targetSchema, targetTable string
+	}{
+		{"shard_1", "orders_1", "merged", "orders"},
+		{"SHARD_2", "orders_2", "merged", "orders"},
+		{"app", "users", "app_bak", "users"},
+		{"app", "", "app_bak", ""},
+		{"log", "t1", "log", "log_t1"},
+		{"log", "", "log", ""},
+		{"test", "t1", "t1_db", "t1"},
+		// the target schema depends on the tables
+		{"test", "", "test", ""},
+		// the internal tables are never routed
+		{"tidb_cdc", "syncpoint_v1", "tidb_cdc", "syncpoint_v1"},
+	}
+	for _, tc := range testCases {
+		schema, table := r.route(tc.schema, tc.table)
+		c.Assert(schema, check.Equals, tc.targetSchema, check.Commentf("%v", tc))
+		c.Assert(table, check.Equals, tc.targetTable, check.Commentf("%v", tc))
+	}
+
+	var nilRouter *tableRouter
+	c.Assert(nilRouter.quoteTable(&model.TableName{Schema: "app", Table: "t1"}), check.Equals, "`app`.`t1`")
+	c.Assert(r.quoteTable(&model.TableName{Schema: "app", Table: "t1"}), check.Equals, "`app_bak`.`t1`")
+}
+
+func (s MySQLSinkSuite) TestRouteDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	r := newTableRouter4Test(c)
+	testCases := []struct {
+		schema, table string
+		query         string
+		targetSchema  string
+		targetQuery   string
+	}{{
+		schema:       "shard_1",
+		table:        "orders_1",
+		query:        "CREATE TABLE orders_1 (id INT PRIMARY KEY)",
+		targetSchema: "merged",
+		targetQuery:  "CREATE TABLE `merged`.`orders` (`id` INT PRIMARY KEY)",
+	}, {
+		schema:       "app",
+		table:        "t2",
+		query:        "RENAME TABLE app.t1 TO app.t2",
+		targetSchema: "app_bak",
+		targetQuery:  "RENAME TABLE `app_bak`.`t1` TO `app_bak`.`t2`",
+	}, {
+		schema:       "app",
+		query:        "CREATE DATABASE app",
+		targetSchema: "app_bak",
+		targetQuery:  "CREATE DATABASE `app_bak`",
+	}, {
+		schema:       "log",
+		table:        "t1",
+		query:        "ALTER TABLE t1 ADD COLUMN a INT",
+		targetSchema: "log",
+		targetQuery:  "ALTER TABLE `log`.`log_t1` ADD COLUMN `a` INT",
+	}, {
+		// the query is kept if nothing is routed
+		schema:       "test",
+		query:        "DROP DATABASE test",
+		targetSchema: "test",
+		targetQuery:  "DROP DATABASE test",
+	}}
+	for _, tc := range testCases {
+		ddl := &model.DDLEvent{
+			TableInfo: &model.SimpleTableInfo{Schema: tc.schema, Table: tc.table},
+			Query:     tc.query,
+		}
+		schema, query, err := r.routeDDL(ddl)
+		c.Assert(err, check.IsNil)
+		c.Assert(schema, check.Equals, tc.targetSchema, check.Commentf("%s", tc.query))
+		c.Assert(query, check.Equals, tc.targetQuery, check.Commentf("%s", tc.query))
+	}
+
+	_, _, err := r.routeDDL(&model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "app", Table: "t1"},
+		Query:     "ALTER TABLE",
+	})
+	c.Assert(err, check.ErrorMatches, ".*route the DDL ALTER TABLE failed.*")
+}
+
+func (s MySQLSinkSuite) TestRoutePrepareDMLs(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	ms := newMySQLSink4Test(ctx, c)
+	ms.router = newTableRouter4Test(c)
+	ms.cyclic = cyclic.NewCyclic(&config.CyclicConfig{Enable: true, ReplicaID: 1})
+	newRow := func(schema, table string, id int) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			StartTs:  1,
+			CommitTs: 2,
+			Table:    &model.TableName{Schema: schema, Table: table},
+			PreColumns: []*model.Column{
+				{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+			},
+		}
+	}
+	rows := []*model.RowChangedEvent{newRow("shard_1", "orders_1", 1), newRow("shard_2", "orders_2", 2)}
+	dmls := ms.prepareDMLs(rows, 1, 0)
+	c.Assert(dmls.sqls, check.DeepEquals, []string{
+		"DELETE FROM `merged`.`orders` WHERE `id` = ? LIMIT 1;",
+		"DELETE FROM `merged`.`orders` WHERE `id` = ? LIMIT 1;",
+	})
+	c.Assert(dmls.markSQL, check.Equals, "INSERT INTO `tidb_cdc`.`repl_mark_merged_orders` VALUES"+
+		" (0, 1, 0, 1) ON DUPLICATE KEY UPDATE val = val + 1;")
+
+	ms.params.batchDMLEnabled = true
+	ms.params.batchDMLSize = 2
+	dmls = ms.prepareDMLs(rows, 1, 0)
+	c.Assert(dmls.sqls, check.DeepEquals, []string{"DELETE FROM `merged`.`orders` WHERE (`id`) IN ((?),(?))"})
+}
+
+func (s MySQLSinkSuite) TestRouteExecDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+	mock.ExpectBegin()
+	mock.ExpectExec("USE `merged`;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE `merged`.`orders` ADD COLUMN `a` INT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ms := newMySQLSink4Test(ctx, c)
+	ms.db = db
+	ms.router = newTableRouter4Test(c)
+	err = ms.execDDL(ctx, &model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "shard_1", Table: "orders_1"},
+		Query:     "ALTER TABLE orders_1 ADD COLUMN a INT",
+		Type:      timodel.ActionAddColumn,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestRouteDDLSharedTarget(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.RouteRules = []*config.RouteRule{
+		{Matcher: []string{"shard_*.orders_*"}, TargetSchema: "merged", TargetTable: "orders"},
+		{Matcher: []string{"db_*.*"}, TargetSchema: "db"},
+		{Matcher: []string{"single.t1"}, TargetSchema: "other", TargetTable: "t2"},
+		{Matcher: []string{"a.t1"}, TargetSchema: "m", TargetTable: "t"},
+		{Matcher: []string{"b.t1"}, TargetSchema: "m", TargetTable: "t"},
+		{Matcher: []string{"app.*"}, TargetSchema: "{schema}_bak"},
+	}
+	r, err := newTableRouter(cfg)
+	c.Assert(err, check.IsNil)
+
+	testCases := []struct {
+		schema      string
+		table       string
+		query       string
+		targetQuery string
+	}{{
+		schema: "shard_1",
+		table:  "orders_1",
+		query:  "DROP TABLE orders_1",
+	}, {
+		schema: "shard_1",
+		table:  "orders_1",
+		query:  "TRUNCATE TABLE shard_1.orders_1",
+	}, {
+		schema: "shard_1",
+		table:  "orders_old",
+		query:  "RENAME TABLE shard_1.orders_1 TO shard_1.orders_old",
+	}, {
+		// the table is renamed into the shared table
+		schema: "test",
+		table:  "orders_1",
+		query:  "RENAME TABLE test.t1 TO shard_1.orders_1",
+	}, {
+		schema: "db_1",
+		query:  "DROP DATABASE db_1",
+	}, {
+		// the rules with the same target share it
+		schema: "a",
+		table:  "t1",
+		query:  "DROP TABLE a.t1",
+	}, {
+		// the other DDLs of the shared table are routed
+		schema:      "shard_1",
+		table:       "orders_1",
+		query:       "ALTER TABLE orders_1 ADD COLUMN a INT",
+		targetQuery: "ALTER TABLE `merged`.`orders` ADD COLUMN `a` INT",
+	}, {
+		// the exact table is not shared
+		schema:      "single",
+		table:       "t1",
+		query:       "DROP TABLE single.t1",
+		targetQuery: "DROP TABLE `other`.`t2`",
+	}, {
+		schema:      "app",
+		table:       "t1",
+		query:       "TRUNCATE TABLE t1",
+		targetQuery: "TRUNCATE TABLE `app_bak`.`t1`",
+	}, {
+		schema:      "app",
+		query:       "DROP DATABASE app",
+		targetQuery: "DROP DATABASE `app_bak`",
+	}}
+	for _, tc := range testCases {
+		ddl := &model.DDLEvent{
+			TableInfo: &model.SimpleTableInfo{Schema: tc.schema, Table: tc.table},
+			Query:     tc.query,
+		}
+		_, query, err := r.routeDDL(ddl)
+		c.Assert(err, check.IsNil)
+		c.Assert(query, check.Equals, tc.targetQuery, check.Commentf("%s", tc.query))
+	}
+
+	// the DDL is skipped by the sink without being executed
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+	ms := newMySQLSink4Test(ctx, c)
+	ms.db = db
+	ms.router = r
+	err = ms.execDDLWithMaxRetries(ctx, &model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "shard_1", Table: "orders_1"},
+		Query:     "DROP TABLE orders_1",
+		Type:      timodel.ActionDropTable,
+	}, 3)
+	c.Assert(cerror.ErrDDLEventIgnored.Equal(err), check.IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/mysql"
+	"go.uber.org/zap"
+)
+
+// defaultMaxPreparedStmts is the max number of the server-side statements
+// prepared by a MySQL sink on all of its connections, the statements exceeding
+// the limit are executed without being prepared, which keeps the sink away from
+// the max_prepared_stmt_count of the downstream.
+const defaultMaxPreparedStmts = 1024
+
+// maxCachedStmts returns the max number of the statements cached by a MySQL
+// sink with workerCount connections. A cached statement is prepared on every
+// connection executing it, so the cache is bounded per connection.
+func maxCachedStmts(workerCount int) int {
+	return defaultMaxPreparedStmts / workerCount
+}
+
+// isMaxPreparedStmtCountError returns true if the downstream refuses to prepare
+// more statements because of the max_prepared_stmt_count
+func isMaxPreparedStmtCountError(err error) bool {
+	code, ok := getSQLErrCode(err)
+	return ok && code == mysql.ErrMaxPreparedStmtCountReached
+}
+
+// stmtKey is the table of a statement and the version of the table info which
+// the statement is generated by
+type stmtKey struct {
+	quoteTable string
+	version    uint64
+}
+
+type tableStmts struct {
+	version uint64
+	stmts   map[string]*sql.Stmt
+}
+
+// stmtCache caches the server-side prepared statements of every table, the
+// statements of a table are closed once a row of a newer table info version
+// comes, which means the schema of the table is changed.
+type stmtCache struct {
+	db       *sql.DB
+	maxStmts int
+
+	mu     sync.Mutex
+	count  int
+	tables map[string]*tableStmts
+	// disabled is set once the downstream reaches the max_prepared_stmt_count,
+	// no more statements are prepared after that
+	disabled bool
+}
+
+func newStmtCache(db *sql.DB, maxStmts int) *stmtCache {
+	return &stmtCache{
+		db:       db,
+		maxStmts: maxStmts,
+		tables:   make(map[string]*tableStmts),
+	}
+}
+
+// prepare returns the prepared statement of the query, it returns nil if the
+// query should be executed without being prepared.
+func (c *stmtCache) prepare(ctx context.Context, key stmtKey, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	table, ok := c.tables[key.quoteTable]
+	if !ok || table.version < key.version {
+		if ok {
+			c.closeTableLocked(key.quoteTable, table)
+		}
+		table = &tableStmts{version: key.version, stmts: make(map[string]*sql.Stmt)}
+		c.tables[key.quoteTable] = table
+	}
+	if table.version > key.version {
+		// the statement of an older schema is never cached
+		return nil, nil
+	}
+	if stmt, ok := table.stmts[query]; ok {
+		return stmt, nil
+	}
+	if c.disabled || c.count >= c.maxStmts {
+		return nil, nil
+	}
+	stmt, err := c.db.PrepareContext(ctx, strings.TrimSuffix(query, ";"))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	table.stmts[query] = stmt
+	c.count++
+	return stmt, nil
+}
+
+// exec executes the query in the transaction by the prepared statement
+func (c *stmtCache) exec(
+	ctx context.Context, tx *sql.Tx, key stmtKey, query string, args []interface{},
+) (sql.Result, error) {
+	stmt, err := c.prepare(ctx, key, query)
+	if err != nil {
+		if !isMaxPreparedStmtCountError(err) {
+			return nil, errors.Trace(err)
+		}
+		c.disable(err)
+		return tx.ExecContext(ctx, query, args...)
+	}
+	if stmt == nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	// the statement is prepared again on the connection of the transaction if
+	// it has not been prepared on it
+	result, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	if err != nil && isMaxPreparedStmtCountError(err) {
+		c.disable(err)
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return result, err
+}
+
+// disable stops preparing statements, the statements are executed as plain
+// statements after that
+func (c *stmtCache) disable(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.disabled {
+		log.Warn("the downstream reaches the max prepared statement count, "+
+			"the statements are executed without being prepared", zap.Error(err))
+	}
+	c.disabled = true
+}
+
+func (c *stmtCache) closeTableLocked(quoteTable string, table *tableStmts) {
+	for _, stmt := range table.stmts {
+		if err := stmt.Close(); err != nil {
+			log.Warn("close prepared statement failed", zap.String("table", quoteTable), zap.Error(err))
+		}
+	}
+	c.count -= len(table.stmts)
+	delete(c.tables, quoteTable)
+}
+
+// Close closes all the prepared statements
+func (c *stmtCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for quoteTable, table := range c.tables {
+		c.closeTableLocked(quoteTable, table)
+	}
+}
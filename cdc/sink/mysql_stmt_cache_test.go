@@ -0,0 +1,61 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func (s MySQLSinkSuite) TestMaxCachedStmts(c *check.C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(maxCachedStmts(1), check.Equals, defaultMaxPreparedStmts)
+	c.Assert(maxCachedStmts(16), check.Equals, defaultMaxPreparedStmts/16)
+	// every connection can't prepare any statement
+	c.Assert(maxCachedStmts(defaultMaxPreparedStmts+1), check.Equals, 0)
+}
+
+func (s MySQLSinkSuite) TestStmtCacheMaxPreparedStmtCount(c *check.C) {
+	defer testleak.AfterTest(c)()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+
+	query1 := "INSERT INTO `s1`.`t1`(`a`) VALUES (?)"
+	query2 := "INSERT INTO `s1`.`t2`(`a`) VALUES (?)"
+	mock.ExpectBegin()
+	mock.ExpectPrepare(query1).
+		WillReturnError(&dmysql.MySQLError{Number: mysql.ErrMaxPreparedStmtCountReached})
+	mock.ExpectExec(query1).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	// the statements are not prepared any more
+	mock.ExpectExec(query2).WithArgs(2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	cache := newStmtCache(db, maxCachedStmts(4))
+	tx, err := db.BeginTx(ctx, nil)
+	c.Assert(err, check.IsNil)
+	_, err = cache.exec(ctx, tx, stmtKey{quoteTable: "`s1`.`t1`", version: 1}, query1, []interface{}{1})
+	c.Assert(err, check.IsNil)
+	_, err = cache.exec(ctx, tx, stmtKey{quoteTable: "`s1`.`t2`", version: 1}, query2, []interface{}{2})
+	c.Assert(err, check.IsNil)
+	c.Assert(tx.Commit(), check.IsNil)
+	cache.Close()
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
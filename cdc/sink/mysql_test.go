@@ -372,6 +372,22 @@ func (s MySQLSinkSuite) TestPrepareUpdate(c *check.C) {
 			expectedSQL:  "UPDATE `test`.`t1` SET `a`=?,`b`=? WHERE `a`=? AND `b`=? LIMIT 1;",
 			expectedArgs: []interface{}{2, "test2", 1, "test"},
 		},
+		{
+			// the column b is dropped by the column filter
+			quoteTable: "`test`.`t1`",
+			preCols: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+				nil,
+				{Name: "c", Type: mysql.TypeLong, Flag: 0, Value: 100},
+			},
+			cols: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+				nil,
+				{Name: "c", Type: mysql.TypeLong, Flag: 0, Value: 101},
+			},
+			expectedSQL:  "UPDATE `test`.`t1` SET `a`=?,`c`=? WHERE `a`=? LIMIT 1;",
+			expectedArgs: []interface{}{1, 101, 1},
+		},
 	}
 	for _, tc := range testCases {
 		query, args := prepareUpdate(tc.quoteTable, tc.preCols, tc.cols, false)
@@ -681,6 +697,7 @@ func (s MySQLSinkSuite) TestSinkParamsClone(c *check.C) {
 		writeTimeout:        defaultWriteTimeout,
 		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		downstreamType:      downstreamTypeTiDB,
 	})
 	c.Assert(param2, check.DeepEquals, &sinkParams{
 		changefeedID:        "123",
@@ -693,6 +710,7 @@ func (s MySQLSinkSuite) TestSinkParamsClone(c *check.C) {
 		writeTimeout:        defaultWriteTimeout,
 		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		downstreamType:      downstreamTypeTiDB,
 	})
 }
 
@@ -799,9 +817,12 @@ func (s MySQLSinkSuite) TestParseSinkURI(c *check.C) {
 	expected.changefeedID = "cf-id"
 	expected.captureAddr = "127.0.0.1:8300"
 	expected.tidbTxnMode = "pessimistic"
+	expected.batchDMLEnabled = true
+	expected.batchDMLSize = 32
+	expected.preparedStmtEnabled = true
 	uriStr := "mysql://127.0.0.1:3306/?worker-count=64&max-txn-row=20" +
 		"&batch-replace-enable=true&batch-replace-size=50&safe-mode=true" +
-		"&tidb-txn-mode=pessimistic"
+		"&tidb-txn-mode=pessimistic&batch-dml-enable=true&batch-dml-size=32&prepared-stmt-enable=true"
 	opts := map[string]string{
 		OptChangefeedID: expected.changefeedID,
 		OptCaptureAddr:  expected.captureAddr,
@@ -1370,3 +1391,98 @@ func (s MySQLSinkSuite) TestNewMySQLSink(c *check.C) {
 	err = sink.Close()
 	c.Assert(err, check.IsNil)
 }
+
+func newBenchmarkRows(count int) []*model.RowChangedEvent {
+	rows := make([]*model.RowChangedEvent, 0, count)
+	newColumns := func(id int, value string) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+			{Name: "v", Type: mysql.TypeVarchar, Value: value},
+		}
+	}
+	for i := 0; i < count; i++ {
+		row := &model.RowChangedEvent{
+			StartTs:  1,
+			CommitTs: 2,
+			Table:    &model.TableName{Schema: "test", Table: "t"},
+		}
+		// inserts, updates and deletes of the same table
+		switch i * 3 / count {
+		case 0:
+			row.Columns = newColumns(i, "a")
+		case 1:
+			row.PreColumns = newColumns(i, "a")
+			row.Columns = newColumns(i, "b")
+		default:
+			row.PreColumns = newColumns(i, "b")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func benchmarkPrepareDMLs(b *testing.B, batchDMLEnabled bool) {
+	rows := newBenchmarkRows(defaultMaxTxnRow)
+	params := defaultParams.Clone()
+	params.enableOldValue = true
+	params.safeMode = false
+	params.batchDMLEnabled = batchDMLEnabled
+	params.batchDMLSize = defaultBatchDMLSize
+	ms := &mysqlSink{params: params}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.prepareDMLs(rows, 0, 0)
+	}
+}
+
+func BenchmarkPrepareDMLs(b *testing.B) {
+	benchmarkPrepareDMLs(b, false)
+}
+
+func BenchmarkPrepareBatchDMLs(b *testing.B) {
+	benchmarkPrepareDMLs(b, true)
+}
+
+// benchmarkExecDMLs executes a transaction with a simulated round trip time
+// for every statement, which dominates the latency of a remote downstream.
+func benchmarkExecDMLs(b *testing.B, batchDMLEnabled bool) {
+	const rtt = 100 * time.Microsecond
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(
+		func(expectedSQL, actualSQL string) error { return nil })))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows := newBenchmarkRows(defaultMaxTxnRow)
+	params := defaultParams.Clone()
+	params.enableOldValue = true
+	params.safeMode = false
+	params.batchDMLEnabled = batchDMLEnabled
+	params.batchDMLSize = defaultBatchDMLSize
+	ms := &mysqlSink{db: db, params: params, statistics: NewStatistics(ctx, "test", make(map[string]string))}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dmls := ms.prepareDMLs(rows, 0, 0)
+		b.StopTimer()
+		mock.ExpectBegin()
+		for range dmls.sqls {
+			mock.ExpectExec("").WillDelayFor(rtt).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectCommit()
+		b.StartTimer()
+		if err := ms.execDMLWithMaxRetries(ctx, dmls, 1, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecDMLs(b *testing.B) {
+	benchmarkExecDMLs(b, false)
+}
+
+func BenchmarkExecBatchDMLs(b *testing.B) {
+	benchmarkExecDMLs(b, true)
+}
@@ -44,10 +44,12 @@ type Config struct {
 	Compression     string
 	ClientID        string
 	Credential      *security.Credential
-	// TODO support SASL authentication
+	SASL            *security.SASL
 
 	// control whether to create topic and verify partition number
 	TopicPreProcess bool
+	// send the messages in transactions, see kafkaTransactionalProducer
+	Transactional bool
 }
 
 // NewKafkaConfig returns a default Kafka configuration
@@ -58,6 +60,7 @@ func NewKafkaConfig() Config {
 		ReplicationFactor: 1,
 		Compression:       "none",
 		Credential:        &security.Credential{},
+		SASL:              &security.SASL{},
 		TopicPreProcess:   true,
 	}
 }
@@ -69,10 +72,17 @@ type kafkaSaramaProducer struct {
 	clientLock   sync.RWMutex
 	asyncClient  sarama.AsyncProducer
 	syncClient   sarama.SyncProducer
-	topic        string
 	partitionNum int32
 
-	partitionOffset []struct {
+	// the following fields are used to create or validate topics lazily
+	address string
+	config  Config
+	cfg     *sarama.Config
+
+	// topicLock protects partitionOffset, a topic is added to partitionOffset
+	// once it is created or validated.
+	topicLock       sync.RWMutex
+	partitionOffset map[string][]struct {
 		flushed uint64
 		sent    uint64
 	}
@@ -85,16 +95,60 @@ type kafkaSaramaProducer struct {
 	closed  int32
 }
 
-func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error {
+// getPartitionOffset returns the partition offsets of the topic, the topic
+// is created or validated if it is the first time to be used.
+func (k *kafkaSaramaProducer) getPartitionOffset(topic string) ([]struct {
+	flushed uint64
+	sent    uint64
+}, error) {
+	k.topicLock.RLock()
+	offsets, ok := k.partitionOffset[topic]
+	k.topicLock.RUnlock()
+	if ok {
+		return offsets, nil
+	}
+
+	// The topic is created or validated without holding topicLock, so that
+	// sending messages to the existing topics is not blocked by the network
+	// I/O. The topic may be processed more than once concurrently, which is
+	// harmless because creating an existing topic is tolerated.
+	if k.config.TopicPreProcess {
+		// All topics share the same partition number, so that the dispatchers
+		// can work without knowing the topic.
+		config := k.config
+		config.PartitionNum = k.partitionNum
+		if _, err := kafkaTopicPreProcess(topic, k.address, config, k.cfg); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	k.topicLock.Lock()
+	defer k.topicLock.Unlock()
+	if offsets, ok := k.partitionOffset[topic]; ok {
+		return offsets, nil
+	}
+	offsets = make([]struct {
+		flushed uint64
+		sent    uint64
+	}, k.partitionNum)
+	k.partitionOffset[topic] = offsets
+	return offsets, nil
+}
+
+func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, topic string, message *codec.MQMessage, partition int32) error {
 	k.clientLock.RLock()
 	defer k.clientLock.RUnlock()
+	offsets, err := k.getPartitionOffset(topic)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	msg := &sarama.ProducerMessage{
-		Topic:     k.topic,
+		Topic:     topic,
 		Key:       sarama.ByteEncoder(message.Key),
 		Value:     sarama.ByteEncoder(message.Value),
 		Partition: partition,
 	}
-	msg.Metadata = atomic.AddUint64(&k.partitionOffset[partition].sent, 1)
+	msg.Metadata = atomic.AddUint64(&offsets[partition].sent, 1)
 
 	failpoint.Inject("KafkaSinkAsyncSendError", func() {
 		// simulate sending message to intput channel successfully but flushing
@@ -120,13 +174,16 @@ func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, message *codec.MQ
 	return nil
 }
 
-func (k *kafkaSaramaProducer) SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error {
+func (k *kafkaSaramaProducer) SyncBroadcastMessage(ctx context.Context, topic string, message *codec.MQMessage) error {
 	k.clientLock.RLock()
 	defer k.clientLock.RUnlock()
+	if _, err := k.getPartitionOffset(topic); err != nil {
+		return errors.Trace(err)
+	}
 	msgs := make([]*sarama.ProducerMessage, k.partitionNum)
 	for i := 0; i < int(k.partitionNum); i++ {
 		msgs[i] = &sarama.ProducerMessage{
-			Topic:     k.topic,
+			Topic:     topic,
 			Key:       sarama.ByteEncoder(message.Key),
 			Value:     sarama.ByteEncoder(message.Value),
 			Partition: int32(i),
@@ -144,33 +201,42 @@ func (k *kafkaSaramaProducer) SyncBroadcastMessage(ctx context.Context, message
 }
 
 func (k *kafkaSaramaProducer) Flush(ctx context.Context) error {
-	targetOffsets := make([]uint64, k.partitionNum)
-	for i := 0; i < len(k.partitionOffset); i++ {
-		targetOffsets[i] = atomic.LoadUint64(&k.partitionOffset[i].sent)
-	}
-
-	noEventsToFLush := true
-	for i, target := range targetOffsets {
-		if target > atomic.LoadUint64(&k.partitionOffset[i].flushed) {
-			noEventsToFLush = false
-			break
+	type flushTarget struct {
+		offsets []struct {
+			flushed uint64
+			sent    uint64
 		}
+		targets []uint64
+	}
+	k.topicLock.RLock()
+	flushTargets := make([]flushTarget, 0, len(k.partitionOffset))
+	for _, offsets := range k.partitionOffset {
+		targets := make([]uint64, len(offsets))
+		for i := 0; i < len(offsets); i++ {
+			targets[i] = atomic.LoadUint64(&offsets[i].sent)
+		}
+		flushTargets = append(flushTargets, flushTarget{offsets: offsets, targets: targets})
 	}
-	if noEventsToFLush {
-		// no events to flush
-		return nil
-	}
+	k.topicLock.RUnlock()
 
-	// checkAllPartitionFlushed checks whether data in each partition is flushed
+	// checkAllPartitionFlushed checks whether data in each partition of each
+	// topic is flushed
 	checkAllPartitionFlushed := func() bool {
-		for i, target := range targetOffsets {
-			if target > atomic.LoadUint64(&k.partitionOffset[i].flushed) {
-				return false
+		for _, t := range flushTargets {
+			for i, target := range t.targets {
+				if target > atomic.LoadUint64(&t.offsets[i].flushed) {
+					return false
+				}
 			}
 		}
 		return true
 	}
 
+	if checkAllPartitionFlushed() {
+		// no events to flush
+		return nil
+	}
+
 flushLoop:
 	for {
 		select {
@@ -249,7 +315,10 @@ func (k *kafkaSaramaProducer) run(ctx context.Context) error {
 				continue
 			}
 			flushedOffset := msg.Metadata.(uint64)
-			atomic.StoreUint64(&k.partitionOffset[msg.Partition].flushed, flushedOffset)
+			k.topicLock.RLock()
+			offsets := k.partitionOffset[msg.Topic]
+			k.topicLock.RUnlock()
+			atomic.StoreUint64(&offsets[msg.Partition].flushed, flushedOffset)
 			k.flushedNotifier.Notify()
 		case err := <-k.asyncClient.Errors():
 			// We should not wrap a nil pointer if the pointer is of a subtype of `error`
@@ -315,9 +384,13 @@ func kafkaTopicPreProcess(topic, address string, config Config, cfg *sarama.Conf
 
 var newSaramaConfigImpl = newSaramaConfig
 
-// NewKafkaSaramaProducer creates a kafka sarama producer
+// NewKafkaSaramaProducer creates a kafka sarama producer, the topic is the
+// default topic whose partition number is shared by all topics used by the
+// producer. Other topics are created or validated when they are firstly used.
 func NewKafkaSaramaProducer(ctx context.Context, address string, topic string, config Config, errCh chan error) (*kafkaSaramaProducer, error) {
-	log.Info("Starting kafka sarama producer ...", zap.Reflect("config", config))
+	logConfig := config
+	logConfig.SASL = config.SASL.Redacted()
+	log.Info("Starting kafka sarama producer ...", zap.Reflect("config", logConfig))
 	cfg, err := newSaramaConfigImpl(ctx, config)
 	if err != nil {
 		return nil, err
@@ -350,12 +423,19 @@ func NewKafkaSaramaProducer(ctx context.Context, address string, topic string, c
 	k := &kafkaSaramaProducer{
 		asyncClient:  asyncClient,
 		syncClient:   syncClient,
-		topic:        topic,
 		partitionNum: partitionNum,
-		partitionOffset: make([]struct {
+		address:      address,
+		config:       config,
+		cfg:          cfg,
+		partitionOffset: map[string][]struct {
 			flushed uint64
 			sent    uint64
-		}, partitionNum),
+		}{
+			topic: make([]struct {
+				flushed uint64
+				sent    uint64
+			}, partitionNum),
+		},
 		flushedNotifier: notifier,
 		flushedReceiver: flushedReceiver,
 		closeCh:         make(chan struct{}),
@@ -397,6 +477,17 @@ func kafkaClientID(role, captureAddr, changefeedID, configuredClientID string) (
 	return
 }
 
+// producerIdentityFromCtx returns the role, capture address and changefeed ID
+// of the producer, which identify the producer in a TiCDC cluster
+func producerIdentityFromCtx(ctx context.Context) (role, captureAddr, changefeedID string) {
+	if util.IsOwnerFromCtx(ctx) {
+		role = "owner"
+	} else {
+		role = "processor"
+	}
+	return role, util.CaptureAddrFromCtx(ctx), util.ChangefeedIDFromCtx(ctx)
+}
+
 // NewSaramaConfig return the default config and set the according version and metrics
 func newSaramaConfig(ctx context.Context, c Config) (*sarama.Config, error) {
 	config := sarama.NewConfig()
@@ -405,15 +496,7 @@ func newSaramaConfig(ctx context.Context, c Config) (*sarama.Config, error) {
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidVersion, err)
 	}
-	var role string
-	if util.IsOwnerFromCtx(ctx) {
-		role = "owner"
-	} else {
-		role = "processor"
-	}
-	captureAddr := util.CaptureAddrFromCtx(ctx)
-	changefeedID := util.ChangefeedIDFromCtx(ctx)
-
+	role, captureAddr, changefeedID := producerIdentityFromCtx(ctx)
 	config.ClientID, err = kafkaClientID(role, captureAddr, changefeedID, c.ClientID)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -464,5 +547,63 @@ func newSaramaConfig(ctx context.Context, c Config) (*sarama.Config, error) {
 		}
 	}
 
+	if c.SASL.IsSASLEnabled() {
+		if err := applySASLConfig(config, c.SASL); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	return config, err
 }
+
+// applySASLConfig enables SASL authentication in the sarama config. It is
+// shared by the owner and processor producers since both of them are built
+// through newSaramaConfig.
+func applySASLConfig(config *sarama.Config, s *security.SASL) error {
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Mechanism = sarama.SASLMechanism(s.SASLMechanism)
+	// SaslAuthenticate request is supported since Kafka 1.0.0, use the
+	// handshake v1 protocol if possible.
+	if config.Version.IsAtLeast(sarama.V1_0_0_0) {
+		config.Net.SASL.Version = sarama.SASLHandshakeV1
+	}
+	switch s.SASLMechanism {
+	case security.PlainMechanism:
+		config.Net.SASL.User = s.SASLUser
+		config.Net.SASL.Password = s.SASLPassword
+	case security.SCRAM256Mechanism, security.SCRAM512Mechanism:
+		config.Net.SASL.User = s.SASLUser
+		config.Net.SASL.Password = s.SASLPassword
+		hashGenerator := sha256HashGenerator
+		if s.SASLMechanism == security.SCRAM512Mechanism {
+			hashGenerator = sha512HashGenerator
+		}
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: hashGenerator}
+		}
+	case security.GSSAPIMechanism:
+		config.Net.SASL.GSSAPI = sarama.GSSAPIConfig{
+			KeyTabPath:         s.GSSAPI.KeyTabPath,
+			KerberosConfigPath: s.GSSAPI.KerberosConfigPath,
+			ServiceName:        s.GSSAPI.ServiceName,
+			Username:           s.GSSAPI.Username,
+			Password:           s.GSSAPI.Password,
+			Realm:              s.GSSAPI.Realm,
+			DisablePAFXFAST:    s.GSSAPI.DisablePAFXFAST,
+		}
+		switch s.GSSAPI.AuthType {
+		case security.UserAuth:
+			config.Net.SASL.GSSAPI.AuthType = sarama.KRB5_USER_AUTH
+		case security.KeyTabAuth:
+			config.Net.SASL.GSSAPI.AuthType = sarama.KRB5_KEYTAB_AUTH
+		default:
+			return cerror.ErrKafkaInvalidConfig.GenWithStack("unknown GSSAPI auth type %d", s.GSSAPI.AuthType)
+		}
+	default:
+		return cerror.ErrKafkaInvalidConfig.GenWithStack("unsupported SASL mechanism %s", s.SASLMechanism)
+	}
+	if err := config.Validate(); err != nil {
+		return cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+	}
+	return nil
+}
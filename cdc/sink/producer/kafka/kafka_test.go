@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/xdg/scram"
 )
 
 type kafkaSuite struct{}
@@ -109,12 +110,12 @@ func (s *kafkaSuite) TestSaramaProducer(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(producer.GetPartitionNum(), check.Equals, int32(2))
 	for i := 0; i < 100; i++ {
-		err = producer.SendMessage(ctx, &codec.MQMessage{
+		err = producer.SendMessage(ctx, topic, &codec.MQMessage{
 			Key:   []byte("test-key-1"),
 			Value: []byte("test-value"),
 		}, int32(0))
 		c.Assert(err, check.IsNil)
-		err = producer.SendMessage(ctx, &codec.MQMessage{
+		err = producer.SendMessage(ctx, topic, &codec.MQMessage{
 			Key:   []byte("test-key-1"),
 			Value: []byte("test-value"),
 		}, int32(1))
@@ -146,7 +147,7 @@ func (s *kafkaSuite) TestSaramaProducer(c *check.C) {
 		{100, 100},
 		{100, 100},
 	}
-	c.Assert(producer.partitionOffset, check.DeepEquals, expected)
+	c.Assert(producer.partitionOffset[topic], check.DeepEquals, expected)
 	select {
 	case err := <-errCh:
 		c.Fatalf("unexpected err: %s", err)
@@ -156,7 +157,7 @@ func (s *kafkaSuite) TestSaramaProducer(c *check.C) {
 	err = producer.Flush(ctx)
 	c.Assert(err, check.IsNil)
 
-	err = producer.SyncBroadcastMessage(ctx, &codec.MQMessage{
+	err = producer.SyncBroadcastMessage(ctx, topic, &codec.MQMessage{
 		Key:   []byte("test-broadcast"),
 		Value: nil,
 	})
@@ -171,14 +172,14 @@ func (s *kafkaSuite) TestSaramaProducer(c *check.C) {
 	wg.Wait()
 
 	// check send messages when context is canceled or producer closed
-	err = producer.SendMessage(ctx, &codec.MQMessage{
+	err = producer.SendMessage(ctx, topic, &codec.MQMessage{
 		Key:   []byte("cancel"),
 		Value: nil,
 	}, int32(0))
 	if err != nil {
 		c.Assert(err, check.Equals, context.Canceled)
 	}
-	err = producer.SyncBroadcastMessage(ctx, &codec.MQMessage{
+	err = producer.SyncBroadcastMessage(ctx, topic, &codec.MQMessage{
 		Key:   []byte("cancel"),
 		Value: nil,
 	})
@@ -301,3 +302,208 @@ func (s *kafkaSuite) TestCreateProducerFailed(c *check.C) {
 	_, err = NewKafkaSaramaProducer(ctx, "127.0.0.1:1111", "topic", config, errCh)
 	c.Assert(cerror.ErrKafkaInvalidPartitionNum.Equal(err), check.IsTrue)
 }
+
+func (s *kafkaSuite) TestNewSaramaConfigSASL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	config := NewKafkaConfig()
+	cfg, err := newSaramaConfigImpl(ctx, config)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Net.SASL.Enable, check.IsFalse)
+
+	config.SASL = &security.SASL{
+		SASLUser:      "user",
+		SASLPassword:  "password",
+		SASLMechanism: security.PlainMechanism,
+	}
+	cfg, err = newSaramaConfigImpl(ctx, config)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Net.SASL.Enable, check.IsTrue)
+	c.Assert(cfg.Net.SASL.Mechanism, check.Equals, sarama.SASLMechanism(sarama.SASLTypePlaintext))
+	c.Assert(cfg.Net.SASL.Version, check.Equals, sarama.SASLHandshakeV1)
+	c.Assert(cfg.Net.SASL.User, check.Equals, "user")
+	c.Assert(cfg.Net.SASL.Password, check.Equals, "password")
+
+	for _, mechanism := range []security.SASLMechanism{security.SCRAM256Mechanism, security.SCRAM512Mechanism} {
+		config.SASL.SASLMechanism = mechanism
+		cfg, err = newSaramaConfigImpl(ctx, config)
+		c.Assert(err, check.IsNil)
+		c.Assert(cfg.Net.SASL.Mechanism, check.Equals, sarama.SASLMechanism(mechanism))
+		c.Assert(cfg.Net.SASL.SCRAMClientGeneratorFunc, check.NotNil)
+		c.Assert(cfg.Net.SASL.SCRAMClientGeneratorFunc(), check.FitsTypeOf, &scramClient{})
+	}
+
+	// sasl user is required by SCRAM
+	config.SASL.SASLUser = ""
+	_, err = newSaramaConfigImpl(ctx, config)
+	c.Assert(errors.Cause(err), check.ErrorMatches, ".*Net.SASL.User must not be empty.*")
+
+	config.SASL = &security.SASL{
+		SASLMechanism: security.GSSAPIMechanism,
+		GSSAPI: security.GSSAPI{
+			AuthType:           security.KeyTabAuth,
+			KeyTabPath:         "/var/keytab",
+			KerberosConfigPath: "/etc/krb5.conf",
+			ServiceName:        "kafka",
+			Username:           "alice",
+			Realm:              "EXAMPLE.COM",
+		},
+	}
+	cfg, err = newSaramaConfigImpl(ctx, config)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Net.SASL.Mechanism, check.Equals, sarama.SASLMechanism(sarama.SASLTypeGSSAPI))
+	c.Assert(cfg.Net.SASL.GSSAPI.AuthType, check.Equals, sarama.KRB5_KEYTAB_AUTH)
+	c.Assert(cfg.Net.SASL.GSSAPI.KeyTabPath, check.Equals, "/var/keytab")
+	c.Assert(cfg.Net.SASL.GSSAPI.ServiceName, check.Equals, "kafka")
+
+	config.SASL.GSSAPI.AuthType = security.UnknownAuth
+	_, err = newSaramaConfigImpl(ctx, config)
+	c.Assert(cerror.ErrKafkaInvalidConfig.Equal(err), check.IsTrue)
+}
+
+func (s *kafkaSuite) TestTopicPreProcessWithSASL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	topic := "unit_test_4"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := sarama.NewMockBroker(c, 1)
+	defer broker.Close()
+	authResponse := sarama.NewMockSaslAuthenticateResponse(c)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"SaslHandshakeRequest": sarama.NewMockSaslHandshakeResponse(c).
+			SetEnabledMechanisms([]string{sarama.SASLTypePlaintext}),
+		"SaslAuthenticateRequest": authResponse,
+		"MetadataRequest": sarama.NewMockMetadataResponse(c).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetController(broker.BrokerID()),
+		"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(c),
+	})
+
+	config := NewKafkaConfig()
+	config.SASL = &security.SASL{
+		SASLUser:      "user",
+		SASLPassword:  "password",
+		SASLMechanism: security.PlainMechanism,
+	}
+	cfg, err := newSaramaConfigImpl(ctx, config)
+	c.Assert(err, check.IsNil)
+	num, err := kafkaTopicPreProcess(topic, broker.Addr(), config, cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(num, check.Equals, int32(1))
+
+	// the broker rejects the credential
+	authResponse.SetError(sarama.ErrSASLAuthenticationFailed)
+	cfg.Metadata.Retry.Max = 1
+	_, err = kafkaTopicPreProcess(topic, broker.Addr(), config, cfg)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *kafkaSuite) TestSCRAMClient(c *check.C) {
+	defer testleak.AfterTest(c)()
+	for _, hashGenerator := range []scram.HashGeneratorFcn{sha256HashGenerator, sha512HashGenerator} {
+		client, err := hashGenerator.NewClientUnprepped("user", "password", "")
+		c.Assert(err, check.IsNil)
+		storedCredential := client.GetStoredCredentials(scram.KeyFactors{Salt: "salt", Iters: 4096})
+		server, err := hashGenerator.NewServer(func(user string) (scram.StoredCredentials, error) {
+			if user != "user" {
+				return scram.StoredCredentials{}, errors.New("unknown user")
+			}
+			return storedCredential, nil
+		})
+		c.Assert(err, check.IsNil)
+
+		for _, tc := range []struct {
+			password string
+			success  bool
+		}{{"password", true}, {"wrong-password", false}} {
+			client := &scramClient{HashGeneratorFcn: hashGenerator}
+			err = client.Begin("user", tc.password, "")
+			c.Assert(err, check.IsNil)
+			conversation := server.NewConversation()
+			var challenge string
+			var clientErr, serverErr error
+			for {
+				var response string
+				response, clientErr = client.Step(challenge)
+				if clientErr != nil || client.Done() {
+					break
+				}
+				challenge, serverErr = conversation.Step(response)
+				if serverErr != nil {
+					break
+				}
+			}
+			if tc.success {
+				c.Assert(clientErr, check.IsNil)
+				c.Assert(serverErr, check.IsNil)
+				c.Assert(conversation.Valid(), check.IsTrue)
+			} else {
+				c.Assert(serverErr, check.NotNil)
+				c.Assert(conversation.Valid(), check.IsFalse)
+			}
+		}
+	}
+}
+
+func (s *kafkaSuite) TestProducerLazyCreateTopic(c *check.C) {
+	defer testleak.AfterTest(c)()
+	topic := "unit_test_5"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := sarama.NewMockBroker(c, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(c).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()).
+			SetController(broker.BrokerID()),
+		"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(c),
+		"CreateTopicsRequest":    sarama.NewMockCreateTopicsResponse(c),
+	})
+
+	errCh := make(chan error, 1)
+	config := NewKafkaConfig()
+	producer, err := NewKafkaSaramaProducer(ctx, broker.Addr(), topic, config, errCh)
+	c.Assert(err, check.IsNil)
+	defer producer.Close() //nolint:errcheck
+	c.Assert(producer.GetPartitionNum(), check.Equals, int32(2))
+	c.Assert(producer.partitionOffset, check.HasLen, 1)
+
+	// a new topic is created with the partition number of the default topic
+	offsets, err := producer.getPartitionOffset("unit_test_5_new")
+	c.Assert(err, check.IsNil)
+	c.Assert(offsets, check.HasLen, 2)
+	c.Assert(producer.partitionOffset, check.HasLen, 2)
+
+	// the topic used concurrently shares the same offsets
+	var wg sync.WaitGroup
+	results := make([][]struct {
+		flushed uint64
+		sent    uint64
+	}, 4)
+	for i := range results {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			offsets, err := producer.getPartitionOffset("unit_test_5_concurrent")
+			c.Assert(err, check.IsNil)
+			results[i] = offsets
+		}()
+	}
+	wg.Wait()
+	for _, offsets := range results {
+		c.Assert(&offsets[0], check.Equals, &results[0][0])
+	}
+	c.Assert(producer.partitionOffset, check.HasLen, 3)
+
+	// the topic is not created again
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{})
+	offsets, err = producer.getPartitionOffset("unit_test_5_new")
+	c.Assert(err, check.IsNil)
+	c.Assert(offsets, check.HasLen, 2)
+}
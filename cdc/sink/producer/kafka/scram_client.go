@@ -0,0 +1,61 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+
+	"github.com/pingcap/errors"
+	"github.com/xdg/scram"
+)
+
+var (
+	// sha256HashGenerator is the hash generator for SCRAM-SHA-256
+	sha256HashGenerator scram.HashGeneratorFcn = func() hash.Hash { return sha256.New() }
+	// sha512HashGenerator is the hash generator for SCRAM-SHA-512
+	sha512HashGenerator scram.HashGeneratorFcn = func() hash.Hash { return sha512.New() }
+)
+
+// scramClient implements the sarama.SCRAMClient interface on top of xdg/scram
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+// Begin prepares the client for the SCRAM exchange with the server with a
+// user name and a password
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.Client = client
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+// Step steps client through the SCRAM exchange. It is called repeatedly
+// until it errors or `Done` returns true.
+func (c *scramClient) Step(challenge string) (string, error) {
+	response, err := c.ClientConversation.Step(challenge)
+	return response, errors.Trace(err)
+}
+
+// Done should return true when the SCRAM conversation is over.
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
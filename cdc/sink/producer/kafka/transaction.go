@@ -0,0 +1,926 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// transactionTimeout is the time the transaction coordinator waits before
+	// aborting an ongoing transaction, it must not be larger than the
+	// transaction.max.timeout.ms of the brokers, which is 15 minutes by default.
+	transactionTimeout = 5 * time.Minute
+
+	transactionRetryInterval = 100 * time.Millisecond
+	transactionMaxRetries    = 10
+
+	// transactionMetadataChunkSize is the max size of the offset metadata
+	// committed to a partition, it must not be larger than the
+	// offset.metadata.max.bytes of the brokers, which is 4096 by default.
+	transactionMetadataChunkSize = 4000
+)
+
+// kafkaTransactionalProducer sends the messages in Kafka transactions. The
+// messages are buffered until Flush, and become visible to the read_committed
+// consumers after CommitTransaction.
+//
+// The owner and the processor of a changefeed on every capture have their own
+// transactional IDs derived from the changefeed and the capture, so the
+// producer restarted on the same capture fences the previous one, and its
+// ongoing transaction is aborted. All the messages of the producer are sent in
+// one transaction per flush. The ts passed to CommitTransaction is committed in
+// the transaction as the offsets of the consumer group named by the
+// transactional ID, and the commit ts of the last rows of the tables are
+// committed as the metadata of the offsets. A table moved to another capture
+// is fenced by the commit ts of its last rows committed by all the producers of
+// the changefeed, which is read by CommittedTs once the table is added, so the
+// rows committed before are never sent again.
+type kafkaTransactionalProducer struct {
+	// mu serializes all operations, since the transaction state is shared
+	mu           sync.Mutex
+	client       sarama.Client
+	cfg          *sarama.Config
+	config       Config
+	address      string
+	partitionNum int32
+	changefeedID string
+	// offsetTopic is the topic whose partitions are used to commit the ts of
+	// the transactions and the commit ts of the tables
+	offsetTopic string
+
+	// topics records the topics created or validated
+	topics  map[string]struct{}
+	session *txnSession
+	closed  bool
+}
+
+// txnSession is the transaction state of a transactional ID
+type txnSession struct {
+	transactionalID string
+	coordinators    map[sarama.CoordinatorType]*sarama.Broker
+	initialized     bool
+	producerID      int64
+	producerEpoch   int16
+	// committedTs is the ts committed by the last transaction
+	committedTs uint64
+	// tableTs records the commit ts of the last rows of the tables committed
+	// by the transactions of the transactional ID
+	tableTs map[int64]uint64
+
+	// sequences records the next sequence numbers of the partitions
+	sequences map[string][]int32
+	// buffered records the messages of the partitions not sent yet
+	buffered map[string]map[int32][]*codec.MQMessage
+	// txnPartitions records the partitions added to the ongoing transaction
+	txnPartitions map[string]map[int32]struct{}
+}
+
+func newTxnSession(transactionalID string) *txnSession {
+	return &txnSession{
+		transactionalID: transactionalID,
+		coordinators:    make(map[sarama.CoordinatorType]*sarama.Broker),
+		tableTs:         make(map[int64]uint64),
+	}
+}
+
+// kafkaTransactionalIDPrefix returns the prefix of the transactional IDs of
+// the changefeed
+func kafkaTransactionalIDPrefix(changefeedID string) string {
+	return commonInvalidChar.ReplaceAllString(fmt.Sprintf("TiCDC_transaction_%s_", changefeedID), "_")
+}
+
+// kafkaTransactionalID returns the transactional ID of the owner of the
+// changefeed, or the processor of the changefeed on the capture
+func kafkaTransactionalID(changefeedID, captureAddr string, isOwner bool) string {
+	if isOwner {
+		captureAddr = "owner"
+	}
+	return kafkaTransactionalIDPrefix(changefeedID) + commonInvalidChar.ReplaceAllString(captureAddr, "_")
+}
+
+// NewKafkaTransactionalProducer creates a transactional kafka producer, the
+// topic is the default topic whose partition number is shared by all topics
+// used by the producer.
+func NewKafkaTransactionalProducer(ctx context.Context, address string, topic string, config Config) (*kafkaTransactionalProducer, error) {
+	logConfig := config
+	logConfig.SASL = config.SASL.Redacted()
+	log.Info("Starting kafka transactional producer ...", zap.Reflect("config", logConfig))
+	cfg, err := newSaramaConfigImpl(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack(
+			"kafka transactions require kafka-version 0.11.0.0 or later, but got %s", cfg.Version)
+	}
+	if config.PartitionNum < 0 {
+		return nil, cerror.ErrKafkaInvalidPartitionNum.GenWithStackByArgs(config.PartitionNum)
+	}
+	partitionNum := config.PartitionNum
+	if config.TopicPreProcess {
+		partitionNum, err = kafkaTopicPreProcess(topic, address, config, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	client, err := sarama.NewClient(strings.Split(address, ","), cfg)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+
+	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	k := &kafkaTransactionalProducer{
+		client:       client,
+		cfg:          cfg,
+		config:       config,
+		address:      address,
+		partitionNum: partitionNum,
+		changefeedID: changefeedID,
+		offsetTopic:  topic,
+		topics:       map[string]struct{}{topic: {}},
+		session: newTxnSession(kafkaTransactionalID(
+			changefeedID, util.CaptureAddrFromCtx(ctx), util.IsOwnerFromCtx(ctx))),
+	}
+	return k, nil
+}
+
+// isRetriableTxnError returns true if the transaction request can be retried,
+// the coordinator is reset if it has been moved.
+func (k *kafkaTransactionalProducer) isRetriableTxnError(session *txnSession, tp sarama.CoordinatorType, err sarama.KError) bool {
+	switch err {
+	case sarama.ErrConsumerCoordinatorNotAvailable, sarama.ErrNotCoordinatorForConsumer:
+		k.resetCoordinator(session, tp)
+		return true
+	case sarama.ErrOffsetsLoadInProgress, sarama.ErrConcurrentTransactions:
+		return true
+	}
+	return false
+}
+
+// runTxnRequest retries f on the network errors and the retriable errors
+// returned by the coordinator of the transaction or the consumer group of the
+// session
+func (k *kafkaTransactionalProducer) runTxnRequest(
+	session *txnSession, tp sarama.CoordinatorType, f func(coordinator *sarama.Broker) (sarama.KError, error),
+) error {
+	err := retry.Run(transactionRetryInterval, transactionMaxRetries, func() error {
+		coordinator, err := k.getCoordinator(session, tp)
+		if err != nil {
+			return err
+		}
+		kerr, err := f(coordinator)
+		if err != nil {
+			// the connection may be broken
+			k.resetCoordinator(session, tp)
+			return err
+		}
+		if kerr == sarama.ErrNoError {
+			return nil
+		}
+		if k.isRetriableTxnError(session, tp, kerr) {
+			return kerr
+		}
+		return backoff.Permanent(kerr)
+	})
+	return cerror.WrapError(cerror.ErrKafkaTransaction, err)
+}
+
+func (k *kafkaTransactionalProducer) getCoordinator(session *txnSession, tp sarama.CoordinatorType) (*sarama.Broker, error) {
+	if coordinator, ok := session.coordinators[tp]; ok {
+		return coordinator, nil
+	}
+	controller, err := k.client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	// the consumer group of the session shares the name of the transactional ID
+	resp, err := controller.FindCoordinator(&sarama.FindCoordinatorRequest{
+		Version:         1,
+		CoordinatorKey:  session.transactionalID,
+		CoordinatorType: tp,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, resp.Err
+	}
+	coordinator := resp.Coordinator
+	if err := coordinator.Open(k.cfg); err != nil && err != sarama.ErrAlreadyConnected {
+		return nil, err
+	}
+	session.coordinators[tp] = coordinator
+	return coordinator, nil
+}
+
+func (k *kafkaTransactionalProducer) resetCoordinator(session *txnSession, tp sarama.CoordinatorType) {
+	coordinator, ok := session.coordinators[tp]
+	if !ok {
+		return
+	}
+	if err := coordinator.Close(); err != nil && err != sarama.ErrNotConnected {
+		log.Warn("close kafka coordinator failed", zap.Error(err))
+	}
+	delete(session.coordinators, tp)
+}
+
+// initSession initializes the producer ID of the transactional ID, which
+// fences the previous producers of the transactional ID. The ongoing
+// transaction of them is aborted, or completed if it's being committed, so the
+// ts committed by the last transaction is read after that.
+func (k *kafkaTransactionalProducer) initSession() error {
+	session := k.session
+	err := k.runTxnRequest(session, sarama.CoordinatorTransaction, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		resp, err := coordinator.InitProducerID(&sarama.InitProducerIDRequest{
+			TransactionalID:    &session.transactionalID,
+			TransactionTimeout: transactionTimeout,
+		})
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		session.producerID, session.producerEpoch = resp.ProducerID, resp.ProducerEpoch
+		return resp.Err, nil
+	})
+	if err != nil {
+		k.closeSession(session)
+		return errors.Trace(err)
+	}
+	// the sequence numbers are reset with the new producer epoch
+	session.sequences = make(map[string][]int32)
+	session.buffered = make(map[string]map[int32][]*codec.MQMessage)
+	session.txnPartitions = make(map[string]map[int32]struct{})
+	session.committedTs, session.tableTs, err = k.fetchCommitted(session)
+	if err != nil {
+		k.closeSession(session)
+		return errors.Trace(err)
+	}
+	session.initialized = true
+	log.Info("kafka transaction initialized",
+		zap.String("transactionalID", session.transactionalID),
+		zap.Int64("producerID", session.producerID),
+		zap.Int16("producerEpoch", session.producerEpoch),
+		zap.Uint64("committedTs", session.committedTs),
+		zap.Int("tables", len(session.tableTs)))
+	return nil
+}
+
+func (k *kafkaTransactionalProducer) closeSession(session *txnSession) {
+	k.resetCoordinator(session, sarama.CoordinatorTransaction)
+	k.resetCoordinator(session, sarama.CoordinatorGroup)
+}
+
+func (k *kafkaTransactionalProducer) getSession() (*txnSession, error) {
+	if k.closed {
+		return nil, cerror.ErrKafkaTransaction.GenWithStack("producer is closed")
+	}
+	if !k.session.initialized {
+		if err := k.initSession(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return k.session, nil
+}
+
+// fetchCommitted fetches the ts committed by the last transaction of the
+// transactional ID of the session, and the commit ts of the tables committed
+// with it
+func (k *kafkaTransactionalProducer) fetchCommitted(session *txnSession) (uint64, map[int64]uint64, error) {
+	var (
+		committedTs uint64
+		metadata    string
+	)
+	err := k.runTxnRequest(session, sarama.CoordinatorGroup, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		req := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: session.transactionalID}
+		for i := int32(0); i < k.partitionNum; i++ {
+			req.AddPartition(k.offsetTopic, i)
+		}
+		resp, err := coordinator.FetchOffset(req)
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		var chunks []string
+		for i := int32(0); i < k.partitionNum; i++ {
+			block := resp.GetBlock(k.offsetTopic, i)
+			if block == nil {
+				return sarama.ErrNoError, sarama.ErrIncompleteResponse
+			}
+			if block.Err != sarama.ErrNoError {
+				return block.Err, nil
+			}
+			if i == 0 && block.Offset > 0 {
+				committedTs = uint64(block.Offset)
+			}
+			chunks = append(chunks, block.Metadata)
+		}
+		metadata = strings.Join(chunks, "")
+		return sarama.ErrNoError, nil
+	})
+	if err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	tableTs, err := decodeTableTs(k.changefeedID, committedTs, metadata)
+	if err != nil {
+		// the commit ts of the tables only prevent the rows from being sent
+		// again, the rows are never lost without them
+		log.Warn("the commit ts of the tables committed by the kafka transaction are ignored",
+			zap.String("transactionalID", session.transactionalID), zap.Error(err))
+		tableTs = make(map[int64]uint64)
+	}
+	return committedTs, tableTs, nil
+}
+
+// listTransactionalIDs lists the transactional IDs of the other producers of
+// the changefeed, whose consumer groups have been created by committing the
+// offsets.
+func (k *kafkaTransactionalProducer) listTransactionalIDs() ([]string, error) {
+	prefix := kafkaTransactionalIDPrefix(k.changefeedID)
+	var ids []string
+	err := retry.Run(transactionRetryInterval, transactionMaxRetries, func() error {
+		ids = ids[:0]
+		for _, broker := range k.client.Brokers() {
+			if err := broker.Open(k.cfg); err != nil && err != sarama.ErrAlreadyConnected {
+				return err
+			}
+			resp, err := broker.ListGroups(&sarama.ListGroupsRequest{})
+			if err != nil {
+				return err
+			}
+			if resp.Err != sarama.ErrNoError {
+				return resp.Err
+			}
+			for group := range resp.Groups {
+				if strings.HasPrefix(group, prefix) && group != k.session.transactionalID {
+					ids = append(ids, group)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaTransaction, err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// encodeTableTs encodes the commit ts of the tables as the metadata of the
+// offsets committed to the partitions, every chunk of the metadata is
+// committed to a partition. The metadata contains the changefeed ID, and the
+// sorted table IDs along with the distance from their commit ts to the ts
+// committed by the transaction. The tables committed earliest are dropped if
+// the metadata doesn't fit into the partitions, whose rows may be sent again
+// once they are moved to other captures.
+func encodeTableTs(changefeedID string, committedTs uint64, tableTs map[int64]uint64, partitionNum int32) []string {
+	tableIDs := make([]int64, 0, len(tableTs))
+	for tableID := range tableTs {
+		tableIDs = append(tableIDs, tableID)
+	}
+	// the tables committed earliest are in the front
+	sort.Slice(tableIDs, func(i, j int) bool {
+		if tableTs[tableIDs[i]] != tableTs[tableIDs[j]] {
+			return tableTs[tableIDs[i]] < tableTs[tableIDs[j]]
+		}
+		return tableIDs[i] < tableIDs[j]
+	})
+	for dropped := 0; ; dropped += len(tableIDs)/10 + 1 {
+		if dropped > len(tableIDs) {
+			dropped = len(tableIDs)
+		}
+		kept := append([]int64(nil), tableIDs[dropped:]...)
+		sort.Slice(kept, func(i, j int) bool { return kept[i] < kept[j] })
+		buf := make([]byte, 0, binary.MaxVarintLen64*(2*len(kept)+1)+len(changefeedID))
+		buf = appendUvarint(buf, uint64(len(changefeedID)))
+		buf = append(buf, changefeedID...)
+		prev := int64(0)
+		for _, tableID := range kept {
+			buf = appendVarint(buf, tableID-prev)
+			buf = appendUvarint(buf, committedTs-tableTs[tableID])
+			prev = tableID
+		}
+		metadata := base64.StdEncoding.EncodeToString(buf)
+		if len(metadata) <= transactionMetadataChunkSize*int(partitionNum) || dropped == len(tableIDs) {
+			if dropped != 0 {
+				log.Warn("too many tables to commit their commit ts in the kafka transaction, the rows of the dropped tables may be sent again",
+					zap.Int("tables", len(tableIDs)), zap.Int("dropped", dropped))
+			}
+			chunks := make([]string, partitionNum)
+			for i := range chunks {
+				if len(metadata) > transactionMetadataChunkSize {
+					chunks[i], metadata = metadata[:transactionMetadataChunkSize], metadata[transactionMetadataChunkSize:]
+				} else {
+					chunks[i], metadata = metadata, ""
+				}
+			}
+			return chunks
+		}
+	}
+}
+
+// decodeTableTs decodes the commit ts of the tables encoded by encodeTableTs,
+// the tables of other changefeeds sharing the prefix of the transactional IDs
+// are ignored.
+func decodeTableTs(changefeedID string, committedTs uint64, metadata string) (map[int64]uint64, error) {
+	tableTs := make(map[int64]uint64)
+	if metadata == "" {
+		return tableTs, nil
+	}
+	buf, err := base64.StdEncoding.DecodeString(metadata)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	errInvalid := errors.New("invalid metadata of the commit ts of the tables")
+	n, l := binary.Uvarint(buf)
+	if l <= 0 || uint64(len(buf)-l) < n {
+		return nil, errInvalid
+	}
+	if string(buf[l:l+int(n)]) != changefeedID {
+		return tableTs, nil
+	}
+	buf = buf[l+int(n):]
+	tableID := int64(0)
+	for len(buf) > 0 {
+		delta, l := binary.Varint(buf)
+		if l <= 0 {
+			return nil, errInvalid
+		}
+		buf = buf[l:]
+		distance, l := binary.Uvarint(buf)
+		if l <= 0 || distance > committedTs {
+			return nil, errInvalid
+		}
+		buf = buf[l:]
+		tableID += delta
+		tableTs[tableID] = committedTs - distance
+	}
+	return tableTs, nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+// addPartitionsToTxn adds the partitions of the buffered messages to the
+// ongoing transaction, the transaction is started implicitly by the first
+// added partition.
+func (k *kafkaTransactionalProducer) addPartitionsToTxn(session *txnSession) error {
+	partitions := make(map[string][]int32)
+	for topic, buffered := range session.buffered {
+		for partition := range buffered {
+			if _, ok := session.txnPartitions[topic][partition]; !ok {
+				partitions[topic] = append(partitions[topic], partition)
+			}
+		}
+	}
+	if len(partitions) == 0 {
+		return nil
+	}
+	err := k.runTxnRequest(session, sarama.CoordinatorTransaction, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		resp, err := coordinator.AddPartitionsToTxn(&sarama.AddPartitionsToTxnRequest{
+			TransactionalID: session.transactionalID,
+			ProducerID:      session.producerID,
+			ProducerEpoch:   session.producerEpoch,
+			TopicPartitions: partitions,
+		})
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		for _, partitionErrors := range resp.Errors {
+			for _, partitionError := range partitionErrors {
+				if partitionError.Err != sarama.ErrNoError {
+					return partitionError.Err, nil
+				}
+			}
+		}
+		return sarama.ErrNoError, nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for topic, ps := range partitions {
+		if session.txnPartitions[topic] == nil {
+			session.txnPartitions[topic] = make(map[int32]struct{})
+		}
+		for _, partition := range ps {
+			session.txnPartitions[topic][partition] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// commitTs commits the ts as the offsets of the consumer group of the session
+// in the ongoing transaction, along with the commit ts of the tables
+func (k *kafkaTransactionalProducer) commitTs(session *txnSession, ts uint64, tableTs map[int64]uint64) error {
+	err := k.runTxnRequest(session, sarama.CoordinatorTransaction, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		resp, err := coordinator.AddOffsetsToTxn(&sarama.AddOffsetsToTxnRequest{
+			TransactionalID: session.transactionalID,
+			ProducerID:      session.producerID,
+			ProducerEpoch:   session.producerEpoch,
+			GroupID:         session.transactionalID,
+		})
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		return resp.Err, nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	chunks := encodeTableTs(k.changefeedID, ts, tableTs, k.partitionNum)
+	offsets := make([]*sarama.PartitionOffsetMetadata, 0, len(chunks))
+	for i := range chunks {
+		offsets = append(offsets, &sarama.PartitionOffsetMetadata{
+			Partition: int32(i),
+			Offset:    int64(ts),
+			Metadata:  &chunks[i],
+		})
+	}
+	return k.runTxnRequest(session, sarama.CoordinatorGroup, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		resp, err := coordinator.TxnOffsetCommit(&sarama.TxnOffsetCommitRequest{
+			TransactionalID: session.transactionalID,
+			GroupID:         session.transactionalID,
+			ProducerID:      session.producerID,
+			ProducerEpoch:   session.producerEpoch,
+			Topics:          map[string][]*sarama.PartitionOffsetMetadata{k.offsetTopic: offsets},
+		})
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		for _, partitionErrors := range resp.Topics {
+			for _, partitionError := range partitionErrors {
+				if partitionError.Err != sarama.ErrNoError {
+					return partitionError.Err, nil
+				}
+			}
+		}
+		return sarama.ErrNoError, nil
+	})
+}
+
+func (k *kafkaTransactionalProducer) endTxn(session *txnSession, commit bool) error {
+	err := k.runTxnRequest(session, sarama.CoordinatorTransaction, func(coordinator *sarama.Broker) (sarama.KError, error) {
+		resp, err := coordinator.EndTxn(&sarama.EndTxnRequest{
+			TransactionalID:   session.transactionalID,
+			ProducerID:        session.producerID,
+			ProducerEpoch:     session.producerEpoch,
+			TransactionResult: commit,
+		})
+		if err != nil {
+			return sarama.ErrNoError, err
+		}
+		return resp.Err, nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	session.txnPartitions = make(map[string]map[int32]struct{})
+	return nil
+}
+
+type partitionBatch struct {
+	topic     string
+	partition int32
+	batch     *sarama.RecordBatch
+}
+
+// buildBatches splits the messages of a partition into record batches, each
+// of them is not larger than MaxMessageBytes unless it contains only one
+// message.
+func (k *kafkaTransactionalProducer) buildBatches(session *txnSession, topic string, partition int32, messages []*codec.MQMessage) []*partitionBatch {
+	var batches []*partitionBatch
+	var batch *sarama.RecordBatch
+	size := 0
+	sequence := session.sequences[topic][partition]
+	now := time.Now().Truncate(time.Millisecond)
+	for _, message := range messages {
+		messageSize := len(message.Key) + len(message.Value)
+		if batch == nil || (size+messageSize > k.config.MaxMessageBytes && len(batch.Records) > 0) {
+			batch = &sarama.RecordBatch{
+				Version:          2,
+				Codec:            k.cfg.Producer.Compression,
+				CompressionLevel: k.cfg.Producer.CompressionLevel,
+				FirstTimestamp:   now,
+				MaxTimestamp:     now,
+				ProducerID:       session.producerID,
+				ProducerEpoch:    session.producerEpoch,
+				FirstSequence:    sequence,
+				IsTransactional:  true,
+			}
+			batches = append(batches, &partitionBatch{topic: topic, partition: partition, batch: batch})
+			size = 0
+		}
+		batch.Records = append(batch.Records, &sarama.Record{
+			Key:         message.Key,
+			Value:       message.Value,
+			OffsetDelta: int64(len(batch.Records)),
+		})
+		batch.LastOffsetDelta = int32(len(batch.Records) - 1)
+		size += messageSize
+		sequence++
+	}
+	return batches
+}
+
+// sendBatches sends the batches to the leaders of the partitions, the batches
+// of a partition must be in order and are sent one by one.
+func (k *kafkaTransactionalProducer) sendBatches(session *txnSession, batches []*partitionBatch) error {
+	requests := make(map[*sarama.Broker]*sarama.ProduceRequest)
+	requestBatches := make(map[*sarama.Broker][]*partitionBatch)
+	for _, b := range batches {
+		leader, err := k.client.Leader(b.topic, b.partition)
+		if err != nil {
+			return cerror.WrapError(cerror.ErrKafkaSendMessage, err)
+		}
+		req, ok := requests[leader]
+		if !ok {
+			req = &sarama.ProduceRequest{
+				TransactionalID: &session.transactionalID,
+				// the transactional messages must be acknowledged by all ISRs
+				RequiredAcks: sarama.WaitForAll,
+				Timeout:      int32(k.cfg.Producer.Timeout / time.Millisecond),
+				Version:      3,
+			}
+			requests[leader] = req
+		}
+		req.AddBatch(b.topic, b.partition, b.batch)
+		requestBatches[leader] = append(requestBatches[leader], b)
+	}
+	for leader, req := range requests {
+		resp, err := leader.Produce(req)
+		if err != nil {
+			return cerror.WrapError(cerror.ErrKafkaSendMessage, err)
+		}
+		for _, b := range requestBatches[leader] {
+			block := resp.GetBlock(b.topic, b.partition)
+			if block == nil {
+				return cerror.ErrKafkaSendMessage.GenWithStack(
+					"no response of topic %s partition %d", b.topic, b.partition)
+			}
+			if block.Err != sarama.ErrNoError {
+				return cerror.WrapError(cerror.ErrKafkaSendMessage, block.Err)
+			}
+			session.sequences[b.topic][b.partition] += int32(len(b.batch.Records))
+		}
+	}
+	return nil
+}
+
+// sendBuffered sends the buffered messages in the ongoing transaction
+func (k *kafkaTransactionalProducer) sendBuffered(session *txnSession) error {
+	buffered := session.buffered
+	// the i-th batches of all partitions are sent in the i-th round
+	var rounds [][]*partitionBatch
+	topics := make([]string, 0, len(buffered))
+	for topic := range buffered {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		for partition, messages := range buffered[topic] {
+			for i, b := range k.buildBatches(session, topic, partition, messages) {
+				if i == len(rounds) {
+					rounds = append(rounds, nil)
+				}
+				rounds[i] = append(rounds[i], b)
+			}
+		}
+	}
+	for _, batches := range rounds {
+		if err := k.sendBatches(session, batches); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (k *kafkaTransactionalProducer) flushSession(session *txnSession) error {
+	if len(session.buffered) == 0 {
+		return nil
+	}
+	if err := k.addPartitionsToTxn(session); err != nil {
+		return errors.Trace(err)
+	}
+	if err := k.sendBuffered(session); err != nil {
+		return errors.Trace(err)
+	}
+	session.buffered = make(map[string]map[int32][]*codec.MQMessage)
+	return nil
+}
+
+func (k *kafkaTransactionalProducer) checkTopic(topic string) error {
+	if _, ok := k.topics[topic]; ok {
+		return nil
+	}
+	if k.config.TopicPreProcess {
+		config := k.config
+		config.PartitionNum = k.partitionNum
+		if _, err := kafkaTopicPreProcess(topic, k.address, config, k.cfg); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	k.topics[topic] = struct{}{}
+	return nil
+}
+
+func appendMessage(buffered map[string]map[int32][]*codec.MQMessage, topic string, message *codec.MQMessage, partition int32) {
+	if buffered[topic] == nil {
+		buffered[topic] = make(map[int32][]*codec.MQMessage)
+	}
+	buffered[topic][partition] = append(buffered[topic][partition], message)
+}
+
+// bufferMessage buffers the message in the ongoing transaction
+func (k *kafkaTransactionalProducer) bufferMessage(topic string, message *codec.MQMessage, partition int32) (*txnSession, error) {
+	session, err := k.getSession()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := k.checkTopic(topic); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, ok := session.sequences[topic]; !ok {
+		session.sequences[topic] = make([]int32, k.partitionNum)
+	}
+	appendMessage(session.buffered, topic, message, partition)
+	return session, nil
+}
+
+// SendMessage implements the Producer interface, the message is buffered until Flush
+func (k *kafkaTransactionalProducer) SendMessage(ctx context.Context, topic string, message *codec.MQMessage, partition int32) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_, err := k.bufferMessage(topic, message, partition)
+	return err
+}
+
+// SyncBroadcastMessage implements the Producer interface, the message is
+// sent to all partitions in the ongoing transaction
+func (k *kafkaTransactionalProducer) SyncBroadcastMessage(ctx context.Context, topic string, message *codec.MQMessage) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var session *txnSession
+	for i := int32(0); i < k.partitionNum; i++ {
+		var err error
+		session, err = k.bufferMessage(topic, message, i)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if session == nil {
+		return nil
+	}
+	return k.flushSession(session)
+}
+
+// Flush implements the Producer interface, the buffered messages are sent in
+// the ongoing transaction
+func (k *kafkaTransactionalProducer) Flush(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	session, err := k.getSession()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return k.flushSession(session)
+}
+
+// CommittedTs implements the TransactionalProducer interface, the commit ts of
+// the table is the maximum one committed by all the producers of the
+// changefeed
+func (k *kafkaTransactionalProducer) CommittedTs(ctx context.Context, tableID int64) (uint64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	session, err := k.getSession()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if tableID == producer.NoTableID {
+		return session.committedTs, nil
+	}
+	ts := session.tableTs[tableID]
+	ids, err := k.listTransactionalIDs()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	for _, id := range ids {
+		other := newTxnSession(id)
+		_, tableTs, err := k.fetchCommitted(other)
+		k.closeSession(other)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if tableTs[tableID] > ts {
+			log.Info("the table has been committed by another kafka transactional ID",
+				zap.String("transactionalID", id), zap.Int64("tableID", tableID), zap.Uint64("commitTs", tableTs[tableID]))
+			ts = tableTs[tableID]
+		}
+	}
+	return ts, nil
+}
+
+// CommitTransaction implements the TransactionalProducer interface
+func (k *kafkaTransactionalProducer) CommitTransaction(ctx context.Context, ts uint64, tableTs map[int64]uint64) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	session, err := k.getSession()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := k.flushSession(session); err != nil {
+		return errors.Trace(err)
+	}
+	commitOffsets := ts > session.committedTs || len(tableTs) != 0
+	if len(session.txnPartitions) == 0 && !commitOffsets {
+		return nil
+	}
+	// the commit ts of the tables committed before are committed again, since
+	// only the offsets of the last transaction are kept
+	committedTs, committedTableTs := session.committedTs, session.tableTs
+	if commitOffsets {
+		if ts > committedTs {
+			committedTs = ts
+		}
+		committedTableTs = make(map[int64]uint64, len(session.tableTs)+len(tableTs))
+		for tableID, commitTs := range session.tableTs {
+			committedTableTs[tableID] = commitTs
+		}
+		for tableID, commitTs := range tableTs {
+			if commitTs > committedTableTs[tableID] {
+				committedTableTs[tableID] = commitTs
+			}
+			if commitTs > committedTs {
+				committedTs = commitTs
+			}
+		}
+		if err := k.commitTs(session, committedTs, committedTableTs); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := k.endTxn(session, true); err != nil {
+		return errors.Trace(err)
+	}
+	session.committedTs, session.tableTs = committedTs, committedTableTs
+	return nil
+}
+
+// GetPartitionNum implements the Producer interface
+func (k *kafkaTransactionalProducer) GetPartitionNum() int32 {
+	return k.partitionNum
+}
+
+// Close implements the Producer interface, the ongoing transaction is aborted
+func (k *kafkaTransactionalProducer) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closed {
+		return nil
+	}
+	k.closed = true
+	session := k.session
+	if len(session.txnPartitions) != 0 {
+		if err := k.endTxn(session, false); err != nil {
+			log.Warn("abort kafka transaction failed",
+				zap.String("transactionalID", session.transactionalID), zap.Error(err))
+		}
+	}
+	k.closeSession(session)
+	if err := k.client.Close(); err != nil {
+		log.Error("close kafka client with error", zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+const (
+	transactionTestCapture = "127.0.0.1:8300"
+	transactionTestID      = "TiCDC_transaction_test-cf_127.0.0.1_8300"
+	transactionOtherID     = "TiCDC_transaction_test-cf_127.0.0.1_8301"
+	transactionOwnerID     = "TiCDC_transaction_test-cf_owner"
+	// transactionPrefixID shares the prefix of the transactional IDs of the
+	// changefeed test-cf, but belongs to the changefeed test-cf_2
+	transactionPrefixID = "TiCDC_transaction_test-cf_2_127.0.0.1_8300"
+)
+
+// setCommitted sets the offsets and the commit ts of the tables committed by
+// the transactional ID
+func setCommitted(
+	resp *sarama.MockOffsetFetchResponse, id, changefeedID, topic string, ts uint64, tableTs map[int64]uint64,
+) *sarama.MockOffsetFetchResponse {
+	offset := int64(ts)
+	if ts == 0 {
+		offset = -1
+	}
+	for i, chunk := range encodeTableTs(changefeedID, ts, tableTs, 2) {
+		resp.SetOffset(id, topic, int32(i), offset, chunk, sarama.ErrNoError)
+	}
+	return resp
+}
+
+func newTransactionTestBroker(c *check.C, topic string, endTxn sarama.MockResponse) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(c, 1)
+	offsets := sarama.NewMockOffsetFetchResponse(c)
+	setCommitted(offsets, transactionTestID, "test-cf", topic, 100, map[int64]uint64{1: 90})
+	setCommitted(offsets, transactionOtherID, "test-cf", topic, 200, map[int64]uint64{1: 150, 2: 180})
+	setCommitted(offsets, transactionOwnerID, "test-cf", topic, 300, nil)
+	setCommitted(offsets, transactionPrefixID, "test-cf_2", topic, 400, map[int64]uint64{2: 400})
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(c).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockWrapper(&sarama.FindCoordinatorResponse{
+			Version:     1,
+			Coordinator: sarama.NewBroker(broker.Addr()),
+		}),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID:    1000,
+			ProducerEpoch: 1,
+		}),
+		"ListGroupsRequest": sarama.NewMockListGroupsResponse(c).
+			AddGroup(transactionTestID, "").
+			AddGroup(transactionOtherID, "").
+			AddGroup(transactionOwnerID, "").
+			AddGroup(transactionPrefixID, "").
+			AddGroup("other-group", "consumer"),
+		"OffsetFetchRequest":        offsets,
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{}),
+		"AddOffsetsToTxnRequest":    sarama.NewMockWrapper(&sarama.AddOffsetsToTxnResponse{}),
+		"TxnOffsetCommitRequest":    sarama.NewMockWrapper(&sarama.TxnOffsetCommitResponse{}),
+		"ProduceRequest":            sarama.NewMockProduceResponse(c).SetVersion(3),
+		"EndTxnRequest":             endTxn,
+	})
+	return broker
+}
+
+func countRequests(broker *sarama.MockBroker, match func(req interface{}) bool) int {
+	count := 0
+	for _, r := range broker.History() {
+		if match(r.Request) {
+			count++
+		}
+	}
+	return count
+}
+
+func isEndTxn(commit bool) func(req interface{}) bool {
+	return func(req interface{}) bool {
+		r, ok := req.(*sarama.EndTxnRequest)
+		return ok && r.TransactionResult == commit
+	}
+}
+
+func newTransactionTestConfig() Config {
+	config := NewKafkaConfig()
+	config.Version = "0.11.0.0"
+	config.PartitionNum = int32(2)
+	config.TopicPreProcess = false
+	config.Transactional = true
+	return config
+}
+
+func newTransactionTestContext() context.Context {
+	ctx := util.PutChangefeedIDInCtx(context.Background(), "test-cf")
+	return util.PutCaptureAddrInCtx(ctx, transactionTestCapture)
+}
+
+func (s *kafkaSuite) TestTransactionalProducer(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := newTransactionTestContext()
+
+	topic := "unit_test_txn"
+	broker := newTransactionTestBroker(c, topic, sarama.NewMockWrapper(&sarama.EndTxnResponse{}))
+	defer broker.Close()
+
+	config := newTransactionTestConfig()
+	config.MaxMessageBytes = 40
+	producer, err := NewKafkaTransactionalProducer(ctx, broker.Addr(), topic, config)
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.GetPartitionNum(), check.Equals, int32(2))
+
+	// the commit ts of a table is the maximum one committed by the producers
+	// of the changefeed
+	for tableID, expected := range map[int64]uint64{1: 150, 2: 180, 3: 0} {
+		ts, err := producer.CommittedTs(ctx, tableID)
+		c.Assert(err, check.IsNil)
+		c.Assert(ts, check.Equals, expected)
+	}
+	session := producer.session
+	c.Assert(session.transactionalID, check.Equals, transactionTestID)
+	c.Assert(session.producerID, check.Equals, int64(1000))
+	c.Assert(session.producerEpoch, check.Equals, int16(1))
+	c.Assert(session.committedTs, check.Equals, uint64(100))
+	c.Assert(session.tableTs, check.DeepEquals, map[int64]uint64{1: 90})
+	c.Assert(countRequests(broker, func(req interface{}) bool {
+		_, ok := req.(*sarama.InitProducerIDRequest)
+		return ok
+	}), check.Equals, 1)
+
+	// nothing is committed without messages
+	c.Assert(producer.CommitTransaction(ctx, 100, nil), check.IsNil)
+	c.Assert(countRequests(broker, isEndTxn(true)), check.Equals, 0)
+
+	for i := 0; i < 5; i++ {
+		err = producer.SendMessage(ctx, topic, &codec.MQMessage{
+			Key:   []byte("test-key"),
+			Value: []byte("test-value"),
+		}, int32(0))
+		c.Assert(err, check.IsNil)
+	}
+	// the messages of partition 0 are split into 3 batches
+	c.Assert(producer.Flush(ctx), check.IsNil)
+	c.Assert(session.sequences[topic], check.DeepEquals, []int32{5, 0})
+	c.Assert(session.txnPartitions[topic], check.HasLen, 1)
+
+	// the resolved events are sent in the same transaction
+	err = producer.SyncBroadcastMessage(ctx, topic, &codec.MQMessage{Key: []byte("resolved")})
+	c.Assert(err, check.IsNil)
+	c.Assert(session.sequences[topic], check.DeepEquals, []int32{6, 1})
+	c.Assert(producer.CommitTransaction(ctx, 200, map[int64]uint64{2: 190}), check.IsNil)
+	c.Assert(session.txnPartitions, check.HasLen, 0)
+	c.Assert(session.committedTs, check.Equals, uint64(200))
+	c.Assert(session.tableTs, check.DeepEquals, map[int64]uint64{1: 90, 2: 190})
+	c.Assert(countRequests(broker, isEndTxn(true)), check.Equals, 1)
+	c.Assert(countRequests(broker, func(req interface{}) bool {
+		r, ok := req.(*sarama.ProduceRequest)
+		return ok && r.TransactionalID == nil
+	}), check.Equals, 0)
+	// the commit ts of the tables are committed along with the ts
+	var commits []*sarama.TxnOffsetCommitRequest
+	for _, r := range broker.History() {
+		if req, ok := r.Request.(*sarama.TxnOffsetCommitRequest); ok {
+			commits = append(commits, req)
+		}
+	}
+	c.Assert(commits, check.HasLen, 1)
+	c.Assert(commits[0].GroupID, check.Equals, transactionTestID)
+	offsets := commits[0].Topics[topic]
+	c.Assert(offsets, check.HasLen, 2)
+	metadata := ""
+	for i, offset := range offsets {
+		c.Assert(offset.Partition, check.Equals, int32(i))
+		c.Assert(offset.Offset, check.Equals, int64(200))
+		metadata += *offset.Metadata
+	}
+	tableTs, err := decodeTableTs("test-cf", 200, metadata)
+	c.Assert(err, check.IsNil)
+	c.Assert(tableTs, check.DeepEquals, map[int64]uint64{1: 90, 2: 190})
+
+	// the transaction is aborted on close
+	err = producer.SendMessage(ctx, topic, &codec.MQMessage{Key: []byte("aborted")}, int32(1))
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.Flush(ctx), check.IsNil)
+	c.Assert(producer.Close(), check.IsNil)
+	// check reentrant close
+	c.Assert(producer.Close(), check.IsNil)
+
+	c.Assert(countRequests(broker, isEndTxn(true)), check.Equals, 1)
+	c.Assert(countRequests(broker, isEndTxn(false)), check.Equals, 1)
+
+	err = producer.SendMessage(ctx, topic, &codec.MQMessage{Key: []byte("closed")}, int32(0))
+	c.Assert(err, check.ErrorMatches, ".*producer is closed.*")
+}
+
+func (s *kafkaSuite) TestTransactionalProducerOwner(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := util.SetOwnerInCtx(newTransactionTestContext())
+
+	topic := "unit_test_txn"
+	broker := newTransactionTestBroker(c, topic, sarama.NewMockWrapper(&sarama.EndTxnResponse{}))
+	defer broker.Close()
+
+	producer, err := NewKafkaTransactionalProducer(ctx, broker.Addr(), topic, newTransactionTestConfig())
+	c.Assert(err, check.IsNil)
+	defer producer.Close()
+	ts, err := producer.CommittedTs(ctx, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(300))
+
+	// the messages of the owner are sent in its own transaction
+	err = producer.SyncBroadcastMessage(ctx, topic, &codec.MQMessage{Key: []byte("ddl")})
+	c.Assert(err, check.IsNil)
+	session := producer.session
+	c.Assert(session.transactionalID, check.Equals, transactionOwnerID)
+	c.Assert(session.sequences[topic], check.DeepEquals, []int32{1, 1})
+	c.Assert(producer.CommitTransaction(ctx, 310, nil), check.IsNil)
+	c.Assert(session.committedTs, check.Equals, uint64(310))
+	c.Assert(countRequests(broker, isEndTxn(true)), check.Equals, 1)
+
+	// the checkpoint events are committed without the ts
+	err = producer.SyncBroadcastMessage(ctx, topic, &codec.MQMessage{Key: []byte("checkpoint")})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.CommitTransaction(ctx, 0, nil), check.IsNil)
+	c.Assert(session.committedTs, check.Equals, uint64(310))
+	c.Assert(countRequests(broker, isEndTxn(true)), check.Equals, 2)
+	c.Assert(countRequests(broker, func(req interface{}) bool {
+		_, ok := req.(*sarama.TxnOffsetCommitRequest)
+		return ok
+	}), check.Equals, 1)
+}
+
+func (s *kafkaSuite) TestTransactionalProducerFenced(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := newTransactionTestContext()
+	topic := "unit_test_txn"
+	broker := newTransactionTestBroker(c, topic, sarama.NewMockWrapper(&sarama.EndTxnResponse{
+		Err: sarama.ErrInvalidProducerEpoch,
+	}))
+	defer broker.Close()
+
+	config := newTransactionTestConfig()
+	config.Version = "0.10.2.0"
+	_, err := NewKafkaTransactionalProducer(ctx, broker.Addr(), topic, config)
+	c.Assert(err, check.ErrorMatches, ".*require kafka-version 0.11.0.0 or later.*")
+
+	producer, err := NewKafkaTransactionalProducer(ctx, broker.Addr(), topic, newTransactionTestConfig())
+	c.Assert(err, check.IsNil)
+	defer producer.Close()
+	err = producer.SendMessage(ctx, topic, &codec.MQMessage{Key: []byte("fenced")}, int32(0))
+	c.Assert(err, check.IsNil)
+	// a fenced producer can't commit the transaction, and is never retried
+	err = producer.CommitTransaction(ctx, 100, nil)
+	c.Assert(err, check.ErrorMatches, ".*ErrKafkaTransaction.*")
+	c.Assert(countRequests(broker, func(req interface{}) bool {
+		_, ok := req.(*sarama.EndTxnRequest)
+		return ok
+	}), check.Equals, 1)
+}
+
+func (s *kafkaSuite) TestEncodeTableTs(c *check.C) {
+	defer testleak.AfterTest(c)()
+	tableTs := map[int64]uint64{1: 100, 45: 180, 1000: 200}
+	chunks := encodeTableTs("test-cf", 200, tableTs, 3)
+	c.Assert(chunks, check.HasLen, 3)
+	c.Assert(chunks[1], check.Equals, "")
+	decoded, err := decodeTableTs("test-cf", 200, strings.Join(chunks, ""))
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, tableTs)
+
+	// the tables of other changefeeds are ignored
+	decoded, err = decodeTableTs("test", 200, strings.Join(chunks, ""))
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.HasLen, 0)
+	_, err = decodeTableTs("test-cf", 50, strings.Join(chunks, ""))
+	c.Assert(err, check.NotNil)
+
+	// the tables committed earliest are dropped if the metadata is too large
+	tableTs = make(map[int64]uint64)
+	for i := int64(1); i <= 2000; i++ {
+		tableTs[i*1000] = uint64(i) << 30
+	}
+	chunks = encodeTableTs("test-cf", 2000<<30, tableTs, 2)
+	c.Assert(chunks, check.HasLen, 2)
+	for _, chunk := range chunks {
+		c.Assert(len(chunk) <= transactionMetadataChunkSize, check.IsTrue, check.Commentf("%d", len(chunk)))
+	}
+	decoded, err = decodeTableTs("test-cf", 2000<<30, strings.Join(chunks, ""))
+	c.Assert(err, check.IsNil)
+	c.Assert(len(decoded) > 0 && len(decoded) < len(tableTs), check.IsTrue, check.Commentf("%d", len(decoded)))
+	for tableID, ts := range decoded {
+		c.Assert(ts, check.Equals, tableTs[tableID])
+	}
+	c.Assert(decoded[2000*1000], check.Equals, uint64(2000)<<30)
+	_, ok := decoded[1000]
+	c.Assert(ok, check.IsFalse)
+}
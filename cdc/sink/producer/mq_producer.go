@@ -21,9 +21,31 @@ import (
 
 // Producer is a interface of mq producer
 type Producer interface {
-	SendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error
-	SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error
+	// SendMessage sends a message to the partition of the topic asynchronously
+	SendMessage(ctx context.Context, topic string, message *codec.MQMessage, partition int32) error
+	// SyncBroadcastMessage sends a message to all partitions of the topic synchronously
+	SyncBroadcastMessage(ctx context.Context, topic string, message *codec.MQMessage) error
 	Flush(ctx context.Context) error
+	// GetPartitionNum returns the partition number, which is the same for all topics
 	GetPartitionNum() int32
 	Close() error
 }
+
+// NoTableID is the table ID of the messages not belonging to any table, such
+// as the DDL events
+const NoTableID int64 = 0
+
+// TransactionalProducer is a Producer sending messages in transactions, the
+// messages are invisible to the read_committed consumers until the transaction
+// is committed
+type TransactionalProducer interface {
+	Producer
+	// CommittedTs returns the commit ts of the last rows of the table
+	// committed by the transactions of the changefeed, or the ts committed by
+	// the last transaction of the producer if the table ID is NoTableID
+	CommittedTs(ctx context.Context, tableID int64) (uint64, error)
+	// CommitTransaction flushes the messages and commits the ongoing
+	// transaction, the ts and the commit ts of the last rows of the tables
+	// sent in the transaction are committed in the transaction atomically
+	CommitTransaction(ctx context.Context, ts uint64, tableTs map[int64]uint64) error
+}
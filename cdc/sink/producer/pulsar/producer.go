@@ -85,8 +85,9 @@ func createProperties(message *codec.MQMessage, partition int32) map[string]stri
 	return properties
 }
 
-// SendMessage send key-value msg to target partition.
-func (p *Producer) SendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error {
+// SendMessage send key-value msg to target partition. The topic is ignored
+// since a pulsar producer is bound to the topic in sink uri.
+func (p *Producer) SendMessage(ctx context.Context, _ string, message *codec.MQMessage, partition int32) error {
 	p.producer.SendAsync(ctx, &pulsar.ProducerMessage{
 		Payload:    message.Value,
 		Key:        string(message.Key),
@@ -107,7 +108,7 @@ func (p *Producer) errors(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err err
 }
 
 // SyncBroadcastMessage send key-value msg to all partition.
-func (p *Producer) SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error {
+func (p *Producer) SyncBroadcastMessage(ctx context.Context, _ string, message *codec.MQMessage) error {
 	for partition := 0; partition < p.partitions; partition++ {
 		_, err := p.producer.Send(ctx, &pulsar.ProducerMessage{
 			Payload:    message.Value,
@@ -16,6 +16,7 @@ package sink
 import (
 	"context"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/pingcap/ticdc/cdc/model"
@@ -55,6 +56,22 @@ type Sink interface {
 	Close() error
 }
 
+// DeadLetterCounter is implemented by the sinks writing the rows they can't
+// apply to the dead letter instead of failing the changefeed
+type DeadLetterCounter interface {
+	// DeadLetterCount returns the number of the rows written to the dead letter
+	DeadLetterCount() uint64
+}
+
+// TableCheckpointLoader is implemented by the sinks recording the commit ts of
+// the last transaction of every table written to the downstream, the rows
+// written before are skipped once the table is added to the sink again.
+type TableCheckpointLoader interface {
+	// LoadTableCheckpoint returns the commit ts of the last transaction of the
+	// table written to the downstream, or zero if there isn't any.
+	LoadTableCheckpoint(ctx context.Context, tableID model.TableID) (uint64, error)
+}
+
 var sinkIniterMap = make(map[string]sinkInitFunc)
 
 type sinkInitFunc func(context.Context, model.ChangeFeedID, *url.URL, *filter.Filter, *config.ReplicaConfig, map[string]string, chan error) (Sink, error)
@@ -89,6 +106,13 @@ func init() {
 	}
 	sinkIniterMap["pulsar+ssl"] = sinkIniterMap["pulsar"]
 
+	// register http sink
+	sinkIniterMap["http"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
+		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+		return newHTTPSink(ctx, sinkURI, filter, config, opts)
+	}
+	sinkIniterMap["https"] = sinkIniterMap["http"]
+
 	// register local sink
 	sinkIniterMap["local"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
 		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
@@ -114,3 +138,26 @@ func NewSink(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr st
 	}
 	return nil, cerror.ErrSinkURIInvalid.GenWithStack("the sink scheme (%s) is not supported", sinkURI.Scheme)
 }
+
+// SupportsTableSplit returns true if the sink of the sink-uri can replicate a
+// table by the sub tables on different captures. The sub tables are flushed
+// independently, so the rows of an upstream transaction spanning several sub
+// tables are emitted separately, only the sinks emitting the rows one by one
+// support it. The MySQL sink would split the transactions, the cdclog sinks
+// write a file per table, and the checkpoints recorded by the Kafka
+// transactions are keyed by the physical table IDs.
+func SupportsTableSplit(sinkURIStr string) bool {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(sinkURI.Scheme) {
+	case "kafka", "kafka+ssl":
+		transactional, err := strconv.ParseBool(sinkURI.Query().Get("transactional"))
+		return err != nil || !transactional
+	case "pulsar", "pulsar+ssl", "blackhole":
+		return true
+	default:
+		return false
+	}
+}
@@ -34,8 +34,19 @@ type SyncpointStore interface {
 	Close() error
 }
 
-// NewSyncpointStore creates a new Spyncpoint sink with the sink-uri
-func NewSyncpointStore(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string) (SyncpointStore, error) {
+// SyncpointEmitter is implemented by the sinks which record the syncpoints in
+// the data they write, such as the MQ sinks and the cdclog sinks
+type SyncpointEmitter interface {
+	// EmitSyncpoint records a syncpoint, the data before ts written by the
+	// sink is a consistent snapshot of the upstream
+	EmitSyncpoint(ctx context.Context, ts uint64) error
+}
+
+// NewSyncpointStore creates a new Spyncpoint sink with the sink-uri, the
+// syncpoints of the sinks other than MySQL are emitted by the primary sink
+func NewSyncpointStore(
+	ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string, primarySink Sink,
+) (SyncpointStore, error) {
 	// parse sinkURI as a URI
 	sinkURI, err := url.Parse(sinkURIStr)
 	if err != nil {
@@ -45,6 +56,28 @@ func NewSyncpointStore(ctx context.Context, changefeedID model.ChangeFeedID, sin
 	case "mysql", "tidb", "mysql+ssl", "tidb+ssl":
 		return newMySQLSyncpointStore(ctx, changefeedID, sinkURI)
 	default:
+		if emitter, ok := primarySink.(SyncpointEmitter); ok {
+			return &emitterSyncpointStore{emitter: emitter}, nil
+		}
 		return nil, cerror.ErrSinkURIInvalid.GenWithStack("the sink scheme (%s) is not supported", sinkURI.Scheme)
 	}
 }
+
+// emitterSyncpointStore records the syncpoints by a SyncpointEmitter
+type emitterSyncpointStore struct {
+	emitter SyncpointEmitter
+}
+
+// CreateSynctable is no-op, the syncpoints are recorded in the data
+func (s *emitterSyncpointStore) CreateSynctable(ctx context.Context) error {
+	return nil
+}
+
+func (s *emitterSyncpointStore) SinkSyncpoint(ctx context.Context, id string, checkpointTs uint64) error {
+	return s.emitter.EmitSyncpoint(ctx, checkpointTs)
+}
+
+// Close is no-op, the emitter is closed with the primary sink
+func (s *emitterSyncpointStore) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type mockSyncpointEmitter struct {
+	Sink
+	syncpoints []uint64
+}
+
+func (m *mockSyncpointEmitter) EmitSyncpoint(ctx context.Context, ts uint64) error {
+	m.syncpoints = append(m.syncpoints, ts)
+	return nil
+}
+
+func (s MySQLSinkSuite) TestEmitterSyncpointStore(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+
+	emitter := &mockSyncpointEmitter{}
+	store, err := NewSyncpointStore(ctx, "test-cf", "kafka://127.0.0.1:9092/test", emitter)
+	c.Assert(err, check.IsNil)
+	c.Assert(store.CreateSynctable(ctx), check.IsNil)
+	c.Assert(store.SinkSyncpoint(ctx, "test-cf", 10), check.IsNil)
+	c.Assert(store.SinkSyncpoint(ctx, "test-cf", 20), check.IsNil)
+	c.Assert(store.Close(), check.IsNil)
+	c.Assert(emitter.syncpoints, check.DeepEquals, []uint64{10, 20})
+
+	// the primary sink which can't emit syncpoints is not supported
+	_, err = NewSyncpointStore(ctx, "test-cf", "blackhole://", nil)
+	c.Assert(err, check.ErrorMatches, ".*the sink scheme \\(blackhole\\) is not supported.*")
+}
+
+func (s MySQLSinkSuite) TestMySQLSyncpointStore(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	store := &mysqlSyncpointStore{db: db, isTiDB: false}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE DATABASE IF NOT EXISTS tidb_cdc").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("USE tidb_cdc").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS syncpoint_pos_v1" +
+		" (cf varchar(255),primary_ts varchar(18),gtid_set text,binlog_file varchar(255),binlog_pos bigint unsigned," +
+		"PRIMARY KEY ( `cf`, `primary_ts` ) )").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	c.Assert(store.CreateSynctable(ctx), check.IsNil)
+
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+			AddRow("binlog.000002", "1024", "", "", "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,\n"+
+				"3E11FA47-71CA-11E1-9E33-C80AA9429563:1-3"))
+	mock.ExpectExec("insert ignore into tidb_cdc.syncpoint_pos_v1(cf, primary_ts, gtid_set, binlog_file, binlog_pos) VALUES (?,?,?,?,?)").
+		WithArgs("test-cf", 10, "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,3E11FA47-71CA-11E1-9E33-C80AA9429563:1-3",
+			"binlog.000002", 1024).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	c.Assert(store.SinkSyncpoint(ctx, "test-cf", 10), check.IsNil)
+
+	// binlog is not enabled
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}))
+	err = store.SinkSyncpoint(ctx, "test-cf", 20)
+	c.Assert(err, check.ErrorMatches, ".*binlog is not enabled in the downstream.*")
+
+	mock.ExpectClose()
+	c.Assert(store.Close(), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
@@ -0,0 +1,255 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/tidb/util/codec"
+	pd "github.com/tikv/pd/client"
+	"go.uber.org/zap"
+)
+
+// scanRegionBatchSize is the max number of the regions scanned from PD at once
+const scanRegionBatchSize = 1024
+
+// scanRegionStartKeys returns the start keys of at most limit regions in the
+// span, the keys are decoded from the memcomparable format. There is no limit
+// if limit is not positive.
+func scanRegionStartKeys(ctx context.Context, pdClient pd.Client, span regionspan.Span, limit int) ([][]byte, error) {
+	comparableSpan := regionspan.ToComparableSpan(span)
+	nextKey := comparableSpan.Start
+	var startKeys [][]byte
+	for limit <= 0 || len(startKeys) < limit {
+		batchSize := scanRegionBatchSize
+		if limit > 0 && limit-len(startKeys) < batchSize {
+			batchSize = limit - len(startKeys)
+		}
+		regions, err := pdClient.ScanRegions(ctx, nextKey, comparableSpan.End, batchSize)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrPDBatchLoadRegions, err)
+		}
+		if len(regions) == 0 {
+			break
+		}
+		for _, region := range regions {
+			startKey := region.Meta.GetStartKey()
+			if len(startKey) == 0 {
+				startKeys = append(startKeys, []byte{})
+				continue
+			}
+			_, key, err := codec.DecodeBytes(startKey, nil)
+			if err != nil {
+				return nil, cerror.WrapError(cerror.ErrPDBatchLoadRegions, err)
+			}
+			startKeys = append(startKeys, key)
+		}
+		endKey := regions[len(regions)-1].Meta.GetEndKey()
+		if len(endKey) == 0 || bytes.Compare(endKey, comparableSpan.End) >= 0 {
+			break
+		}
+		nextKey = endKey
+	}
+	return startKeys, nil
+}
+
+// splitSpan splits the span into at most n sub spans by the start keys of the
+// regions in the span, the sub spans have about the same number of regions.
+func splitSpan(span regionspan.Span, regionStartKeys [][]byte, n int) []regionspan.Span {
+	// the start keys which can be the boundaries of the sub spans
+	boundaries := make([][]byte, 0, len(regionStartKeys))
+	for _, key := range regionStartKeys {
+		if bytes.Compare(key, span.Start) > 0 && bytes.Compare(key, span.End) < 0 {
+			boundaries = append(boundaries, key)
+		}
+	}
+	regionNum := len(boundaries) + 1
+	if n > regionNum {
+		n = regionNum
+	}
+	spans := make([]regionspan.Span, 0, n)
+	start := span.Start
+	for i := 1; i < n; i++ {
+		end := boundaries[i*regionNum/n-1]
+		if bytes.Compare(end, start) <= 0 {
+			continue
+		}
+		spans = append(spans, regionspan.Span{Start: start, End: end})
+		start = end
+	}
+	return append(spans, regionspan.Span{Start: start, End: span.End})
+}
+
+// uncoveredSpans returns the key ranges in the span which are not covered by
+// any of the sub spans.
+func uncoveredSpans(span regionspan.Span, subSpans []regionspan.Span) []regionspan.Span {
+	sorted := make([]regionspan.Span, len(subSpans))
+	copy(sorted, subSpans)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Start, sorted[j].Start) < 0
+	})
+	var spans []regionspan.Span
+	start := span.Start
+	for _, subSpan := range sorted {
+		if bytes.Compare(subSpan.Start, start) > 0 {
+			spans = append(spans, regionspan.Span{Start: start, End: subSpan.Start})
+		}
+		if bytes.Compare(subSpan.End, start) > 0 {
+			start = subSpan.End
+		}
+	}
+	if bytes.Compare(start, span.End) < 0 {
+		spans = append(spans, regionspan.Span{Start: start, End: span.End})
+	}
+	return spans
+}
+
+// isTableSplittable returns whether the table can be split into sub tables.
+// The changes of a unique key may be replicated by different captures if the
+// unique key is not the handle, which may conflict in the downstream, so only
+// the tables without such unique keys are split.
+func isTableSplittable(tableInfo *model.TableInfo) bool {
+	uniqueKeys := tableInfo.GetUniqueKeys()
+	if len(uniqueKeys) == 0 {
+		return true
+	}
+	return len(uniqueKeys) == 1 && (tableInfo.PKIsHandle || tableInfo.IsCommonHandle)
+}
+
+// collectSubTables collects the spans of the sub tables in the task statuses,
+// the result is keyed by the physical table IDs.
+func collectSubTables(taskStatus model.ProcessorsInfos) map[model.TableID]map[model.TableID]regionspan.Span {
+	subTables := make(map[model.TableID]map[model.TableID]regionspan.Span)
+	for _, status := range taskStatus {
+		for tableID, replicaInfo := range status.Tables {
+			if !model.IsSubTableID(tableID) || replicaInfo.Span == nil {
+				continue
+			}
+			physicalTableID := model.PhysicalTableID(tableID)
+			if subTables[physicalTableID] == nil {
+				subTables[physicalTableID] = make(map[model.TableID]regionspan.Span)
+			}
+			subTables[physicalTableID][tableID] = *replicaInfo.Span
+		}
+	}
+	return subTables
+}
+
+// addUncoveredSubTables adds the key ranges of a split table which are not
+// replicated by any sub table as new orphan sub tables. It's used to recover
+// the sub tables which are being moved when the owner restarts.
+func addUncoveredSubTables(
+	tableID model.TableID, tableSpan regionspan.Span, subTables map[model.TableID]regionspan.Span,
+	orphanTables map[model.TableID]model.Ts, startTs model.Ts) {
+	subSpans := make([]regionspan.Span, 0, len(subTables))
+	for _, span := range subTables {
+		subSpans = append(subSpans, span)
+	}
+	index := 0
+	for _, span := range uncoveredSpans(tableSpan, subSpans) {
+		for ; index < model.MaxSubTableNum; index++ {
+			if _, exist := subTables[model.SubTableID(tableID, index)]; !exist {
+				break
+			}
+		}
+		if index >= model.MaxSubTableNum {
+			log.Panic("too many sub tables", zap.Int64("tableID", tableID))
+		}
+		subTableID := model.SubTableID(tableID, index)
+		subTables[subTableID] = span
+		orphanTables[subTableID] = startTs
+		log.Info("add the uncovered sub table", zap.Int64("tableID", tableID),
+			zap.Int64("subTableID", subTableID), zap.Stringer("span", span))
+	}
+}
+
+// splitOrphanTables splits the orphan tables with many regions into sub tables,
+// so that they can be replicated by different captures.
+func (c *changeFeed) splitOrphanTables(ctx context.Context, captureNum int) {
+	threshold := c.info.Config.Scheduler.SplitRegionThreshold
+	if threshold <= 0 || captureNum < 2 || c.cyclicEnabled || c.pdClient == nil {
+		return
+	}
+	// the sub tables are flushed by different captures at different times, the
+	// sinks keeping the upstream transactions atomic can't replicate them
+	if !sink.SupportsTableSplit(c.info.SinkURI) {
+		if len(c.orphanTables) > 0 {
+			log.Debug("the sink doesn't support splitting the tables, the tables are not split",
+				zap.String("changefeed", c.id))
+		}
+		return
+	}
+	for tableID, startTs := range c.orphanTables {
+		if model.IsSubTableID(tableID) {
+			continue
+		}
+		if _, exist := c.subTables[tableID]; exist {
+			continue
+		}
+		tableInfo, exist := c.schema.PhysicalTableByID(tableID)
+		if !exist || !isTableSplittable(tableInfo) {
+			continue
+		}
+		span := regionspan.GetTableSpan(tableID, c.info.Config.EnableOldValue)
+		// check whether the table is large enough to be split before scanning all
+		// the regions of the table
+		startKeys, err := scanRegionStartKeys(ctx, c.pdClient, span, 2*threshold)
+		if err == nil && len(startKeys) >= 2*threshold {
+			startKeys, err = scanRegionStartKeys(ctx, c.pdClient, span, 0)
+		}
+		if err != nil {
+			log.Warn("scan the regions of the table failed, the table is not split",
+				zap.String("changefeed", c.id), zap.Int64("tableID", tableID), zap.Error(err))
+			continue
+		}
+		n := len(startKeys) / threshold
+		if n > captureNum {
+			n = captureNum
+		}
+		if n > model.MaxSubTableNum {
+			n = model.MaxSubTableNum
+		}
+		if n < 2 {
+			continue
+		}
+		spans := splitSpan(span, startKeys, n)
+		if len(spans) < 2 {
+			continue
+		}
+		subTables := make(map[model.TableID]regionspan.Span, len(spans))
+		for i, span := range spans {
+			subTableID := model.SubTableID(tableID, i)
+			subTables[subTableID] = span
+			c.orphanTables[subTableID] = startTs
+		}
+		c.subTables[tableID] = subTables
+		delete(c.orphanTables, tableID)
+		log.Info("split the table into sub tables", zap.String("changefeed", c.id),
+			zap.Int64("tableID", tableID), zap.Int("regionCount", len(startKeys)),
+			zap.Int("subTableNum", len(spans)))
+	}
+}
+
+// subTableSpan returns the span of a sub table
+func (c *changeFeed) subTableSpan(tableID model.TableID) (regionspan.Span, bool) {
+	span, exist := c.subTables[model.PhysicalTableID(tableID)][tableID]
+	return span, exist
+}
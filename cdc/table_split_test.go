@@ -0,0 +1,267 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/codec"
+	pd "github.com/tikv/pd/client"
+)
+
+type tableSplitSuite struct{}
+
+var _ = check.Suite(&tableSplitSuite{})
+
+// mockRegionPDClient returns the regions split at the specified keys
+type mockRegionPDClient struct {
+	pd.Client
+	regions []*pd.Region
+}
+
+func newMockRegionPDClient(splitKeys ...[]byte) *mockRegionPDClient {
+	client := &mockRegionPDClient{}
+	startKey := []byte{}
+	for _, key := range splitKeys {
+		endKey := codec.EncodeBytes(nil, key)
+		client.regions = append(client.regions, &pd.Region{Meta: &metapb.Region{StartKey: startKey, EndKey: endKey}})
+		startKey = endKey
+	}
+	client.regions = append(client.regions, &pd.Region{Meta: &metapb.Region{StartKey: startKey}})
+	return client
+}
+
+func (m *mockRegionPDClient) ScanRegions(ctx context.Context, key, endKey []byte, limit int) ([]*pd.Region, error) {
+	var regions []*pd.Region
+	for _, region := range m.regions {
+		if len(region.Meta.EndKey) != 0 && bytes.Compare(region.Meta.EndKey, key) <= 0 {
+			continue
+		}
+		if len(endKey) != 0 && bytes.Compare(region.Meta.StartKey, endKey) >= 0 {
+			break
+		}
+		regions = append(regions, region)
+		if len(regions) == limit {
+			break
+		}
+	}
+	return regions, nil
+}
+
+func rowKey(tableID model.TableID, handle int64) []byte {
+	return tablecodec.EncodeRowKeyWithHandle(tableID, kv.IntHandle(handle))
+}
+
+func (s *tableSplitSuite) TestSplitSpan(c *check.C) {
+	defer testleak.AfterTest(c)()
+	span := regionspan.GetTableSpan(47, true)
+	keys := [][]byte{{}, rowKey(47, 100), rowKey(47, 200), rowKey(47, 300), rowKey(47, 400), rowKey(48, 0)}
+	c.Assert(splitSpan(span, keys, 2), check.DeepEquals, []regionspan.Span{
+		{Start: span.Start, End: rowKey(47, 200)},
+		{Start: rowKey(47, 200), End: span.End},
+	})
+	c.Assert(splitSpan(span, keys, 3), check.DeepEquals, []regionspan.Span{
+		{Start: span.Start, End: rowKey(47, 100)},
+		{Start: rowKey(47, 100), End: rowKey(47, 300)},
+		{Start: rowKey(47, 300), End: span.End},
+	})
+	// there are only 5 regions in the span
+	c.Assert(splitSpan(span, keys, 10), check.HasLen, 5)
+	c.Assert(splitSpan(span, keys[:1], 2), check.DeepEquals, []regionspan.Span{span})
+}
+
+func (s *tableSplitSuite) TestUncoveredSpans(c *check.C) {
+	defer testleak.AfterTest(c)()
+	span := regionspan.Span{Start: []byte("a"), End: []byte("z")}
+	c.Assert(uncoveredSpans(span, nil), check.DeepEquals, []regionspan.Span{span})
+	c.Assert(uncoveredSpans(span, []regionspan.Span{
+		{Start: []byte("m"), End: []byte("p")},
+		{Start: []byte("a"), End: []byte("c")},
+		{Start: []byte("e"), End: []byte("m")},
+	}), check.DeepEquals, []regionspan.Span{
+		{Start: []byte("c"), End: []byte("e")},
+		{Start: []byte("p"), End: []byte("z")},
+	})
+	c.Assert(uncoveredSpans(span, []regionspan.Span{
+		{Start: []byte("a"), End: []byte("m")},
+		{Start: []byte("m"), End: []byte("z")},
+	}), check.HasLen, 0)
+}
+
+func (s *tableSplitSuite) TestIsTableSplittable(c *check.C) {
+	defer testleak.AfterTest(c)()
+	pkCol := &timodel.ColumnInfo{ID: 1, Name: timodel.NewCIStr("id"), FieldType: types.FieldType{Flag: mysql.PriKeyFlag}, State: timodel.StatePublic}
+	ukCol := &timodel.ColumnInfo{ID: 2, Name: timodel.NewCIStr("uk"), FieldType: types.FieldType{Flag: mysql.UniqueKeyFlag | mysql.NotNullFlag}, State: timodel.StatePublic}
+	uk := func(offset int) *timodel.IndexInfo {
+		return &timodel.IndexInfo{ID: 1, Name: timodel.NewCIStr("uk"), Unique: true, State: timodel.StatePublic,
+			Columns: []*timodel.IndexColumn{{Name: ukCol.Name, Offset: offset}}}
+	}
+	testCases := []struct {
+		tableInfo  *timodel.TableInfo
+		splittable bool
+	}{
+		{&timodel.TableInfo{Columns: []*timodel.ColumnInfo{pkCol}, PKIsHandle: true}, true},
+		{&timodel.TableInfo{Columns: []*timodel.ColumnInfo{pkCol, ukCol}, PKIsHandle: true, Indices: []*timodel.IndexInfo{uk(1)}}, false},
+		{&timodel.TableInfo{Columns: []*timodel.ColumnInfo{ukCol}}, true},
+		{&timodel.TableInfo{Columns: []*timodel.ColumnInfo{ukCol}, Indices: []*timodel.IndexInfo{uk(0)}}, false},
+	}
+	for i, tc := range testCases {
+		tableInfo := model.WrapTableInfo(1, "test", 1, tc.tableInfo)
+		c.Assert(isTableSplittable(tableInfo), check.Equals, tc.splittable, check.Commentf("%d", i))
+	}
+}
+
+func (s *tableSplitSuite) TestSplitOrphanTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	store, err := mockstore.NewMockStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+	txn, err := store.Begin()
+	c.Assert(err, check.IsNil)
+	defer txn.Rollback() //nolint:errcheck
+	schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta.NewMeta(txn), 0, false)
+	c.Assert(err, check.IsNil)
+	dbInfo := &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")}
+	jobs := []*timodel.Job{{
+		ID: 1, SchemaID: 1, Type: timodel.ActionCreateSchema, State: timodel.JobStateSynced,
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 1, DBInfo: dbInfo},
+	}}
+	for i, tableID := range []model.TableID{47, 49} {
+		jobs = append(jobs, &timodel.Job{
+			ID: int64(i + 2), SchemaID: 1, TableID: tableID, Type: timodel.ActionCreateTable, State: timodel.JobStateSynced,
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: int64(i + 2),
+				DBInfo:        dbInfo,
+				TableInfo: &timodel.TableInfo{
+					ID:         tableID,
+					Name:       timodel.NewCIStr("t" + string(rune('0'+i))),
+					PKIsHandle: true,
+					Columns: []*timodel.ColumnInfo{
+						{ID: 1, Name: timodel.NewCIStr("id"), FieldType: types.FieldType{Flag: mysql.PriKeyFlag}, State: timodel.StatePublic},
+					},
+				},
+			},
+		})
+	}
+	for _, job := range jobs {
+		c.Assert(schemaSnap.HandleDDL(job), check.IsNil)
+	}
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = true
+	cfg.Scheduler.SplitRegionThreshold = 2
+	cf := &changeFeed{
+		info:          &model.ChangeFeedInfo{Config: cfg},
+		schema:        schemaSnap,
+		orphanTables:  map[model.TableID]model.Ts{47: 10, 49: 10},
+		toCleanTables: make(map[model.TableID]model.Ts),
+		subTables:     make(map[model.TableID]map[model.TableID]regionspan.Span),
+		// the table 47 has 7 regions, and the table 49 has 2 regions
+		pdClient: newMockRegionPDClient(rowKey(47, 100), rowKey(47, 200), rowKey(47, 300),
+			rowKey(47, 400), rowKey(47, 500), rowKey(47, 600), rowKey(49, 100)),
+	}
+	// no table is split if there is only one capture
+	cf.splitOrphanTables(context.Background(), 1)
+	c.Assert(cf.orphanTables, check.HasLen, 2)
+	// no table is split if the sink keeps the upstream transactions atomic or
+	// records the checkpoints of the tables
+	for _, sinkURI := range []string{
+		"mysql://127.0.0.1:3306/",
+		"mysql://127.0.0.1:3306/?checkpoint-table=true",
+		"kafka://127.0.0.1:9092/cdc-test?transactional=true",
+		"s3://bucket/prefix",
+	} {
+		cf.info.SinkURI = sinkURI
+		cf.splitOrphanTables(context.Background(), 3)
+		c.Assert(cf.orphanTables, check.HasLen, 2, check.Commentf("%s", sinkURI))
+	}
+	cf.info.SinkURI = "kafka://127.0.0.1:9092/cdc-test"
+	cf.splitOrphanTables(context.Background(), 3)
+	c.Assert(cf.orphanTables, check.DeepEquals, map[model.TableID]model.Ts{
+		49:                      10,
+		model.SubTableID(47, 0): 10,
+		model.SubTableID(47, 1): 10,
+		model.SubTableID(47, 2): 10,
+	})
+	span := regionspan.GetTableSpan(47, true)
+	c.Assert(cf.subTables, check.DeepEquals, map[model.TableID]map[model.TableID]regionspan.Span{
+		47: {
+			model.SubTableID(47, 0): {Start: span.Start, End: rowKey(47, 200)},
+			model.SubTableID(47, 1): {Start: rowKey(47, 200), End: rowKey(47, 400)},
+			model.SubTableID(47, 2): {Start: rowKey(47, 400), End: span.End},
+		},
+	})
+	// the rows of an upstream transaction spanning two sub tables are emitted
+	// by different sub tables, which is why only the sinks emitting the rows
+	// one by one split the tables
+	var txnSubTables []model.TableID
+	for _, key := range [][]byte{rowKey(47, 150), rowKey(47, 450)} {
+		for subTableID, subTableSpan := range cf.subTables[47] {
+			if regionspan.KeyInSpan(regionspan.ToComparableKey(key), regionspan.ToComparableSpan(subTableSpan)) {
+				txnSubTables = append(txnSubTables, subTableID)
+			}
+		}
+	}
+	c.Assert(txnSubTables, check.DeepEquals, []model.TableID{model.SubTableID(47, 0), model.SubTableID(47, 2)})
+	subTableSpan, ok := cf.subTableSpan(model.SubTableID(47, 1))
+	c.Assert(ok, check.IsTrue)
+	c.Assert(subTableSpan, check.DeepEquals, regionspan.Span{Start: rowKey(47, 200), End: rowKey(47, 400)})
+	_, ok = cf.subTableSpan(49)
+	c.Assert(ok, check.IsFalse)
+
+	// the sub tables are dropped with the table
+	delete(cf.orphanTables, model.SubTableID(47, 2))
+	cf.removeTableOrSubTables(47, 20)
+	c.Assert(cf.orphanTables, check.DeepEquals, map[model.TableID]model.Ts{49: 10})
+	c.Assert(cf.toCleanTables, check.DeepEquals, map[model.TableID]model.Ts{model.SubTableID(47, 2): 20})
+	c.Assert(cf.subTables, check.HasLen, 0)
+}
+
+func (s *tableSplitSuite) TestRecoverSubTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	span := regionspan.Span{Start: []byte("a"), End: []byte("z")}
+	taskStatus := model.ProcessorsInfos{
+		"capture-1": {Tables: map[model.TableID]*model.TableReplicaInfo{
+			47:                      {StartTs: 10},
+			model.SubTableID(49, 0): {StartTs: 10, Span: &regionspan.Span{Start: []byte("a"), End: []byte("h")}},
+		}},
+		"capture-2": {Tables: map[model.TableID]*model.TableReplicaInfo{
+			model.SubTableID(49, 2): {StartTs: 10, Span: &regionspan.Span{Start: []byte("p"), End: []byte("z")}},
+		}},
+	}
+	subTables := collectSubTables(taskStatus)
+	c.Assert(subTables, check.HasLen, 1)
+	c.Assert(subTables[49], check.HasLen, 2)
+
+	// the sub table 1 is being moved, it's recovered by a new sub table
+	orphanTables := make(map[model.TableID]model.Ts)
+	addUncoveredSubTables(49, span, subTables[49], orphanTables, 20)
+	c.Assert(orphanTables, check.DeepEquals, map[model.TableID]model.Ts{model.SubTableID(49, 1): 20})
+	c.Assert(subTables[49][model.SubTableID(49, 1)], check.DeepEquals, regionspan.Span{Start: []byte("h"), End: []byte("p")})
+}
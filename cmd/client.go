@@ -81,6 +81,12 @@ var (
 
 	syncPointEnabled  bool
 	syncPointInterval time.Duration
+	syncPointBarrier  bool
+
+	verifyUpstreamURI   string
+	verifyPrimaryTs     uint64
+	verifyChunkSize     int
+	verifyMaxReportRows int
 
 	optForceRemove bool
 
@@ -95,22 +101,29 @@ type changefeedCommonInfo struct {
 
 // capture holds capture information
 type capture struct {
-	ID            string `json:"id"`
-	IsOwner       bool   `json:"is-owner"`
-	AdvertiseAddr string `json:"address"`
+	ID            string            `json:"id"`
+	IsOwner       bool              `json:"is-owner"`
+	AdvertiseAddr string            `json:"address"`
+	Labels        map[string]string `json:"labels,omitempty"`
 }
 
 // cfMeta holds changefeed info and changefeed status
 type cfMeta struct {
-	Info       *model.ChangeFeedInfo   `json:"info"`
-	Status     *model.ChangeFeedStatus `json:"status"`
-	Count      uint64                  `json:"count"`
-	TaskStatus []captureTaskStatus     `json:"task-status"`
+	Info   *model.ChangeFeedInfo   `json:"info"`
+	Status *model.ChangeFeedStatus `json:"status"`
+	Count  uint64                  `json:"count"`
+	// DeadLetterCount is the count of rows written to the dead letter
+	DeadLetterCount uint64 `json:"dead-letter-count"`
+	// MemoryUsage is the estimated memory used by the changefeed on all the captures
+	MemoryUsage uint64              `json:"memory-usage"`
+	TaskStatus  []captureTaskStatus `json:"task-status"`
 }
 
 type captureTaskStatus struct {
 	CaptureID  string            `json:"capture-id"`
 	TaskStatus *model.TaskStatus `json:"status"`
+	// MemoryUsage is the estimated memory used by the changefeed on the capture
+	MemoryUsage uint64 `json:"memory-usage"`
 }
 
 type profileStatus struct {
@@ -14,10 +14,15 @@
 package cmd
 
 import (
+	"time"
+
 	_ "github.com/go-sql-driver/mysql" // mysql driver
 	"github.com/spf13/cobra"
 )
 
+// drainPollInterval is the interval of polling the progress of draining a capture
+const drainPollInterval = time.Second
+
 func newCaptureCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "capture",
@@ -25,6 +30,7 @@ func newCaptureCommand() *cobra.Command {
 	}
 	command.AddCommand(
 		newListCaptureCommand(),
+		newDrainCaptureCommand(),
 		// TODO: add resign owner command
 	)
 	return command
@@ -45,3 +51,44 @@ func newListCaptureCommand() *cobra.Command {
 	}
 	return command
 }
+
+func newDrainCaptureCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "drain",
+		Short: "Move all tables off a capture and wait until they are replicated by other captures",
+		Long: `Move all tables off a capture and wait until they are replicated by other captures.
+No table is dispatched to the drained capture until it's restarted, so it can be
+stopped without replication lag, e.g. in a rolling upgrade. The capture keeps
+draining even if the command exits or the owner is changed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			ticker := time.NewTicker(drainPollInterval)
+			defer ticker.Stop()
+			lastRemaining := -1
+			for {
+				// the request is sent repeatedly to query the progress, which
+				// is counted by the current owner
+				resp, err := applyDrainCapture(ctx, captureID, getCredential())
+				if err != nil {
+					return err
+				}
+				if resp.Done {
+					cmd.Printf("capture %s is drained\n", captureID)
+					return nil
+				}
+				if resp.RemainingTables >= 0 && resp.RemainingTables != lastRemaining {
+					cmd.Printf("capture %s: %d tables remaining\n", captureID, resp.RemainingTables)
+				}
+				lastRemaining = resp.RemainingTables
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+	command.PersistentFlags().StringVarP(&captureID, "capture-id", "p", "", "Capture ID")
+	_ = command.MarkPersistentFlagRequired("capture-id")
+	return command
+}
@@ -26,12 +26,15 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/redo"
 	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/cyclic"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/ticdc/pkg/verify"
 	"github.com/pingcap/ticdc/pkg/version"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/r3labs/diff"
@@ -49,6 +52,7 @@ const (
 var forceEnableOldValueProtocols = []string{
 	"canal",
 	"maxwell",
+	"debezium",
 }
 
 func newChangefeedCommand() *cobra.Command {
@@ -62,6 +66,7 @@ func newChangefeedCommand() *cobra.Command {
 		newCreateChangefeedCommand(),
 		newUpdateChangefeedCommand(),
 		newStatisticsChangefeedCommand(),
+		newVerifyChangefeedCommand(),
 		newCreateChangefeedCyclicCommand(),
 	)
 	// Add pause, resume, remove changefeed
@@ -218,9 +223,11 @@ func newQueryChangefeedCommand() *cobra.Command {
 			if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
 				return err
 			}
-			var count uint64
+			var count, deadLetterCount, memoryUsage uint64
 			for _, pinfo := range taskPositions {
 				count += pinfo.Count
+				deadLetterCount += pinfo.DeadLetterCount
+				memoryUsage += pinfo.MemoryUsage
 			}
 			processorInfos, err := cdcEtcdCli.GetAllTaskStatus(ctx, changefeedID)
 			if err != nil {
@@ -228,9 +235,16 @@ func newQueryChangefeedCommand() *cobra.Command {
 			}
 			taskStatus := make([]captureTaskStatus, 0, len(processorInfos))
 			for captureID, status := range processorInfos {
-				taskStatus = append(taskStatus, captureTaskStatus{CaptureID: captureID, TaskStatus: status})
+				captureStatus := captureTaskStatus{CaptureID: captureID, TaskStatus: status}
+				if pinfo, ok := taskPositions[captureID]; ok {
+					captureStatus.MemoryUsage = pinfo.MemoryUsage
+				}
+				taskStatus = append(taskStatus, captureStatus)
+			}
+			meta := &cfMeta{
+				Info: info, Status: status, Count: count, DeadLetterCount: deadLetterCount,
+				MemoryUsage: memoryUsage, TaskStatus: taskStatus,
 			}
-			meta := &cfMeta{Info: info, Status: status, Count: count, TaskStatus: taskStatus}
 			if info == nil {
 				log.Warn("this changefeed has been deleted, the residual meta data will be completely deleted within 24 hours.")
 			}
@@ -287,6 +301,9 @@ func verifyChangefeedParamers(ctx context.Context, cmd *cobra.Command, isCreate
 	if disableGCSafePointCheck {
 		cfg.CheckGCSafePoint = false
 	}
+	if err := redo.ValidateConsistentConfig(cfg.Consistent); err != nil {
+		return nil, err
+	}
 	if cyclicReplicaID != 0 || len(cyclicFilterReplicaIDs) != 0 {
 		if !(cyclicReplicaID != 0 && len(cyclicFilterReplicaIDs) != 0) {
 			return nil, errors.New("invaild cyclic config, please make sure using " +
@@ -341,6 +358,9 @@ func verifyChangefeedParamers(ctx context.Context, cmd *cobra.Command, isCreate
 	default:
 		return nil, errors.Errorf("Creating chengfeed with an invalid sort engine(%s), `%s`,`%s` and `%s` are optional.", sortEngine, model.SortUnified, model.SortInMemory, model.SortInFile)
 	}
+	if syncPointBarrier && !syncPointEnabled {
+		return nil, errors.New("--sync-point-barrier requires --sync-point")
+	}
 	info := &model.ChangeFeedInfo{
 		SinkURI:           sinkURI,
 		Opts:              make(map[string]string),
@@ -353,6 +373,7 @@ func verifyChangefeedParamers(ctx context.Context, cmd *cobra.Command, isCreate
 		State:             model.StateNormal,
 		SyncPointEnabled:  syncPointEnabled,
 		SyncPointInterval: syncPointInterval,
+		SyncPointBarrier:  syncPointBarrier,
 		CreatorVersion:    version.ReleaseVersion,
 	}
 
@@ -448,6 +469,7 @@ func changefeedConfigVariables(command *cobra.Command) {
 	command.PersistentFlags().BoolVar(&cyclicSyncDDL, "cyclic-sync-ddl", true, "(Expremental) Cyclic replication sync DDL of changefeed")
 	command.PersistentFlags().BoolVar(&syncPointEnabled, "sync-point", false, "(Expremental) Set and Record syncpoint in replication(default off)")
 	command.PersistentFlags().DurationVar(&syncPointInterval, "sync-interval", 10*time.Minute, "(Expremental) Set the interval for syncpoint in replication(default 10min)")
+	command.PersistentFlags().BoolVar(&syncPointBarrier, "sync-point-barrier", false, "(Expremental) Pause the replication at every syncpoint until all the data before it is flushed, which makes the syncpoints consistent snapshots(default off)")
 }
 
 func newCreateChangefeedCommand() *cobra.Command {
@@ -555,6 +577,8 @@ func newUpdateChangefeedCommand() *cobra.Command {
 					info.SyncPointEnabled = syncPointEnabled
 				case "sync-interval":
 					info.SyncPointInterval = syncPointInterval
+				case "sync-point-barrier":
+					info.SyncPointBarrier = syncPointBarrier
 				case "pd", "tz", "start-ts", "changefeed-id", "no-confirm":
 					// do nothing
 				default:
@@ -565,6 +589,9 @@ func newUpdateChangefeedCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if info.SyncPointBarrier && !info.SyncPointEnabled {
+				return errors.New("--sync-point-barrier requires --sync-point")
+			}
 
 			resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, getCredential())
 			// if no cdc owner exists, allow user to update changefeed config
@@ -679,6 +706,72 @@ func newStatisticsChangefeedCommand() *cobra.Command {
 	return command
 }
 
+func newVerifyChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the data of the upstream and the downstream TiDB at a syncpoint of a replication task (changefeed)",
+		Long: `Verify the data of the upstream and the downstream TiDB at a syncpoint of a replication task (changefeed).
+The changefeed must be created with --sync-point, and --sync-point-barrier makes
+the syncpoints consistent snapshots. Both sides are read at the ts of the
+syncpoint by the snapshot read of TiDB, so only the TiDB downstreams are
+supported, the MySQL downstreams are rejected even if the replication is paused
+by the syncpoint barrier.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			info, err := cdcEtcdCli.GetChangeFeedInfo(ctx, changefeedID)
+			if err != nil {
+				return err
+			}
+			f, err := filter.NewFilter(info.Config)
+			if err != nil {
+				return err
+			}
+			upstream, err := verify.OpenDB(verifyUpstreamURI)
+			if err != nil {
+				return err
+			}
+			defer upstream.Close()
+			downstream, err := verify.OpenDB(info.SinkURI)
+			if err != nil {
+				return err
+			}
+			defer downstream.Close()
+			if err := verify.CheckTiDB(ctx, downstream); err != nil {
+				return err
+			}
+
+			syncpoint, err := verify.LoadSyncpoint(ctx, downstream, changefeedID, verifyPrimaryTs)
+			if err != nil {
+				return err
+			}
+			verifier := verify.NewVerifier(upstream, downstream, &verify.Config{
+				Filter:        f,
+				ChunkSize:     verifyChunkSize,
+				MaxReportRows: verifyMaxReportRows,
+			})
+			report, err := verifier.Verify(ctx, syncpoint)
+			if err != nil {
+				return err
+			}
+			if err := jsonPrint(cmd, report); err != nil {
+				return err
+			}
+			if count := report.InconsistentTables(); count != 0 {
+				return cerror.ErrDataInconsistent.GenWithStackByArgs(count)
+			}
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().StringVar(&verifyUpstreamURI, "upstream-uri", "", "URI of the upstream TiDB, e.g. mysql://root@127.0.0.1:4000/")
+	command.PersistentFlags().Uint64Var(&verifyPrimaryTs, "primary-ts", 0, "Primary ts of the syncpoint to verify, the latest syncpoint is verified if it's 0")
+	command.PersistentFlags().IntVar(&verifyChunkSize, "chunk-size", 1000, "Number of the rows compared in a chunk")
+	command.PersistentFlags().IntVar(&verifyMaxReportRows, "max-report-rows", 100, "Max number of the mismatching rows reported for a table")
+	_ = command.MarkPersistentFlagRequired("changefeed-id")
+	_ = command.MarkPersistentFlagRequired("upstream-uri")
+	return command
+}
+
 func newCreateChangefeedCyclicCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "cyclic",
@@ -111,6 +111,8 @@ func (s *decodeFileSuite) TestAndWriteExampleReplicaTOML(c *check.C) {
 	c.Assert(err, check.IsNil)
 
 	c.Assert(cfg.CaseSensitive, check.IsTrue)
+	c.Assert(cfg.MemoryQuota, check.Equals, uint64(0))
+	c.Assert(cfg.GoroutineQuota, check.Equals, 0)
 	c.Assert(cfg.Filter, check.DeepEquals, &config.FilterConfig{
 		IgnoreTxnStartTs: []uint64{1, 2},
 		Rules:            []string{"*.*", "!test.*"},
@@ -131,6 +133,16 @@ func (s *decodeFileSuite) TestAndWriteExampleReplicaTOML(c *check.C) {
 		FilterReplicaID: []uint64{2, 3},
 		SyncDDL:         true,
 	})
+	c.Assert(cfg.Scheduler, check.DeepEquals, &config.SchedulerConfig{
+		Tp:          "table-number",
+		PollingTime: -1,
+	})
+	c.Assert(cfg.Consistent, check.DeepEquals, &config.ConsistentConfig{
+		Level:             "none",
+		MaxLogSize:        64,
+		FlushIntervalInMs: 1000,
+		Storage:           "s3://bucket/prefix",
+	})
 }
 
 func (s *decodeFileSuite) TestAndWriteExampleServerTOML(c *check.C) {
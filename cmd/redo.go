@@ -0,0 +1,79 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pingcap/ticdc/cdc/redo"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/logutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redoStorage  string
+	redoSinkURI  string
+	redoLogLevel string
+	redoLogFile  string
+)
+
+func init() {
+	rootCmd.AddCommand(newRedoCommand())
+}
+
+func newRedoCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "redo",
+		Short: "Utility about the redo log of a replication task (changefeed)",
+	}
+	command.PersistentFlags().StringVar(&redoLogLevel, "log-level", "info", "log level (etc: debug|info|warn|error)")
+	command.PersistentFlags().StringVar(&redoLogFile, "log-file", "", "log file path")
+	command.AddCommand(newRedoApplyCommand())
+	return command
+}
+
+func newRedoApplyCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply the redo log to the downstream to recover it to a consistent state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel := initCmd(cmd, &logutil.Config{Level: redoLogLevel, File: redoLogFile})
+			defer cancel()
+			cfg := config.GetDefaultReplicaConfig()
+			if len(configFile) > 0 {
+				if err := strictDecodeFile(configFile, "TiCDC changefeed", cfg); err != nil {
+					return err
+				}
+			}
+			meta, err := redo.Apply(defaultContext, &redo.ApplyConfig{
+				Storage:       redoStorage,
+				ChangefeedID:  changefeedID,
+				SinkURI:       redoSinkURI,
+				ReplicaConfig: cfg,
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("redo log applied, the downstream is consistent at ts %d\n", meta.ResolvedTs)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&redoStorage, "storage", "", "URI of the storage the redo log is written to, e.g. s3://bucket/prefix or local:///data/redo")
+	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().StringVar(&redoSinkURI, "sink-uri", "", "URI of the downstream MySQL or TiDB, e.g. mysql://root@127.0.0.1:3306/")
+	command.PersistentFlags().StringVar(&configFile, "config", "", "Path of the configuration file of the changefeed, e.g. for the route rules of the sink")
+	_ = command.MarkPersistentFlagRequired("storage")
+	_ = command.MarkPersistentFlagRequired("changefeed-id")
+	_ = command.MarkPersistentFlagRequired("sink-uri")
+	return command
+}
@@ -36,6 +36,7 @@ import (
 var (
 	serverPdAddr         string
 	serverConfigFilePath string
+	serverLabels         string
 
 	serverConfig = config.GetDefaultServerConfig()
 
@@ -79,6 +80,7 @@ func initServerCmd(cmd *cobra.Command) {
 	// We use 8GB as a safe default before we support local configuration file.
 	cmd.Flags().Uint64Var(&serverConfig.Sorter.MaxMemoryConsumption, "sorter-max-memory-consumption", defaultServerConfig.Sorter.MaxMemoryConsumption, "maximum memory consumption of in-memory sort")
 	cmd.Flags().StringVar(&serverConfig.Sorter.SortDir, "sort-dir", defaultServerConfig.Sorter.SortDir, "sorter's temporary file directory")
+	cmd.Flags().StringVar(&serverLabels, "labels", "", "Set the labels of the capture used by the placement rules, e.g. zone=a,tier=large")
 
 	addSecurityFlags(cmd.Flags(), true /* isServer */)
 
@@ -132,6 +134,7 @@ func loadAndVerifyServerConfig(cmd *cobra.Command) (*config.ServerConfig, error)
 			return nil, err
 		}
 	}
+	var labelErr error
 	cmd.Flags().Visit(func(flag *pflag.Flag) {
 		switch flag.Name {
 		case "addr":
@@ -170,12 +173,17 @@ func loadAndVerifyServerConfig(cmd *cobra.Command) (*config.ServerConfig, error)
 			conf.Security.CertAllowedCN = serverConfig.Security.CertAllowedCN
 		case "sort-dir":
 			conf.Sorter.SortDir = serverConfig.Sorter.SortDir
+		case "labels":
+			conf.Labels, labelErr = config.ParseLabels(serverLabels)
 		case "pd", "config":
 			// do nothing
 		default:
 			log.Panic("unknown flag, please report a bug", zap.String("flagName", flag.Name))
 		}
 	})
+	if labelErr != nil {
+		return nil, errors.Trace(labelErr)
+	}
 	if err := conf.ValidateAndAdjust(); err != nil {
 		return nil, errors.Trace(err)
 	}
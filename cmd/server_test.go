@@ -63,6 +63,13 @@ func (s *serverSuite) TestLoadAndVerifyServerConfig(c *check.C) {
 	_, err = loadAndVerifyServerConfig(cmd)
 	c.Assert(err, check.ErrorMatches, ".*PD endpoint scheme should be http.*")
 
+	// test invalid labels
+	cmd = new(cobra.Command)
+	initServerCmd(cmd)
+	c.Assert(cmd.ParseFlags([]string{"--labels=zone"}), check.IsNil)
+	_, err = loadAndVerifyServerConfig(cmd)
+	c.Assert(err, check.ErrorMatches, ".*invalid label zone.*")
+
 	// test undefined flag
 	cmd = new(cobra.Command)
 	initServerCmd(cmd)
@@ -91,6 +98,7 @@ func (s *serverSuite) TestLoadAndVerifyServerConfig(c *check.C) {
 		"--sorter-num-concurrent-worker", "80",
 		"--sorter-num-workerpool-goroutine", "90",
 		"--sort-dir", "/tmp/just_a_test",
+		"--labels", "zone=a, tier=large",
 	}), check.IsNil)
 	cfg, err = loadAndVerifyServerConfig(cmd)
 	c.Assert(err, check.IsNil)
@@ -102,6 +110,7 @@ func (s *serverSuite) TestLoadAndVerifyServerConfig(c *check.C) {
 		GcTTL:                  10,
 		TZ:                     "UTC",
 		CaptureSessionTTL:      10,
+		Labels:                 map[string]string{"zone": "a", "tier": "large"},
 		OwnerFlushInterval:     config.TomlDuration(150 * time.Millisecond),
 		ProcessorFlushInterval: config.TomlDuration(150 * time.Millisecond),
 		Sorter: &config.SorterConfig{
@@ -132,6 +141,7 @@ log-level = "warn"
 gc-ttl = 500
 tz = "US"
 capture-session-ttl = 10
+labels = { zone = "b" }
 
 owner-flush-interval = "600ms"
 processor-flush-interval = "600ms"
@@ -159,6 +169,7 @@ sort-dir = "/tmp/just_a_test"
 		GcTTL:                  500,
 		TZ:                     "US",
 		CaptureSessionTTL:      10,
+		Labels:                 map[string]string{"zone": "b"},
 		OwnerFlushInterval:     config.TomlDuration(600 * time.Millisecond),
 		ProcessorFlushInterval: config.TomlDuration(600 * time.Millisecond),
 		Sorter: &config.SorterConfig{
@@ -208,6 +219,7 @@ cert-allowed-cn = ["dd","ee"]
 		GcTTL:                  10,
 		TZ:                     "UTC",
 		CaptureSessionTTL:      10,
+		Labels:                 map[string]string{"zone": "b"},
 		OwnerFlushInterval:     config.TomlDuration(150 * time.Millisecond),
 		ProcessorFlushInterval: config.TomlDuration(150 * time.Millisecond),
 		Sorter: &config.SorterConfig{
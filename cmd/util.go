@@ -122,7 +122,7 @@ func getAllCaptures(ctx context.Context) ([]*capture, error) {
 	for _, c := range raw {
 		isOwner := c.ID == ownerID
 		captures = append(captures,
-			&capture{ID: c.ID, IsOwner: isOwner, AdvertiseAddr: c.AdvertiseAddr})
+			&capture{ID: c.ID, IsOwner: isOwner, AdvertiseAddr: c.AdvertiseAddr, Labels: c.Labels})
 	}
 	return captures, nil
 }
@@ -208,6 +208,43 @@ func applyOwnerChangefeedQuery(
 	return string(body), nil
 }
 
+func applyDrainCapture(
+	ctx context.Context, captureID model.CaptureID, credential *security.Credential,
+) (*cdc.DrainCaptureResp, error) {
+	owner, err := getOwnerCapture(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if credential.IsTLSEnabled() {
+		scheme = "https"
+	}
+	addr := fmt.Sprintf("%s://%s/capture/owner/drain_capture", scheme, owner.AdvertiseAddr)
+	cli, err := httputil.NewClient(credential)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cli.PostForm(addr, url.Values(map[string][]string{
+		cdc.APIOpVarCaptureID: {captureID},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.BadRequestf("drain capture failed")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.BadRequestf("%s", string(body))
+	}
+	drainResp := &cdc.DrainCaptureResp{}
+	if err := json.Unmarshal(body, drainResp); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return drainResp, nil
+}
+
 func jsonPrint(cmd *cobra.Command, v interface{}) error {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -261,9 +298,12 @@ func verifyTables(ctx context.Context, credential *security.Credential, cfg *con
 		}
 		if !tableInfo.IsEligible(false /* forceReplicate */) {
 			ineligibleTables = append(ineligibleTables, tableName)
-		} else {
-			eligibleTables = append(eligibleTables, tableName)
+			continue
+		}
+		if err := filter.VerifyTableColumns(tableInfo); err != nil {
+			return nil, nil, err
 		}
+		eligibleTables = append(eligibleTables, tableName)
 	}
 	return
 }
@@ -41,6 +41,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/quotes"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
 	"go.uber.org/zap"
 )
 
@@ -53,8 +54,10 @@ var (
 	kafkaVersion         = "2.4.0"
 	kafkaMaxMessageBytes = math.MaxInt64
 	kafkaMaxBatchSize    = math.MaxInt64
+	kafkaProtocol        = codec.ProtocolDefault
 
-	downstreamURIStr string
+	downstreamURIStr  string
+	schemaRegistryURI string
 
 	logPath       string
 	logLevel      string
@@ -63,7 +66,7 @@ var (
 )
 
 func init() {
-	var upstreamURIStr string
+	var upstreamURIStr, protocol string
 
 	flag.StringVar(&upstreamURIStr, "upstream-uri", "", "Kafka uri")
 	flag.StringVar(&downstreamURIStr, "downstream-uri", "", "downstream sink uri")
@@ -73,6 +76,8 @@ func init() {
 	flag.StringVar(&ca, "ca", "", "CA certificate path for Kafka SSL connection")
 	flag.StringVar(&cert, "cert", "", "Certificate path for Kafka SSL connection")
 	flag.StringVar(&key, "key", "", "Private key path for Kafka SSL connection")
+	flag.StringVar(&protocol, "protocol", "", "Protocol of the Kafka messages, overrides the protocol of upstream-uri")
+	flag.StringVar(&schemaRegistryURI, "schema-registry", "", "Schema registry URI, required by the avro protocol")
 	flag.Parse()
 
 	err := logutil.InitLogger(&logutil.Config{
@@ -143,6 +148,25 @@ func init() {
 		log.Info("Setting max-batch-size", zap.Int("max-batch-size", c))
 		kafkaMaxBatchSize = c
 	}
+
+	s = upstreamURI.Query().Get("protocol")
+	if protocol != "" {
+		s = protocol
+	}
+	if s != "" {
+		kafkaProtocol.FromString(s)
+	}
+	if kafkaProtocol == codec.ProtocolAvro && schemaRegistryURI == "" {
+		log.Fatal("schema-registry must be specified for the avro protocol")
+	}
+}
+
+// withResolvedEvents returns whether the protocol carries resolved events.
+// For protocols without resolved events, the consumer treats the commit ts of
+// received rows and DDLs as the watermark of the partition, which is accurate
+// only if the sink sent the events of a partition in commit ts order.
+func withResolvedEvents(protocol codec.Protocol) bool {
+	return protocol == codec.ProtocolDefault || protocol == codec.ProtocolDebezium
 }
 
 func getPartitionNum(address []string, topic string, cfg *sarama.Config) (int32, error) {
@@ -306,6 +330,8 @@ type Consumer struct {
 
 	ddlSink              sink.Sink
 	fakeTableIDGenerator *fakeTableIDGenerator
+	avroSchemaManager    *codec.AvroSchemaManager
+	tz                   *time.Location
 
 	globalResolvedTs uint64
 }
@@ -323,6 +349,13 @@ func NewConsumer(ctx context.Context) (*Consumer, error) {
 		return nil, errors.Trace(err)
 	}
 	c := new(Consumer)
+	c.tz = tz
+	if kafkaProtocol == codec.ProtocolAvro {
+		c.avroSchemaManager, err = codec.NewAvroSchemaManager(ctx, &security.Credential{}, schemaRegistryURI, "-value")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	c.fakeTableIDGenerator = &fakeTableIDGenerator{
 		tableIDs: make(map[string]int64),
 	}
@@ -388,7 +421,7 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
 ClaimMessages:
 	for message := range claim.Messages() {
 		log.Info("Message claimed", zap.Int32("partition", message.Partition), zap.ByteString("key", message.Key), zap.ByteString("value", message.Value))
-		batchDecoder, err := codec.NewJSONEventBatchDecoder(message.Key, message.Value)
+		batchDecoder, err := c.newBatchDecoder(ctx, message.Key, message.Value)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -417,13 +450,26 @@ ClaimMessages:
 					log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
 				}
 				c.appendDDL(ddl)
+				// DDLs are sent to the partitions carrying the rows of the table,
+				// after the rows before them
+				if !withResolvedEvents(kafkaProtocol) {
+					c.advanceResolvedTs(sink, partition, ddl.CommitTs)
+				}
 			case model.MqMessageTypeRow:
 				row, err := batchDecoder.NextRowChangedEvent()
 				if err != nil {
 					log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
 				}
+				if row.CommitTs == 0 {
+					// avro messages carry no commit ts, use the timestamp of the
+					// message instead
+					row.CommitTs = oracle.ComposeTS(oracle.GetPhysical(message.Timestamp), 0)
+				}
+				if !withResolvedEvents(kafkaProtocol) {
+					c.advanceResolvedTs(sink, partition, row.CommitTs-1)
+				}
 				globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
-				if row.CommitTs <= globalResolvedTs || row.CommitTs <= sink.resolvedTs {
+				if row.CommitTs <= globalResolvedTs || (withResolvedEvents(kafkaProtocol) && row.CommitTs <= sink.resolvedTs) {
 					log.Debug("filter fallback row", zap.ByteString("row", message.Key),
 						zap.Uint64("globalResolvedTs", globalResolvedTs),
 						zap.Uint64("sinkResolvedTs", sink.resolvedTs),
@@ -448,13 +494,13 @@ ClaimMessages:
 				if err != nil {
 					log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
 				}
-				resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
-				if resolvedTs < ts {
-					log.Debug("update sink resolved ts",
-						zap.Uint64("ts", ts),
-						zap.Int32("partition", partition))
-					atomic.StoreUint64(&sink.resolvedTs, ts)
+				c.advanceResolvedTs(sink, partition, ts)
+			case model.MqMessageTypeSyncpoint:
+				ts, err := batchDecoder.NextSyncpointEvent()
+				if err != nil {
+					log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
 				}
+				log.Info("syncpoint received", zap.Int32("partition", partition), zap.Uint64("ts", ts))
 			}
 			session.MarkMessage(message, "")
 		}
@@ -468,6 +514,36 @@ ClaimMessages:
 	return nil
 }
 
+func (c *Consumer) newBatchDecoder(ctx context.Context, key, value []byte) (codec.EventBatchDecoder, error) {
+	switch kafkaProtocol {
+	case codec.ProtocolCanal:
+		return codec.NewCanalEventBatchDecoder(value)
+	case codec.ProtocolCanalJSON:
+		return codec.NewCanalFlatEventBatchDecoder(value)
+	case codec.ProtocolMaxwell:
+		return codec.NewMaxwellEventBatchDecoder(key, value)
+	case codec.ProtocolAvro:
+		return codec.NewAvroEventBatchDecoder(ctx, key, value, c.avroSchemaManager, c.tz)
+	case codec.ProtocolDebezium:
+		return codec.NewDebeziumEventBatchDecoder(key, value)
+	default:
+		return codec.NewJSONEventBatchDecoder(key, value)
+	}
+}
+
+func (c *Consumer) advanceResolvedTs(sink *struct {
+	sink.Sink
+	resolvedTs uint64
+}, partition int32, ts uint64) {
+	resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
+	if resolvedTs < ts {
+		log.Debug("update sink resolved ts",
+			zap.Uint64("ts", ts),
+			zap.Int32("partition", partition))
+		atomic.StoreUint64(&sink.resolvedTs, ts)
+	}
+}
+
 func (c *Consumer) appendDDL(ddl *model.DDLEvent) {
 	c.ddlListMu.Lock()
 	defer c.ddlListMu.Unlock()
@@ -567,18 +643,29 @@ func (c *Consumer) Run(ctx context.Context) error {
 		if todoDDL != nil && todoDDL.CommitTs < globalResolvedTs {
 			globalResolvedTs = todoDDL.CommitTs
 		}
-		if lastGlobalResolvedTs == globalResolvedTs {
+		if lastGlobalResolvedTs == globalResolvedTs && withResolvedEvents(kafkaProtocol) {
 			continue
 		}
-		lastGlobalResolvedTs = globalResolvedTs
-		atomic.StoreUint64(&c.globalResolvedTs, globalResolvedTs)
-		log.Info("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
+		if lastGlobalResolvedTs != globalResolvedTs {
+			lastGlobalResolvedTs = globalResolvedTs
+			atomic.StoreUint64(&c.globalResolvedTs, globalResolvedTs)
+			log.Info("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
+		}
 
 		err = c.forEachSink(func(sink *struct {
 			sink.Sink
 			resolvedTs uint64
 		}) error {
-			return syncFlushRowChangedEvents(ctx, sink, globalResolvedTs)
+			if withResolvedEvents(kafkaProtocol) {
+				return syncFlushRowChangedEvents(ctx, sink, globalResolvedTs)
+			}
+			// the watermark of an idle partition never moves forward without
+			// resolved events, so flush every partition to its own watermark
+			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
+			if todoDDL != nil && todoDDL.CommitTs < resolvedTs {
+				resolvedTs = todoDDL.CommitTs
+			}
+			return syncFlushRowChangedEvents(ctx, sink, resolvedTs)
 		})
 		if err != nil {
 			return errors.Trace(err)
@@ -53,21 +53,30 @@ var defaultReplicaConfig = &ReplicaConfig{
 		Tp:          "table-number",
 		PollingTime: -1,
 	},
+	Consistent: &ConsistentConfig{
+		Level:             "none",
+		MaxLogSize:        64,
+		FlushIntervalInMs: 1000,
+		Storage:           "",
+	},
 }
 
 // ReplicaConfig represents some addition replication config for a changefeed
 type ReplicaConfig replicaConfig
 
 type replicaConfig struct {
-	CaseSensitive    bool             `toml:"case-sensitive" json:"case-sensitive"`
-	EnableOldValue   bool             `toml:"enable-old-value" json:"enable-old-value"`
-	ForceReplicate   bool             `toml:"force-replicate" json:"force-replicate"`
-	CheckGCSafePoint bool             `toml:"check-gc-safe-point" json:"check-gc-safe-point"`
-	Filter           *FilterConfig    `toml:"filter" json:"filter"`
-	Mounter          *MounterConfig   `toml:"mounter" json:"mounter"`
-	Sink             *SinkConfig      `toml:"sink" json:"sink"`
-	Cyclic           *CyclicConfig    `toml:"cyclic-replication" json:"cyclic-replication"`
-	Scheduler        *SchedulerConfig `toml:"scheduler" json:"scheduler"`
+	CaseSensitive    bool              `toml:"case-sensitive" json:"case-sensitive"`
+	EnableOldValue   bool              `toml:"enable-old-value" json:"enable-old-value"`
+	ForceReplicate   bool              `toml:"force-replicate" json:"force-replicate"`
+	CheckGCSafePoint bool              `toml:"check-gc-safe-point" json:"check-gc-safe-point"`
+	MemoryQuota      uint64            `toml:"memory-quota" json:"memory-quota"`
+	GoroutineQuota   int               `toml:"goroutine-quota" json:"goroutine-quota"`
+	Filter           *FilterConfig     `toml:"filter" json:"filter"`
+	Mounter          *MounterConfig    `toml:"mounter" json:"mounter"`
+	Sink             *SinkConfig       `toml:"sink" json:"sink"`
+	Cyclic           *CyclicConfig     `toml:"cyclic-replication" json:"cyclic-replication"`
+	Scheduler        *SchedulerConfig  `toml:"scheduler" json:"scheduler"`
+	Consistent       *ConsistentConfig `toml:"consistent" json:"consistent"`
 }
 
 // Marshal returns the json marshal format of a ReplicationConfig
@@ -176,6 +185,9 @@ type ServerConfig struct {
 
 	CaptureSessionTTL int `toml:"capture-session-ttl" json:"capture-session-ttl"`
 
+	// Labels are the labels of the capture, e.g. zone=a,tier=large
+	Labels map[string]string `toml:"labels" json:"labels"`
+
 	OwnerFlushInterval     TomlDuration `toml:"owner-flush-interval" json:"owner-flush-interval"`
 	ProcessorFlushInterval TomlDuration `toml:"processor-flush-interval" json:"processor-flush-interval"`
 
@@ -250,6 +262,12 @@ func (c *ServerConfig) ValidateAndAdjust() error {
 		c.CaptureSessionTTL = 10
 	}
 
+	for key, value := range c.Labels {
+		if key == "" || value == "" {
+			return cerror.ErrInvalidServerOption.GenWithStack("invalid label %s=%s, the key and the value must not be empty", key, value)
+		}
+	}
+
 	if c.Security != nil && c.Security.IsTLSEnabled() {
 		var err error
 		_, err = c.Security.ToTLSConfig()
@@ -288,6 +306,23 @@ func (c *ServerConfig) ValidateAndAdjust() error {
 	return nil
 }
 
+// ParseLabels parses the labels in the format of key1=value1,key2=value2
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, label := range strings.Split(s, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, cerror.ErrInvalidServerOption.GenWithStack("invalid label %s, the format should be key=value", label)
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return labels, nil
+}
+
 // GetDefaultServerConfig returns the default server config
 func GetDefaultServerConfig() *ServerConfig {
 	return defaultServerConfig.Clone()
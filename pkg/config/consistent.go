@@ -0,0 +1,28 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// ConsistentConfig represents the redo log config for a changefeed
+type ConsistentConfig struct {
+	// Level is the consistent level of the downstream, the redo log is
+	// written only if it is "eventual"
+	Level string `toml:"level" json:"level"`
+	// MaxLogSize is the max size in MB of a redo log file
+	MaxLogSize int64 `toml:"max-log-size" json:"max-log-size"`
+	// FlushIntervalInMs is the interval the redo log is flushed to the storage
+	FlushIntervalInMs int64 `toml:"flush-interval" json:"flush-interval"`
+	// Storage is the URI of the storage the redo log is written to,
+	// such as local:///data/redo or s3://bucket/prefix
+	Storage string `toml:"storage" json:"storage"`
+}
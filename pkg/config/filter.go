@@ -24,4 +24,34 @@ type FilterConfig struct {
 	*filter.MySQLReplicationRules
 	IgnoreTxnStartTs []uint64           `toml:"ignore-txn-start-ts" json:"ignore-txn-start-ts"`
 	DDLAllowlist     []model.ActionType `toml:"ddl-allow-list" json:"ddl-allow-list"`
+	ColumnRules      []*ColumnRule      `toml:"column-rules" json:"column-rules"`
+	EventFilters     []*EventFilterRule `toml:"event-filters" json:"event-filters"`
+}
+
+// ColumnRule selects the columns to replicate for the matched tables. If
+// Columns is not empty, only the listed columns are replicated, and the
+// columns in IgnoreColumns are never replicated. Column names are case
+// insensitive.
+type ColumnRule struct {
+	Matcher       []string `toml:"matcher" json:"matcher"`
+	Columns       []string `toml:"columns" json:"columns"`
+	IgnoreColumns []string `toml:"ignore-columns" json:"ignore-columns"`
+}
+
+// EventFilterRule drops the DML events of the matched tables by the event type
+// or by the row values. The value expressions are SQL expressions referencing
+// the columns of the table, such as `status = 'archived'`, and the event is
+// dropped if the expression evaluates to true. If both the old value and the
+// new value expressions of update are set, an update event is dropped only if
+// both of them evaluate to true.
+type EventFilterRule struct {
+	Matcher      []string `toml:"matcher" json:"matcher"`
+	IgnoreInsert bool     `toml:"ignore-insert" json:"ignore-insert"`
+	IgnoreUpdate bool     `toml:"ignore-update" json:"ignore-update"`
+	IgnoreDelete bool     `toml:"ignore-delete" json:"ignore-delete"`
+
+	IgnoreInsertValueExpr    string `toml:"ignore-insert-value-expr" json:"ignore-insert-value-expr"`
+	IgnoreUpdateOldValueExpr string `toml:"ignore-update-old-value-expr" json:"ignore-update-old-value-expr"`
+	IgnoreUpdateNewValueExpr string `toml:"ignore-update-new-value-expr" json:"ignore-update-new-value-expr"`
+	IgnoreDeleteValueExpr    string `toml:"ignore-delete-value-expr" json:"ignore-delete-value-expr"`
 }
@@ -15,7 +15,25 @@ package config
 
 // SchedulerConfig represents scheduler config for a changefeed
 type SchedulerConfig struct {
+	// Tp is the type of the scheduler, table-number and workload are supported
 	Tp string `toml:"type" json:"type"`
 	// PollingTime represents the polling cycle of checking the skewness of workload and try to do schedule if needed
 	PollingTime int `toml:"polling-time" json:"polling-time"`
+	// SplitRegionThreshold is the min region count of the tables which are
+	// split into sub tables across the captures, 0 means no table is split
+	SplitRegionThreshold int `toml:"split-region-threshold" json:"split-region-threshold"`
+	// CaptureLabels are the labels of the captures the changefeed runs on, the
+	// changefeed runs on all the captures if it's empty
+	CaptureLabels map[string]string `toml:"capture-labels" json:"capture-labels"`
+	// PlacementRules constrain the captures the tables are replicated by
+	PlacementRules []*PlacementRule `toml:"placement-rules" json:"placement-rules"`
+}
+
+// PlacementRule constrains the captures the matched tables are replicated by,
+// the tables are only replicated by the captures having all the Labels and
+// none of the ExcludeLabels.
+type PlacementRule struct {
+	Matcher       []string          `toml:"matcher" json:"matcher"`
+	Labels        map[string]string `toml:"labels" json:"labels"`
+	ExcludeLabels map[string]string `toml:"exclude-labels" json:"exclude-labels"`
 }
@@ -16,11 +16,88 @@ package config
 // SinkConfig represents sink config for a changefeed
 type SinkConfig struct {
 	DispatchRules []*DispatchRule `toml:"dispatchers" json:"dispatchers"`
+	TopicRules    []*TopicRule    `toml:"topic-rules" json:"topic-rules"`
 	Protocol      string          `toml:"protocol" json:"protocol"`
+
+	// OnConflict is the policy resolving the conflicts in the MySQL sink, which
+	// happen when an update or delete affects no row or an insert hits a
+	// duplicate key, it's one of the ConflictPolicyXXX
+	OnConflict string `toml:"on-conflict" json:"on-conflict"`
+	// ConflictTimestampColumn is the column compared by the last-write-wins policy
+	ConflictTimestampColumn string `toml:"conflict-timestamp-column" json:"conflict-timestamp-column"`
+	// ConflictLog enables writing the conflicts into a downstream table
+	ConflictLog bool `toml:"conflict-log" json:"conflict-log"`
+
+	// DeadLetter is where the MySQL sink writes the rows rejected by the
+	// downstream with non-retryable errors, it's one of the DeadLetterTypeXXX,
+	// the rows fail the changefeed if it's empty
+	DeadLetter string `toml:"dead-letter" json:"dead-letter"`
+	// DeadLetterFile is the path of the local dead letter file
+	DeadLetterFile string `toml:"dead-letter-file" json:"dead-letter-file"`
+
+	// RouteRules maps the upstream tables to the downstream schemas and tables
+	// in the MySQL sink, the first matched rule takes effect
+	RouteRules []*RouteRule `toml:"route-rules" json:"route-rules"`
+	// DDLRules skip or rewrite the DDLs executed by the MySQL sink, all the
+	// matched rules take effect in order
+	DDLRules []*DDLRule `toml:"ddl-rules" json:"ddl-rules"`
 }
 
+// The types of the dead letter of the MySQL sink
+const (
+	// DeadLetterTypeTable writes the rows to a downstream table
+	DeadLetterTypeTable = "table"
+	// DeadLetterTypeFile writes the rows to a local file
+	DeadLetterTypeFile = "file"
+)
+
+// The policies resolving the conflicts in the MySQL sink
+const (
+	// ConflictPolicyOverwrite writes the row change over the downstream row
+	ConflictPolicyOverwrite = "overwrite"
+	// ConflictPolicyIgnore drops the conflicting row change
+	ConflictPolicyIgnore = "ignore"
+	// ConflictPolicyError fails the changefeed
+	ConflictPolicyError = "error"
+	// ConflictPolicyLastWriteWins keeps the row with the larger value of the
+	// timestamp column
+	ConflictPolicyLastWriteWins = "last-write-wins"
+)
+
 // DispatchRule represents partition rule for a table
 type DispatchRule struct {
 	Matcher    []string `toml:"matcher" json:"matcher"`
 	Dispatcher string   `toml:"dispatcher" json:"dispatcher"`
+	// Columns are the names of the columns to hash, it is only used by the
+	// columns dispatcher
+	Columns []string `toml:"columns" json:"columns"`
+}
+
+// TopicRule represents the topic routing rule for a table, the topic can be an
+// expression with {schema} and {table} placeholders, such as `{schema}_{table}`
+type TopicRule struct {
+	Matcher []string `toml:"matcher" json:"matcher"`
+	Topic   string   `toml:"topic" json:"topic"`
+}
+
+// RouteRule represents the schema and table routing rule for a table in the
+// MySQL sink, the targets can be expressions with {schema} and {table}
+// placeholders, such as `{schema}_bak`, an empty target keeps the upstream name
+type RouteRule struct {
+	Matcher      []string `toml:"matcher" json:"matcher"`
+	TargetSchema string   `toml:"target-schema" json:"target-schema"`
+	TargetTable  string   `toml:"target-table" json:"target-table"`
+}
+
+// DDLRule skips or rewrites the DDLs of the matched tables in the MySQL sink.
+// DDLTypes are the types of the DDLs to match, such as `add index`, all the
+// DDLs are matched if it's empty. The DDL is skipped if Skip is true, otherwise
+// the matches of the regular expression Pattern in the query are replaced with
+// Replacement, which can reference the submatches like `$1`.
+type DDLRule struct {
+	Matcher     []string `toml:"matcher" json:"matcher"`
+	DDLTypes    []string `toml:"ddl-types" json:"ddl-types"`
+	Skip        bool     `toml:"skip" json:"skip"`
+	Pattern     string   `toml:"pattern" json:"pattern"`
+	Replacement string   `toml:"replacement" json:"replacement"`
 }
@@ -55,9 +55,11 @@ var (
 	ErrNewStore               = errors.Normalize("new store failed", errors.RFCCodeText("CDC:ErrNewStore"))
 
 	// rule related errors
-	ErrEncodeFailed      = errors.Normalize("encode failed: %s", errors.RFCCodeText("CDC:ErrEncodeFailed"))
-	ErrDecodeFailed      = errors.Normalize("decode failed: %s", errors.RFCCodeText("CDC:ErrDecodeFailed"))
-	ErrFilterRuleInvalid = errors.Normalize("filter rule is invalid", errors.RFCCodeText("CDC:ErrFilterRuleInvalid"))
+	ErrEncodeFailed              = errors.Normalize("encode failed: %s", errors.RFCCodeText("CDC:ErrEncodeFailed"))
+	ErrDecodeFailed              = errors.Normalize("decode failed: %s", errors.RFCCodeText("CDC:ErrDecodeFailed"))
+	ErrFilterRuleInvalid         = errors.Normalize("filter rule is invalid", errors.RFCCodeText("CDC:ErrFilterRuleInvalid"))
+	ErrColumnFilterDropHandleKey = errors.Normalize("handle key column %s of table %s.%s can't be dropped by the column filter", errors.RFCCodeText("CDC:ErrColumnFilterDropHandleKey"))
+	ErrEventFilterExprInvalid    = errors.Normalize("event filter expression %s is invalid for table %s.%s", errors.RFCCodeText("CDC:ErrEventFilterExprInvalid"))
 
 	// internal errors
 	ErrAdminStopProcessor = errors.Normalize("stop processor by admin command", errors.RFCCodeText("CDC:ErrAdminStopProcessor"))
@@ -75,6 +77,7 @@ var (
 	ErrKafkaNewSaramaProducer    = errors.Normalize("new sarama producer", errors.RFCCodeText("CDC:ErrKafkaNewSaramaProducer"))
 	ErrKafkaInvalidClientID      = errors.Normalize("invalid kafka client ID '%s'", errors.RFCCodeText("CDC:ErrKafkaInvalidClientID"))
 	ErrKafkaInvalidVersion       = errors.Normalize("invalid kafka version", errors.RFCCodeText("CDC:ErrKafkaInvalidVersion"))
+	ErrKafkaTransaction          = errors.Normalize("kafka transaction failed", errors.RFCCodeText("CDC:ErrKafkaTransaction"))
 	ErrPulsarNewProducer         = errors.Normalize("new pulsar producer", errors.RFCCodeText("CDC:ErrPulsarNewProducer"))
 	ErrPulsarSendMessage         = errors.Normalize("pulsar send message failed", errors.RFCCodeText("CDC:ErrPulsarSendMessage"))
 	ErrFileSinkCreateDir         = errors.Normalize("file sink create dir", errors.RFCCodeText("CDC:ErrFileSinkCreateDir"))
@@ -83,27 +86,41 @@ var (
 	ErrS3SinkWriteStorage        = errors.Normalize("write to storage", errors.RFCCodeText("CDC:ErrS3SinkWriteStorage"))
 	ErrS3SinkInitialzie          = errors.Normalize("new s3 sink", errors.RFCCodeText("CDC:ErrS3SinkInitialzie"))
 	ErrS3SinkStorageAPI          = errors.Normalize("s3 sink storage api", errors.RFCCodeText("CDC:ErrS3SinkStorageAPI"))
+	ErrHTTPSinkInvalidConfig     = errors.Normalize("http sink config invalid", errors.RFCCodeText("CDC:ErrHTTPSinkInvalidConfig"))
+	ErrHTTPSinkSendRequest       = errors.Normalize("http sink send request failed", errors.RFCCodeText("CDC:ErrHTTPSinkSendRequest"))
 	ErrPrepareAvroFailed         = errors.Normalize("prepare avro failed", errors.RFCCodeText("CDC:ErrPrepareAvroFailed"))
 	ErrAsyncBroadcaseNotSupport  = errors.Normalize("Async broadcasts not supported", errors.RFCCodeText("CDC:ErrAsyncBroadcaseNotSupport"))
 	ErrKafkaInvalidConfig        = errors.Normalize("kafka config invalid", errors.RFCCodeText("CDC:ErrKafkaInvalidConfig"))
+	ErrInvalidTopicExpression    = errors.Normalize("invalid topic expression %s", errors.RFCCodeText("CDC:ErrInvalidTopicExpression"))
+	ErrDispatcherInvalidRule     = errors.Normalize("invalid dispatch rule: %s", errors.RFCCodeText("CDC:ErrDispatcherInvalidRule"))
+	ErrDispatcherColumnNotFound  = errors.Normalize("column %s required by the columns dispatcher is not found in table %s.%s", errors.RFCCodeText("CDC:ErrDispatcherColumnNotFound"))
 	ErrSinkURIInvalid            = errors.Normalize("sink uri invalid", errors.RFCCodeText("CDC:ErrSinkURIInvalid"))
 	ErrMySQLTxnError             = errors.Normalize("MySQL txn error", errors.RFCCodeText("CDC:ErrMySQLTxnError"))
 	ErrMySQLQueryError           = errors.Normalize("MySQL query error", errors.RFCCodeText("CDC:ErrMySQLQueryError"))
 	ErrMySQLConnectionError      = errors.Normalize("MySQL connection error", errors.RFCCodeText("CDC:ErrMySQLConnectionError"))
 	ErrMySQLInvalidConfig        = errors.Normalize("MySQL config invaldi", errors.RFCCodeText("CDC:ErrMySQLInvalidConfig"))
 	ErrMySQLWorkerPanic          = errors.Normalize("MySQL worker panic", errors.RFCCodeText("CDC:ErrMySQLWorkerPanic"))
+	ErrMySQLConflict             = errors.Normalize("conflict of table %s.%s at commit ts %d: %s", errors.RFCCodeText("CDC:ErrMySQLConflict"))
+	ErrMySQLRouteDDL             = errors.Normalize("route the DDL %s failed", errors.RFCCodeText("CDC:ErrMySQLRouteDDL"))
+	ErrInvalidRouteRule          = errors.Normalize("route rule %v is invalid: %s", errors.RFCCodeText("CDC:ErrInvalidRouteRule"))
+	ErrInvalidDDLRule            = errors.Normalize("DDL rule %v is invalid: %s", errors.RFCCodeText("CDC:ErrInvalidDDLRule"))
+	ErrMySQLTransformDDL         = errors.Normalize("transform the DDL %s failed", errors.RFCCodeText("CDC:ErrMySQLTransformDDL"))
+	ErrMySQLDeadLetter           = errors.Normalize("write the row to the dead letter failed", errors.RFCCodeText("CDC:ErrMySQLDeadLetter"))
 	ErrAvroToEnvelopeError       = errors.Normalize("to envelope failed", errors.RFCCodeText("CDC:ErrAvroToEnvelopeError"))
 	ErrAvroUnknownType           = errors.Normalize("unknown type for Avro: %v", errors.RFCCodeText("CDC:ErrAvroUnknownType"))
 	ErrAvroMarshalFailed         = errors.Normalize("json marshal failed", errors.RFCCodeText("CDC:ErrAvroMarshalFailed"))
 	ErrAvroEncodeFailed          = errors.Normalize("encode to avro native data", errors.RFCCodeText("CDC:ErrAvroEncodeFailed"))
 	ErrAvroEncodeToBinary        = errors.Normalize("encode to binray from native", errors.RFCCodeText("CDC:ErrAvroEncodeToBinary"))
 	ErrAvroSchemaAPIError        = errors.Normalize("schema manager API error", errors.RFCCodeText("CDC:ErrAvroSchemaAPIError"))
+	ErrAvroDecodeFailed          = errors.Normalize("decode avro data failed", errors.RFCCodeText("CDC:ErrAvroDecodeFailed"))
 	ErrMaxwellEncodeFailed       = errors.Normalize("maxwell encode failed", errors.RFCCodeText("CDC:ErrMaxwellEncodeFailed"))
 	ErrMaxwellDecodeFailed       = errors.Normalize("maxwell decode failed", errors.RFCCodeText("CDC:ErrMaxwellDecodeFailed"))
 	ErrMaxwellInvalidData        = errors.Normalize("maxwell invalid data", errors.RFCCodeText("CDC:ErrMaxwellInvalidData"))
 	ErrJSONCodecInvalidData      = errors.Normalize("json codec invalid data", errors.RFCCodeText("CDC:ErrJSONCodecInvalidData"))
 	ErrCanalDecodeFailed         = errors.Normalize("canal decode failed", errors.RFCCodeText("CDC:ErrCanalDecodeFailed"))
 	ErrCanalEncodeFailed         = errors.Normalize("canal encode failed", errors.RFCCodeText("CDC:ErrCanalEncodeFailed"))
+	ErrDebeziumEncodeFailed      = errors.Normalize("debezium encode failed", errors.RFCCodeText("CDC:ErrDebeziumEncodeFailed"))
+	ErrDebeziumDecodeFailed      = errors.Normalize("debezium decode failed", errors.RFCCodeText("CDC:ErrDebeziumDecodeFailed"))
 	ErrOldValueNotEnabled        = errors.Normalize("old value is not enabled", errors.RFCCodeText("CDC:ErrOldValueNotEnabled"))
 
 	// utilities related errors
@@ -171,6 +188,8 @@ var (
 	ErrInternalServerError          = errors.Normalize("internal server error", errors.RFCCodeText("CDC:ErrInternalServerError"))
 	ErrOwnerSortDir                 = errors.Normalize("owner sort dir", errors.RFCCodeText("CDC:ErrOwnerSortDir"))
 	ErrOwnerChangefeedNotFound      = errors.Normalize("changefeed %s not found in owner cache", errors.RFCCodeText("CDC:ErrOwnerChangefeedNotFound"))
+	ErrDrainCaptureFailed           = errors.Normalize("drain capture %s failed: %s", errors.RFCCodeText("CDC:ErrDrainCaptureFailed"))
+	ErrInvalidPlacementRule         = errors.Normalize("placement rule %v is invalid: %s", errors.RFCCodeText("CDC:ErrInvalidPlacementRule"))
 	ErrChangefeedAbnormalState      = errors.Normalize("changefeed in abnormal state: %s, replication status: %+v", errors.RFCCodeText("CDC:ErrChangefeedAbnormalState"))
 	ErrInvalidAdminJobType          = errors.Normalize("invalid admin job type: %d", errors.RFCCodeText("CDC:ErrInvalidAdminJobType"))
 	ErrOwnerEtcdWatch               = errors.Normalize("etcd watch returns error", errors.RFCCodeText("CDC:ErrOwnerEtcdWatch"))
@@ -208,4 +227,15 @@ var (
 
 	// owner related errors
 	ErrOwnerInconsistentStates = errors.Normalize("owner encountered inconsistent state. report a bug if this happens frequently. %s", errors.RFCCodeText("CDC:ErrOwnerInconsistentStates"))
+
+	// changefeed verification errors
+	ErrSyncpointNotFound = errors.Normalize("syncpoint of changefeed %s is not found in the downstream", errors.RFCCodeText("CDC:ErrSyncpointNotFound"))
+	ErrVerifyFailed      = errors.Normalize("verify changefeed failed", errors.RFCCodeText("CDC:ErrVerifyFailed"))
+	ErrDataInconsistent  = errors.Normalize("data of %d tables is inconsistent between the upstream and the downstream", errors.RFCCodeText("CDC:ErrDataInconsistent"))
+
+	// redo log errors
+	ErrRedoConfigInvalid = errors.Normalize("redo log config invalid: %s", errors.RFCCodeText("CDC:ErrRedoConfigInvalid"))
+	ErrRedoStorage       = errors.Normalize("redo log storage api", errors.RFCCodeText("CDC:ErrRedoStorage"))
+	ErrRedoMetaNotFound  = errors.Normalize("redo log meta of changefeed %s is not found", errors.RFCCodeText("CDC:ErrRedoMetaNotFound"))
+	ErrRedoDecodeFailed  = errors.Normalize("decode redo log %s failed", errors.RFCCodeText("CDC:ErrRedoDecodeFailed"))
 )
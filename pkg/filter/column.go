@@ -0,0 +1,142 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"strings"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+type columnRule struct {
+	filterV2.Filter
+	// columns and ignoreColumns are keyed by the lower case column names
+	columns       map[string]struct{}
+	ignoreColumns map[string]struct{}
+}
+
+func newColumnRules(cfg *config.ReplicaConfig) ([]*columnRule, error) {
+	rules := make([]*columnRule, 0, len(cfg.Filter.ColumnRules))
+	for _, ruleConfig := range cfg.Filter.ColumnRules {
+		if len(ruleConfig.Columns) == 0 && len(ruleConfig.IgnoreColumns) == 0 {
+			return nil, cerror.ErrFilterRuleInvalid.GenWithStack("column rule %v selects no column", ruleConfig.Matcher)
+		}
+		f, err := filterV2.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filterV2.CaseInsensitive(f)
+		}
+		rule := &columnRule{Filter: f}
+		if len(ruleConfig.Columns) != 0 {
+			rule.columns = make(map[string]struct{}, len(ruleConfig.Columns))
+			for _, name := range ruleConfig.Columns {
+				rule.columns[strings.ToLower(name)] = struct{}{}
+			}
+		}
+		rule.ignoreColumns = make(map[string]struct{}, len(ruleConfig.IgnoreColumns))
+		for _, name := range ruleConfig.IgnoreColumns {
+			rule.ignoreColumns[strings.ToLower(name)] = struct{}{}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *columnRule) shouldDropColumn(name string) bool {
+	name = strings.ToLower(name)
+	if r.columns != nil {
+		if _, ok := r.columns[name]; !ok {
+			return true
+		}
+	}
+	_, ok := r.ignoreColumns[name]
+	return ok
+}
+
+func (r *columnRule) filterColumns(table *model.TableName, cols []*model.Column) ([]*model.Column, error) {
+	if len(cols) == 0 {
+		return cols, nil
+	}
+	filtered := make([]*model.Column, len(cols))
+	for i, col := range cols {
+		if col == nil || !r.shouldDropColumn(col.Name) {
+			filtered[i] = col
+			continue
+		}
+		if col.Flag.IsHandleKey() {
+			return nil, cerror.ErrColumnFilterDropHandleKey.GenWithStackByArgs(col.Name, table.Schema, table.Table)
+		}
+	}
+	return filtered, nil
+}
+
+// FilterColumns drops the columns which are not selected by the first column
+// rule matching the table of the row. The dropped columns are set to nil
+// rather than removed, so that the offsets in IndexColumns are still valid.
+// An error is returned if a handle key column would be dropped.
+func (f *Filter) FilterColumns(row *model.RowChangedEvent) error {
+	for _, rule := range f.columnRules {
+		if !rule.MatchTable(row.Table.Schema, row.Table.Table) {
+			continue
+		}
+		columns, err := rule.filterColumns(row.Table, row.Columns)
+		if err != nil {
+			return err
+		}
+		preColumns, err := rule.filterColumns(row.Table, row.PreColumns)
+		if err != nil {
+			return err
+		}
+		row.Columns, row.PreColumns = columns, preColumns
+		return nil
+	}
+	return nil
+}
+
+// ShouldDropColumn returns true if the column is dropped by the first column
+// rule matching the table.
+func (f *Filter) ShouldDropColumn(schema, table, column string) bool {
+	for _, rule := range f.columnRules {
+		if rule.MatchTable(schema, table) {
+			return rule.shouldDropColumn(column)
+		}
+	}
+	return false
+}
+
+// VerifyTableColumns returns an error if the first column rule matching the
+// table drops a handle key column of the table. The rules are verified against
+// the table schema when the changefeed is created and after the DDLs, so the
+// changefeed fails before any row of the table is replicated.
+func (f *Filter) VerifyTableColumns(tableInfo *model.TableInfo) error {
+	schema, table := tableInfo.TableName.Schema, tableInfo.TableName.Table
+	for _, rule := range f.columnRules {
+		if !rule.MatchTable(schema, table) {
+			continue
+		}
+		for _, colInfo := range tableInfo.Columns {
+			flag := tableInfo.ColumnsFlag[colInfo.ID]
+			if flag.IsHandleKey() && rule.shouldDropColumn(colInfo.Name.O) {
+				return cerror.ErrColumnFilterDropHandleKey.GenWithStackByArgs(colInfo.Name.O, schema, table)
+			}
+		}
+		return nil
+	}
+	return nil
+}
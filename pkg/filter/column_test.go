@@ -0,0 +1,154 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type columnFilterSuite struct{}
+
+var _ = check.Suite(&columnFilterSuite{})
+
+func newColumnFilterTestRow(schema, table string) *model.RowChangedEvent {
+	newColumns := func() []*model.Column {
+		return []*model.Column{
+			{Name: "id", Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "name", Value: "Bob"},
+			{Name: "Email", Value: "bob@example.com"},
+			nil,
+			{Name: "phone", Value: "123456"},
+		}
+	}
+	return &model.RowChangedEvent{
+		Table:      &model.TableName{Schema: schema, Table: table},
+		PreColumns: newColumns(),
+		Columns:    newColumns(),
+	}
+}
+
+func columnNames(cols []*model.Column) []string {
+	names := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			names = append(names, "")
+			continue
+		}
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+func (s *columnFilterSuite) TestFilterColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.CaseSensitive = false
+	cfg.Filter.ColumnRules = []*config.ColumnRule{
+		{Matcher: []string{"test.user"}, IgnoreColumns: []string{"email", "PHONE"}},
+		{Matcher: []string{"test.*"}, Columns: []string{"id", "name", "phone"}, IgnoreColumns: []string{"phone"}},
+	}
+	f, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+
+	testCases := []struct {
+		schema   string
+		table    string
+		expected []string
+	}{
+		{"test", "user", []string{"id", "name", "", "", ""}},
+		{"TEST", "USER", []string{"id", "name", "", "", ""}},
+		{"test", "t1", []string{"id", "name", "", "", ""}},
+		{"other", "user", []string{"id", "name", "Email", "", "phone"}},
+	}
+	for _, tc := range testCases {
+		row := newColumnFilterTestRow(tc.schema, tc.table)
+		c.Assert(f.FilterColumns(row), check.IsNil)
+		c.Assert(columnNames(row.Columns), check.DeepEquals, tc.expected, check.Commentf("%#v", tc))
+		c.Assert(columnNames(row.PreColumns), check.DeepEquals, tc.expected, check.Commentf("%#v", tc))
+	}
+
+	// the columns of an insert event
+	row := newColumnFilterTestRow("test", "user")
+	row.PreColumns = nil
+	c.Assert(f.FilterColumns(row), check.IsNil)
+	c.Assert(row.PreColumns, check.IsNil)
+	c.Assert(columnNames(row.Columns), check.DeepEquals, []string{"id", "name", "", "", ""})
+
+	c.Assert(f.ShouldDropColumn("test", "user", "Email"), check.IsTrue)
+	c.Assert(f.ShouldDropColumn("test", "user", "name"), check.IsFalse)
+	c.Assert(f.ShouldDropColumn("test", "t1", "phone"), check.IsTrue)
+	c.Assert(f.ShouldDropColumn("other", "user", "phone"), check.IsFalse)
+}
+
+func (s *columnFilterSuite) TestFilterHandleKeyColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.ColumnRules = []*config.ColumnRule{
+		{Matcher: []string{"test.*"}, Columns: []string{"name"}},
+	}
+	f, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	row := newColumnFilterTestRow("test", "t1")
+	err = f.FilterColumns(row)
+	c.Assert(err, check.ErrorMatches, ".*handle key column id of table test.t1 can't be dropped.*")
+	// the row is untouched on error
+	c.Assert(columnNames(row.Columns), check.DeepEquals, []string{"id", "name", "Email", "", "phone"})
+}
+
+func (s *columnFilterSuite) TestVerifyTableColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	tableInfo := newEventFilterTestTableInfo(1, "test", "t1", 1)
+	tableInfo.Columns[0].Flag = mysql.PriKeyFlag
+	tableInfo.PKIsHandle = true
+	tableInfo = model.WrapTableInfo(1, "test", 1, tableInfo.TableInfo)
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.ColumnRules = []*config.ColumnRule{
+		{Matcher: []string{"test.t1"}, IgnoreColumns: []string{"amount"}},
+		{Matcher: []string{"test.*"}, Columns: []string{"status"}},
+	}
+	f, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(f.VerifyTableColumns(tableInfo), check.IsNil)
+
+	tableInfo = model.WrapTableInfo(1, "test", 1, &timodel.TableInfo{
+		ID:         2,
+		Name:       timodel.NewCIStr("t2"),
+		Columns:    tableInfo.Columns,
+		PKIsHandle: true,
+	})
+	err = f.VerifyTableColumns(tableInfo)
+	c.Assert(err, check.ErrorMatches, ".*handle key column id of table test.t2 can't be dropped.*")
+}
+
+func (s *columnFilterSuite) TestInvalidColumnRules(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.ColumnRules = []*config.ColumnRule{
+		{Matcher: []string{"test.*"}},
+	}
+	_, err := NewFilter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*selects no column.*")
+
+	cfg.Filter.ColumnRules = []*config.ColumnRule{
+		{Matcher: []string{"[test.*"}, Columns: []string{"id"}},
+	}
+	_, err = NewFilter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*ErrFilterRuleInvalid.*")
+}
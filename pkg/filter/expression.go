@@ -0,0 +1,238 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"sync"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tidb/expression"
+	// planner/core registers expression.RewriteAstExpr used to build the expressions
+	_ "github.com/pingcap/tidb/planner/core"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/mock"
+)
+
+type eventRule struct {
+	filterV2.Filter
+	*config.EventFilterRule
+}
+
+func newEventRules(cfg *config.ReplicaConfig) ([]*eventRule, error) {
+	if len(cfg.Filter.EventFilters) != 0 && !cfg.EnableOldValue {
+		return nil, cerror.ErrFilterRuleInvalid.GenWithStack("event filters require enable-old-value")
+	}
+	p := parser.New()
+	rules := make([]*eventRule, 0, len(cfg.Filter.EventFilters))
+	for _, ruleConfig := range cfg.Filter.EventFilters {
+		f, err := filterV2.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filterV2.CaseInsensitive(f)
+		}
+		// The expressions can only be built with the table schema, check the
+		// syntax here to report the obvious mistakes early.
+		for _, expr := range []string{
+			ruleConfig.IgnoreInsertValueExpr, ruleConfig.IgnoreUpdateOldValueExpr,
+			ruleConfig.IgnoreUpdateNewValueExpr, ruleConfig.IgnoreDeleteValueExpr,
+		} {
+			if expr == "" {
+				continue
+			}
+			if _, err := p.ParseOneStmt("select "+expr, "", ""); err != nil {
+				return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+			}
+		}
+		rules = append(rules, &eventRule{Filter: f, EventFilterRule: ruleConfig})
+	}
+	return rules, nil
+}
+
+// tableEventRules holds the expressions of the event rules matching a table,
+// built with a specified version of the table schema.
+type tableEventRules struct {
+	// mu protects the expressions, which are not safe to be evaluated concurrently
+	mu      sync.Mutex
+	version uint64
+	table   model.TableName
+	ctx     sessionctx.Context
+	rules   []*tableEventRule
+}
+
+type tableEventRule struct {
+	*eventRule
+	insertValueExpr    expression.Expression
+	updateOldValueExpr expression.Expression
+	updateNewValueExpr expression.Expression
+	deleteValueExpr    expression.Expression
+}
+
+func newTableEventRules(rules []*eventRule, tableInfo *model.TableInfo) (*tableEventRules, error) {
+	t := &tableEventRules{
+		version: tableInfo.TableInfoVersion,
+		table:   tableInfo.TableName,
+		ctx:     mock.NewContext(),
+	}
+	buildExpr := func(expr string) (expression.Expression, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		e, err := expression.ParseSimpleExprWithTableInfo(t.ctx, expr, tableInfo.TableInfo)
+		if err != nil {
+			return nil, cerror.ErrEventFilterExprInvalid.Wrap(err).GenWithStackByArgs(
+				expr, tableInfo.TableName.Schema, tableInfo.TableName.Table)
+		}
+		return e, nil
+	}
+	for _, rule := range rules {
+		if !rule.MatchTable(tableInfo.TableName.Schema, tableInfo.TableName.Table) {
+			continue
+		}
+		tableRule := &tableEventRule{eventRule: rule}
+		var err error
+		if tableRule.insertValueExpr, err = buildExpr(rule.IgnoreInsertValueExpr); err != nil {
+			return nil, err
+		}
+		if tableRule.updateOldValueExpr, err = buildExpr(rule.IgnoreUpdateOldValueExpr); err != nil {
+			return nil, err
+		}
+		if tableRule.updateNewValueExpr, err = buildExpr(rule.IgnoreUpdateNewValueExpr); err != nil {
+			return nil, err
+		}
+		if tableRule.deleteValueExpr, err = buildExpr(rule.IgnoreDeleteValueExpr); err != nil {
+			return nil, err
+		}
+		t.rules = append(t.rules, tableRule)
+	}
+	return t, nil
+}
+
+// evalExpr returns true if the expression evaluates to true, NULL is regarded
+// as false.
+func (t *tableEventRules) evalExpr(rawExpr string, expr expression.Expression, row chunk.Row) (bool, error) {
+	d, err := expr.Eval(row)
+	if err == nil {
+		if d.IsNull() {
+			return false, nil
+		}
+		var b int64
+		if b, err = d.ToBool(t.ctx.GetSessionVars().StmtCtx); err == nil {
+			return b == 1, nil
+		}
+	}
+	return false, cerror.ErrEventFilterExprInvalid.Wrap(err).GenWithStackByArgs(
+		rawExpr, t.table.Schema, t.table.Table)
+}
+
+func (t *tableEventRules) shouldSkip(preRow, row chunk.Row, preRowExist, rowExist bool) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, rule := range t.rules {
+		var skip bool
+		var err error
+		switch {
+		case rowExist && !preRowExist:
+			skip = rule.IgnoreInsert
+			if !skip && rule.insertValueExpr != nil {
+				skip, err = t.evalExpr(rule.IgnoreInsertValueExpr, rule.insertValueExpr, row)
+			}
+		case rowExist && preRowExist:
+			skip = rule.IgnoreUpdate
+			if skip || (rule.updateOldValueExpr == nil && rule.updateNewValueExpr == nil) {
+				break
+			}
+			skip = true
+			if rule.updateOldValueExpr != nil {
+				skip, err = t.evalExpr(rule.IgnoreUpdateOldValueExpr, rule.updateOldValueExpr, preRow)
+			}
+			if skip && err == nil && rule.updateNewValueExpr != nil {
+				skip, err = t.evalExpr(rule.IgnoreUpdateNewValueExpr, rule.updateNewValueExpr, row)
+			}
+		case preRowExist:
+			skip = rule.IgnoreDelete
+			if !skip && rule.deleteValueExpr != nil {
+				skip, err = t.evalExpr(rule.IgnoreDeleteValueExpr, rule.deleteValueExpr, preRow)
+			}
+		}
+		if err != nil {
+			return false, err
+		}
+		if skip {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func datumsToChunkRow(tableInfo *model.TableInfo, datums map[int64]types.Datum) chunk.Row {
+	row := make([]types.Datum, len(tableInfo.Columns))
+	for _, col := range tableInfo.Columns {
+		// the missing columns, such as the virtual generated columns, are NULL
+		if d, ok := datums[col.ID]; ok {
+			row[col.Offset] = d
+		}
+	}
+	return chunk.MutRowFromDatums(row).ToRow()
+}
+
+// ShouldSkipDMLEvent returns true if the DML event should be dropped by the
+// event filters. preRow and row are the column values keyed by the column IDs,
+// the event is an insert if preRow is nil, or a delete if row is nil.
+func (f *Filter) ShouldSkipDMLEvent(tableInfo *model.TableInfo, preRow, row map[int64]types.Datum) (bool, error) {
+	if len(f.eventRules) == 0 {
+		return false, nil
+	}
+	f.eventRulesMu.Lock()
+	rules, ok := f.tableEventRules[tableInfo.ID]
+	if !ok || rules.version != tableInfo.TableInfoVersion {
+		var err error
+		rules, err = newTableEventRules(f.eventRules, tableInfo)
+		if err != nil {
+			f.eventRulesMu.Unlock()
+			return false, err
+		}
+		f.tableEventRules[tableInfo.ID] = rules
+	}
+	f.eventRulesMu.Unlock()
+	if len(rules.rules) == 0 {
+		return false, nil
+	}
+	var preChunkRow, chunkRow chunk.Row
+	if preRow != nil {
+		preChunkRow = datumsToChunkRow(tableInfo, preRow)
+	}
+	if row != nil {
+		chunkRow = datumsToChunkRow(tableInfo, row)
+	}
+	return rules.shouldSkip(preChunkRow, chunkRow, preRow != nil, row != nil)
+}
+
+// HasEventFilter returns true if some DML events of the table may be dropped
+// by the event filters.
+func (f *Filter) HasEventFilter(schema, table string) bool {
+	for _, rule := range f.eventRules {
+		if rule.MatchTable(schema, table) {
+			return true
+		}
+	}
+	return false
+}
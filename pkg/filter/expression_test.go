@@ -0,0 +1,135 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/types"
+)
+
+type eventFilterSuite struct{}
+
+var _ = check.Suite(&eventFilterSuite{})
+
+func newEventFilterTestTableInfo(tableID int64, schema, table string, version uint64) *model.TableInfo {
+	newColumn := func(id int64, name string, tp byte) *timodel.ColumnInfo {
+		return &timodel.ColumnInfo{
+			ID:        id,
+			Name:      timodel.NewCIStr(name),
+			Offset:    int(id - 1),
+			FieldType: *types.NewFieldType(tp),
+			State:     timodel.StatePublic,
+		}
+	}
+	return model.WrapTableInfo(1, schema, version, &timodel.TableInfo{
+		ID:   tableID,
+		Name: timodel.NewCIStr(table),
+		Columns: []*timodel.ColumnInfo{
+			newColumn(1, "id", mysql.TypeLong),
+			newColumn(2, "status", mysql.TypeVarchar),
+			newColumn(3, "amount", mysql.TypeLong),
+		},
+	})
+}
+
+func newEventFilterTestDatums(id int64, status string, amount interface{}) map[int64]types.Datum {
+	return map[int64]types.Datum{
+		1: types.NewIntDatum(id),
+		2: types.NewStringDatum(status),
+		3: types.NewDatum(amount),
+	}
+}
+
+func (s *eventFilterSuite) TestShouldSkipDMLEvent(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{
+			Matcher:               []string{"test.orders"},
+			IgnoreInsertValueExpr: "status = 'archived'",
+			IgnoreDeleteValueExpr: "amount < 10",
+		},
+		{
+			Matcher:                  []string{"test.orders"},
+			IgnoreUpdateOldValueExpr: "status = 'pending'",
+			IgnoreUpdateNewValueExpr: "status = 'archived'",
+		},
+		{
+			Matcher:      []string{"test.logs"},
+			IgnoreDelete: true,
+		},
+	}
+	f, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(f.HasEventFilter("test", "orders"), check.IsTrue)
+	c.Assert(f.HasEventFilter("test", "users"), check.IsFalse)
+
+	orders := newEventFilterTestTableInfo(100, "test", "orders", 1)
+	logs := newEventFilterTestTableInfo(101, "test", "logs", 1)
+	others := newEventFilterTestTableInfo(102, "test", "others", 1)
+	testCases := []struct {
+		tableInfo *model.TableInfo
+		preRow    map[int64]types.Datum
+		row       map[int64]types.Datum
+		skip      bool
+	}{
+		// insert
+		{orders, nil, newEventFilterTestDatums(1, "archived", 100), true},
+		{orders, nil, newEventFilterTestDatums(1, "pending", 100), false},
+		{others, nil, newEventFilterTestDatums(1, "archived", 100), false},
+		// update, both of the old value and new value expressions must be matched
+		{orders, newEventFilterTestDatums(1, "pending", 100), newEventFilterTestDatums(1, "archived", 100), true},
+		{orders, newEventFilterTestDatums(1, "paid", 100), newEventFilterTestDatums(1, "archived", 100), false},
+		{orders, newEventFilterTestDatums(1, "pending", 100), newEventFilterTestDatums(1, "paid", 100), false},
+		// delete, NULL is not regarded as true
+		{orders, newEventFilterTestDatums(1, "paid", 1), nil, true},
+		{orders, newEventFilterTestDatums(1, "paid", 100), nil, false},
+		{orders, newEventFilterTestDatums(1, "paid", nil), nil, false},
+		{logs, newEventFilterTestDatums(1, "paid", 100), nil, true},
+		{logs, nil, newEventFilterTestDatums(1, "paid", 100), false},
+	}
+	for i, tc := range testCases {
+		skip, err := f.ShouldSkipDMLEvent(tc.tableInfo, tc.preRow, tc.row)
+		c.Assert(err, check.IsNil)
+		c.Assert(skip, check.Equals, tc.skip, check.Commentf("case %d", i))
+	}
+
+	// the expressions are rebuilt after the schema of the table is changed
+	orders = newEventFilterTestTableInfo(100, "test", "orders", 2)
+	orders.Columns[1].Name = timodel.NewCIStr("state")
+	_, err = f.ShouldSkipDMLEvent(orders, nil, newEventFilterTestDatums(1, "archived", 100))
+	c.Assert(err, check.ErrorMatches, ".*ErrEventFilterExprInvalid.*")
+}
+
+func (s *eventFilterSuite) TestInvalidEventFilters(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{Matcher: []string{"test.*"}, IgnoreInsertValueExpr: "status = "},
+	}
+	_, err := NewFilter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*ErrFilterRuleInvalid.*")
+
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{Matcher: []string{"test.*"}, IgnoreDelete: true},
+	}
+	cfg.EnableOldValue = false
+	_, err = NewFilter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*event filters require enable-old-value.*")
+}
@@ -14,6 +14,8 @@
 package filter
 
 import (
+	"sync"
+
 	"github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
@@ -28,6 +30,12 @@ type Filter struct {
 	ignoreTxnStartTs []uint64
 	ddlAllowlist     []model.ActionType
 	isCyclicEnabled  bool
+	columnRules      []*columnRule
+	eventRules       []*eventRule
+
+	eventRulesMu sync.Mutex
+	// tableEventRules caches the event rules of the tables, keyed by table ID
+	tableEventRules map[int64]*tableEventRules
 }
 
 // NewFilter creates a filter
@@ -49,11 +57,22 @@ func NewFilter(cfg *config.ReplicaConfig) (*Filter, error) {
 	if !cfg.CaseSensitive {
 		f = filterV2.CaseInsensitive(f)
 	}
+	columnRules, err := newColumnRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	eventRules, err := newEventRules(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &Filter{
 		filter:           f,
 		ignoreTxnStartTs: cfg.Filter.IgnoreTxnStartTs,
 		ddlAllowlist:     cfg.Filter.DDLAllowlist,
 		isCyclicEnabled:  cfg.Cyclic.IsEnabled(),
+		columnRules:      columnRules,
+		eventRules:       eventRules,
+		tableEventRules:  make(map[int64]*tableEventRules),
 	}, nil
 }
 
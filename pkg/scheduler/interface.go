@@ -34,13 +34,22 @@ type Scheduler interface {
 	// DistributeTables distributes the new tables to the captures
 	// returns the operations of the new tables
 	DistributeTables(tableIDs map[model.TableID]model.Ts) map[model.CaptureID]map[model.TableID]*model.TableOperation
+	// SetPlacement sets the placement of the tables, the tables are only
+	// dispatched or moved to the captures allowed by the placement
+	SetPlacement(placement Placement)
 }
 
+// Placement returns whether the table can be replicated by the capture, a nil
+// Placement allows all the tables to be replicated by any capture
+type Placement func(tableID model.TableID, captureID model.CaptureID) bool
+
 // NewScheduler creates a new Scheduler
 func NewScheduler(tp string) Scheduler {
 	switch tp {
 	case "table-number":
 		return newTableNumberScheduler()
+	case "workload":
+		return newWorkloadScheduler()
 	default:
 		log.Info("invalid scheduler type, using default scheduler")
 		return newTableNumberScheduler()
@@ -18,6 +18,7 @@ import "github.com/pingcap/ticdc/cdc/model"
 // TableNumberScheduler provides a feature that scheduling by the table number
 type TableNumberScheduler struct {
 	workloads workloads
+	placement Placement
 }
 
 // newTableNumberScheduler creates a new table number scheduler
@@ -53,6 +54,16 @@ func (t *TableNumberScheduler) CalRebalanceOperates(targetSkewness float64) (
 	appendTables := make(map[model.TableID]model.Ts)
 	moveTableJobs = make(map[model.TableID]*model.MoveTableJob)
 
+	// the tables replicated by the captures not allowed by the placement are
+	// always moved
+	for tableID, captureID := range t.workloads.MisplacedTables(t.placement) {
+		appendTables[tableID] = 0
+		moveTableJobs[tableID] = &model.MoveTableJob{
+			From:    captureID,
+			TableID: tableID,
+		}
+		t.workloads.RemoveTable(captureID, tableID)
+	}
 	for captureID, captureWorkloads := range t.workloads {
 		for float64(len(captureWorkloads)) >= limitTableNumber {
 			for tableID := range captureWorkloads {
@@ -85,7 +96,7 @@ func (t *TableNumberScheduler) CalRebalanceOperates(targetSkewness float64) (
 func (t *TableNumberScheduler) DistributeTables(tableIDs map[model.TableID]model.Ts) map[model.CaptureID]map[model.TableID]*model.TableOperation {
 	result := make(map[model.CaptureID]map[model.TableID]*model.TableOperation, len(t.workloads))
 	for tableID, boundaryTs := range tableIDs {
-		captureID := t.workloads.SelectIdleCapture()
+		captureID := t.workloads.SelectIdleCaptureFor(tableID, t.placement)
 		operations := result[captureID]
 		if operations == nil {
 			operations = make(map[model.TableID]*model.TableOperation)
@@ -98,3 +109,8 @@ func (t *TableNumberScheduler) DistributeTables(tableIDs map[model.TableID]model
 	}
 	return result
 }
+
+// SetPlacement implements the Scheduler interface
+func (t *TableNumberScheduler) SetPlacement(placement Placement) {
+	t.placement = placement
+}
@@ -113,3 +113,40 @@ func (s *tableNumberSuite) TestCalRebalanceOperates(c *check.C) {
 	}
 	c.Assert(fmt.Sprintf("%.2f%%", skewness*100), check.Equals, "0.00%")
 }
+
+func (s *tableNumberSuite) TestPlacement(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler := newTableNumberScheduler()
+	// the tables larger than 100 are pinned to capture3
+	scheduler.SetPlacement(func(tableID model.TableID, captureID model.CaptureID) bool {
+		return tableID <= 100 || captureID == "capture3"
+	})
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1:   model.WorkloadInfo{Workload: 1},
+		101: model.WorkloadInfo{Workload: 1},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		2: model.WorkloadInfo{Workload: 1},
+	})
+	scheduler.ResetWorkloads("capture3", model.TaskWorkload{
+		3: model.WorkloadInfo{Workload: 1},
+	})
+	// the misplaced table is moved even if the captures are balanced
+	_, moveJobs := scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		101: {From: "capture1", To: "capture3", TableID: 101},
+	})
+
+	result := scheduler.DistributeTables(map[model.TableID]model.Ts{102: 1, 103: 2})
+	c.Assert(result, check.DeepEquals, map[model.CaptureID]map[model.TableID]*model.TableOperation{
+		"capture3": {102: {BoundaryTs: 1}, 103: {BoundaryTs: 2}},
+	})
+
+	// the table is dispatched to the idlest capture if no capture is allowed
+	scheduler.SetPlacement(func(tableID model.TableID, captureID model.CaptureID) bool {
+		return captureID == "capture4"
+	})
+	result = scheduler.DistributeTables(map[model.TableID]model.Ts{4: 1})
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result["capture3"], check.IsNil)
+}
@@ -103,6 +103,59 @@ func (w workloads) SelectIdleCapture() model.CaptureID {
 	return minCapture
 }
 
+// SelectIdleCaptureFor selects the idlest capture which the table can be
+// dispatched to, all the captures are considered if no capture is allowed
+func (w workloads) SelectIdleCaptureFor(tableID model.TableID, placement Placement) model.CaptureID {
+	if placement == nil {
+		return w.SelectIdleCapture()
+	}
+	minWorkload := uint64(math.MaxUint64)
+	var minCapture model.CaptureID
+	found := false
+	for captureID, captureWorkloads := range w {
+		if !placement(tableID, captureID) {
+			continue
+		}
+		var totalWorkloadInCapture uint64
+		for _, workload := range captureWorkloads {
+			totalWorkloadInCapture += workload.Workload
+		}
+		if !found || minWorkload > totalWorkloadInCapture {
+			minWorkload = totalWorkloadInCapture
+			minCapture = captureID
+			found = true
+		}
+	}
+	if !found {
+		return w.SelectIdleCapture()
+	}
+	return minCapture
+}
+
+// MisplacedTables returns the tables replicated by the captures which are not
+// allowed by the placement, the tables which no capture is allowed to
+// replicate are ignored
+func (w workloads) MisplacedTables(placement Placement) map[model.TableID]model.CaptureID {
+	misplaced := make(map[model.TableID]model.CaptureID)
+	if placement == nil {
+		return misplaced
+	}
+	for captureID, captureWorkloads := range w {
+		for tableID := range captureWorkloads {
+			if placement(tableID, captureID) {
+				continue
+			}
+			for otherCaptureID := range w {
+				if placement(tableID, otherCaptureID) {
+					misplaced[tableID] = captureID
+					break
+				}
+			}
+		}
+	}
+	return misplaced
+}
+
 func (w workloads) Clone() workloads {
 	cloneWorkloads := make(map[model.CaptureID]model.TaskWorkload, len(w))
 	for captureID, captureWorkloads := range w {
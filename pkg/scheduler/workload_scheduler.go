@@ -0,0 +1,248 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+const (
+	// regionWorkloadWeight is the workload of a region of the table, it's
+	// comparable to the event rate of a region in a common cluster
+	regionWorkloadWeight = 10
+	// rebalanceStartSkewness is the skewness the rebalance starts at, and
+	// rebalanceStopSkewness is the skewness the rebalance stops at. The gap
+	// between them avoids moving the tables back and forth when the workloads
+	// fluctuate slightly.
+	rebalanceStartSkewness = 0.2
+	rebalanceStopSkewness  = 0.1
+	// moveTableCooldown is the min interval of moving a table twice
+	moveTableCooldown = 5 * time.Minute
+)
+
+// WorkloadScheduler provides a feature that scheduling by the workloads of
+// the tables, the workload of a table is measured by its event rate and
+// region count.
+type WorkloadScheduler struct {
+	workloads workloads
+	// tableWeights remembers the measured workloads of the tables, a table is
+	// not measured for a while after being moved to another capture
+	tableWeights map[model.TableID]uint64
+	// lastMoveTime records the time the tables are moved by rebalance
+	lastMoveTime map[model.TableID]time.Time
+	placement    Placement
+	now          func() time.Time
+}
+
+// newWorkloadScheduler creates a new workload scheduler
+func newWorkloadScheduler() *WorkloadScheduler {
+	return &WorkloadScheduler{
+		workloads:    make(workloads),
+		tableWeights: make(map[model.TableID]uint64),
+		lastMoveTime: make(map[model.TableID]time.Time),
+		now:          time.Now,
+	}
+}
+
+// tableWeight calculates the workload of a table by the measured information
+func tableWeight(info model.WorkloadInfo) uint64 {
+	return 1 + info.EventRate + info.RegionCount*regionWorkloadWeight
+}
+
+// ResetWorkloads implements the Scheduler interface
+func (w *WorkloadScheduler) ResetWorkloads(captureID model.CaptureID, workloads model.TaskWorkload) {
+	weighted := make(model.TaskWorkload, len(workloads))
+	for tableID, info := range workloads {
+		weight, exist := w.tableWeights[tableID]
+		// the region count is zero only if the table is not measured yet
+		if info.RegionCount != 0 || !exist {
+			weight = tableWeight(info)
+			w.tableWeights[tableID] = weight
+		}
+		info.Workload = weight
+		weighted[tableID] = info
+	}
+	w.workloads.SetCapture(captureID, weighted)
+}
+
+// AlignCapture implements the Scheduler interface
+func (w *WorkloadScheduler) AlignCapture(captureIDs map[model.CaptureID]struct{}) {
+	w.workloads.AlignCapture(captureIDs)
+}
+
+// Skewness implements the Scheduler interface
+func (w *WorkloadScheduler) Skewness() float64 {
+	return w.workloads.Skewness()
+}
+
+// CalRebalanceOperates implements the Scheduler interface
+func (w *WorkloadScheduler) CalRebalanceOperates(targetSkewness float64) (
+	skewness float64, moveTableJobs map[model.TableID]*model.MoveTableJob) {
+	moveTableJobs = make(map[model.TableID]*model.MoveTableJob)
+	if len(w.workloads) == 0 {
+		return 0, moveTableJobs
+	}
+	now := w.now()
+	w.gc(now)
+
+	// the tables replicated by the captures not allowed by the placement are
+	// always moved, regardless of the skewness and the cooldown
+	for tableID, from := range w.workloads.MisplacedTables(w.placement) {
+		workload := w.workloads[from][tableID]
+		w.workloads.RemoveTable(from, tableID)
+		to := w.workloads.SelectIdleCaptureFor(tableID, w.placement)
+		w.workloads.SetTable(to, tableID, workload)
+		w.lastMoveTime[tableID] = now
+		moveTableJobs[tableID] = &model.MoveTableJob{
+			From:    from,
+			To:      to,
+			TableID: tableID,
+		}
+	}
+
+	skewness = w.Skewness()
+	if skewness <= rebalanceStartSkewness {
+		return
+	}
+	stopSkewness := math.Max(targetSkewness, rebalanceStopSkewness)
+	for skewness > stopSkewness {
+		from, to, tableID, found := w.selectTableToMove(now)
+		if !found {
+			break
+		}
+		workload := w.workloads[from][tableID]
+		w.workloads.RemoveTable(from, tableID)
+		w.workloads.SetTable(to, tableID, workload)
+		w.lastMoveTime[tableID] = now
+		moveTableJobs[tableID] = &model.MoveTableJob{
+			From:    from,
+			To:      to,
+			TableID: tableID,
+		}
+		skewness = w.Skewness()
+	}
+	return
+}
+
+// gc removes the information of the tables which are not replicated and the
+// moves which are out of the cooldown
+func (w *WorkloadScheduler) gc(now time.Time) {
+	tables := make(map[model.TableID]struct{})
+	for _, captureWorkloads := range w.workloads {
+		for tableID := range captureWorkloads {
+			tables[tableID] = struct{}{}
+		}
+	}
+	for tableID := range w.tableWeights {
+		if _, exist := tables[tableID]; !exist {
+			delete(w.tableWeights, tableID)
+		}
+	}
+	for tableID, moveTime := range w.lastMoveTime {
+		if now.Sub(moveTime) >= moveTableCooldown {
+			delete(w.lastMoveTime, tableID)
+		}
+	}
+}
+
+// selectTableToMove selects the table to move to the idlest capture which is
+// allowed to replicate it. Moving a table with workload w from a capture to
+// an idler one reduces the variance by w*(gap-w), where gap is the difference
+// of their workloads, so the table maximizing it is selected. The tables not
+// lighter than the gap are never selected, because moving them doesn't make
+// the captures less skewed.
+func (w *WorkloadScheduler) selectTableToMove(now time.Time) (
+	from, to model.CaptureID, tableID model.TableID, found bool) {
+	totals := make(map[model.CaptureID]uint64, len(w.workloads))
+	captureIDs := make([]model.CaptureID, 0, len(w.workloads))
+	for captureID, captureWorkloads := range w.workloads {
+		var total uint64
+		for _, workload := range captureWorkloads {
+			total += workload.Workload
+		}
+		totals[captureID] = total
+		captureIDs = append(captureIDs, captureID)
+	}
+	// sort the captures from the idlest one, so the first allowed capture is
+	// the idlest capture a table can be moved to
+	sort.Slice(captureIDs, func(i, j int) bool {
+		if totals[captureIDs[i]] != totals[captureIDs[j]] {
+			return totals[captureIDs[i]] < totals[captureIDs[j]]
+		}
+		return captureIDs[i] < captureIDs[j]
+	})
+	var maxReduction uint64
+	for captureID, captureWorkloads := range w.workloads {
+		for id, workload := range captureWorkloads {
+			if moveTime, exist := w.lastMoveTime[id]; exist && now.Sub(moveTime) < moveTableCooldown {
+				continue
+			}
+			target := captureID
+			for _, cid := range captureIDs {
+				if w.placement == nil || w.placement(id, cid) {
+					target = cid
+					break
+				}
+			}
+			if totals[target] >= totals[captureID] {
+				continue
+			}
+			gap := totals[captureID] - totals[target]
+			if workload.Workload >= gap {
+				continue
+			}
+			reduction := workload.Workload * (gap - workload.Workload)
+			if !found || reduction > maxReduction ||
+				(reduction == maxReduction && (id < tableID || (id == tableID && captureID < from))) {
+				from, to, tableID, maxReduction, found = captureID, target, id, reduction, true
+			}
+		}
+	}
+	return
+}
+
+// DistributeTables implements the Scheduler interface
+func (w *WorkloadScheduler) DistributeTables(tableIDs map[model.TableID]model.Ts) map[model.CaptureID]map[model.TableID]*model.TableOperation {
+	result := make(map[model.CaptureID]map[model.TableID]*model.TableOperation, len(w.workloads))
+	// the workloads of the new tables are unknown, estimate them by the average
+	avgWorkload := uint64(1)
+	for _, captureWorkloads := range w.workloads {
+		if len(captureWorkloads) != 0 {
+			avgWorkload = w.workloads.AvgEachTable()
+			break
+		}
+	}
+	for tableID, boundaryTs := range tableIDs {
+		captureID := w.workloads.SelectIdleCaptureFor(tableID, w.placement)
+		operations := result[captureID]
+		if operations == nil {
+			operations = make(map[model.TableID]*model.TableOperation)
+			result[captureID] = operations
+		}
+		operations[tableID] = &model.TableOperation{
+			BoundaryTs: boundaryTs,
+		}
+		w.workloads.SetTable(captureID, tableID, model.WorkloadInfo{Workload: avgWorkload})
+	}
+	return result
+}
+
+// SetPlacement implements the Scheduler interface
+func (w *WorkloadScheduler) SetPlacement(placement Placement) {
+	w.placement = placement
+}
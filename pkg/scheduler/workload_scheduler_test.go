@@ -0,0 +1,195 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type workloadSchedulerSuite struct{}
+
+var _ = check.Suite(&workloadSchedulerSuite{})
+
+func newWorkloadScheduler4Test() (*WorkloadScheduler, *time.Time) {
+	now := time.Unix(1600000000, 0)
+	scheduler := newWorkloadScheduler()
+	scheduler.now = func() time.Time { return now }
+	return scheduler, &now
+}
+
+func (s *workloadSchedulerSuite) TestResetWorkloads(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, _ := newWorkloadScheduler4Test()
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 1, EventRate: 100, RegionCount: 2},
+		2: model.WorkloadInfo{Workload: 1},
+	})
+	c.Assert(scheduler.workloads["capture1"][1].Workload, check.Equals, uint64(121))
+	c.Assert(scheduler.workloads["capture1"][2].Workload, check.Equals, uint64(1))
+
+	// the table 1 is moved to capture2 and not measured yet
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 1},
+	})
+	c.Assert(scheduler.workloads["capture2"][1].Workload, check.Equals, uint64(121))
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 1, EventRate: 50, RegionCount: 1},
+	})
+	c.Assert(scheduler.workloads["capture2"][1].Workload, check.Equals, uint64(61))
+}
+
+func (s *workloadSchedulerSuite) TestCalRebalanceOperates(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, _ := newWorkloadScheduler4Test()
+	// the weights are 1100, 600, 20 | 20, 20 | none
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{EventRate: 999, RegionCount: 10},
+		2: model.WorkloadInfo{EventRate: 499, RegionCount: 10},
+		3: model.WorkloadInfo{EventRate: 9, RegionCount: 1},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		4: model.WorkloadInfo{EventRate: 9, RegionCount: 1},
+		5: model.WorkloadInfo{EventRate: 9, RegionCount: 1},
+	})
+	scheduler.AlignCapture(map[model.CaptureID]struct{}{"capture1": {}, "capture2": {}, "capture3": {}})
+	skewness, moveJobs := scheduler.CalRebalanceOperates(0)
+	c.Assert(skewness, check.Equals, scheduler.Skewness())
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture1", To: "capture3", TableID: 1},
+		3: {From: "capture1", To: "capture2", TableID: 3},
+	})
+	c.Assert(scheduler.workloads["capture1"], check.HasLen, 1)
+	c.Assert(scheduler.workloads["capture2"], check.HasLen, 3)
+	c.Assert(scheduler.workloads["capture3"], check.HasLen, 1)
+}
+
+func (s *workloadSchedulerSuite) TestMoveTableCooldown(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, now := newWorkloadScheduler4Test()
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{EventRate: 189, RegionCount: 1},
+		2: model.WorkloadInfo{EventRate: 0, RegionCount: 1},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{})
+	_, moveJobs := scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture1", To: "capture2", TableID: 1},
+	})
+
+	// the weights are 11, 11 | 200, 202, moving the table 1 is the best
+	// choice, but it's moved recently
+	resetWorkloads := func() {
+		scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+			2: model.WorkloadInfo{EventRate: 0, RegionCount: 1},
+			4: model.WorkloadInfo{EventRate: 0, RegionCount: 1},
+		})
+		scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+			1: model.WorkloadInfo{EventRate: 189, RegionCount: 1},
+			3: model.WorkloadInfo{EventRate: 191, RegionCount: 1},
+		})
+	}
+	resetWorkloads()
+	_, moveJobs = scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		3: {From: "capture2", To: "capture1", TableID: 3},
+	})
+
+	*now = now.Add(moveTableCooldown)
+	resetWorkloads()
+	_, moveJobs = scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture2", To: "capture1", TableID: 1},
+	})
+}
+
+func (s *workloadSchedulerSuite) TestRebalanceHysteresis(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, _ := newWorkloadScheduler4Test()
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{EventRate: 119},
+		2: model.WorkloadInfo{EventRate: 9},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		3: model.WorkloadInfo{EventRate: 99},
+	})
+	// the captures are slightly skewed, no table is moved
+	skewness, moveJobs := scheduler.CalRebalanceOperates(0)
+	c.Assert(skewness, check.Greater, 0.0)
+	c.Assert(skewness, check.LessEqual, rebalanceStartSkewness)
+	c.Assert(moveJobs, check.HasLen, 0)
+}
+
+func (s *workloadSchedulerSuite) TestDistributeTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, _ := newWorkloadScheduler4Test()
+	scheduler.AlignCapture(map[model.CaptureID]struct{}{"capture1": {}, "capture2": {}})
+	// there is no table in the captures
+	result := scheduler.DistributeTables(map[model.TableID]model.Ts{1: 1, 2: 2})
+	c.Assert(result, check.HasLen, 2)
+
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{EventRate: 999},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		2: model.WorkloadInfo{EventRate: 9},
+		3: model.WorkloadInfo{EventRate: 9},
+	})
+	result = scheduler.DistributeTables(map[model.TableID]model.Ts{4: 4, 5: 5})
+	c.Assert(result, check.DeepEquals, map[model.CaptureID]map[model.TableID]*model.TableOperation{
+		"capture2": {4: {BoundaryTs: 4}, 5: {BoundaryTs: 5}},
+	})
+}
+
+func (s *workloadSchedulerSuite) TestNewScheduler(c *check.C) {
+	defer testleak.AfterTest(c)()
+	_, ok := NewScheduler("workload").(*WorkloadScheduler)
+	c.Assert(ok, check.IsTrue)
+	_, ok = NewScheduler("table-number").(*TableNumberScheduler)
+	c.Assert(ok, check.IsTrue)
+}
+
+func (s *workloadSchedulerSuite) TestPlacement(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler, _ := newWorkloadScheduler4Test()
+	// table 1 is pinned to capture1, and table 4 is not allowed in capture3
+	scheduler.SetPlacement(func(tableID model.TableID, captureID model.CaptureID) bool {
+		switch tableID {
+		case 1:
+			return captureID == "capture1"
+		case 4:
+			return captureID != "capture3"
+		}
+		return true
+	})
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{EventRate: 99, RegionCount: 1},
+		2: model.WorkloadInfo{EventRate: 49, RegionCount: 1},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		3: model.WorkloadInfo{EventRate: 9, RegionCount: 1},
+	})
+	scheduler.ResetWorkloads("capture3", model.TaskWorkload{
+		4: model.WorkloadInfo{EventRate: 9, RegionCount: 1},
+	})
+	_, moveJobs := scheduler.CalRebalanceOperates(0)
+	// table 4 is misplaced, and only table 2 can be moved off capture1
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		2: {From: "capture1", To: "capture3", TableID: 2},
+		4: {From: "capture3", To: "capture2", TableID: 4},
+	})
+}
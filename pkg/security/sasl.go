@@ -0,0 +1,113 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// SASLMechanism defines the SASL mechanism used to authenticate with a broker
+type SASLMechanism string
+
+// The supported SASL mechanisms, the values are the names used on the wire.
+const (
+	UnknownMechanism  SASLMechanism = ""
+	PlainMechanism    SASLMechanism = "PLAIN"
+	SCRAM256Mechanism SASLMechanism = "SCRAM-SHA-256"
+	SCRAM512Mechanism SASLMechanism = "SCRAM-SHA-512"
+	GSSAPIMechanism   SASLMechanism = "GSSAPI"
+)
+
+// SASLMechanismFromString converts the mechanism name to SASLMechanism, the
+// name is case insensitive.
+func SASLMechanismFromString(s string) (SASLMechanism, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case string(PlainMechanism):
+		return PlainMechanism, nil
+	case string(SCRAM256Mechanism):
+		return SCRAM256Mechanism, nil
+	case string(SCRAM512Mechanism):
+		return SCRAM512Mechanism, nil
+	case string(GSSAPIMechanism):
+		return GSSAPIMechanism, nil
+	default:
+		return UnknownMechanism, errors.Errorf("unknown SASL mechanism: %s", s)
+	}
+}
+
+// GSSAPIAuthType defines the way to obtain the kerberos credential
+type GSSAPIAuthType int
+
+// The supported kerberos authentication types
+const (
+	UnknownAuth GSSAPIAuthType = iota
+	UserAuth
+	KeyTabAuth
+)
+
+// GSSAPIAuthTypeFromString converts the auth type name to GSSAPIAuthType,
+// valid names are "user" and "keytab".
+func GSSAPIAuthTypeFromString(s string) (GSSAPIAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "user":
+		return UserAuth, nil
+	case "keytab":
+		return KeyTabAuth, nil
+	default:
+		return UnknownAuth, errors.Errorf("unknown GSSAPI auth type: %s", s)
+	}
+}
+
+// GSSAPI holds the kerberos configuration used by the GSSAPI mechanism
+type GSSAPI struct {
+	AuthType           GSSAPIAuthType `toml:"auth-type" json:"auth-type"`
+	KeyTabPath         string         `toml:"keytab-path" json:"keytab-path"`
+	KerberosConfigPath string         `toml:"kerberos-config-path" json:"kerberos-config-path"`
+	ServiceName        string         `toml:"service-name" json:"service-name"`
+	Username           string         `toml:"user" json:"user"`
+	Password           string         `toml:"password" json:"password"`
+	Realm              string         `toml:"realm" json:"realm"`
+	DisablePAFXFAST    bool           `toml:"disable-pa-fx-fast" json:"disable-pa-fx-fast"`
+}
+
+// SASL holds the necessary parameters to authenticate with SASL
+type SASL struct {
+	SASLUser      string        `toml:"sasl-user" json:"sasl-user"`
+	SASLPassword  string        `toml:"sasl-password" json:"sasl-password"`
+	SASLMechanism SASLMechanism `toml:"sasl-mechanism" json:"sasl-mechanism"`
+	GSSAPI        GSSAPI        `toml:"sasl-gssapi" json:"sasl-gssapi"`
+}
+
+// IsSASLEnabled checks whether SASL authentication is enabled or not.
+func (s *SASL) IsSASLEnabled() bool {
+	return s != nil && s.SASLMechanism != UnknownMechanism
+}
+
+// Redacted returns a copy of the SASL config with all secrets masked, it is
+// used for logging.
+func (s *SASL) Redacted() *SASL {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	if ret.SASLPassword != "" {
+		ret.SASLPassword = "******"
+	}
+	if ret.GSSAPI.Password != "" {
+		ret.GSSAPI.Password = "******"
+	}
+	return &ret
+}
@@ -0,0 +1,506 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/mysql"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultChunkSize     = 1000
+	defaultMaxReportRows = 100
+)
+
+// the types of the mismatching rows
+const (
+	RowMissing   = "missing-in-downstream"
+	RowExtra     = "extra-in-downstream"
+	RowDifferent = "different"
+)
+
+// Config is the configuration of a Verifier
+type Config struct {
+	// Filter selects the tables to verify and the columns to compare
+	Filter *filter.Filter
+	// ChunkSize is the number of the upstream rows of a chunk
+	ChunkSize int
+	// MaxReportRows is the max number of the mismatching rows reported for a table
+	MaxReportRows int
+}
+
+// Report is the result of the verification
+type Report struct {
+	Syncpoint *Syncpoint     `json:"syncpoint"`
+	Tables    []*TableReport `json:"tables"`
+}
+
+// InconsistentTables returns the number of the inconsistent tables
+func (r *Report) InconsistentTables() int {
+	count := 0
+	for _, t := range r.Tables {
+		if !t.IsConsistent() {
+			count++
+		}
+	}
+	return count
+}
+
+// TableReport is the result of the verification of a table
+type TableReport struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// Skipped is the reason why the table is not verified
+	Skipped        string         `json:"skipped,omitempty"`
+	Missing        bool           `json:"missing-in-downstream,omitempty"`
+	Chunks         int            `json:"chunks"`
+	Rows           int            `json:"rows"`
+	MismatchChunks []*ChunkReport `json:"mismatch-chunks,omitempty"`
+	MismatchRows   []*RowDiff     `json:"mismatch-rows,omitempty"`
+}
+
+// IsConsistent returns true if the table is the same in the upstream and the
+// downstream, a skipped table is regarded as consistent.
+func (t *TableReport) IsConsistent() bool {
+	return !t.Missing && len(t.MismatchChunks) == 0
+}
+
+// ChunkReport is a mismatching key range of a table, the range is
+// (LowerBound, UpperBound], nil means unbounded.
+type ChunkReport struct {
+	LowerBound         []string `json:"lower-bound"`
+	UpperBound         []string `json:"upper-bound"`
+	UpstreamCount      int      `json:"upstream-count"`
+	DownstreamCount    int      `json:"downstream-count"`
+	UpstreamChecksum   uint64   `json:"upstream-checksum"`
+	DownstreamChecksum uint64   `json:"downstream-checksum"`
+}
+
+// RowDiff is a mismatching row, the values are keyed by the column names
+type RowDiff struct {
+	Type       string                 `json:"type"`
+	Key        []string               `json:"key"`
+	Upstream   map[string]interface{} `json:"upstream,omitempty"`
+	Downstream map[string]interface{} `json:"downstream,omitempty"`
+}
+
+// Verifier compares the data of the upstream and the downstream at a syncpoint
+type Verifier struct {
+	cfg        *Config
+	upstream   *sql.DB
+	downstream *sql.DB
+}
+
+// NewVerifier creates a Verifier
+func NewVerifier(upstream, downstream *sql.DB, cfg *Config) *Verifier {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = defaultChunkSize
+	}
+	if cfg.MaxReportRows <= 0 {
+		cfg.MaxReportRows = defaultMaxReportRows
+	}
+	return &Verifier{cfg: cfg, upstream: upstream, downstream: downstream}
+}
+
+// tableInfo is the columns to compare of a table
+type tableInfo struct {
+	schema  string
+	table   string
+	columns []string
+	// keyOffsets are the offsets of the key columns in columns
+	keyOffsets []int
+}
+
+type row struct {
+	key    string
+	values [][]byte
+}
+
+// Verify reads every table chunk by chunk from the upstream at the primary ts
+// and from the downstream at the secondary ts, and compares the checksums of
+// the chunks.
+func (v *Verifier) Verify(ctx context.Context, sp *Syncpoint) (*Report, error) {
+	upConn, err := snapshotConn(ctx, v.upstream, sp.PrimaryTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer upConn.Close() //nolint:errcheck
+	downConn, err := snapshotConn(ctx, v.downstream, sp.SecondaryTs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer downConn.Close() //nolint:errcheck
+
+	tables, err := v.listTables(ctx, upConn)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	report := &Report{Syncpoint: sp}
+	for _, t := range tables {
+		tableReport, err := v.verifyTable(ctx, upConn, downConn, t[0], t[1])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		log.Info("table verified", zap.String("schema", t[0]), zap.String("table", t[1]),
+			zap.Bool("consistent", tableReport.IsConsistent()), zap.String("skipped", tableReport.Skipped))
+		report.Tables = append(report.Tables, tableReport)
+	}
+	return report, nil
+}
+
+// snapshotConn returns a connection reading the snapshot at ts
+func snapshotConn(ctx context.Context, db *sql.DB, ts uint64) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLConnectionError, err)
+	}
+	// the values of the TIMESTAMP columns depend on the time zone
+	for _, query := range []string{
+		fmt.Sprintf("SET @@tidb_snapshot = '%d'", ts),
+		"SET @@time_zone = '+00:00'",
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			conn.Close() //nolint:errcheck
+			return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+	}
+	return conn, nil
+}
+
+func (v *Verifier) listTables(ctx context.Context, conn *sql.Conn) ([][2]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT TABLE_SCHEMA, TABLE_NAME FROM information_schema.TABLES"+
+		" WHERE TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_SCHEMA, TABLE_NAME")
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	var tables [][2]string
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		if v.cfg.Filter.ShouldIgnoreTable(schema, table) {
+			continue
+		}
+		tables = append(tables, [2]string{schema, table})
+	}
+	return tables, cerror.WrapError(cerror.ErrMySQLQueryError, rows.Err())
+}
+
+// loadTableInfo loads the columns and the key of the table, the key is the
+// primary key or the first unique key whose columns are not null. It returns
+// the reason if the table can't be verified.
+func (v *Verifier) loadTableInfo(ctx context.Context, conn *sql.Conn, schema, table string) (*tableInfo, string, error) {
+	info := &tableInfo{schema: schema, table: table}
+	rows, err := conn.QueryContext(ctx, "SELECT COLUMN_NAME FROM information_schema.COLUMNS"+
+		" WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	offsets := make(map[string]int)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		if v.cfg.Filter.ShouldDropColumn(schema, table, name) {
+			continue
+		}
+		offsets[strings.ToLower(name)] = len(info.columns)
+		info.columns = append(info.columns, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+
+	rows, err = conn.QueryContext(ctx, "SELECT INDEX_NAME, COLUMN_NAME, NULLABLE FROM information_schema.STATISTICS"+
+		" WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0"+
+		" ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX", schema, table)
+	if err != nil {
+		return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	var indexName string
+	var keyOffsets []int
+	valid := false
+	for rows.Next() {
+		var index, column, nullable string
+		if err := rows.Scan(&index, &column, &nullable); err != nil {
+			return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		if index != indexName {
+			if valid && len(keyOffsets) != 0 {
+				break
+			}
+			indexName, keyOffsets, valid = index, nil, true
+		}
+		offset, ok := offsets[strings.ToLower(column)]
+		// the rows can't be located by a nullable or a dropped column
+		if !ok || nullable == "YES" {
+			valid = false
+			continue
+		}
+		keyOffsets = append(keyOffsets, offset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	if !valid || len(keyOffsets) == 0 {
+		return nil, "no primary key or not null unique key", nil
+	}
+	info.keyOffsets = keyOffsets
+	return info, "", nil
+}
+
+func (v *Verifier) verifyTable(ctx context.Context, upConn, downConn *sql.Conn, schema, table string) (*TableReport, error) {
+	report := &TableReport{Schema: schema, Table: table}
+	if v.cfg.Filter.HasEventFilter(schema, table) {
+		report.Skipped = "some rows may be dropped by the event filters"
+		return report, nil
+	}
+	info, reason, err := v.loadTableInfo(ctx, upConn, schema, table)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info == nil {
+		report.Skipped = reason
+		return report, nil
+	}
+
+	var lower []interface{}
+	for {
+		upRows, err := v.queryChunk(ctx, upConn, info, lower, nil, v.cfg.ChunkSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var upper []interface{}
+		last := len(upRows) < v.cfg.ChunkSize
+		if !last {
+			upper = keyValues(info, upRows[len(upRows)-1])
+		}
+		downRows, err := v.queryChunk(ctx, downConn, info, lower, upper, 0)
+		if isNoSuchTable(err) {
+			report.Missing = true
+			return report, nil
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		report.Chunks++
+		report.Rows += len(upRows)
+		v.compareChunk(report, info, lower, upper, upRows, downRows)
+		if last {
+			return report, nil
+		}
+		lower = upper
+	}
+}
+
+// queryChunk reads the rows whose keys are in (lower, upper], limit is ignored
+// if it's 0.
+func (v *Verifier) queryChunk(
+	ctx context.Context, conn *sql.Conn, info *tableInfo, lower, upper []interface{}, limit int,
+) ([]*row, error) {
+	columns := make([]string, len(info.columns))
+	for i, name := range info.columns {
+		columns[i] = quotes.QuoteName(name)
+	}
+	keys := make([]string, len(info.keyOffsets))
+	for i, offset := range info.keyOffsets {
+		keys[i] = columns[offset]
+	}
+	keyList := "(" + strings.Join(keys, ",") + ")"
+	holders := "(" + strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",") + ")"
+
+	var builder strings.Builder
+	builder.WriteString("SELECT " + strings.Join(columns, ",") + " FROM " + quotes.QuoteSchema(info.schema, info.table))
+	var conds []string
+	var args []interface{}
+	if lower != nil {
+		conds = append(conds, keyList+" > "+holders)
+		args = append(args, lower...)
+	}
+	if upper != nil {
+		conds = append(conds, keyList+" <= "+holders)
+		args = append(args, upper...)
+	}
+	if len(conds) != 0 {
+		builder.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	builder.WriteString(" ORDER BY " + strings.Join(keys, ","))
+	if limit > 0 {
+		builder.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+	}
+
+	rows, err := conn.QueryContext(ctx, builder.String(), args...)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	var result []*row
+	for rows.Next() {
+		values := make([][]byte, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		r := &row{values: values}
+		keys := make([][]byte, len(info.keyOffsets))
+		for i, offset := range info.keyOffsets {
+			keys[i] = values[offset]
+		}
+		r.key = encodeValues(keys)
+		result = append(result, r)
+	}
+	return result, cerror.WrapError(cerror.ErrMySQLQueryError, rows.Err())
+}
+
+func (v *Verifier) compareChunk(
+	report *TableReport, info *tableInfo, lower, upper []interface{}, upRows, downRows []*row,
+) {
+	upChecksum, downChecksum := checksum(upRows), checksum(downRows)
+	if len(upRows) == len(downRows) && upChecksum == downChecksum {
+		return
+	}
+	report.MismatchChunks = append(report.MismatchChunks, &ChunkReport{
+		LowerBound:         boundStrings(lower),
+		UpperBound:         boundStrings(upper),
+		UpstreamCount:      len(upRows),
+		DownstreamCount:    len(downRows),
+		UpstreamChecksum:   upChecksum,
+		DownstreamChecksum: downChecksum,
+	})
+
+	addDiff := func(diff *RowDiff) {
+		if len(report.MismatchRows) < v.cfg.MaxReportRows {
+			report.MismatchRows = append(report.MismatchRows, diff)
+		}
+	}
+	downIndex := make(map[string]*row, len(downRows))
+	for _, r := range downRows {
+		downIndex[r.key] = r
+	}
+	for _, up := range upRows {
+		down, ok := downIndex[up.key]
+		if !ok {
+			addDiff(&RowDiff{Type: RowMissing, Key: keyStrings(info, up), Upstream: rowMap(info, up)})
+			continue
+		}
+		delete(downIndex, up.key)
+		if encodeValues(up.values) != encodeValues(down.values) {
+			addDiff(&RowDiff{
+				Type: RowDifferent, Key: keyStrings(info, up),
+				Upstream: rowMap(info, up), Downstream: rowMap(info, down),
+			})
+		}
+	}
+	// keep the order of the extra rows
+	for _, down := range downRows {
+		if _, ok := downIndex[down.key]; ok {
+			addDiff(&RowDiff{Type: RowExtra, Key: keyStrings(info, down), Downstream: rowMap(info, down)})
+		}
+	}
+}
+
+// encodeValues encodes the values with the length prefixes, so NULL and the
+// empty value are different
+func encodeValues(values [][]byte) string {
+	var builder strings.Builder
+	var buf [binary.MaxVarintLen64]byte
+	for _, value := range values {
+		if value == nil {
+			builder.WriteByte(0)
+			continue
+		}
+		builder.WriteByte(1)
+		n := binary.PutUvarint(buf[:], uint64(len(value)))
+		builder.Write(buf[:n])
+		builder.Write(value)
+	}
+	return builder.String()
+}
+
+func checksum(rows []*row) uint64 {
+	h := fnv.New64a()
+	for _, r := range rows {
+		_, _ = h.Write([]byte(encodeValues(r.values)))
+	}
+	return h.Sum64()
+}
+
+func keyValues(info *tableInfo, r *row) []interface{} {
+	values := make([]interface{}, len(info.keyOffsets))
+	for i, offset := range info.keyOffsets {
+		values[i] = string(r.values[offset])
+	}
+	return values
+}
+
+func keyStrings(info *tableInfo, r *row) []string {
+	values := make([]string, len(info.keyOffsets))
+	for i, offset := range info.keyOffsets {
+		values[i] = string(r.values[offset])
+	}
+	return values
+}
+
+func boundStrings(bound []interface{}) []string {
+	if bound == nil {
+		return nil
+	}
+	values := make([]string, len(bound))
+	for i, value := range bound {
+		values[i] = value.(string)
+	}
+	return values
+}
+
+func rowMap(info *tableInfo, r *row) map[string]interface{} {
+	m := make(map[string]interface{}, len(info.columns))
+	for i, name := range info.columns {
+		if r.values[i] == nil {
+			m[name] = nil
+			continue
+		}
+		m[name] = string(r.values[i])
+	}
+	return m
+}
+
+func isNoSuchTable(err error) bool {
+	if err == nil {
+		return false
+	}
+	mysqlErr, ok := errors.Cause(err).(*dmysql.MySQLError)
+	return ok && mysqlErr.Number == mysql.ErrNoSuchTable
+}
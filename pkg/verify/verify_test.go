@@ -0,0 +1,199 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type verifySuite struct{}
+
+var _ = check.Suite(&verifySuite{})
+
+func newMockDB(c *check.C) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	return db, mock
+}
+
+func (s *verifySuite) TestLoadSyncpoint(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock := newMockDB(c)
+	defer db.Close() //nolint:errcheck
+
+	mock.ExpectQuery("SELECT primary_ts, secondary_ts FROM `tidb_cdc`.`syncpoint_v1` WHERE cf = ?" +
+		" ORDER BY CAST(primary_ts AS UNSIGNED) DESC LIMIT 1").
+		WithArgs("test-cf").
+		WillReturnRows(sqlmock.NewRows([]string{"primary_ts", "secondary_ts"}).
+			AddRow("424296185837240321", "424296186570719233"))
+	sp, err := LoadSyncpoint(ctx, db, "test-cf", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(sp, check.DeepEquals, &Syncpoint{PrimaryTs: 424296185837240321, SecondaryTs: 424296186570719233})
+
+	mock.ExpectQuery("SELECT primary_ts, secondary_ts FROM `tidb_cdc`.`syncpoint_v1` WHERE cf = ? AND primary_ts = ?").
+		WithArgs("test-cf", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"primary_ts", "secondary_ts"}))
+	_, err = LoadSyncpoint(ctx, db, "test-cf", 1)
+	c.Assert(err, check.ErrorMatches, ".*syncpoint of changefeed test-cf is not found.*")
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s *verifySuite) TestCheckTiDB(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	db, mock := newMockDB(c)
+	defer db.Close() //nolint:errcheck
+
+	mock.ExpectQuery("SELECT version()").
+		WillReturnRows(sqlmock.NewRows([]string{"version()"}).AddRow("5.7.25-TiDB-v5.0.0"))
+	c.Assert(CheckTiDB(ctx, db), check.IsNil)
+	mock.ExpectQuery("SELECT version()").
+		WillReturnRows(sqlmock.NewRows([]string{"version()"}).AddRow("8.0.23"))
+	c.Assert(CheckTiDB(ctx, db), check.ErrorMatches, ".*only TiDB can be verified.*8.0.23.*")
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s *verifySuite) TestOpenDB(c *check.C) {
+	defer testleak.AfterTest(c)()
+	for _, uri := range []string{
+		"mysql://root@127.0.0.1:4000/",
+		"tidb://root@127.0.0.1:4000/",
+		"mysql+ssl://root@127.0.0.1:4000/",
+		"tidb+ssl://root@127.0.0.1:4000/",
+	} {
+		db, err := OpenDB(uri)
+		c.Assert(err, check.IsNil, check.Commentf("%s", uri))
+		c.Assert(db.Close(), check.IsNil)
+	}
+	_, err := OpenDB("kafka://127.0.0.1:9092/")
+	c.Assert(err, check.ErrorMatches, ".*not supported by verify.*")
+	_, err = OpenDB("mysql://root@127.0.0.1:4000/?ssl-ca=not-exist.pem")
+	c.Assert(err, check.NotNil)
+}
+
+func expectSnapshot(mock sqlmock.Sqlmock, ts string) {
+	mock.ExpectExec("SET @@tidb_snapshot = '" + ts + "'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET @@time_zone = '+00:00'").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func (s *verifySuite) TestVerify(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	up, upMock := newMockDB(c)
+	defer up.Close() //nolint:errcheck
+	down, downMock := newMockDB(c)
+	defer down.Close() //nolint:errcheck
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = true
+	cfg.Filter.Rules = []string{"test.*"}
+	cfg.Filter.ColumnRules = []*config.ColumnRule{{Matcher: []string{"test.t1"}, IgnoreColumns: []string{"secret"}}}
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{Matcher: []string{"test.t4"}, IgnoreDelete: true}}
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+
+	expectSnapshot(upMock, "10")
+	expectSnapshot(downMock, "20")
+	upMock.ExpectQuery("SELECT TABLE_SCHEMA, TABLE_NAME FROM information_schema.TABLES" +
+		" WHERE TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_SCHEMA, TABLE_NAME").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_SCHEMA", "TABLE_NAME"}).
+			AddRow("mysql", "user").AddRow("other", "t1").
+			AddRow("test", "t1").AddRow("test", "t2").AddRow("test", "t3").AddRow("test", "t4"))
+
+	columnsQuery := "SELECT COLUMN_NAME FROM information_schema.COLUMNS" +
+		" WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION"
+	indexQuery := "SELECT INDEX_NAME, COLUMN_NAME, NULLABLE FROM information_schema.STATISTICS" +
+		" WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0" +
+		" ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX"
+	indexColumns := []string{"INDEX_NAME", "COLUMN_NAME", "NULLABLE"}
+
+	// test.t1 is verified in 2 chunks
+	upMock.ExpectQuery(columnsQuery).WithArgs("test", "t1").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("name").AddRow("secret"))
+	upMock.ExpectQuery(indexQuery).WithArgs("test", "t1").
+		WillReturnRows(sqlmock.NewRows(indexColumns).AddRow("PRIMARY", "id", ""))
+	rowColumns := []string{"id", "name"}
+	upMock.ExpectQuery("SELECT `id`,`name` FROM `test`.`t1` ORDER BY `id` LIMIT 2").
+		WillReturnRows(sqlmock.NewRows(rowColumns).AddRow("1", "a").AddRow("2", nil))
+	downMock.ExpectQuery("SELECT `id`,`name` FROM `test`.`t1` WHERE (`id`) <= (?) ORDER BY `id`").
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows(rowColumns).AddRow("1", "a").AddRow("2", nil))
+	upMock.ExpectQuery("SELECT `id`,`name` FROM `test`.`t1` WHERE (`id`) > (?) ORDER BY `id` LIMIT 2").
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows(rowColumns).AddRow("3", "c"))
+	downMock.ExpectQuery("SELECT `id`,`name` FROM `test`.`t1` WHERE (`id`) > (?) ORDER BY `id`").
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows(rowColumns).AddRow("3", "").AddRow("4", "d"))
+
+	// test.t2 has no key
+	upMock.ExpectQuery(columnsQuery).WithArgs("test", "t2").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("a").AddRow("b"))
+	upMock.ExpectQuery(indexQuery).WithArgs("test", "t2").
+		WillReturnRows(sqlmock.NewRows(indexColumns).AddRow("uk", "a", "YES"))
+
+	// test.t3 is missing in the downstream
+	upMock.ExpectQuery(columnsQuery).WithArgs("test", "t3").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("a").AddRow("b"))
+	upMock.ExpectQuery(indexQuery).WithArgs("test", "t3").
+		WillReturnRows(sqlmock.NewRows(indexColumns).
+			AddRow("uk1", "a", "").AddRow("uk1", "b", "YES").AddRow("uk2", "b", "").AddRow("uk2", "a", ""))
+	upMock.ExpectQuery("SELECT `a`,`b` FROM `test`.`t3` ORDER BY `b`,`a` LIMIT 2").
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b"}))
+	downMock.ExpectQuery("SELECT `a`,`b` FROM `test`.`t3` ORDER BY `b`,`a`").
+		WillReturnError(&dmysql.MySQLError{Number: mysql.ErrNoSuchTable})
+
+	v := NewVerifier(up, down, &Config{Filter: f, ChunkSize: 2})
+	report, err := v.Verify(ctx, &Syncpoint{PrimaryTs: 10, SecondaryTs: 20})
+	c.Assert(err, check.IsNil)
+	c.Assert(upMock.ExpectationsWereMet(), check.IsNil)
+	c.Assert(downMock.ExpectationsWereMet(), check.IsNil)
+
+	c.Assert(report.Tables, check.HasLen, 4)
+	c.Assert(report.InconsistentTables(), check.Equals, 2)
+	t1 := report.Tables[0]
+	c.Assert(t1.Chunks, check.Equals, 2)
+	c.Assert(t1.Rows, check.Equals, 3)
+	c.Assert(t1.MismatchChunks, check.HasLen, 1)
+	c.Assert(t1.MismatchChunks[0].LowerBound, check.DeepEquals, []string{"2"})
+	c.Assert(t1.MismatchChunks[0].UpperBound, check.IsNil)
+	c.Assert(t1.MismatchChunks[0].UpstreamCount, check.Equals, 1)
+	c.Assert(t1.MismatchChunks[0].DownstreamCount, check.Equals, 2)
+	c.Assert(t1.MismatchRows, check.DeepEquals, []*RowDiff{{
+		Type:       RowDifferent,
+		Key:        []string{"3"},
+		Upstream:   map[string]interface{}{"id": "3", "name": "c"},
+		Downstream: map[string]interface{}{"id": "3", "name": ""},
+	}, {
+		Type:       RowExtra,
+		Key:        []string{"4"},
+		Downstream: map[string]interface{}{"id": "4", "name": "d"},
+	}})
+	c.Assert(report.Tables[1].Skipped, check.Equals, "no primary key or not null unique key")
+	c.Assert(report.Tables[1].IsConsistent(), check.IsTrue)
+	c.Assert(report.Tables[2].Missing, check.IsTrue)
+	c.Assert(report.Tables[3].Skipped, check.Matches, ".*event filters.*")
+}